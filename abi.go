@@ -0,0 +1,98 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// ABI identifies the binary format of a protoc-gen-prost build.
+type ABI int
+
+const (
+	// ABIUnknown is an unrecognized binary.
+	ABIUnknown ABI = iota
+	// ABICoreModule is a core WebAssembly module using WASI preview1.
+	// This is the format of the embedded build.
+	ABICoreModule
+	// ABIComponent is a component-model binary (WASI preview2).
+	ABIComponent
+)
+
+// String returns the name of the ABI.
+func (a ABI) String() string {
+	switch a {
+	case ABICoreModule:
+		return "core-module"
+	case ABIComponent:
+		return "component"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the ABI as its name.
+func (a ABI) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes an ABI name. Unknown names decode as ABIUnknown.
+func (a *ABI) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "core-module":
+		*a = ABICoreModule
+	case "component":
+		*a = ABIComponent
+	default:
+		*a = ABIUnknown
+	}
+	return nil
+}
+
+// ErrComponentModelUnsupported is returned when compiling a component-model
+// build, which the runtime cannot host yet.
+var ErrComponentModelUnsupported = errors.New("component-model binaries are not supported by the runtime")
+
+// wasmMagic is the magic number at the start of every WebAssembly binary.
+var wasmMagic = []byte{0x00, 'a', 's', 'm'}
+
+// Version and layer fields following the magic number.
+var (
+	coreModulePreamble = []byte{0x01, 0x00, 0x00, 0x00}
+	componentLayer     = []byte{0x01, 0x00}
+)
+
+// DetectABI returns the ABI of a WebAssembly binary from its preamble.
+//
+// Core modules use version 1 and layer 0. Components use layer 1 with a
+// pre-release version number, which is not checked.
+func DetectABI(wasm []byte) (ABI, error) {
+	if len(wasm) < 8 || !bytes.Equal(wasm[:4], wasmMagic) {
+		return ABIUnknown, errors.New("not a WebAssembly binary")
+	}
+	switch {
+	case bytes.Equal(wasm[4:8], coreModulePreamble):
+		return ABICoreModule, nil
+	case bytes.Equal(wasm[6:8], componentLayer):
+		return ABIComponent, nil
+	default:
+		return ABIUnknown, fmt.Errorf("unrecognized WebAssembly preamble: % x", wasm[4:8])
+	}
+}
+
+// compileWASM compiles a protoc-gen-prost build, selecting the binding by ABI.
+func compileWASM(ctx context.Context, r wazero.Runtime, wasm []byte) (wazero.CompiledModule, error) {
+	abi, err := DetectABI(wasm)
+	if err != nil {
+		return nil, err
+	}
+	switch abi {
+	case ABICoreModule:
+		return r.CompileModule(ctx, wasm)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrComponentModelUnsupported, abi)
+	}
+}
@@ -0,0 +1,39 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestDetectABI(t *testing.T) {
+	abi, err := DetectABI(ProtocGenProstWASM())
+	if err != nil {
+		t.Fatalf("DetectABI failed: %v", err)
+	}
+	if abi != ABICoreModule {
+		t.Fatalf("expected %s, got %s", ABICoreModule, abi)
+	}
+
+	component := []byte{0x00, 'a', 's', 'm', 0x0d, 0x00, 0x01, 0x00}
+	if abi, err := DetectABI(component); err != nil || abi != ABIComponent {
+		t.Fatalf("expected %s, got %s: %v", ABIComponent, abi, err)
+	}
+
+	if _, err := DetectABI([]byte("not wasm")); err == nil {
+		t.Fatal("expected error for invalid binary")
+	}
+}
+
+func TestCompileWASM_Component(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	component := []byte{0x00, 'a', 's', 'm', 0x0d, 0x00, 0x01, 0x00}
+	if _, err := compileWASM(ctx, r, component); !errors.Is(err, ErrComponentModelUnsupported) {
+		t.Fatalf("expected ErrComponentModelUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FailureArtifactsStderrLimit is the amount of guest stderr kept for failure
+// artifacts. Older output is dropped first.
+const FailureArtifactsStderrLimit = 1 << 20
+
+// Files written to each failure artifact directory.
+const (
+	// FailureRequestFile holds the exact request bytes passed to Execute.
+	FailureRequestFile = "request.binpb"
+	// FailureErrorFile holds the error message.
+	FailureErrorFile = "error.txt"
+	// FailureStderrFile holds the guest stderr of the failed run.
+	FailureStderrFile = "stderr.txt"
+	// FailureInfoFile holds the FailureInfo as JSON.
+	FailureInfoFile = "info.json"
+	// FailureCrashFile holds the TrapError report if the guest trapped.
+	FailureCrashFile = "crash.txt"
+)
+
+// FailureInfo describes the environment of a failed call, written to
+// FailureInfoFile by WithFailureArtifacts.
+type FailureInfo struct {
+	// Time is when the call failed.
+	Time time.Time `json:"time"`
+	// Digest is the RequestDigest of the request.
+	Digest string `json:"digest"`
+	// PluginVersion is the version of the plugin module, if known.
+	PluginVersion string `json:"pluginVersion,omitempty"`
+	// Command is set if the module ran in command mode.
+	Command bool `json:"command,omitempty"`
+	// Options lists the options of the instance, e.g. "WithRetry(2, 100ms)".
+	Options []string `json:"options,omitempty"`
+	// GoVersion is the Go version of the host.
+	GoVersion string `json:"goVersion"`
+	// Platform is the host GOOS/GOARCH.
+	Platform string `json:"platform"`
+	// Error is the error message.
+	Error string `json:"error"`
+}
+
+// FailureArtifactsError is returned by Execute with WithFailureArtifacts if
+// the call failed and the artifacts were written.
+type FailureArtifactsError struct {
+	// Dir is the directory holding the artifacts.
+	Dir string
+	// Err is the error of the call.
+	Err error
+}
+
+// Error returns the error message.
+func (e *FailureArtifactsError) Error() string {
+	return fmt.Sprintf("%v (failure artifacts: %s)", e.Err, e.Dir)
+}
+
+// Unwrap returns the error of the call.
+func (e *FailureArtifactsError) Unwrap() error {
+	return e.Err
+}
+
+// WithFailureArtifacts writes a reproduction bundle to a new timestamped
+// directory under dir whenever Execute fails: the exact request bytes, the
+// error, the guest stderr of the failed run, a FailureInfo with the plugin
+// version and options, and the trap diagnostics if the guest trapped. The
+// error is then wrapped in a *FailureArtifactsError naming the directory, so
+// CI logs point at a bundle that can be attached to a bug report.
+//
+// Responses reporting a plugin error are not failures of Execute and are not
+// captured. Failures to write the artifacts are ignored.
+func WithFailureArtifacts(dir string) Option {
+	return func(o *options) {
+		o.failureDir = dir
+	}
+}
+
+// failureContextKey is the context key of the failureCapture of a call.
+type failureContextKey struct{}
+
+// failureCapture collects the guest stderr of a call.
+type failureCapture struct {
+	stderr []byte
+}
+
+// captureStderr records the stderr of the latest run in the capture of ctx.
+// Must be called with mu held.
+func (p *ProtocGenProst) captureStderr(ctx context.Context) {
+	if c, ok := ctx.Value(failureContextKey{}).(*failureCapture); ok && p.stderr != nil {
+		c.stderr = p.stderr.bytes()
+	}
+}
+
+// writeFailureArtifacts writes the artifacts of a failed call and returns
+// the wrapped error, or callErr if they could not be written.
+func (p *ProtocGenProst) writeFailureArtifacts(input []byte, capture *failureCapture, callErr error) error {
+	now := time.Now().UTC()
+	digest := RequestDigest(input).String()
+	dir := filepath.Join(p.opts.failureDir, now.Format("20060102T150405.000000000Z")+"-"+digest[:12])
+	info := &FailureInfo{
+		Time:          now,
+		Digest:        digest,
+		PluginVersion: p.version,
+		Command:       p.command,
+		Options:       p.opts.summary(),
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Error:         callErr.Error(),
+	}
+	infoJSON, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return callErr
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return callErr
+	}
+	files := map[string][]byte{
+		FailureRequestFile: input,
+		FailureErrorFile:   []byte(callErr.Error() + "\n"),
+		FailureStderrFile:  capture.stderr,
+		FailureInfoFile:    append(infoJSON, '\n'),
+	}
+	var trapErr *TrapError
+	if errors.As(callErr, &trapErr) {
+		var report bytes.Buffer
+		_ = trapErr.WriteReport(&report)
+		files[FailureCrashFile] = report.Bytes()
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return callErr
+		}
+	}
+	return &FailureArtifactsError{Dir: dir, Err: callErr}
+}
+
+// stderrBuffer keeps the latest output written to the guest stderr, up to
+// FailureArtifactsStderrLimit bytes.
+type stderrBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p, dropping the oldest output above the limit.
+func (b *stderrBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - FailureArtifactsStderrLimit; over > 0 {
+		b.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+// reset discards the buffered output.
+func (b *stderrBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// bytes returns a copy of the buffered output.
+func (b *stderrBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// summary describes the configured options for FailureInfo.
+func (o *options) summary() []string {
+	var s []string
+	add := func(enabled bool, format string, args ...any) {
+		if enabled {
+			s = append(s, fmt.Sprintf(format, args...))
+		}
+	}
+	add(o.cache != nil, "WithCache")
+	add(o.retries != 0, "WithRetry(%d, %s)", o.retries, o.retryBackoff)
+	add(o.fileFilter != nil, "WithFileFilter(%+v)", o.fileFilter)
+	add(o.sortResponse, "WithSortedResponse")
+	add(o.noBufferPool, "WithoutBufferPool")
+	add(o.checkCollisions, "WithCollisionCheck")
+	add(o.checkSyntax, "WithSyntaxCheck")
+	add(o.injectWKT, "WithWellKnownTypes")
+	add(o.prune, "WithPruning")
+	add(o.defaultParams != "", "WithDefaultParameters(%q)", o.defaultParams)
+	add(o.pristine, "WithPristineState")
+	add(o.maxExecutions != 0, "WithMaxExecutions(%d)", o.maxExecutions)
+	add(o.instanceTTL != 0, "WithInstanceTTL(%s)", o.instanceTTL)
+	add(o.memoryCapacity != 0, "WithMemoryCapacity(%d)", o.memoryCapacity)
+	add(o.listenerFactory != nil, "WithFunctionListenerFactory")
+	add(o.dumpDir != "", "WithDumpDir(%q)", o.dumpDir)
+	add(o.hardened, "WithHardenedSandbox")
+	if o.walltime != nil {
+		add(true, "WithFixedWalltime(%s)", o.walltime.Format(time.RFC3339Nano))
+	}
+	if o.nanotime != nil {
+		add(true, "WithFixedNanotime(%d)", *o.nanotime)
+	}
+	if o.randomSeed != nil {
+		add(true, "WithRandomSeed(%d)", *o.randomSeed)
+	}
+	add(o.wasiAudit != nil, "WithWASIAudit")
+	add(o.logger != nil, "WithLogger")
+	add(o.guestCache != nil, "WithGuestCache")
+	add(o.observer != nil, "WithObserver")
+	add(o.progress != nil, "WithProgress(%s)", o.progressInterval)
+	add(o.failureDir != "", "WithFailureArtifacts(%q)", o.failureDir)
+	add(len(o.interceptors) != 0, "WithInterceptors(%d)", len(o.interceptors))
+	return s
+}
@@ -0,0 +1,77 @@
+package prost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_WithFailureArtifacts(t *testing.T) {
+	wasm := buildCommandPlugin(t)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	dir := t.TempDir()
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithFailureArtifacts(dir), WithSortedResponse())
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if _, err := p.ExecuteRequest(ctx, &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"a.proto"}}); err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no artifacts for a successful call, got %d", len(entries))
+	}
+
+	input, err := proto.Marshal(&pluginpb.CodeGeneratorRequest{Parameter: proto.String("fail")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Execute(ctx, input)
+	var artErr *FailureArtifactsError
+	if !errors.As(err, &artErr) {
+		t.Fatalf("expected a FailureArtifactsError, got %v", err)
+	}
+	var trapErr *TrapError
+	if !errors.As(err, &trapErr) {
+		t.Fatalf("expected the trap to be wrapped, got %v", err)
+	}
+
+	request, err := os.ReadFile(filepath.Join(artErr.Dir, FailureRequestFile))
+	if err != nil || string(request) != string(input) {
+		t.Fatalf("expected the exact request bytes: %v", err)
+	}
+	stderr, err := os.ReadFile(filepath.Join(artErr.Dir, FailureStderrFile))
+	if err != nil || !strings.Contains(string(stderr), "requested failure") {
+		t.Fatalf("expected captured stderr, got %q: %v", stderr, err)
+	}
+	data, err := os.ReadFile(filepath.Join(artErr.Dir, FailureInfoFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info FailureInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if !info.Command || info.Digest != RequestDigest(input).String() || !strings.Contains(strings.Join(info.Options, ","), "WithSortedResponse") {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if _, err := os.Stat(filepath.Join(artErr.Dir, FailureErrorFile)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package prost
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASIAuditor is a FunctionListenerFactory logging every WASI host call.
+//
+// Each call is logged once when it returns, with the calling module, the
+// function name, its arguments by name and the returned errno. fd_write also
+// logs the number of bytes written. Calls that do not return, such as
+// proc_exit, are logged with the error that ended them.
+//
+// Attach it to the WASI host module, either with WithWASIAudit or by passing
+// it to experimental.WithFunctionListenerFactory when instantiating
+// wasi_snapshot_preview1 on a shared runtime.
+// Safe for concurrent use by multiple instances.
+type WASIAuditor struct {
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// params holds the arguments of the active call of each module.
+	// Host calls do not nest, so one entry per module suffices.
+	params map[api.Module][]uint64
+}
+
+// NewWASIAuditor creates a WASIAuditor logging to logger at info level.
+// If logger is nil, slog.Default is used.
+func NewWASIAuditor(logger *slog.Logger) *WASIAuditor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &WASIAuditor{logger: logger, params: make(map[api.Module][]uint64)}
+}
+
+// NewFunctionListener implements experimental.FunctionListenerFactory.
+// Only functions of the WASI host module are audited.
+func (a *WASIAuditor) NewFunctionListener(def api.FunctionDefinition) experimental.FunctionListener {
+	if def.ModuleName() != wasi_snapshot_preview1.ModuleName {
+		return nil
+	}
+	return a
+}
+
+// Before implements experimental.FunctionListener.
+func (a *WASIAuditor) Before(_ context.Context, mod api.Module, _ api.FunctionDefinition, params []uint64, _ experimental.StackIterator) {
+	a.mu.Lock()
+	a.params[mod] = append(a.params[mod][:0], params...)
+	a.mu.Unlock()
+}
+
+// After implements experimental.FunctionListener.
+func (a *WASIAuditor) After(ctx context.Context, mod api.Module, def api.FunctionDefinition, results []uint64) {
+	params := a.pop(mod)
+	attrs := a.callAttrs(mod, def, params)
+	if len(results) != 0 {
+		errno := uint32(results[0])
+		attrs = append(attrs, slog.Uint64("errno", uint64(errno)))
+		if def.Name() == "fd_write" && errno == 0 {
+			if n, ok := fdWriteLen(mod.Memory(), params); ok {
+				attrs = append(attrs, slog.Uint64("bytes", uint64(n)))
+			}
+		}
+	}
+	a.logger.LogAttrs(ctx, slog.LevelInfo, "wasi call", attrs...)
+}
+
+// Abort implements experimental.FunctionListener.
+func (a *WASIAuditor) Abort(ctx context.Context, mod api.Module, def api.FunctionDefinition, err error) {
+	params := a.pop(mod)
+	attrs := append(a.callAttrs(mod, def, params), slog.String("error", err.Error()))
+	a.logger.LogAttrs(ctx, slog.LevelInfo, "wasi call", attrs...)
+}
+
+// pop returns and removes the arguments of the active call of mod.
+func (a *WASIAuditor) pop(mod api.Module) []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	params := a.params[mod]
+	delete(a.params, mod)
+	return params
+}
+
+// callAttrs returns the log attributes identifying a call.
+func (a *WASIAuditor) callAttrs(mod api.Module, def api.FunctionDefinition, params []uint64) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(params)+4)
+	attrs = append(attrs, slog.String("module", mod.Name()), slog.String("function", def.Name()))
+	names := def.ParamNames()
+	args := make([]any, 0, 2*len(params))
+	for i, v := range params {
+		name := "$" + strconv.Itoa(i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		args = append(args, slog.Uint64(name, v))
+	}
+	return append(attrs, slog.Group("args", args...))
+}
+
+// fdWriteLen sums the iovec lengths passed to fd_write.
+func fdWriteLen(mem api.Memory, params []uint64) (uint32, bool) {
+	if mem == nil || len(params) < 3 {
+		return 0, false
+	}
+	iovs, count := uint32(params[1]), uint32(params[2])
+	data, ok := mem.Read(iovs, count*8)
+	if !ok {
+		return 0, false
+	}
+	var n uint32
+	for i := uint32(0); i < count; i++ {
+		n += binary.LittleEndian.Uint32(data[i*8+4:])
+	}
+	return n, true
+}
@@ -0,0 +1,57 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestWithWASIAudit(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	p, err := NewProtocGenProst(ctx, r, WithWASIAudit(logger))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// The embedded plugin seeds its hash maps from random_get.
+	var found bool
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry struct {
+			Msg      string            `json:"msg"`
+			Module   string            `json:"module"`
+			Function string            `json:"function"`
+			Args     map[string]uint64 `json:"args"`
+			Errno    *uint64           `json:"errno"`
+		}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("invalid log entry: %v", err)
+		}
+		if entry.Msg != "wasi call" || entry.Module != ProtocGenProstWASMFilename {
+			t.Fatalf("unexpected log entry %+v", entry)
+		}
+		if entry.Function == "random_get" {
+			found = true
+			if _, ok := entry.Args["buf_len"]; !ok || entry.Errno == nil || *entry.Errno != 0 {
+				t.Fatalf("unexpected random_get entry %+v", entry)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected random_get call in audit log:\n%s", buf.String())
+	}
+}
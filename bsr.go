@@ -0,0 +1,214 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DefaultBSRRemote is the Buf Schema Registry used by module references
+// without a remote.
+const DefaultBSRRemote = "buf.build"
+
+// BSRTokenEnv is the environment variable BSRClient reads the token from
+// if none is set, like the buf CLI. See BSRClient.Token for the format.
+const BSRTokenEnv = "BUF_TOKEN"
+
+// bsrGetImagePath is the Connect procedure returning the image of a module.
+const bsrGetImagePath = "/buf.alpha.registry.v1alpha1.ImageService/GetImage"
+
+// ModuleRef references a module of the Buf Schema Registry.
+type ModuleRef struct {
+	// Remote is the registry host, e.g. buf.build.
+	Remote string
+	// Owner is the user or organization owning the module.
+	Owner string
+	// Module is the module name.
+	Module string
+	// Ref is a label, tag or commit, or empty for the default label.
+	Ref string
+}
+
+// ParseModuleRef parses a module reference of the form
+// [remote/]owner/module[:ref], e.g. "buf.build/googleapis/googleapis:main".
+// The remote defaults to DefaultBSRRemote.
+func ParseModuleRef(s string) (ModuleRef, error) {
+	name, ref, _ := strings.Cut(s, ":")
+	parts := strings.Split(name, "/")
+	if len(parts) == 2 {
+		parts = append([]string{DefaultBSRRemote}, parts...)
+	}
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return ModuleRef{}, fmt.Errorf("invalid module reference %q: expected [remote/]owner/module[:ref]", s)
+	}
+	return ModuleRef{Remote: parts[0], Owner: parts[1], Module: parts[2], Ref: ref}, nil
+}
+
+// String returns the reference as remote/owner/module[:ref].
+func (r ModuleRef) String() string {
+	s := r.Remote + "/" + r.Owner + "/" + r.Module
+	if r.Ref != "" {
+		s += ":" + r.Ref
+	}
+	return s
+}
+
+// BSRError is returned by BSRClient if the registry rejected a call.
+type BSRError struct {
+	// StatusCode is the HTTP status code.
+	StatusCode int
+	// Code is the Connect error code, e.g. "not_found" or "unauthenticated".
+	Code string
+	// Message is the error message of the registry.
+	Message string
+}
+
+// Error returns the error message.
+func (e *BSRError) Error() string {
+	code := e.Code
+	if code == "" {
+		code = http.StatusText(e.StatusCode)
+	}
+	if e.Message == "" {
+		return "registry error: " + code
+	}
+	return fmt.Sprintf("registry error: %s: %s", code, e.Message)
+}
+
+// UnmarshalJSON decodes a Connect error.
+func (e *BSRError) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	e.Code, e.Message = v.Code, v.Message
+	return nil
+}
+
+// BSRClient downloads module images from the Buf Schema Registry, to
+// generate code for schemas that are not vendored locally.
+type BSRClient struct {
+	// Token authenticates the calls. Defaults to the BSRTokenEnv variable.
+	// Public modules need no token.
+	//
+	// Like the buf CLI, it is a comma-separated list of token@remote
+	// entries, each sent only to the registry of that remote. A token
+	// without a remote is only sent to DefaultBSRRemote, so references
+	// naming another host never receive it.
+	Token string
+	// BaseURL overrides the registry URL, https://<remote> by default.
+	BaseURL string
+	// HTTPClient makes the calls. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// FetchRequest downloads the image of a module, including its dependencies,
+// and returns it as a request generating the files of the module itself.
+func (c *BSRClient) FetchRequest(ctx context.Context, ref ModuleRef) (*pluginpb.CodeGeneratorRequest, error) {
+	body, err := json.Marshal(map[string]string{
+		"owner":      ref.Owner,
+		"repository": ref.Module,
+		"reference":  ref.Ref,
+	})
+	if err != nil {
+		return nil, err
+	}
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = "https://" + ref.Remote
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+bsrGetImagePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Connect-Protocol-Version", "1")
+	tokens := c.Token
+	if tokens == "" {
+		tokens = os.Getenv(BSRTokenEnv)
+	}
+	if token := bsrToken(tokens, ref.Remote); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bsrErr := &BSRError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(data, bsrErr)
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref, bsrErr)
+	}
+	req, err := decodeBSRImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image of %s: %w", ref, err)
+	}
+	return req, nil
+}
+
+// bsrToken returns the token of remote from a list of token@remote entries,
+// where a token without a remote belongs to DefaultBSRRemote.
+func bsrToken(tokens, remote string) string {
+	for _, entry := range strings.Split(tokens, ",") {
+		entry = strings.TrimSpace(entry)
+		token, host, scoped := strings.Cut(entry, "@")
+		if !scoped {
+			host = DefaultBSRRemote
+		}
+		if token != "" && host == remote {
+			return token
+		}
+	}
+	return ""
+}
+
+// decodeBSRImage converts a JSON GetImageResponse to a request.
+//
+// Image files are FileDescriptorProtos with a buf extension marking the
+// files of dependencies, which are only imported.
+func decodeBSRImage(data []byte) (*pluginpb.CodeGeneratorRequest, error) {
+	var resp struct {
+		Image struct {
+			File []json.RawMessage `json:"file"`
+		} `json:"image"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	for _, raw := range resp.Image.File {
+		f, err := decodeJSONImageFile(raw)
+		if err != nil {
+			return nil, err
+		}
+		req.ProtoFile = append(req.ProtoFile, f.fd)
+		if !f.isImport {
+			req.FileToGenerate = append(req.FileToGenerate, f.fd.GetName())
+		}
+	}
+	if len(req.FileToGenerate) == 0 {
+		return nil, errors.New("image contains no module files")
+	}
+	return req, nil
+}
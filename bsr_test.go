@@ -0,0 +1,84 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseModuleRef(t *testing.T) {
+	for s, want := range map[string]ModuleRef{
+		"acme/petapis":                  {Remote: DefaultBSRRemote, Owner: "acme", Module: "petapis"},
+		"buf.example.com/acme/api:v1.2": {Remote: "buf.example.com", Owner: "acme", Module: "api", Ref: "v1.2"},
+	} {
+		got, err := ParseModuleRef(s)
+		if err != nil || got != want {
+			t.Fatalf("%s: unexpected ref %+v: %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"petapis", "a/b/c/d", "acme/:main"} {
+		if _, err := ParseModuleRef(s); err == nil {
+			t.Fatalf("%s: expected error", s)
+		}
+	}
+}
+
+func TestBSRClient_FetchRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path != bsrGetImagePath:
+			http.NotFound(w, r)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"code":"unauthenticated","message":"missing token"}`)
+		case !strings.Contains(string(body), `"reference":"main"`):
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"code":"not_found"}`)
+		default:
+			io.WriteString(w, `{"image":{"file":[
+				{"name":"dep/dep.proto","package":"dep","syntax":"proto3","bufExtension":{"isImport":true}},
+				{"name":"acme/pet.proto","package":"acme","syntax":"proto3","dependency":["dep/dep.proto"],
+				 "bufExtension":{"moduleInfo":{"name":{"owner":"acme"}}}}
+			]}}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	ref, _ := ParseModuleRef("acme/petapis:main")
+	c := &BSRClient{BaseURL: srv.URL, Token: "secret"}
+	req, err := c.FetchRequest(ctx, ref)
+	if err != nil {
+		t.Fatalf("FetchRequest failed: %v", err)
+	}
+	if !slices.Equal(req.GetFileToGenerate(), []string{"acme/pet.proto"}) || len(req.GetProtoFile()) != 2 {
+		t.Fatalf("unexpected request: %v", req)
+	}
+
+	var bsrErr *BSRError
+	c.Token = "wrong"
+	if _, err := c.FetchRequest(ctx, ref); !errors.As(err, &bsrErr) || bsrErr.Code != "unauthenticated" || bsrErr.Message != "missing token" {
+		t.Fatalf("expected unauthenticated error, got %v", err)
+	}
+	// Tokens without a remote are only sent to the default registry.
+	c.Token = "secret"
+	other, _ := ParseModuleRef("evil.example/acme/petapis:main")
+	if _, err := c.FetchRequest(ctx, other); !errors.As(err, &bsrErr) || bsrErr.Code != "unauthenticated" {
+		t.Fatalf("expected the token to be withheld, got %v", err)
+	}
+	c.Token = "secret@evil.example"
+	if _, err := c.FetchRequest(ctx, other); err != nil {
+		t.Fatalf("FetchRequest with a scoped token failed: %v", err)
+	}
+	c.Token = "secret"
+	ref.Ref = "missing"
+	if _, err := c.FetchRequest(ctx, ref); !errors.As(err, &bsrErr) || bsrErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package prost
+
+import "sync"
+
+// maxPooledBufferSize is the largest buffer capacity kept in the pool.
+// Larger buffers are released to the GC to avoid pinning memory after
+// an unusually large generation.
+const maxPooledBufferSize = 16 << 20
+
+// bufferPool holds host-side buffers shared by all instances.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new([]byte)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+// Returns a new buffer if pooling is disabled.
+func (p *ProtocGenProst) getBuffer() *[]byte {
+	if p.opts.noBufferPool {
+		return new([]byte)
+	}
+	return bufferPool.Get().(*[]byte)
+}
+
+// putBuffer returns a buffer obtained from getBuffer to the pool.
+// The buffer must not be used after calling putBuffer.
+func (p *ProtocGenProst) putBuffer(buf *[]byte) {
+	if p.opts.noBufferPool || cap(*buf) > maxPooledBufferSize {
+		return
+	}
+	*buf = (*buf)[:0]
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,42 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_BufferPoolReuse(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithSortedResponse())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(minimalRequestInput(t), req); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Responses decoded from pooled buffers must not be corrupted by reuse
+	first, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	want := proto.Clone(first)
+	for i := 0; i < 5; i++ {
+		if _, err := p.ExecuteRequest(ctx, req); err != nil {
+			t.Fatalf("ExecuteRequest %d failed: %v", i, err)
+		}
+	}
+	if !proto.Equal(first, want) {
+		t.Fatal("response was modified by buffer reuse")
+	}
+}
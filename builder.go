@@ -0,0 +1,124 @@
+package prost
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RequestBuilder assembles a CodeGeneratorRequest from individual file descriptors.
+//
+// Files may be added in any order, for example from the raw descriptors
+// embedded in generated Go code. Build sorts them into dependency order and
+// checks that the set is complete and valid.
+type RequestBuilder struct {
+	files    map[string]*descriptorpb.FileDescriptorProto
+	order    []string
+	generate []string
+	params   string
+}
+
+// NewRequestBuilder creates a new empty RequestBuilder.
+func NewRequestBuilder() *RequestBuilder {
+	return &RequestBuilder{files: make(map[string]*descriptorpb.FileDescriptorProto)}
+}
+
+// AddRawFile adds a serialized FileDescriptorProto.
+func (b *RequestBuilder) AddRawFile(raw []byte) error {
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(raw, fd); err != nil {
+		return fmt.Errorf("failed to unmarshal file descriptor: %w", err)
+	}
+	return b.AddFile(fd)
+}
+
+// AddFile adds a FileDescriptorProto.
+// Adding a file with the same name twice is an error unless the contents are equal.
+func (b *RequestBuilder) AddFile(fd *descriptorpb.FileDescriptorProto) error {
+	name := fd.GetName()
+	if name == "" {
+		return fmt.Errorf("file descriptor has no name")
+	}
+	if existing, ok := b.files[name]; ok {
+		if !proto.Equal(existing, fd) {
+			return fmt.Errorf("%s: conflicting file descriptors", name)
+		}
+		return nil
+	}
+	b.files[name] = fd
+	b.order = append(b.order, name)
+	return nil
+}
+
+// Generate marks files to be generated.
+// If no files are marked, all added files are generated.
+func (b *RequestBuilder) Generate(names ...string) *RequestBuilder {
+	b.generate = append(b.generate, names...)
+	return b
+}
+
+// SetParameter sets the plugin parameter string.
+func (b *RequestBuilder) SetParameter(params string) *RequestBuilder {
+	b.params = params
+	return b
+}
+
+// Build assembles the request.
+// Returns an error if an import is missing or the descriptors do not link.
+func (b *RequestBuilder) Build() (*pluginpb.CodeGeneratorRequest, error) {
+	var sorted []*descriptorpb.FileDescriptorProto
+	state := make(map[string]int) // 1 = visiting, 2 = done
+	var visit func(name, from string) error
+	visit = func(name, from string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("%s: import cycle", name)
+		case 2:
+			return nil
+		}
+		fd, ok := b.files[name]
+		if !ok {
+			return fmt.Errorf("%s: missing import %s", from, name)
+		}
+		state[name] = 1
+		for _, dep := range fd.GetDependency() {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		sorted = append(sorted, fd)
+		return nil
+	}
+	for _, name := range b.order {
+		if err := visit(name, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: sorted}); err != nil {
+		return nil, fmt.Errorf("invalid file descriptors: %w", err)
+	}
+
+	generate := b.generate
+	if len(generate) == 0 {
+		generate = b.order
+	}
+	for _, name := range generate {
+		if _, ok := b.files[name]; !ok {
+			return nil, fmt.Errorf("%s: file to generate was not added", name)
+		}
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: append([]string(nil), generate...),
+		ProtoFile:      sorted,
+	}
+	if b.params != "" {
+		req.Parameter = proto.String(b.params)
+	}
+	return req, nil
+}
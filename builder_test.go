@@ -0,0 +1,47 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	typeFile := typepb.File_google_protobuf_type_proto
+	raw, err := proto.Marshal(protodesc.ToFileDescriptorProto(typeFile))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	b := NewRequestBuilder()
+	if err := b.AddRawFile(raw); err != nil {
+		t.Fatalf("AddRawFile failed: %v", err)
+	}
+	b.Generate(typeFile.Path())
+
+	// Missing imports are reported
+	if _, err := b.Build(); err == nil {
+		t.Fatal("expected error for missing imports")
+	}
+
+	// Add the imports after the file importing them
+	for i := 0; i < typeFile.Imports().Len(); i++ {
+		if err := b.AddFile(protodesc.ToFileDescriptorProto(typeFile.Imports().Get(i).FileDescriptor)); err != nil {
+			t.Fatalf("AddFile failed: %v", err)
+		}
+	}
+
+	req, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	files := req.GetProtoFile()
+	if files[len(files)-1].GetName() != typeFile.Path() {
+		t.Fatalf("expected %s last, got %s", typeFile.Path(), files[len(files)-1].GetName())
+	}
+	if len(req.GetFileToGenerate()) != 1 {
+		t.Fatalf("unexpected files to generate: %v", req.GetFileToGenerate())
+	}
+}
@@ -0,0 +1,107 @@
+package prost
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Digest is the content address of a generation request.
+type Digest [sha256.Size]byte
+
+// String returns the hex encoding of the digest.
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+// RequestDigest computes the digest for a serialized CodeGeneratorRequest.
+// The embedded plugin Version is included. It names requests in dumps,
+// failure artifacts and manifests; results are cached by CacheKey, which
+// also identifies the module.
+func RequestDigest(input []byte) Digest {
+	h := sha256.New()
+	h.Write([]byte(Version))
+	h.Write([]byte{0})
+	h.Write(input)
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// CacheKey returns the digest Execute uses as the WithCache key of a
+// serialized CodeGeneratorRequest. Unlike RequestDigest, it identifies the
+// module the instance runs, so instances built from different plugin
+// builds never share cached results: the WASM checksum for the embedded
+// build and GeneratorRegistry.Load, and otherwise a fingerprint of the
+// compiled module including its recorded version.
+func (p *ProtocGenProst) CacheKey(input []byte) Digest {
+	h := sha256.New()
+	h.Write([]byte(p.moduleID))
+	h.Write([]byte{0})
+	h.Write(input)
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// wasmModuleID identifies a module by the SHA-256 of its bytes.
+func wasmModuleID(wasm []byte) string {
+	sum := sha256.Sum256(wasm)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Cache stores serialized CodeGeneratorResponses keyed by request digest.
+//
+// Implementations must be safe for concurrent use. Backing the cache with a
+// shared store (e.g. Redis or S3) allows sharing results across machines.
+type Cache interface {
+	// Get returns the cached response for the digest.
+	// Returns false if the digest is not in the cache.
+	Get(ctx context.Context, digest Digest) ([]byte, bool, error)
+	// Put stores the response for the digest.
+	// Implementations must not modify or retain resp after returning.
+	Put(ctx context.Context, digest Digest, resp []byte) error
+}
+
+// DiskCache is a Cache storing responses as files in a local directory.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a new DiskCache rooted at dir.
+// The directory is created on the first Put if it does not exist.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// Get returns the cached response for the digest.
+func (c *DiskCache) Get(ctx context.Context, digest Digest) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(digest))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Put stores the response for the digest.
+// The file is written to a temporary file and renamed into place.
+func (c *DiskCache) Put(ctx context.Context, digest Digest, resp []byte) error {
+	return writeFileAtomic(c.path(digest), resp)
+}
+
+// path returns the file path for the digest.
+// Entries are sharded by the first byte of the digest.
+func (c *DiskCache) path(digest Digest) string {
+	key := digest.String()
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// _ is a type assertion
+var _ Cache = ((*DiskCache)(nil))
@@ -0,0 +1,90 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewDiskCache(t.TempDir())
+
+	digest := RequestDigest([]byte("request"))
+	if _, ok, err := c.Get(ctx, digest); err != nil || ok {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+	if err := c.Put(ctx, digest, []byte("response")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	data, ok, err := c.Get(ctx, digest)
+	if err != nil || !ok {
+		t.Fatalf("expected hit, got ok=%v err=%v", ok, err)
+	}
+	if string(data) != "response" {
+		t.Fatalf("unexpected cached data: %q", data)
+	}
+}
+
+func TestProtocGenProst_Cache(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	c := NewDiskCache(t.TempDir())
+	p, err := NewProtocGenProst(ctx, r, WithCache(c))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input := minimalRequestInput(t)
+	output, err := p.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	cached, ok, err := c.Get(ctx, p.CacheKey(input))
+	if err != nil || !ok {
+		t.Fatalf("expected result to be cached, got ok=%v err=%v", ok, err)
+	}
+	if !bytes.Equal(cached, output) {
+		t.Fatal("cached result does not match output")
+	}
+
+	// Close the module: a cache hit must not touch the plugin.
+	if err := p.mod.Close(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	again, err := p.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute from cache failed: %v", err)
+	}
+	if !bytes.Equal(again, output) {
+		t.Fatal("cache hit returned different output")
+	}
+}
+
+func TestProtocGenProst_CacheKey(t *testing.T) {
+	ctx := context.Background()
+	reg := NewGeneratorRegistry()
+	defer reg.Close(ctx)
+
+	// Builds differing only in code must not share cache entries.
+	for name, status := range map[string]int32{"a": 0, "b": 1} {
+		if err := reg.Load(ctx, name, "1.0.0", abiStubModule(status).Encode()); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+	}
+	a := reg.lookup("a@1.0.0").exec.(*ProtocGenProst)
+	b := reg.lookup("b@1.0.0").exec.(*ProtocGenProst)
+	input := []byte("request")
+	if a.CacheKey(input) == b.CacheKey(input) {
+		t.Fatal("expected different cache keys for different modules")
+	}
+	if a.CacheKey(input) == RequestDigest(input) {
+		t.Fatal("expected the cache key to identify the module")
+	}
+}
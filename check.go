@@ -0,0 +1,78 @@
+package prost
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FileStatus describes how a file on disk differs from the generated output.
+type FileStatus string
+
+const (
+	// FileMissing is a generated file absent from disk.
+	FileMissing FileStatus = "missing"
+	// FileChanged is a file on disk with different content.
+	FileChanged FileStatus = "changed"
+	// FileStale is a file listed in the marker that is no longer generated.
+	FileStale FileStatus = "stale"
+)
+
+// FileDiff is a file on disk that is out of date.
+type FileDiff struct {
+	// Name is the slash-separated path relative to the output directory.
+	Name string `json:"name"`
+	// Status is the kind of difference.
+	Status FileStatus `json:"status"`
+}
+
+// CheckDir compares the files WriteResponse would write with the contents of
+// dir without modifying it. Returns the out of date files sorted by name; an
+// empty result means dir is up to date. Stale files are only reported if
+// opts.RemoveStale is set.
+func CheckDir(dir string, resp *pluginpb.CodeGeneratorResponse, opts *WriteOptions) ([]FileDiff, error) {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	files, err := prepareFiles(resp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []FileDiff
+	current := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		current[f.Name] = struct{}{}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Name)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			diffs = append(diffs, FileDiff{Name: f.Name, Status: FileMissing})
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(data, []byte(f.Content)):
+			diffs = append(diffs, FileDiff{Name: f.Name, Status: FileChanged})
+		}
+	}
+
+	if opts.RemoveStale {
+		previous, err := readMarker(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range previous {
+			if _, ok := current[name]; !ok {
+				diffs = append(diffs, FileDiff{Name: name, Status: FileStale})
+			}
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs, nil
+}
@@ -0,0 +1,50 @@
+package prost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	resp := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("a.rs"), Content: proto.String("a\n")},
+		{Name: proto.String("b.rs"), Content: proto.String("b\n")},
+	}}
+	if err := WriteResponse(dir, resp, nil); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	opts := &WriteOptions{RemoveStale: true}
+	if diffs, err := CheckDir(dir, resp, opts); err != nil || len(diffs) != 0 {
+		t.Fatalf("expected up to date dir, got %v: %v", diffs, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.rs"), []byte("edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("a.rs"), Content: proto.String("a\n")},
+		{Name: proto.String("c.rs"), Content: proto.String("c\n")},
+	}}
+	diffs, err := CheckDir(dir, next, opts)
+	if err != nil {
+		t.Fatalf("CheckDir failed: %v", err)
+	}
+	want := []FileDiff{
+		{Name: "a.rs", Status: FileChanged},
+		{Name: "b.rs", Status: FileStale},
+		{Name: "c.rs", Status: FileMissing},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, diffs)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, diffs)
+		}
+	}
+}
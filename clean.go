@@ -0,0 +1,138 @@
+package prost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CleanOptions configures CleanDir.
+type CleanOptions struct {
+	// ManifestFilename is the path of the manifest relative to the directory.
+	// Defaults to DefaultManifestFilename. If the manifest does not exist,
+	// the marker file written by WriteResponse is used instead.
+	ManifestFilename string
+	// Force removes files modified since they were generated. By default
+	// they are kept and reported. Modifications are only detected with a
+	// manifest.
+	Force bool
+	// DryRun reports the files that would be removed without removing them.
+	DryRun bool
+}
+
+// CleanResult lists the files handled by CleanDir.
+type CleanResult struct {
+	// Removed lists the removed files, sorted by name.
+	Removed []string `json:"removed"`
+	// Modified lists the files that were kept because they were edited after
+	// generation, sorted by name.
+	Modified []string `json:"modified,omitempty"`
+}
+
+// CleanDir removes previously generated files from dir that are not in
+// current, using the manifest or marker file of the previous run to tell
+// generated files from hand-written ones. Pass nil to remove all generated
+// files. Directories left empty are removed.
+//
+// The manifest and marker are updated to list the remaining files, and
+// removed once no generated files remain.
+func CleanDir(dir string, current []string, opts *CleanOptions) (*CleanResult, error) {
+	if opts == nil {
+		opts = &CleanOptions{}
+	}
+	manifestName := opts.ManifestFilename
+	if manifestName == "" {
+		manifestName = DefaultManifestFilename
+	}
+	manifestPath := filepath.Join(dir, manifestName)
+
+	// Prefer the manifest, which records content hashes
+	var previous []ManifestFile
+	m, err := ReadManifest(manifestPath)
+	switch {
+	case err == nil:
+		previous = m.Files
+	case errors.Is(err, fs.ErrNotExist):
+		m = nil
+		names, err := readMarker(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			previous = append(previous, ManifestFile{Name: name})
+		}
+	default:
+		return nil, err
+	}
+
+	keep := make(map[string]struct{}, len(current))
+	for _, name := range current {
+		keep[name] = struct{}{}
+	}
+	res := &CleanResult{Removed: []string{}}
+	var remaining []ManifestFile
+	for _, f := range previous {
+		if _, ok := keep[f.Name]; ok || !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			remaining = append(remaining, f)
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.SHA256 != "" && !opts.Force {
+			if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != f.SHA256 {
+				res.Modified = append(res.Modified, f.Name)
+				remaining = append(remaining, f)
+				continue
+			}
+		}
+		res.Removed = append(res.Removed, f.Name)
+	}
+	sort.Strings(res.Removed)
+	sort.Strings(res.Modified)
+	if opts.DryRun {
+		return res, nil
+	}
+
+	if err := removeStale(dir, res.Removed, nil); err != nil {
+		return nil, err
+	}
+
+	remainingFiles := make([]ResolvedFile, len(remaining))
+	for i, f := range remaining {
+		remainingFiles[i] = ResolvedFile{Name: f.Name}
+	}
+	if len(remaining) == 0 {
+		for _, path := range []string{manifestPath, filepath.Join(dir, MarkerFilename)} {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	if m != nil {
+		m.Files = remaining
+		data, err := m.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileIfChanged(manifestPath, data); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, MarkerFilename)); err == nil {
+		if err := writeMarker(dir, remainingFiles); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestCleanDir(t *testing.T) {
+	dir := t.TempDir()
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a/a.pb.rs"), Content: proto.String("// a\n")},
+			{Name: proto.String("b/b.pb.rs"), Content: proto.String("// b\n")},
+			{Name: proto.String("c.pb.rs"), Content: proto.String("// c\n")},
+		},
+	}
+	if err := WriteResponse(dir, resp, &WriteOptions{ManifestFilename: DefaultManifestFilename}); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.pb.rs"), []byte("// edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib.rs"), []byte("// mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := CleanDir(dir, []string{"a/a.pb.rs"}, &CleanOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("CleanDir failed: %v", err)
+	}
+	if !reflect.DeepEqual(res.Removed, []string{"b/b.pb.rs"}) || !reflect.DeepEqual(res.Modified, []string{"c.pb.rs"}) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b", "b.pb.rs")); err != nil {
+		t.Fatal("dry run removed a file")
+	}
+
+	if _, err := CleanDir(dir, []string{"a/a.pb.rs"}, nil); err != nil {
+		t.Fatalf("CleanDir failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Fatalf("expected b/ to be removed, got %v", err)
+	}
+	m, err := ReadManifest(filepath.Join(dir, DefaultManifestFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 2 {
+		t.Fatalf("expected manifest to list the remaining files, got %+v", m.Files)
+	}
+
+	res, err = CleanDir(dir, nil, &CleanOptions{Force: true})
+	if err != nil {
+		t.Fatalf("CleanDir failed: %v", err)
+	}
+	if !reflect.DeepEqual(res.Removed, []string{"a/a.pb.rs", "c.pb.rs"}) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "lib.rs" {
+		t.Fatalf("expected only the hand-written file to remain, got %v", entries)
+	}
+}
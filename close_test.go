@@ -0,0 +1,101 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_ExecuteAfterClose(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestProtocGenProst_CloseWaitsForCalls(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+
+	// Simulate an in-flight call
+	if err := p.acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Close(ctx) }()
+
+	select {
+	case <-done:
+		t.Fatal("Close returned while a call was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if p.mod.IsClosed() {
+		t.Fatal("module closed while a call was in flight")
+	}
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed for new calls, got %v", err)
+	}
+
+	p.release()
+	if err := <-done; err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !p.mod.IsClosed() {
+		t.Fatal("expected module to be closed")
+	}
+}
+
+func TestProtocGenProst_CloseAbortsOnCancel(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	if err := p.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	mod := p.mod
+
+	closeCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	done := make(chan error, 1)
+	go func() { done <- p.Close(closeCtx) }()
+
+	// The in-flight instance is interrupted
+	deadline := time.Now().Add(time.Second)
+	for !mod.IsClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected in-flight instance to be interrupted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	p.release()
+	<-done
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	commands["bench"] = &command{
+		usage: "measure execute latency and throughput for a request",
+		run:   runBench,
+	}
+}
+
+// benchResult is the outcome of a benchmark.
+type benchResult struct {
+	Iterations  int     `json:"iterations"`
+	Concurrency int     `json:"concurrency"`
+	Runtime     string  `json:"runtime"`
+	RequestSize int     `json:"requestSize"`
+	Setup       string  `json:"setup"`
+	Elapsed     string  `json:"elapsed"`
+	Throughput  float64 `json:"throughput"`
+	P50         string  `json:"p50"`
+	P90         string  `json:"p90"`
+	P99         string  `json:"p99"`
+	Max         string  `json:"max"`
+}
+
+// runBench runs a request repeatedly on concurrent instances.
+func runBench(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost bench", stdio)
+	requestPath := fs.String("request", "", "request file, or - for stdin (required)")
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	iterations := fs.Int("iterations", 100, "number of timed executions")
+	concurrency := fs.Int("concurrency", 1, "number of instances executing in parallel")
+	warmup := fs.Int("warmup", 1, "untimed executions per instance before measuring")
+	pluginPath := fs.String("plugin", "", "plugin .wasm or .wasm.gz (default: the embedded plugin)")
+	interpreter := fs.Bool("interpreter", false, "use the wazero interpreter instead of the compiler")
+	jsonOut := fs.Bool("json", false, "write the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost bench --request req.binpb [--iterations N] [--concurrency M]")
+		fmt.Fprintln(fs.Output(), "\nReports latency percentiles and throughput. Each concurrent worker uses")
+		fmt.Fprintln(fs.Output(), "its own runtime and instance, like the members of a pool.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *requestPath == "" {
+		fs.Usage()
+		return inputError(errors.New("bench requires -request"))
+	}
+	if *iterations < 1 || *concurrency < 1 || *warmup < 0 {
+		return inputError(errors.New("-iterations and -concurrency must be positive, -warmup must not be negative"))
+	}
+	req, err := readRequestFile(stdio, *requestPath, prost.RequestFormat(*inputFormat))
+	if err != nil {
+		return err
+	}
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var wasm []byte
+	if *pluginPath != "" {
+		if wasm, err = readWASM(*pluginPath); err != nil {
+			return inputError(err)
+		}
+	}
+
+	cfg := wazero.NewRuntimeConfig()
+	runtimeName := "compiler"
+	if *interpreter {
+		cfg = wazero.NewRuntimeConfigInterpreter()
+		runtimeName = "interpreter"
+	}
+	b := &bench{cfg: cfg, wasm: wasm, input: input, warmup: *warmup}
+	latencies, setup, elapsed, err := b.run(ctx, *iterations, *concurrency)
+	if err != nil {
+		return err
+	}
+
+	slices.Sort(latencies)
+	round := func(d time.Duration) string { return d.Round(time.Microsecond).String() }
+	res := &benchResult{
+		Iterations:  *iterations,
+		Concurrency: *concurrency,
+		Runtime:     runtimeName,
+		RequestSize: len(input),
+		Setup:       round(setup),
+		Elapsed:     round(elapsed),
+		Throughput:  float64(*iterations) / elapsed.Seconds(),
+		P50:         round(percentile(latencies, 50)),
+		P90:         round(percentile(latencies, 90)),
+		P99:         round(percentile(latencies, 99)),
+		Max:         round(latencies[len(latencies)-1]),
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(stdio.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	fmt.Fprintf(stdio.out, "%d iterations, concurrency %d, %s, %d byte request\n", res.Iterations, res.Concurrency, res.Runtime, res.RequestSize)
+	fmt.Fprintf(stdio.out, "setup       %s\n", res.Setup)
+	fmt.Fprintf(stdio.out, "elapsed     %s\n", res.Elapsed)
+	fmt.Fprintf(stdio.out, "throughput  %.1f req/s\n", res.Throughput)
+	fmt.Fprintf(stdio.out, "latency     p50 %s, p90 %s, p99 %s, max %s\n", res.P50, res.P90, res.P99, res.Max)
+	return nil
+}
+
+// bench runs the benchmark workers.
+type bench struct {
+	cfg wazero.RuntimeConfig
+	// wasm is the plugin build, or nil for the embedded plugin.
+	wasm   []byte
+	input  []byte
+	warmup int
+}
+
+// run executes iterations requests on concurrency workers. Returns the
+// latencies, the slowest worker setup time, and the wall time of the timed
+// phase.
+func (b *bench) run(ctx context.Context, iterations, concurrency int) ([]time.Duration, time.Duration, time.Duration, error) {
+	type worker struct {
+		p     *prost.ProtocGenProst
+		close func()
+	}
+	workers := make([]worker, concurrency)
+	defer func() {
+		for _, w := range workers {
+			if w.close != nil {
+				w.close()
+			}
+		}
+	}()
+	var maxSetup time.Duration
+	for i := range workers {
+		start := time.Now()
+		p, closeFn, err := b.newInstance(ctx)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		workers[i] = worker{p: p, close: closeFn}
+		for range b.warmup {
+			if _, err := p.Execute(ctx, b.input); err != nil {
+				return nil, 0, 0, err
+			}
+		}
+		maxSetup = max(maxSetup, time.Since(start))
+	}
+
+	latencies := make([]time.Duration, iterations)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	start := time.Now()
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := int(next.Add(1)) - 1
+				if n >= iterations {
+					return
+				}
+				callStart := time.Now()
+				if _, err := w.p.Execute(ctx, b.input); err != nil {
+					errs[i] = err
+					return
+				}
+				latencies[n] = time.Since(callStart)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if err := errors.Join(errs...); err != nil {
+		return nil, 0, 0, err
+	}
+	return latencies, maxSetup, elapsed, nil
+}
+
+// newInstance creates an instance in a new runtime.
+// The returned function closes both.
+func (b *bench) newInstance(ctx context.Context) (*prost.ProtocGenProst, func(), error) {
+	r := wazero.NewRuntimeWithConfig(ctx, b.cfg)
+	var p *prost.ProtocGenProst
+	var err error
+	if b.wasm == nil {
+		p, err = prost.NewProtocGenProst(ctx, r)
+	} else {
+		var compiled wazero.CompiledModule
+		if compiled, err = r.CompileModule(ctx, b.wasm); err == nil {
+			p, err = prost.NewProtocGenProstWithModule(ctx, r, compiled)
+		}
+	}
+	if err != nil {
+		r.Close(ctx)
+		return nil, nil, err
+	}
+	return p, func() {
+		p.Close(ctx)
+		r.Close(ctx)
+	}, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	idx := (len(sorted)*pct + 99) / 100
+	return sorted[max(idx-1, 0)]
+}
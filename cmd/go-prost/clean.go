@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["clean"] = &command{
+		usage: "remove generated files that are no longer produced",
+		run:   runClean,
+	}
+}
+
+// runClean removes stale generated files from an output directory.
+func runClean(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost clean", stdio)
+	out := fs.String("out", "", "output directory to clean (required)")
+	all := fs.Bool("all", false, "remove all generated files instead of reading a request")
+	opts := &prost.CleanOptions{}
+	fs.StringVar(&opts.ManifestFilename, "manifest", prost.DefaultManifestFilename, "manifest path relative to the output directory")
+	fs.BoolVar(&opts.Force, "force", false, "also remove generated files edited since generation")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "list the files to remove without removing them")
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost clean --out <dir> [flags] < request")
+		fmt.Fprintln(fs.Output(), "       go-prost clean --out <dir> --all [flags]")
+		fmt.Fprintln(fs.Output(), "\nRemoves files listed in the manifest (or marker) of the previous run that")
+		fmt.Fprintln(fs.Output(), "the request no longer generates. Hand-written files are never touched.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *out == "" {
+		fs.Usage()
+		return inputError(errors.New("clean requires -out"))
+	}
+
+	var current []string
+	if !*all {
+		input, err := io.ReadAll(stdio.in)
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+		req, err := prost.UnmarshalRequest(input, prost.RequestFormat(*inputFormat))
+		if err != nil {
+			return inputError(err)
+		}
+
+		r := wazero.NewRuntime(ctx)
+		defer r.Close(ctx)
+		p, err := prost.NewProtocGenProst(ctx, r)
+		if err != nil {
+			return err
+		}
+		defer p.Close(ctx)
+
+		resp, err := p.ExecuteRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		if msg := resp.GetError(); msg != "" {
+			return &prost.PluginError{Message: msg}
+		}
+		current = responseFileNames(resp)
+	}
+
+	res, err := prost.CleanDir(*out, current, opts)
+	if err != nil {
+		return err
+	}
+	verb := "removed"
+	if opts.DryRun {
+		verb = "would remove"
+	}
+	for _, name := range res.Removed {
+		fmt.Fprintf(stdio.out, "%s %s\n", verb, name)
+	}
+	for _, name := range res.Modified {
+		fmt.Fprintf(stdio.err, "go-prost: kept %s: edited since generation (use -force)\n", name)
+	}
+	return nil
+}
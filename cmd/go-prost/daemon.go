@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["daemon"] = &command{
+		usage: "serve JSON-RPC over stdio for editor tooling",
+		run:   runDaemon,
+	}
+}
+
+// runDaemon serves prost.ServeJSONRPC on stdin and stdout.
+func runDaemon(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost daemon", stdio)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost daemon")
+		fmt.Fprintln(fs.Output(), "\nServes newline-delimited JSON-RPC 2.0 on stdin/stdout.")
+		fmt.Fprintln(fs.Output(), "Methods: generate, verify, shutdown.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+
+	return prost.ServeJSONRPC(ctx, p, stdio.in, stdio.out)
+}
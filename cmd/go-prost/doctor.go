@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"golang.org/x/sys/cpu"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func init() {
+	commands["doctor"] = &command{
+		usage: "report runtime, performance and cache health",
+		run:   runDoctor,
+	}
+}
+
+// Doctor check statuses.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the outcome of one doctor check.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// runDoctor reports on the environment go-prost runs in.
+func runDoctor(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost doctor", stdio)
+	cacheDir := fs.String("cache-dir", defaultCacheDir(), "cache directory to check")
+	runs := fs.Int("runs", 5, "number of warm executions to time")
+	jsonOut := fs.Bool("json", false, "write the checks as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost doctor [flags]")
+		fmt.Fprintln(fs.Output(), "\nReports the runtime backend, execute times, embedded WASM checksum")
+		fmt.Fprintln(fs.Output(), "and cache directory health, e.g. to attach to performance reports.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *runs < 1 {
+		return inputError(errors.New("-runs must be at least 1"))
+	}
+
+	checks := []doctorCheck{
+		{Name: "platform", Status: checkOK, Detail: fmt.Sprintf("%s/%s, %s, plugin %s", runtime.GOOS, runtime.GOARCH, runtime.Version(), prost.Version)},
+		compilerCheck(),
+		embeddedCheck(),
+	}
+	checks = append(checks, timingChecks(ctx, *runs)...)
+	checks = append(checks, cacheDirCheck(*cacheDir))
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdio.out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(checks); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range checks {
+			fmt.Fprintf(stdio.out, "%-4s  %-13s %s\n", c.Status, c.Name, c.Detail)
+		}
+	}
+	for _, c := range checks {
+		if c.Status == checkFail {
+			return &exitError{code: exitInternal, err: errors.New("doctor found problems")}
+		}
+	}
+	return nil
+}
+
+// defaultCacheDir returns the conventional go-prost cache directory.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-prost")
+}
+
+// compilerCheck reports whether wazero can use its compiler on this
+// platform. wazero does not export the check, so its conditions are
+// mirrored here.
+func compilerCheck() doctorCheck {
+	var supported bool
+	switch runtime.GOARCH {
+	case "arm64":
+		supported = slices.Contains([]string{"linux", "darwin", "freebsd", "netbsd", "windows"}, runtime.GOOS)
+	case "amd64":
+		supported = slices.Contains([]string{"linux", "darwin", "freebsd", "netbsd", "windows", "dragonfly", "solaris", "illumos"}, runtime.GOOS) &&
+			cpu.X86.HasSSE41
+	}
+	if !supported {
+		return doctorCheck{Name: "compiler", Status: checkWarn, Detail: "not supported, using the interpreter (expect 10x slower execution)"}
+	}
+	return doctorCheck{Name: "compiler", Status: checkOK, Detail: "native compiler"}
+}
+
+// embeddedCheck verifies the embedded WASM checksum.
+func embeddedCheck() doctorCheck {
+	if err := prost.VerifyEmbeddedWASM(); err != nil {
+		return doctorCheck{Name: "embedded wasm", Status: checkFail, Detail: err.Error()}
+	}
+	return doctorCheck{Name: "embedded wasm", Status: checkOK, Detail: "sha256 " + prost.WASMSHA256[:16]}
+}
+
+// timingChecks measures compilation, the first execution on a fresh
+// instance, and the median of runs warm executions.
+func timingChecks(ctx context.Context, runs int) []doctorCheck {
+	fail := func(name string, err error) []doctorCheck {
+		return []doctorCheck{{Name: name, Status: checkFail, Detail: err.Error()}}
+	}
+	input, err := proto.Marshal(doctorRequest())
+	if err != nil {
+		return fail("execute", err)
+	}
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	start := time.Now()
+	compiled, err := prost.CompileProtocGenProst(ctx, r)
+	if err != nil {
+		return fail("compile", err)
+	}
+	compileTime := time.Since(start)
+
+	start = time.Now()
+	p, err := prost.NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		return fail("cold execute", err)
+	}
+	defer p.Close(ctx)
+	if _, err := p.Execute(ctx, input); err != nil {
+		return fail("cold execute", err)
+	}
+	coldTime := time.Since(start)
+
+	warm := make([]time.Duration, runs)
+	for i := range warm {
+		start = time.Now()
+		if _, err := p.Execute(ctx, input); err != nil {
+			return fail("warm execute", err)
+		}
+		warm[i] = time.Since(start)
+	}
+	slices.Sort(warm)
+
+	return []doctorCheck{
+		{Name: "compile", Status: checkOK, Detail: compileTime.Round(time.Millisecond).String()},
+		{Name: "cold execute", Status: checkOK, Detail: coldTime.Round(time.Microsecond).String() + " (instantiate and first run)"},
+		{Name: "warm execute", Status: checkOK, Detail: fmt.Sprintf("%s (median of %d)", warm[len(warm)/2].Round(time.Microsecond), runs)},
+	}
+}
+
+// doctorRequest returns a small request with one message.
+func doctorRequest() *pluginpb.CodeGeneratorRequest {
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"doctor.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("doctor.proto"),
+			Package: proto.String("doctor"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Ping"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("id"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					JsonName: proto.String("id"),
+				}},
+			}},
+		}},
+	}
+}
+
+// cacheDirCheck checks that dir exists and is writable. A missing directory
+// is fine since caches create it on first use.
+func cacheDirCheck(dir string) doctorCheck {
+	c := doctorCheck{Name: "cache dir"}
+	if dir == "" {
+		c.Status, c.Detail = checkWarn, "no user cache directory"
+		return c
+	}
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.Status, c.Detail = checkOK, dir+" (not created yet)"
+		return c
+	case err != nil:
+		c.Status, c.Detail = checkFail, err.Error()
+		return c
+	case !info.IsDir():
+		c.Status, c.Detail = checkFail, dir+" is not a directory"
+		return c
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		c.Status, c.Detail = checkFail, dir+" is not writable: "+err.Error()
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.Status, c.Detail = checkOK, dir+" (writable)"
+	return c
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errReported is returned after errors were already written in the selected
+// error format, so main does not print them again.
+var errReported = errors.New("errors reported")
+
+// Error formats accepted by -error-format.
+const (
+	errorFormatText   = "text"
+	errorFormatJSON   = "json"
+	errorFormatGitHub = "github"
+)
+
+// diagnostic is an error, optionally located in a proto file.
+type diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// diagnosticPattern matches "file.proto:line:col: message" with the line and
+// column optional, as printed by protoc and most plugins.
+var diagnosticPattern = regexp.MustCompile(`^(\S+\.proto)(?::(\d+))?(?::(\d+))?:\s*(.*)$`)
+
+// parseDiagnostics splits a plugin error message into diagnostics, one per
+// non-empty line. Lines without a location are attributed to the first of
+// files they mention.
+func parseDiagnostics(msg string, files []string) []diagnostic {
+	var diags []diagnostic
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := diagnosticPattern.FindStringSubmatch(line); m != nil {
+			d := diagnostic{File: m[1], Message: m[4]}
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Column, _ = strconv.Atoi(m[3])
+			diags = append(diags, d)
+			continue
+		}
+		d := diagnostic{Message: line}
+		for _, f := range files {
+			if strings.Contains(line, f) {
+				d.File = f
+				break
+			}
+		}
+		diags = append(diags, d)
+	}
+	if len(diags) == 0 {
+		diags = append(diags, diagnostic{Message: msg})
+	}
+	return diags
+}
+
+// checkErrorFormat validates an -error-format value.
+func checkErrorFormat(format string) error {
+	switch format {
+	case errorFormatText, errorFormatJSON, errorFormatGitHub:
+		return nil
+	default:
+		return fmt.Errorf("unknown error format: %q", format)
+	}
+}
+
+// writeDiagnostics writes diags to w in the given error format.
+func writeDiagnostics(w io.Writer, format string, diags []diagnostic) error {
+	for _, d := range diags {
+		var err error
+		switch format {
+		case errorFormatJSON:
+			err = json.NewEncoder(w).Encode(d)
+		case errorFormatGitHub:
+			_, err = io.WriteString(w, githubAnnotation(d)+"\n")
+		default:
+			loc := d.File
+			if loc != "" && d.Line != 0 {
+				loc += ":" + strconv.Itoa(d.Line)
+				if d.Column != 0 {
+					loc += ":" + strconv.Itoa(d.Column)
+				}
+			}
+			if loc != "" {
+				_, err = fmt.Fprintf(w, "go-prost: %s: %s\n", loc, d.Message)
+			} else {
+				_, err = fmt.Fprintf(w, "go-prost: %s\n", d.Message)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// githubAnnotation formats d as a GitHub Actions error workflow command.
+func githubAnnotation(d diagnostic) string {
+	var props []string
+	if d.File != "" {
+		props = append(props, "file="+escapeGitHubProperty(d.File))
+	}
+	if d.Line != 0 {
+		props = append(props, "line="+strconv.Itoa(d.Line))
+	}
+	if d.Column != 0 {
+		props = append(props, "col="+strconv.Itoa(d.Column))
+	}
+	cmd := "::error"
+	if len(props) != 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + escapeGitHubData(d.Message)
+}
+
+// escapeGitHubData escapes a workflow command message.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a workflow command property value.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+)
+
+// Exit codes of go-prost.
+const (
+	// exitOK reports success.
+	exitOK = 0
+	// exitInternal reports an unexpected failure, e.g. an I/O error or trap.
+	exitInternal = 1
+	// exitInput reports invalid flags or an unreadable request.
+	exitInput = 2
+	// exitPlugin reports an error returned by the plugin, or nondeterministic
+	// output found by -verify-deterministic.
+	exitPlugin = 3
+	// exitDrift reports that -check found out of date files.
+	exitDrift = 4
+	// exitHook reports that a post-generation hook failed.
+	exitHook = 5
+)
+
+// exitStatuses are the result JSON names of the exit codes.
+var exitStatuses = map[int]string{
+	exitOK:       "ok",
+	exitInternal: "internal_error",
+	exitInput:    "input_error",
+	exitPlugin:   "plugin_error",
+	exitDrift:    "drift",
+	exitHook:     "hook_error",
+}
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error returns the error message.
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// inputError marks err as caused by invalid input.
+func inputError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitInput, err: err}
+}
+
+// driftError reports out of date files found by -check.
+type driftError struct {
+	diffs []prost.FileDiff
+}
+
+// Error returns the error message.
+func (e *driftError) Error() string {
+	return fmt.Sprintf("%d generated files out of date", len(e.diffs))
+}
+
+// exitCode returns the process exit code for an error returned by run.
+func exitCode(err error) int {
+	var exitErr *exitError
+	var pluginErr *prost.PluginError
+	var drift *driftError
+	var hookErr *prost.HookError
+	var nondeterminism *prost.NondeterminismError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &drift):
+		return exitDrift
+	case errors.As(err, &hookErr):
+		return exitHook
+	case errors.As(err, &pluginErr), errors.As(err, &nondeterminism):
+		return exitPlugin
+	default:
+		return exitInternal
+	}
+}
+
+// result is the summary written by -result-json.
+type result struct {
+	// Status is the name of the exit code.
+	Status string `json:"status"`
+	// ExitCode is the process exit code.
+	ExitCode int `json:"exitCode"`
+	// Files lists the generated file names.
+	Files []string `json:"files"`
+	// Diffs lists the out of date files found by -check.
+	Diffs []prost.FileDiff `json:"diffs,omitempty"`
+	// Hooks lists the results of the post-generation hooks that ran.
+	Hooks []*prost.HookResult `json:"hooks,omitempty"`
+	// Targets lists the results of the targets run by go-prost generate.
+	Targets []*prost.TargetResult `json:"targets,omitempty"`
+	// Error is the error message, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// writeResult writes res to path as indented JSON.
+func writeResult(path string, res *result) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["gencrate"] = &command{
+		usage: "generate a publishable crate or workspace from a request",
+		run:   runGenCrate,
+	}
+}
+
+// runGenCrate runs a request read from stdin and writes the output as a crate.
+func runGenCrate(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost gencrate", stdio)
+	cfg := &prost.CrateConfig{}
+	fs.StringVar(&cfg.Name, "name", "", "crate name (required)")
+	fs.StringVar(&cfg.Version, "version", "0.1.0", "crate version")
+	fs.StringVar(&cfg.Edition, "edition", "2021", "Rust edition")
+	fs.StringVar(&cfg.Description, "description", "", "crate description")
+	fs.StringVar(&cfg.License, "license", "", "SPDX license expression")
+	fs.BoolVar(&cfg.Tonic, "tonic", false, "add tonic dependencies for gRPC services")
+	fs.BoolVar(&cfg.README, "readme", false, "write a README.md stub")
+	var rules crateRulesFlag
+	fs.Var(&rules, "crate", "map proto packages to a workspace crate as `pattern=name` (repeatable)")
+	out := fs.String("out", "", "crate or workspace directory to write (required)")
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost gencrate --name <crate> --out <dir> [flags] < request")
+		fmt.Fprintln(fs.Output(), "       go-prost gencrate --crate <pattern>=<crate>... --out <dir> [flags] < request")
+		fmt.Fprintln(fs.Output(), "\nWrites Cargo.toml, src/lib.rs and the generated modules to the crate directory.")
+		fmt.Fprintln(fs.Output(), "With -crate, writes a Cargo workspace with one crate per mapping instead.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	switch {
+	case *out == "":
+		fs.Usage()
+		return inputError(errors.New("gencrate requires -out"))
+	case cfg.Name == "" && len(rules) == 0:
+		fs.Usage()
+		return inputError(errors.New("gencrate requires -name or -crate"))
+	case cfg.Name != "" && len(rules) != 0:
+		return inputError(errors.New("-name and -crate are mutually exclusive"))
+	}
+
+	input, err := io.ReadAll(stdio.in)
+	if err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	req, err := prost.UnmarshalRequest(input, prost.RequestFormat(*inputFormat))
+	if err != nil {
+		return inputError(err)
+	}
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+
+	if len(rules) != 0 {
+		ws, err := prost.GenerateWorkspace(ctx, p, req, prost.CrateRules(rules), &prost.WorkspaceConfig{
+			Version: cfg.Version,
+			Edition: cfg.Edition,
+			License: cfg.License,
+			Tonic:   cfg.Tonic,
+			README:  cfg.README,
+		})
+		if err != nil {
+			return err
+		}
+		if err := prost.WriteResponse(*out, ws, &prost.WriteOptions{RemoveStale: true}); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdio.err, "go-prost: wrote workspace to %s\n", *out)
+		return nil
+	}
+
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	crate, err := prost.BuildCrate(resp, req, cfg)
+	if err != nil {
+		return err
+	}
+	if err := prost.WriteResponse(*out, crate, &prost.WriteOptions{RemoveStale: true}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdio.err, "go-prost: wrote crate %s to %s\n", cfg.Name, *out)
+	return nil
+}
+
+// crateRulesFlag collects -crate flags.
+type crateRulesFlag prost.CrateRules
+
+// String returns the rules as pattern=name pairs.
+func (f *crateRulesFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, r := range *f {
+		parts[i] = r.Package + "=" + r.Crate
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set adds a pattern=name rule.
+func (f *crateRulesFlag) Set(s string) error {
+	pkg, crate, ok := strings.Cut(s, "=")
+	if !ok || pkg == "" || crate == "" {
+		return fmt.Errorf("expected pattern=name, got %q", s)
+	}
+	*f = append(*f, prost.CrateRule{Package: pkg, Crate: crate})
+	return nil
+}
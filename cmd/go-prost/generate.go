@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func init() {
+	commands["generate"] = &command{
+		usage: "run the targets of prost.yaml on a descriptor set",
+		run:   runGenerate,
+	}
+}
+
+// runGenerate runs every target of the config on a descriptor set or a Buf
+// Schema Registry module.
+func runGenerate(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost generate", stdio)
+	descriptorSet := fs.String("descriptor-set", "", "descriptor set file, or - for stdin")
+	module := fs.String("module", "", "Buf Schema Registry module `[remote/]owner/module[:ref]` to generate instead of a descriptor set (token from "+prost.BSRTokenEnv+")")
+	config := fs.String("config", prost.DefaultConfigFilename, "configuration file listing the targets")
+	resultJSON := fs.String("result-json", "", "write a JSON summary of the outcome to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost generate --descriptor-set set.binpb [--config prost.yaml] [file.proto...]")
+		fmt.Fprintln(fs.Output(), "       go-prost generate --module owner/module[:ref] [--config prost.yaml]")
+		fmt.Fprintln(fs.Output(), "\nRuns the embedded prost plugin and native protoc plugins such as protoc-gen-go")
+		fmt.Fprintln(fs.Output(), "on one descriptor set, writing each target to its own directory. Without")
+		fmt.Fprintln(fs.Output(), "file arguments, all files of the set are generated. With -module, the image")
+		fmt.Fprintln(fs.Output(), "of a registry module is downloaded and the files of the module are generated.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	switch {
+	case (*descriptorSet == "") == (*module == ""):
+		fs.Usage()
+		return inputError(errors.New("generate requires either -descriptor-set or -module"))
+	case *module != "" && fs.NArg() != 0:
+		return inputError(errors.New("file arguments require -descriptor-set"))
+	}
+	cfg, err := prost.LoadConfig(*config)
+	if err != nil {
+		return inputError(err)
+	}
+	if len(cfg.Targets) == 0 {
+		return inputError(fmt.Errorf("%s: no targets configured", *config))
+	}
+
+	var req *pluginpb.CodeGeneratorRequest
+	if *module != "" {
+		ref, err := prost.ParseModuleRef(*module)
+		if err != nil {
+			return inputError(err)
+		}
+		if req, err = (&prost.BSRClient{}).FetchRequest(ctx, ref); err != nil {
+			return err
+		}
+	} else if req, err = readDescriptorSet(stdio, *descriptorSet, fs.Args()); err != nil {
+		return err
+	}
+
+	// The prost targets share one instance; calls on it are serialized.
+	var p *prost.ProtocGenProst
+	targets := make([]*prost.Target, len(cfg.Targets))
+	for i, tc := range cfg.Targets {
+		t := &prost.Target{
+			Name:         tc.Name,
+			Parameter:    strings.Join(tc.Opt, ","),
+			Out:          tc.Out,
+			WriteOptions: prost.WriteOptions{RemoveStale: true},
+		}
+		for _, h := range tc.Hooks {
+			t.Hooks = append(t.Hooks, h)
+		}
+		if len(tc.Plugin) != 0 {
+			t.Executor = &prost.PluginCommand{Command: tc.Plugin}
+		} else {
+			if p == nil {
+				r := wazero.NewRuntime(ctx)
+				defer r.Close(ctx)
+				if p, err = prost.NewProtocGenProst(ctx, r); err != nil {
+					return err
+				}
+				defer p.Close(ctx)
+			}
+			t.Executor = p
+		}
+		targets[i] = t
+	}
+
+	results, err := prost.GenerateTargets(ctx, req, targets)
+	if results == nil {
+		return inputError(err)
+	}
+	res := &result{Files: []string{}}
+	for _, tr := range results {
+		status := fmt.Sprintf("wrote %d files to %s", len(tr.Files), tr.Out)
+		if tr.Err != nil {
+			status = "failed: " + tr.Err.Error()
+		}
+		fmt.Fprintf(stdio.err, "go-prost: %s: %s (%s)\n", tr.Name, status, tr.Duration.Round(time.Millisecond))
+		res.Targets = append(res.Targets, tr)
+	}
+	if *resultJSON != "" {
+		res.ExitCode = exitCode(err)
+		res.Status = exitStatuses[res.ExitCode]
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if werr := writeResult(*resultJSON, res); werr != nil && err == nil {
+			err = fmt.Errorf("failed to write result: %w", werr)
+		}
+	}
+	if err != nil {
+		return &exitError{code: exitCode(err), err: errReported}
+	}
+	return nil
+}
+
+// readDescriptorSet reads a descriptor set from path, or stdin if path is -,
+// and returns a request generating files, or all files if empty.
+func readDescriptorSet(stdio *stdio, path string, files []string) (*pluginpb.CodeGeneratorRequest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdio.in)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, inputError(fmt.Errorf("failed to read descriptor set: %w", err))
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, inputError(fmt.Errorf("failed to unmarshal descriptor set: %w", err))
+	}
+	req, err := prost.RequestFromDescriptorSet(set, files)
+	if err != nil {
+		return nil, inputError(err)
+	}
+	return req, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+)
+
+func init() {
+	commands["graph"] = &command{
+		usage: "print the import graph of a request as DOT or JSON",
+		run:   runGraph,
+	}
+}
+
+// runGraph writes the dependency graph of a request.
+func runGraph(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost graph", stdio)
+	requestPath := fs.String("request", "-", "request file, or - for stdin")
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	format := fs.String("format", "dot", "output format: dot or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost graph [--format dot|json] < request")
+		fmt.Fprintln(fs.Output(), "\nPrints the imports of each proto file with its encoded size, and whether it")
+		fmt.Fprintln(fs.Output(), "is generated, a dependency, unused, or missing from the request.")
+		fmt.Fprintln(fs.Output(), "Render the DOT output with: dot -Tsvg -o graph.svg")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *format != "dot" && *format != "json" {
+		return inputError(fmt.Errorf("unknown output format: %q", *format))
+	}
+	req, err := readRequestFile(stdio, *requestPath, prost.RequestFormat(*inputFormat))
+	if err != nil {
+		return err
+	}
+
+	g := prost.NewDependencyGraph(req)
+	if *format == "dot" {
+		return g.WriteDOT(stdio.out)
+	}
+	enc := json.NewEncoder(stdio.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
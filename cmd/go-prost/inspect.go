@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+)
+
+func init() {
+	commands["inspect"] = &command{
+		usage: "describe the exports, memory and ABI of a plugin WASM file",
+		run:   runInspect,
+	}
+}
+
+// runInspect prints the prost.InspectWASM description of a WASM file.
+func runInspect(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost inspect", stdio)
+	jsonOut := fs.Bool("json", false, "write the description as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost inspect [flags] plugin.wasm[.gz]")
+		fmt.Fprintln(fs.Output(), "\nDescribes a plugin build without running it.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return inputError(errors.New("inspect requires one WASM file"))
+	}
+	wasm, err := readWASM(fs.Arg(0))
+	if err != nil {
+		return inputError(err)
+	}
+	info, err := prost.InspectWASM(ctx, wasm)
+	if err != nil {
+		return inputError(fmt.Errorf("%s: %w", fs.Arg(0), err))
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdio.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	printModuleInfo(stdio.out, info)
+	return nil
+}
+
+// printModuleInfo writes info as text.
+func printModuleInfo(w io.Writer, info *prost.ModuleInfo) {
+	orNone := func(s string) string {
+		if s == "" {
+			return "(none)"
+		}
+		return s
+	}
+	fmt.Fprintf(w, "abi:        %s\n", info.ABI)
+	fmt.Fprintf(w, "size:       %d bytes\n", info.Size)
+	fmt.Fprintf(w, "sha256:     %s\n", info.SHA256)
+	if info.ABI != prost.ABICoreModule {
+		return
+	}
+	fmt.Fprintf(w, "version:    %s\n", orNone(info.Version))
+	for _, p := range info.Producers {
+		fmt.Fprintf(w, "producer:   %s\n", p)
+	}
+
+	switch {
+	case info.Names != nil:
+		fmt.Fprintf(w, "interface:  reactor, %s/%s (%d-bit pointers)\n", info.Names.Execute, info.Names.Malloc, info.PointerWidth)
+	case info.Command:
+		fmt.Fprintln(w, "interface:  WASI command (stdin/stdout)")
+	default:
+		fmt.Fprintln(w, "interface:  (none, not a protoc-gen-prost build)")
+	}
+	fmt.Fprintf(w, "extensions: %s\n", orNone(strings.Join(info.Extensions, ", ")))
+
+	maxPages := "unlimited"
+	if info.MemoryMaxPages != 0 {
+		maxPages = fmt.Sprintf("%d pages (%dMiB)", info.MemoryMaxPages, info.MemoryMaxPages>>4)
+	}
+	fmt.Fprintf(w, "memory:     min %d pages (%dMiB), max %s\n", info.MemoryMinPages, info.MemoryMinPages>>4, maxPages)
+
+	fmt.Fprintf(w, "\nexports (%d):\n", len(info.Exports))
+	for _, f := range info.Exports {
+		fmt.Fprintf(w, "  %s%s\n", f.Name, f.Signature)
+	}
+	fmt.Fprintf(w, "\nimports (%d):\n", len(info.Imports))
+	for _, f := range info.Imports {
+		fmt.Fprintf(w, "  %s.%s%s\n", f.Module, f.Name, f.Signature)
+	}
+}
@@ -0,0 +1,84 @@
+// Command go-prost runs the embedded protoc-gen-prost plugin.
+//
+// Without a subcommand it behaves as a protoc plugin: it reads a
+// CodeGeneratorRequest from stdin and writes the CodeGeneratorResponse to
+// stdout. Requests may be encoded as protobuf or protojson.
+//
+// Started under a protoc-gen-* name, e.g. through a protoc-gen-prost
+// symlink, it always runs as a plugin and ignores its arguments except
+// --version, so it can be used as a protoc or buf local plugin as is.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a go-prost subcommand.
+type command struct {
+	// usage is a one-line description.
+	usage string
+	// run runs the command with the remaining arguments.
+	run func(ctx context.Context, args []string, stdio *stdio) error
+}
+
+// stdio holds the standard streams of the process.
+type stdio struct {
+	in       io.Reader
+	out, err io.Writer
+}
+
+// commands are the subcommands by name.
+var commands = map[string]*command{}
+
+func main() {
+	stdio := &stdio{in: os.Stdin, out: os.Stdout, err: os.Stderr}
+	runFn := run
+	if isPluginName(os.Args[0]) {
+		runFn = runPlugin
+	}
+	if err := runFn(context.Background(), os.Args[1:], stdio); err != nil {
+		if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, errReported) {
+			fmt.Fprintln(os.Stderr, "go-prost:", err)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// run dispatches to a subcommand or runs the plugin in pipe mode.
+func run(ctx context.Context, args []string, stdio *stdio) error {
+	if len(args) != 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd.run(ctx, args[1:], stdio)
+		}
+	}
+	return runPipe(ctx, args, stdio)
+}
+
+// newFlagSet creates a FlagSet writing usage to stdio.err.
+func newFlagSet(name string, stdio *stdio) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdio.err)
+	return fs
+}
+
+// printCommands writes the subcommand list.
+func printCommands(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].usage)
+	}
+}
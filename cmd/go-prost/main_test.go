@@ -0,0 +1,728 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const testJSONRequest = `{"fileToGenerate": ["test.proto"],
+  "protoFile": [{"name": "test.proto", "package": "test", "syntax": "proto3"}]}`
+
+// runTest runs the CLI with args and stdin, returning stdout.
+func runTest(t *testing.T, stdin []byte, args ...string) ([]byte, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), args, &stdio{in: bytes.NewReader(stdin), out: &out, err: &errOut})
+	return out.Bytes(), err
+}
+
+func TestPipe_JSONInput(t *testing.T) {
+	out, err := runTest(t, []byte(testJSONRequest))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.GetFile()) == 0 {
+		t.Fatal("expected generated files")
+	}
+}
+
+func TestPipe_JSONOutput(t *testing.T) {
+	out, err := runTest(t, []byte(testJSONRequest), "-input-format", "json", "-output-format", "json")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := protojson.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to unmarshal json response: %v", err)
+	}
+}
+
+func TestPipe_UnknownCommand(t *testing.T) {
+	_, err := runTest(t, nil, "frobnicate")
+	if err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("expected unknown command error, got %v", err)
+	}
+}
+
+func TestDaemon_Shutdown(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"method":"shutdown"}` + "\n"
+	out, err := runTest(t, []byte(input), "daemon")
+	if err != nil {
+		t.Fatalf("daemon failed: %v", err)
+	}
+	if !strings.Contains(string(out), `"id":1`) {
+		t.Fatalf("expected shutdown reply, got %s", out)
+	}
+}
+
+func TestPluginServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Reserve a free port for the server.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- run(ctx, []string{"plugin-server", "-listen", addr, "-owner", "acme"}, &stdio{in: bytes.NewReader(nil), out: io.Discard, err: io.Discard})
+	}()
+
+	body := `{"image": {"file": [{"name": "test.proto", "package": "test", "syntax": "proto3"}]},
+	  "requests": [{"pluginReference": {"owner": "acme", "name": "prost"}}]}`
+	var resp *http.Response
+	for i := 0; i < 200; i++ {
+		resp, err = http.Post("http://"+addr+prost.RemotePluginPath, "application/json", strings.NewReader(body))
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %s: %s (%v)", resp.Status, data, err)
+	}
+	if !strings.Contains(string(data), "test/test.pb.rs") {
+		t.Fatalf("expected generated file, got %s", data)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("plugin-server failed: %v", err)
+	}
+	if code := exitCode(run(ctx, []string{"plugin-server", "-listen", addr}, &stdio{in: bytes.NewReader(nil), out: io.Discard, err: io.Discard})); code != exitInput {
+		t.Fatalf("expected input error without -owner, got exit code %d", code)
+	}
+}
+
+func TestServe_Socket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sock := filepath.Join(t.TempDir(), "prost.sock")
+	var errOut bytes.Buffer
+	served := make(chan error, 1)
+	go func() {
+		served <- run(ctx, []string{"serve", "-socket", sock}, &stdio{in: bytes.NewReader(nil), out: io.Discard, err: &errOut})
+	}()
+
+	var c *prost.Client
+	var err error
+	for i := 0; i < 200; i++ {
+		if c, err = prost.DialSocket(ctx, sock); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("DialSocket failed: %v", err)
+	}
+	defer c.Close()
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := protojson.Unmarshal([]byte(testJSONRequest), req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.ExecuteRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	if len(resp.GetFile()) == 0 {
+		t.Fatal("expected generated files")
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("expected socket to be removed, got %v", err)
+	}
+}
+
+func TestServe_PoolBulkSocket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	sock, bulkSock := filepath.Join(dir, "prost.sock"), filepath.Join(dir, "bulk.sock")
+	var errOut bytes.Buffer
+	served := make(chan error, 1)
+	go func() {
+		served <- run(ctx, []string{"serve", "-socket", sock, "-pool", "2", "-bulk-socket", bulkSock}, &stdio{in: bytes.NewReader(nil), out: io.Discard, err: &errOut})
+	}()
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := protojson.Unmarshal([]byte(testJSONRequest), req); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{sock, bulkSock} {
+		var c *prost.Client
+		var err error
+		for i := 0; i < 200; i++ {
+			if c, err = prost.DialSocket(ctx, path); err == nil {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("DialSocket failed: %v", err)
+		}
+		resp, err := c.ExecuteRequest(ctx, req)
+		c.Close()
+		if err != nil {
+			t.Fatalf("ExecuteRequest on %s failed: %v", path, err)
+		}
+		if len(resp.GetFile()) == 0 {
+			t.Fatal("expected generated files")
+		}
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if _, err := os.Stat(bulkSock); !os.IsNotExist(err) {
+		t.Fatalf("expected bulk socket to be removed, got %v", err)
+	}
+
+	if code := exitCode(run(ctx, []string{"serve", "-socket", sock, "-bulk-socket", bulkSock}, &stdio{in: bytes.NewReader(nil), out: io.Discard, err: io.Discard})); code != exitInput {
+		t.Fatalf("expected input error without -pool, got exit code %d", code)
+	}
+}
+
+// testBadParameterRequest makes the plugin report an error.
+const testBadParameterRequest = `{"fileToGenerate": ["test.proto"], "parameter": "frobnicate",
+  "protoFile": [{"name": "test.proto", "package": "test", "syntax": "proto3"}]}`
+
+func TestPipe_ErrorFormatText(t *testing.T) {
+	// Plugin errors are only reported in the response by default.
+	out, err := runTest(t, []byte(testBadParameterRequest))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.GetError() == "" {
+		t.Fatal("expected plugin error")
+	}
+}
+
+func TestPipe_ErrorFormatJSON(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), []string{"-error-format", "json"}, &stdio{in: strings.NewReader(testBadParameterRequest), out: &out, err: &errOut})
+	if !errors.Is(err, errReported) {
+		t.Fatalf("expected errReported, got %v", err)
+	}
+	if out.Len() == 0 {
+		t.Fatal("expected response on stdout")
+	}
+	var d diagnostic
+	if err := json.Unmarshal(errOut.Bytes(), &d); err != nil {
+		t.Fatalf("expected JSON diagnostic, got %q: %v", errOut.String(), err)
+	}
+	if !strings.Contains(d.Message, "frobnicate") {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+}
+
+func TestPipe_ErrorFormatGitHub(t *testing.T) {
+	var errOut bytes.Buffer
+	err := run(context.Background(), []string{"-error-format", "github", "-input-format", "json"}, &stdio{in: strings.NewReader("{"), out: io.Discard, err: &errOut})
+	if !errors.Is(err, errReported) {
+		t.Fatalf("expected errReported, got %v", err)
+	}
+	if !strings.HasPrefix(errOut.String(), "::error::") {
+		t.Fatalf("expected annotation, got %q", errOut.String())
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	diags := parseDiagnostics("a/b.proto:3:7: bad field\nfailed to compile c.proto\n\nother", []string{"c.proto"})
+	want := []diagnostic{
+		{File: "a/b.proto", Line: 3, Column: 7, Message: "bad field"},
+		{File: "c.proto", Message: "failed to compile c.proto"},
+		{Message: "other"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %+v", diags)
+	}
+	for i := range want {
+		if diags[i] != want[i] {
+			t.Fatalf("diagnostic %d: got %+v, want %+v", i, diags[i], want[i])
+		}
+	}
+	got := githubAnnotation(diags[0])
+	if got != "::error file=a/b.proto,line=3,col=7::bad field" {
+		t.Fatalf("unexpected annotation %q", got)
+	}
+}
+
+func TestPipe_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		code  int
+	}{
+		{"ok", testJSONRequest, nil, exitOK},
+		{"help", "", []string{"-h"}, exitOK},
+		{"bad flag", "", []string{"-frobnicate"}, exitInput},
+		{"bad request", "{", []string{"-input-format", "json"}, exitInput},
+		{"plugin error", testBadParameterRequest, []string{"-out", dir}, exitPlugin},
+		{"drift", testJSONRequest, []string{"-out", dir, "-check"}, exitDrift},
+		{"write", testJSONRequest, []string{"-out", dir}, exitOK},
+		{"up to date", testJSONRequest, []string{"-out", dir, "-check"}, exitOK},
+	}
+	for _, tt := range tests {
+		var errOut bytes.Buffer
+		err := run(context.Background(), tt.args, &stdio{in: strings.NewReader(tt.stdin), out: io.Discard, err: &errOut})
+		if code := exitCode(err); code != tt.code {
+			t.Errorf("%s: got exit code %d, want %d (err: %v)", tt.name, code, tt.code, err)
+		}
+	}
+}
+
+func TestPipe_ResultJSON(t *testing.T) {
+	dir := t.TempDir()
+	resultPath := filepath.Join(t.TempDir(), "result.json")
+	var errOut bytes.Buffer
+	err := run(context.Background(), []string{"-out", dir, "-check", "-result-json", resultPath}, &stdio{in: strings.NewReader(testJSONRequest), out: io.Discard, err: &errOut})
+	if exitCode(err) != exitDrift {
+		t.Fatalf("expected drift, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), "test.pb.rs") {
+		t.Fatalf("expected out of date file in %q", errOut.String())
+	}
+
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res result
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if res.Status != "drift" || res.ExitCode != exitDrift || len(res.Diffs) == 0 || len(res.Files) == 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestGenCrate(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "gencrate", "-name", "test-proto", "-out", dir, "-readme"); err != nil {
+		t.Fatalf("gencrate failed: %v", err)
+	}
+	for _, name := range []string{"Cargo.toml", "README.md", "src/lib.rs", "src/test/test.pb.rs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	lib, err := os.ReadFile(filepath.Join(dir, "src", "lib.rs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(lib), "pub mod test {") {
+		t.Fatalf("unexpected lib.rs:\n%s", lib)
+	}
+}
+
+func TestGenCrate_Workspace(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "gencrate", "-crate", "*=test-proto", "-out", dir); err != nil {
+		t.Fatalf("gencrate failed: %v", err)
+	}
+	root, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(root), `"crates/test-proto"`) {
+		t.Fatalf("unexpected workspace Cargo.toml:\n%s", root)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "crates", "test-proto", "src", "lib.rs")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipe_Changed(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "-out", dir, "-changed", "other.proto"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no files for unrelated change, got %d", len(entries))
+	}
+	if _, err := runTest(t, []byte(testJSONRequest), "-out", dir, "-changed", "test.proto"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test", "test.pb.rs")); err != nil {
+		t.Fatalf("expected regenerated file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, prost.MarkerFilename)); err == nil {
+		t.Fatal("partial regeneration should not write the marker")
+	}
+}
+
+func TestPipe_Types(t *testing.T) {
+	input := []byte(`{"fileToGenerate": ["test.proto"],
+  "protoFile": [{"name": "test.proto", "package": "test", "syntax": "proto3",
+    "messageType": [{"name": "Kept"}, {"name": "Dropped"}]}]}`)
+	out, err := runTest(t, input, "-types", "test.Kept")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	content := resp.GetFile()[0].GetContent()
+	if !strings.Contains(content, "struct Kept") || strings.Contains(content, "Dropped") {
+		t.Fatalf("unexpected output:\n%s", content)
+	}
+
+	if _, err := runTest(t, input, "-types", "test.Missing"); exitCode(err) != 2 {
+		t.Fatalf("expected exit code 2 for an unknown type, got %v", err)
+	}
+}
+
+func TestPipe_Stream(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "-out", dir, "-stream"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	// The streamed output is up to date with the regular output.
+	if _, err := runTest(t, []byte(testJSONRequest), "-out", dir, "-check"); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if _, err := runTest(t, []byte(testBadParameterRequest), "-out", t.TempDir(), "-stream"); exitCode(err) != exitPlugin {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if _, err := runTest(t, []byte(testJSONRequest), "-stream"); exitCode(err) != exitInput {
+		t.Fatalf("expected exit code 2 without -out, got %v", err)
+	}
+}
+
+func TestPipe_SyntaxCheck(t *testing.T) {
+	input := []byte(`{"fileToGenerate": ["test.proto"],
+  "protoFile": [{"name": "test.proto", "package": "test", "syntax": "editions", "edition": "EDITION_2023"}]}`)
+	_, err := runTest(t, input)
+	if exitCode(err) != exitInput {
+		t.Fatalf("expected exit code 2, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "test.proto: edition 2023") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "-out", dir); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	// Simulate a file generated by an earlier run
+	stale := filepath.Join(dir, "old", "old.pb.rs")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("// old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, prost.MarkerFilename), []byte("old/old.pb.rs\ntest/test.pb.rs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runTest(t, []byte(testJSONRequest), "clean", "-out", dir)
+	if err != nil {
+		t.Fatalf("clean failed: %v", err)
+	}
+	if string(out) != "removed old/old.pb.rs\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test", "test.pb.rs")); err != nil {
+		t.Fatalf("current file was removed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale file to be removed, got %v", err)
+	}
+}
+
+func TestPipe_Config(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, prost.DefaultConfigFilename)
+	data := "out: gen\nroutes:\n  - package: test\n    out: crates/test/src\n    trim_prefix: test/\n"
+	if err := os.WriteFile(config, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runTest(t, []byte(testJSONRequest), "-config", config); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "crates", "test", "src", "test.pb.rs")); err != nil {
+		t.Fatalf("expected routed file: %v", err)
+	}
+	if _, err := runTest(t, []byte(testJSONRequest), "-config", config, "-check"); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+}
+
+func TestPipe_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, prost.DefaultConfigFilename)
+	cfg := "out: gen\nhooks:\n  - name: fail\n    command: [sh, -c, \"echo bad; exit 1\"]\n"
+	if err := os.WriteFile(config, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resultPath := filepath.Join(dir, "result.json")
+	_, err := runTest(t, []byte(testJSONRequest), "-config", config, "-result-json", resultPath)
+	if code := exitCode(err); code != exitHook {
+		t.Fatalf("expected hook exit code, got %d (%v)", code, err)
+	}
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res result
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hooks) != 1 || res.Hooks[0].OK || res.Hooks[0].Name != "fail" {
+		t.Fatalf("unexpected hook results %+v", res.Hooks)
+	}
+}
+
+func TestPipe_VerifyDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runTest(t, []byte(testJSONRequest), "-verify-deterministic", "-out", dir); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test", "test.pb.rs")); err != nil {
+		t.Fatalf("expected generated file: %v", err)
+	}
+}
+
+func TestPluginDiff(t *testing.T) {
+	out, err := runTest(t, []byte(testJSONRequest), "plugin-diff", "-new", "../../embedded/protoc-gen-prost.wasm.gz", "-request", "-", "-exit-code")
+	if err != nil {
+		t.Fatalf("plugin-diff failed: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("expected no differences, got:\n%s", out)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	out, err := runTest(t, nil, "doctor", "-json", "-runs", "1", "-cache-dir", t.TempDir())
+	if err != nil {
+		t.Fatalf("doctor failed: %v\n%s", err, out)
+	}
+	var checks []doctorCheck
+	if err := json.Unmarshal(out, &checks); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	names := make(map[string]string)
+	for _, c := range checks {
+		names[c.Name] = c.Status
+	}
+	for _, name := range []string{"embedded wasm", "warm execute", "cache dir"} {
+		if names[name] != checkOK {
+			t.Fatalf("expected %s check to pass, got %+v", name, checks)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	out, err := runTest(t, nil, "inspect", "-json", "../../embedded/protoc-gen-prost.wasm.gz")
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	var info prost.ModuleInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out)
+	}
+	if info.SHA256 != prost.WASMSHA256 || info.Names == nil {
+		t.Fatalf("unexpected info: %s", out)
+	}
+}
+
+func TestBench(t *testing.T) {
+	out, err := runTest(t, []byte(testJSONRequest), "bench", "-request", "-", "-iterations", "4", "-concurrency", "2", "-json")
+	if err != nil {
+		t.Fatalf("bench failed: %v", err)
+	}
+	var res benchResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out)
+	}
+	if res.Iterations != 4 || res.Concurrency != 2 || res.Throughput <= 0 || res.P50 == "" {
+		t.Fatalf("unexpected result: %s", out)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	for pct, want := range map[int]time.Duration{50: 50, 90: 90, 99: 99, 100: 100} {
+		if got := percentile(sorted, pct); got != want {
+			t.Fatalf("p%d: expected %d, got %d", pct, want, got)
+		}
+	}
+	if got := percentile(sorted[:1], 50); got != 1 {
+		t.Fatalf("expected the only value, got %d", got)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := protojson.Unmarshal([]byte(testJSONRequest), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Parameter = proto.String("compile_well_known_types")
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runTest(t, input, "plugin")
+	if err != nil {
+		t.Fatalf("plugin failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.GetError() != "" || len(resp.GetFile()) == 0 {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	out, err = runTest(t, nil, "plugin", "--version")
+	if err != nil || !strings.Contains(string(out), prost.Version) {
+		t.Fatalf("unexpected version output %q: %v", out, err)
+	}
+	if _, err := runTest(t, input, "plugin", "-out", t.TempDir()); exitCode(err) != exitInput {
+		t.Fatalf("expected input error for flags, got %v", err)
+	}
+}
+
+func TestIsPluginName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"/usr/local/bin/protoc-gen-prost": true,
+		`C:\bin\protoc-gen-prost.exe`:     filepath.Separator == '\\',
+		"protoc-gen-prost-wasm":           true,
+		"/usr/local/bin/go-prost":         false,
+		"protoc":                          false,
+	} {
+		if got := isPluginName(name); got != want {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, prost.DefaultConfigFilename)
+	data := "targets:\n  - name: rust\n    out: rust\n  - name: broken\n    plugin: [\"" + filepath.Join(dir, "missing") + "\"]\n    out: broken\n"
+	if err := os.WriteFile(config, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := protojson.Unmarshal([]byte(testJSONRequest), req); err != nil {
+		t.Fatal(err)
+	}
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: req.GetProtoFile()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultPath := filepath.Join(dir, "result.json")
+	_, err = runTest(t, set, "generate", "-descriptor-set", "-", "-config", config, "-result-json", resultPath)
+	if exitCode(err) != exitInternal {
+		t.Fatalf("expected the broken target to fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rust", prost.MarkerFilename)); err != nil {
+		t.Fatalf("expected rust output: %v", err)
+	}
+	resData, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		Targets []struct {
+			Name  string   `json:"name"`
+			Files []string `json:"files"`
+		} `json:"targets"`
+	}
+	if err := json.Unmarshal(resData, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Targets) != 2 || res.Targets[0].Name != "rust" || len(res.Targets[0].Files) == 0 {
+		t.Fatalf("unexpected result: %s", resData)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	out, err := runTest(t, []byte(testJSONRequest), "graph")
+	if err != nil || !strings.HasPrefix(string(out), "digraph imports {") {
+		t.Fatalf("unexpected DOT output %q: %v", out, err)
+	}
+	out, err = runTest(t, []byte(testJSONRequest), "graph", "-format", "json")
+	if err != nil {
+		t.Fatalf("graph failed: %v", err)
+	}
+	var g prost.DependencyGraph
+	if err := json.Unmarshal(out, &g); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out)
+	}
+	if len(g.Files) != 1 || g.Files[0].Status != prost.DependencyGenerate {
+		t.Fatalf("unexpected graph: %s", out)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	out, err := runTest(t, nil, "verify")
+	if err != nil {
+		t.Fatalf("verify failed: %v\n%s", err, out)
+	}
+	if want := "ok " + prost.SelfTestDigest + "\n"; string(out) != want {
+		t.Fatalf("unexpected output %q, want %q", out, want)
+	}
+
+	out, err = runTest(t, nil, "verify", "-print-digest", "-plugin", "../../embedded/protoc-gen-prost.wasm.gz")
+	if err != nil {
+		t.Fatalf("verify -print-digest failed: %v", err)
+	}
+	if string(out) != prost.SelfTestDigest+"\n" {
+		t.Fatalf("unexpected digest %q", out)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// pipeOptions are the flags of pipe mode.
+type pipeOptions struct {
+	inputFormat  prost.RequestFormat
+	outputFormat prost.RequestFormat
+	out          string
+	check        bool
+	// changed limits generation to the packages affected by these proto files
+	// if non-nil.
+	changed []string
+	// types limits generation to these messages, enums and services and the
+	// types they use if non-nil.
+	types []string
+	// routes send packages to other output roots than out.
+	routes prost.Routes
+	// hooks run in each output root after writing.
+	hooks []prost.Hook
+	// deterministic runs the request on two fresh instances and fails if
+	// the outputs differ.
+	deterministic bool
+	// progress is the interval of the progress reports written to stderr,
+	// or 0 to disable them.
+	progress time.Duration
+	// failureDir receives a reproduction bundle if the plugin fails, or is
+	// empty to disable them.
+	failureDir string
+	// stream writes the files to out as they are decoded from guest memory.
+	stream bool
+}
+
+// runPipe runs the plugin on a request read from stdin.
+//
+// With -error-format json or github, errors are written to stderr in that
+// format. Plugin errors are then also reported, mapped to the proto files
+// they mention, and the command fails after writing the response.
+func runPipe(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost", stdio)
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	outputFormat := fs.String("output-format", string(prost.FormatBinary), "response encoding: binary or json")
+	errorFormat := fs.String("error-format", errorFormatText, "error output: text, json (JSON lines), or github (Actions annotations)")
+	out := fs.String("out", "", "write the generated files to this directory instead of the response to stdout")
+	check := fs.Bool("check", false, "with -out, report out of date files instead of writing them")
+	resultJSON := fs.String("result-json", "", "write a JSON summary of the outcome to this file")
+	changed := fs.String("changed", "", "comma-separated changed proto files; only regenerate the packages they affect")
+	types := fs.String("types", "", "comma-separated fully qualified messages, enums and services; only generate these and the types they use")
+	deterministic := fs.Bool("verify-deterministic", false, "run the request on two fresh instances and fail if the outputs differ")
+	progress := fs.Duration("progress", 0, "report to stderr at this interval that the plugin is still running")
+	failureDir := fs.String("failure-artifacts", "", "write the request, stderr and environment of failed runs to a new directory under this one")
+	stream := fs.Bool("stream", false, "with -out, write each file as it is decoded from plugin memory, for very large outputs")
+	config := fs.String("config", "", "read the output root and per-package routes from this "+prost.DefaultConfigFilename)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost [flags] < request > response")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nExit codes: 0 success, 1 internal error, 2 input error, 3 plugin error, 4 drift detected, 5 hook failed.")
+		printCommands(fs.Output())
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if fs.NArg() != 0 {
+		return inputError(fmt.Errorf("unknown command: %s", fs.Arg(0)))
+	}
+	if err := checkErrorFormat(*errorFormat); err != nil {
+		return inputError(err)
+	}
+	var routes prost.Routes
+	var hooks []prost.Hook
+	if *config != "" {
+		cfg, err := prost.LoadConfig(*config)
+		if err != nil {
+			return inputError(err)
+		}
+		if *out == "" {
+			*out = cfg.Out
+		}
+		routes = cfg.Routes
+		for _, h := range cfg.Hooks {
+			hooks = append(hooks, h)
+		}
+	}
+	if *check && *out == "" && len(routes) == 0 {
+		return inputError(errors.New("-check requires -out"))
+	}
+	if *stream && (*out == "" || *check || len(routes) != 0 || *deterministic) {
+		return inputError(errors.New("-stream requires -out, without -check, routes or -verify-deterministic"))
+	}
+
+	popts := &pipeOptions{
+		inputFormat:   prost.RequestFormat(*inputFormat),
+		outputFormat:  prost.RequestFormat(*outputFormat),
+		out:           *out,
+		check:         *check,
+		routes:        routes,
+		hooks:         hooks,
+		deterministic: *deterministic,
+		progress:      *progress,
+		failureDir:    *failureDir,
+		stream:        *stream,
+	}
+	if *changed != "" {
+		popts.changed = strings.Split(*changed, ",")
+	}
+	if *types != "" {
+		popts.types = strings.Split(*types, ",")
+	}
+	res := &result{Files: []string{}}
+	req, err := pipe(ctx, stdio, popts, res)
+
+	var pluginErr *prost.PluginError
+	if *out == "" && len(routes) == 0 && *errorFormat == errorFormatText && errors.As(err, &pluginErr) {
+		// Like protoc plugins, report plugin errors only in the response.
+		err = nil
+	}
+	if *resultJSON != "" {
+		res.ExitCode = exitCode(err)
+		res.Status = exitStatuses[res.ExitCode]
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if werr := writeResult(*resultJSON, res); werr != nil && err == nil {
+			err = fmt.Errorf("failed to write result: %w", werr)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	diags := errorDiagnostics(err, req)
+	if *errorFormat == errorFormatText {
+		var hookErr *prost.HookError
+		if errors.As(err, &hookErr) {
+			for _, r := range hookErr.Results {
+				if !r.OK {
+					fmt.Fprintf(stdio.err, "go-prost: hook %s: %s\n%s", r.Name, r.Error, r.Output)
+				}
+			}
+		}
+		var drift *driftError
+		if !errors.As(err, &drift) {
+			return err
+		}
+		if err := writeDiagnostics(stdio.err, *errorFormat, diags); err != nil {
+			return err
+		}
+		return &exitError{code: exitDrift, err: errReported}
+	}
+	if werr := writeDiagnostics(stdio.err, *errorFormat, diags); werr != nil {
+		return werr
+	}
+	return &exitError{code: exitCode(err), err: errReported}
+}
+
+// errorDiagnostics converts an error returned by pipe to diagnostics.
+func errorDiagnostics(err error, req *pluginpb.CodeGeneratorRequest) []diagnostic {
+	var pluginErr *prost.PluginError
+	var drift *driftError
+	var hookErr *prost.HookError
+	var nondeterminism *prost.NondeterminismError
+	var execErr *prost.ExecuteError
+	switch {
+	case errors.As(err, &pluginErr):
+		return parseDiagnostics(pluginErr.Message, req.GetFileToGenerate())
+	case errors.As(err, &execErr) && execErr.Info != nil:
+		info := execErr.Info
+		return []diagnostic{{File: info.File, Line: info.Line, Column: info.Column, Message: info.Message}}
+	case errors.As(err, &nondeterminism):
+		diags := []diagnostic{}
+		if nondeterminism.ErrorDiffers {
+			diags = append(diags, diagnostic{Message: "plugin error differs between runs"})
+		}
+		for _, name := range nondeterminism.Files {
+			diags = append(diags, diagnostic{File: name, Message: "generated file differs between runs"})
+		}
+		return diags
+	case errors.As(err, &hookErr):
+		var diags []diagnostic
+		for _, r := range hookErr.Results {
+			if !r.OK {
+				diags = append(diags, diagnostic{Message: fmt.Sprintf("hook %s failed (%s): %s", r.Name, r.Error, strings.TrimSpace(r.Output))})
+			}
+		}
+		return diags
+	case errors.As(err, &drift):
+		diags := make([]diagnostic, 0, len(drift.diffs))
+		for _, d := range drift.diffs {
+			diags = append(diags, diagnostic{File: d.Name, Message: fmt.Sprintf("generated file is out of date (%s)", d.Status)})
+		}
+		return diags
+	default:
+		return []diagnostic{{Message: err.Error()}}
+	}
+}
+
+// pipe reads a request from stdin and runs it. The response is written to
+// stdout, or to (or checked against) opts.out if set. Returns the request
+// and a *prost.PluginError if the plugin reported an error. Records the
+// outcome in res.
+func pipe(ctx context.Context, stdio *stdio, opts *pipeOptions, res *result) (*pluginpb.CodeGeneratorRequest, error) {
+	input, err := io.ReadAll(stdio.in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	req, err := prost.UnmarshalRequest(input, opts.inputFormat)
+	if err != nil {
+		return nil, inputError(err)
+	}
+	if opts.changed != nil {
+		sel := prost.SelectChanged(req, opts.changed)
+		if sel == nil {
+			// Nothing to regenerate
+			return req, writeOutput(ctx, stdio, opts, req, res, &pluginpb.CodeGeneratorResponse{})
+		}
+		req = sel
+	}
+	if opts.types != nil {
+		sel, err := prost.SelectTypes(req, opts.types)
+		if err != nil {
+			return req, inputError(err)
+		}
+		req = sel
+	}
+
+	if opts.deterministic {
+		resp, err := prost.CheckDeterministicFresh(ctx, req)
+		if err != nil {
+			return req, err
+		}
+		return req, writeOutput(ctx, stdio, opts, req, res, resp)
+	}
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	// Reject requests the plugin cannot handle before it panics on them
+	pluginOpts := []prost.Option{prost.WithSyntaxCheck()}
+	if opts.progress > 0 {
+		pluginOpts = append(pluginOpts, prost.WithProgress(opts.progress, func(prog prost.Progress) {
+			if prog.Stage == prost.ProgressRunning {
+				fmt.Fprintf(stdio.err, "go-prost: generating (%s elapsed)\n", prog.Elapsed.Round(time.Second))
+			}
+		}))
+	}
+	if opts.failureDir != "" {
+		pluginOpts = append(pluginOpts, prost.WithFailureArtifacts(opts.failureDir))
+	}
+	p, err := prost.NewProtocGenProst(ctx, r, pluginOpts...)
+	if err != nil {
+		return req, err
+	}
+	defer p.Close(ctx)
+
+	if opts.stream {
+		return req, streamOutput(ctx, p, opts, req, res)
+	}
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		return req, executeError(err)
+	}
+	return req, writeOutput(ctx, stdio, opts, req, res, resp)
+}
+
+// executeError marks errors of requests the plugin cannot handle as input
+// errors.
+func executeError(err error) error {
+	var syntaxErr *prost.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return inputError(err)
+	}
+	return err
+}
+
+// streamOutput runs req with prost.ProtocGenProst.ExecuteToDir, writing the
+// files to opts.out as they are decoded, then runs the hooks.
+func streamOutput(ctx context.Context, p *prost.ProtocGenProst, opts *pipeOptions, req *pluginpb.CodeGeneratorRequest, res *result) error {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	// A partial regeneration must keep the marker of the full output
+	writeOpts := &prost.WriteOptions{Request: req, NoMarker: opts.changed != nil}
+	names, err := p.ExecuteToDir(ctx, input, opts.out, writeOpts)
+	if err != nil {
+		return executeError(err)
+	}
+	res.Files = names
+	results, err := prost.RunHooks(ctx, opts.out, names, opts.hooks)
+	res.Hooks = append(res.Hooks, results...)
+	return err
+}
+
+// writeOutput writes resp to stdout, or to (or checks it against) opts.out
+// and the roots of opts.routes.
+// Returns a *prost.PluginError if the plugin reported an error.
+func writeOutput(ctx context.Context, stdio *stdio, opts *pipeOptions, req *pluginpb.CodeGeneratorRequest, res *result, resp *pluginpb.CodeGeneratorResponse) error {
+	res.Files = responseFileNames(resp)
+
+	// A partial regeneration must keep the marker of the full output
+	writeOpts := &prost.WriteOptions{Request: req, NoMarker: opts.changed != nil}
+	if opts.out != "" || len(opts.routes) != 0 {
+		routed := []prost.RoutedResponse{{Dir: opts.out, Response: resp}}
+		if len(opts.routes) != 0 {
+			var err error
+			if routed, err = prost.RouteResponse(resp, req, opts.routes, opts.out); err != nil {
+				return err
+			}
+		}
+		for _, r := range routed {
+			if err := writeDir(r.Dir, r.Response, opts, writeOpts, res); err != nil {
+				return err
+			}
+		}
+		if !opts.check {
+			for _, r := range routed {
+				results, err := prost.RunHooks(ctx, r.Dir, responseFileNames(r.Response), opts.hooks)
+				res.Hooks = append(res.Hooks, results...)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(res.Diffs) != 0 {
+			return &driftError{diffs: res.Diffs}
+		}
+		return nil
+	}
+
+	var output []byte
+	var err error
+	switch opts.outputFormat {
+	case prost.FormatBinary:
+		output, err = proto.Marshal(resp)
+	case prost.FormatJSON:
+		output, err = protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+	default:
+		return inputError(fmt.Errorf("unknown output format: %q", opts.outputFormat))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	if _, err := stdio.out.Write(output); err != nil {
+		return err
+	}
+	if msg := resp.GetError(); msg != "" {
+		return &prost.PluginError{Message: msg}
+	}
+	return nil
+}
+
+// writeDir writes resp to dir, or with -check records its out of date files
+// in res.Diffs. Diffs outside of opts.out are named by their path.
+func writeDir(dir string, resp *pluginpb.CodeGeneratorResponse, opts *pipeOptions, writeOpts *prost.WriteOptions, res *result) error {
+	if !opts.check {
+		return prost.WriteResponse(dir, resp, writeOpts)
+	}
+	diffs, err := prost.CheckDir(dir, resp, writeOpts)
+	if err != nil {
+		return err
+	}
+	for _, d := range diffs {
+		if dir != opts.out {
+			d.Name = filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(d.Name)))
+		}
+		res.Diffs = append(res.Diffs, d)
+	}
+	return nil
+}
+
+// responseFileNames lists the names of the complete files in a response.
+func responseFileNames(resp *pluginpb.CodeGeneratorResponse) []string {
+	names := []string{}
+	for _, f := range resp.GetFile() {
+		if f.GetInsertionPoint() == "" {
+			names = append(names, f.GetName())
+		}
+	}
+	return names
+}
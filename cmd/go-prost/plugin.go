@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+)
+
+// pluginPrefix is the executable name prefix protoc and buf look plugins up
+// by, e.g. protoc-gen-prost for --prost_out.
+const pluginPrefix = "protoc-gen-"
+
+func init() {
+	commands["plugin"] = &command{
+		usage: "run as a protoc or buf local plugin",
+		run:   runPlugin,
+	}
+}
+
+// isPluginName checks if argv0 names a protoc plugin executable, e.g. a
+// protoc-gen-prost symlink to go-prost.
+func isPluginName(argv0 string) bool {
+	name := strings.TrimSuffix(filepath.Base(argv0), ".exe")
+	return strings.HasPrefix(name, pluginPrefix)
+}
+
+// runPlugin runs as a protoc or buf local plugin: the request is read from
+// stdin and the response written to stdout. The plugin options are passed in
+// the request parameter, which buf joins from the opt list of buf.gen.yaml.
+//
+// Arguments are never parsed as go-prost flags or subcommands, so a plugin
+// invocation cannot select another mode. Only --version is accepted.
+func runPlugin(ctx context.Context, args []string, stdio *stdio) error {
+	if len(args) == 1 && (args[0] == "--version" || args[0] == "-version") {
+		_, err := fmt.Fprintf(stdio.out, "protoc-gen-prost %s\n", prost.Version)
+		return err
+	}
+	if len(args) != 0 {
+		return inputError(fmt.Errorf("unexpected arguments %q: plugin options are passed in the request parameter", args))
+	}
+	return runPipe(ctx, nil, stdio)
+}
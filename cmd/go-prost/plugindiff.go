@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func init() {
+	commands["plugin-diff"] = &command{
+		usage: "diff the output of two plugin builds for a request",
+		run:   runPluginDiff,
+	}
+}
+
+// runPluginDiff runs a request on two plugin builds and writes a unified diff.
+func runPluginDiff(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost plugin-diff", stdio)
+	oldPath := fs.String("old", "", "old plugin .wasm or .wasm.gz (default: the embedded plugin)")
+	newPath := fs.String("new", "", "new plugin .wasm or .wasm.gz (required)")
+	requestPath := fs.String("request", "", "request file, or - for stdin (required)")
+	inputFormat := fs.String("input-format", string(prost.FormatAuto), "request encoding: auto, binary, or json")
+	exitOnDiff := fs.Bool("exit-code", false, "exit with code 4 if the outputs differ")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost plugin-diff --new new.wasm [--old old.wasm] --request req.binpb")
+		fmt.Fprintln(fs.Output(), "\nRuns both plugins on the request and writes a unified diff of the generated files.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *newPath == "" || *requestPath == "" {
+		fs.Usage()
+		return inputError(errors.New("plugin-diff requires -new and -request"))
+	}
+
+	req, err := readRequestFile(stdio, *requestPath, prost.RequestFormat(*inputFormat))
+	if err != nil {
+		return err
+	}
+
+	oldResp, err := runPluginFile(ctx, *oldPath, req)
+	if err != nil {
+		return fmt.Errorf("old plugin: %w", err)
+	}
+	newResp, err := runPluginFile(ctx, *newPath, req)
+	if err != nil {
+		return fmt.Errorf("new plugin: %w", err)
+	}
+	diff, err := prost.DiffResponses(oldResp, newResp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(stdio.out, diff); err != nil {
+		return err
+	}
+	if diff != "" && *exitOnDiff {
+		return &exitError{code: exitDrift, err: errReported}
+	}
+	return nil
+}
+
+// readRequestFile reads a request from path, or from stdin if path is "-".
+// Errors are input errors.
+func readRequestFile(stdio *stdio, path string, format prost.RequestFormat) (*pluginpb.CodeGeneratorRequest, error) {
+	var input []byte
+	var err error
+	if path == "-" {
+		input, err = io.ReadAll(stdio.in)
+	} else {
+		input, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, inputError(fmt.Errorf("failed to read request: %w", err))
+	}
+	req, err := prost.UnmarshalRequest(input, format)
+	if err != nil {
+		return nil, inputError(err)
+	}
+	return req, nil
+}
+
+// runPluginFile runs req on the plugin at path in a new runtime.
+// An empty path runs the embedded plugin.
+func runPluginFile(ctx context.Context, path string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	var p *prost.ProtocGenProst
+	if path == "" {
+		var err error
+		if p, err = prost.NewProtocGenProst(ctx, r); err != nil {
+			return nil, err
+		}
+	} else {
+		wasm, err := readWASM(path)
+		if err != nil {
+			return nil, inputError(err)
+		}
+		compiled, err := r.CompileModule(ctx, wasm)
+		if err != nil {
+			return nil, inputError(fmt.Errorf("%s: %w", path, err))
+		}
+		if p, err = prost.NewProtocGenProstWithModule(ctx, r, compiled); err != nil {
+			return nil, err
+		}
+	}
+	defer p.Close(ctx)
+	return p.ExecuteRequest(ctx, req)
+}
+
+// readWASM reads a WASM file, decompressing it if gzipped.
+func readWASM(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["plugin-server"] = &command{
+		usage: "serve the plugin to buf remote plugin references over HTTP",
+		run:   runPluginServer,
+	}
+}
+
+// runPluginServer serves prost.NewRemotePluginHandler for the embedded
+// plugin until interrupted.
+func runPluginServer(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost plugin-server", stdio)
+	listen := fs.String("listen", "", "address to listen on, e.g. :8443 (required)")
+	owner := fs.String("owner", "", "owner of the plugin in remote references (required)")
+	name := fs.String("name", "prost", "name of the plugin in remote references")
+	tlsCert := fs.String("tls-cert", "", "serve HTTPS with this certificate file")
+	tlsKey := fs.String("tls-key", "", "private key file of -tls-cert")
+	rate := fs.Float64("rate", 0, "requests per second accepted from all clients (0 for unlimited)")
+	clientRate := fs.Float64("client-rate", 0, "requests per second accepted from each client IP address (0 for unlimited)")
+	maxInFlight := fs.Int("max-in-flight", 0, "requests executing or waiting at once (0 for unlimited)")
+	maxRequestSize := fs.Int("max-request-size", 0, "largest request accepted in bytes (0 for 64 MiB)")
+	readHeaderTimeout := fs.Duration("read-header-timeout", 10*time.Second, "time allowed to read request headers")
+	readTimeout := fs.Duration("read-timeout", time.Minute, "time allowed to read a whole request")
+	idleTimeout := fs.Duration("idle-timeout", 2*time.Minute, "time an idle keep-alive connection is kept open")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost plugin-server --listen <addr> --owner <owner>")
+		fmt.Fprintln(fs.Output(), "\nServes buf's remote plugin code generation API for <owner>/<name>,")
+		fmt.Fprintln(fs.Output(), "at the embedded protoc-gen-prost version or the latest.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *listen == "" || *owner == "" {
+		fs.Usage()
+		return inputError(errors.New("plugin-server requires -listen and -owner"))
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return inputError(errors.New("-tls-cert and -tls-key must be set together"))
+	}
+	if *rate < 0 || *clientRate < 0 || *maxInFlight < 0 || *maxRequestSize < 0 {
+		return inputError(errors.New("plugin-server limits must not be negative"))
+	}
+	if *readHeaderTimeout <= 0 || *readTimeout <= 0 || *idleTimeout <= 0 {
+		return inputError(errors.New("plugin-server timeouts must be positive"))
+	}
+	limits := prost.ServeLimits{Rate: *rate, ClientRate: *clientRate, MaxInFlight: *maxInFlight, MaxRequestSize: *maxRequestSize}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+	reg := prost.NewGeneratorRegistry()
+	if err := reg.Register(*name, prost.Version, p); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	handler := prost.NewRemotePluginHandler(prost.RegistryPluginResolver(reg, *owner))
+	srv := &http.Server{
+		Handler:           prost.LimitHandler(handler, limits),
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		IdleTimeout:       *idleTimeout,
+	}
+	context.AfterFunc(ctx, func() { srv.Close() })
+	fmt.Fprintf(stdio.err, "go-prost: serving %s/%s on %s\n", *owner, *name, ln.Addr())
+	if *tlsCert != "" {
+		err = srv.ServeTLS(ln, *tlsCert, *tlsKey)
+	} else {
+		err = srv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
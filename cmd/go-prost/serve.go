@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/aperturerobotics/go-protoc-gen-prost/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["serve"] = &command{
+		usage: "share one warm instance over a local socket",
+		run:   runServe,
+	}
+}
+
+// runServe serves prost.ServeListenerWithLimits, or prost.ServePool with
+// -pool, on a Unix socket until interrupted.
+func runServe(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost serve", stdio)
+	socket := fs.String("socket", "", "path of the Unix socket to listen on (required)")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics on this address, e.g. :9090")
+	rate := fs.Float64("rate", 0, "requests per second accepted from all clients (0 for unlimited)")
+	clientRate := fs.Float64("client-rate", 0, "requests per second accepted from each client user (0 for unlimited)")
+	maxInFlight := fs.Int("max-in-flight", 0, "requests executing or waiting at once (0 for unlimited)")
+	maxRequestSize := fs.Int("max-request-size", 0, "largest request accepted in bytes (0 for 64 MiB)")
+	poolSize := fs.Int("pool", 0, "serve from a pool of this many instances running requests in parallel (0 for one shared instance)")
+	bulkSocket := fs.String("bulk-socket", "", "also listen on this Unix socket, queuing its requests behind interactive ones (requires -pool)")
+	instanceTTL := fs.Duration("instance-ttl", 0, "re-instantiate each instance once it is older than this, e.g. 24h (0 for never)")
+	maxExecutions := fs.Int("max-executions", 0, "re-instantiate each instance after this many requests (0 for never)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost serve --socket <path>")
+		fmt.Fprintln(fs.Output(), "\nServes length-prefixed CodeGeneratorRequests on a Unix socket.")
+		fmt.Fprintln(fs.Output(), "Use prost.DialSocket to connect.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if *socket == "" {
+		fs.Usage()
+		return inputError(errors.New("serve requires -socket"))
+	}
+	if *rate < 0 || *clientRate < 0 || *maxInFlight < 0 || *maxRequestSize < 0 {
+		return inputError(errors.New("serve limits must not be negative"))
+	}
+	if *instanceTTL < 0 || *maxExecutions < 0 {
+		return inputError(errors.New("-instance-ttl and -max-executions must not be negative"))
+	}
+	if *poolSize < 0 {
+		return inputError(errors.New("-pool must not be negative"))
+	}
+	if *bulkSocket != "" && *poolSize == 0 {
+		return inputError(errors.New("-bulk-socket requires -pool"))
+	}
+	limits := prost.ServeLimits{Rate: *rate, ClientRate: *clientRate, MaxInFlight: *maxInFlight, MaxRequestSize: *maxRequestSize}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := []prost.Option{prost.WithInstanceTTL(*instanceTTL), prost.WithMaxExecutions(*maxExecutions)}
+	var collectors []prometheus.Collector
+	if *metricsAddr != "" {
+		collector := metrics.NewCollector()
+		opts = append(opts, collector.Option())
+		collectors = append(collectors, collector)
+	}
+
+	var serve func(ctx context.Context, ln net.Listener, pri prost.Priority) error
+	if *poolSize != 0 {
+		pool, err := prost.NewPool(ctx, &prost.PoolOptions{Size: *poolSize, Options: opts})
+		if err != nil {
+			return err
+		}
+		defer pool.Close(context.WithoutCancel(ctx))
+		collectors = append(collectors, metrics.NewPoolCollector(pool))
+		serve = func(ctx context.Context, ln net.Listener, pri prost.Priority) error {
+			return prost.ServePool(ctx, pool, ln, &prost.ServePoolOptions{
+				Limits:   limits,
+				Priority: func(net.Conn) prost.Priority { return pri },
+			})
+		}
+	} else {
+		r := wazero.NewRuntime(ctx)
+		defer r.Close(ctx)
+		p, err := prost.NewProtocGenProst(ctx, r, opts...)
+		if err != nil {
+			return err
+		}
+		defer p.Close(ctx)
+		serve = func(ctx context.Context, ln net.Listener, _ prost.Priority) error {
+			return prost.ServeListenerWithLimits(ctx, p, ln, limits)
+		}
+	}
+	if *metricsAddr != "" {
+		if err := serveMetrics(ctx, *metricsAddr, collectors...); err != nil {
+			return err
+		}
+	}
+
+	sockets := map[string]prost.Priority{*socket: prost.PriorityInteractive}
+	if *bulkSocket != "" {
+		sockets[*bulkSocket] = prost.PriorityBulk
+	}
+	return serveSockets(ctx, stdio, sockets, serve)
+}
+
+// serveSockets serves each Unix socket of sockets at its priority until ctx
+// is canceled or one of them fails.
+func serveSockets(ctx context.Context, stdio *stdio, sockets map[string]prost.Priority, serve func(context.Context, net.Listener, prost.Priority) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	served := make(chan error, len(sockets))
+	for path, pri := range sockets {
+		ln, err := listenSocket(ctx, path)
+		if err != nil {
+			served <- err
+			cancel()
+			continue
+		}
+		defer os.Remove(path)
+		if len(sockets) > 1 {
+			fmt.Fprintf(stdio.err, "go-prost: serving %s requests on %s\n", pri, path)
+		} else {
+			fmt.Fprintln(stdio.err, "go-prost: serving on", path)
+		}
+		go func() { served <- serve(ctx, ln, pri) }()
+	}
+	var errs []error
+	for range sockets {
+		errs = append(errs, <-served)
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+// listenSocket listens on the Unix socket at path, replacing a stale socket
+// file.
+func listenSocket(ctx context.Context, path string) (net.Listener, error) {
+	if err := removeStaleSocket(ctx, path); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// serveMetrics serves the metrics of collectors over HTTP on addr until ctx
+// is canceled.
+func serveMetrics(ctx context.Context, addr string, collectors ...prometheus.Collector) error {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors...)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	context.AfterFunc(ctx, func() { srv.Close() })
+	return nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous server.
+// Returns an error if another server is still listening on path.
+func removeStaleSocket(ctx context.Context, path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	var d net.Dialer
+	if conn, err := d.DialContext(ctx, "unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: already serving", path)
+	}
+	return os.Remove(path)
+}
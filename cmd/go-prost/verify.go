@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func init() {
+	commands["verify"] = &command{
+		usage: "run the built-in self-test against the plugin build",
+		run:   runVerify,
+	}
+}
+
+// runVerify runs prost.ProtocGenProst.Verify on the embedded or given build.
+func runVerify(ctx context.Context, args []string, stdio *stdio) error {
+	fs := newFlagSet("go-prost verify", stdio)
+	pluginPath := fs.String("plugin", "", "plugin .wasm or .wasm.gz (default: the embedded plugin)")
+	printDigest := fs.Bool("print-digest", false, "print the self-test response digest instead of checking it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-prost verify [flags]")
+		fmt.Fprintln(fs.Output(), "\nRuns a canned request and checks the response digest against the one")
+		fmt.Fprintln(fs.Output(), "recorded for the embedded build, detecting a miscompiling runtime or a")
+		fmt.Fprintln(fs.Output(), "corrupted or mismatched artifact.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return inputError(err)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return inputError(errors.New("verify takes no arguments"))
+	}
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	var p *prost.ProtocGenProst
+	var err error
+	if *pluginPath != "" {
+		wasm, err := readWASM(*pluginPath)
+		if err != nil {
+			return inputError(err)
+		}
+		compiled, err := r.CompileModule(ctx, wasm)
+		if err != nil {
+			return inputError(fmt.Errorf("%s: %w", *pluginPath, err))
+		}
+		p, err = prost.NewProtocGenProstWithModule(ctx, r, compiled)
+	} else {
+		p, err = prost.NewProtocGenProst(ctx, r)
+	}
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+
+	if *printDigest {
+		digest, err := p.SelfTest(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdio.out, digest)
+		return nil
+	}
+	if err := p.Verify(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdio.out, "ok", prost.SelfTestDigest)
+	return nil
+}
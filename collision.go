@@ -0,0 +1,153 @@
+package prost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// rustKeywords contains the Rust keywords escaped by prost in module names.
+var rustKeywords = map[string]struct{}{
+	"as": {}, "break": {}, "const": {}, "continue": {}, "else": {}, "enum": {},
+	"false": {}, "fn": {}, "for": {}, "if": {}, "impl": {}, "in": {}, "let": {},
+	"loop": {}, "match": {}, "mod": {}, "move": {}, "mut": {}, "pub": {},
+	"ref": {}, "return": {}, "static": {}, "struct": {}, "trait": {}, "true": {},
+	"type": {}, "unsafe": {}, "use": {}, "where": {}, "while": {}, "dyn": {},
+	"abstract": {}, "become": {}, "box": {}, "do": {}, "final": {}, "macro": {},
+	"override": {}, "priv": {}, "typeof": {}, "unsized": {}, "virtual": {},
+	"yield": {}, "async": {}, "await": {}, "try": {}, "gen": {},
+}
+
+// rustRawForbidden contains keywords that cannot be raw identifiers.
+// prost appends an underscore to these instead.
+var rustRawForbidden = map[string]struct{}{
+	"self": {}, "super": {}, "extern": {}, "crate": {}, "Self": {},
+}
+
+// RustModulePath returns the Rust module path prost generates for a proto package.
+// For example "my.Package.v1" maps to "my::package::v1".
+func RustModulePath(pkg string) string {
+	if pkg == "" {
+		return ""
+	}
+	parts := strings.Split(pkg, ".")
+	for i, part := range parts {
+		parts[i] = rustIdent(snakeCase(part))
+	}
+	return strings.Join(parts, "::")
+}
+
+// rustIdent escapes Rust keywords the way prost does.
+func rustIdent(s string) string {
+	if _, ok := rustRawForbidden[s]; ok {
+		return s + "_"
+	}
+	if _, ok := rustKeywords[s]; ok {
+		return "r#" + s
+	}
+	return s
+}
+
+// snakeCase converts an identifier to snake_case.
+// Word boundaries are underscores, lower-to-upper transitions, and the end
+// of an acronym (e.g. "HTTPServer" becomes "http_server").
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if r == '_' {
+			if sb.Len() != 0 && !strings.HasSuffix(sb.String(), "_") {
+				sb.WriteByte('_')
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && i != 0 && sb.Len() != 0 && !strings.HasSuffix(sb.String(), "_") {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
+// Collision is a set of inputs mapping to the same generated name.
+type Collision struct {
+	// Kind is "module" for Rust module paths or "file" for output file names.
+	Kind string
+	// Target is the colliding Rust module path or output file name.
+	Target string
+	// Sources are the proto packages or proto files mapping to Target.
+	Sources []string
+}
+
+// CollisionError is returned if a request has colliding generated names.
+type CollisionError struct {
+	// Collisions lists the conflicts sorted by kind and target.
+	Collisions []Collision
+}
+
+// Error returns the error message listing all conflicts.
+func (e *CollisionError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("generated names collide:")
+	for _, c := range e.Collisions {
+		fmt.Fprintf(&sb, "\n  %s %s: %s", c.Kind, c.Target, strings.Join(c.Sources, ", "))
+	}
+	return sb.String()
+}
+
+// CheckCollisions detects proto packages mapping to the same Rust module path
+// and proto files mapping to output file names that differ only in case.
+// Returns a *CollisionError listing the conflicts, or nil.
+func CheckCollisions(req *pluginpb.CodeGeneratorRequest) error {
+	modules := make(map[string]map[string]struct{})
+	files := make(map[string]map[string]struct{})
+	for _, fd := range FilesToGenerate(req) {
+		if pkg := fd.GetPackage(); pkg != "" {
+			addSource(modules, RustModulePath(pkg), pkg)
+		}
+		addSource(files, strings.ToLower(OutputFileName(fd.GetName())), fd.GetName())
+	}
+
+	var collisions []Collision
+	collisions = appendCollisions(collisions, "module", modules)
+	collisions = appendCollisions(collisions, "file", files)
+	if len(collisions) == 0 {
+		return nil
+	}
+	return &CollisionError{Collisions: collisions}
+}
+
+// addSource records that source maps to target.
+func addSource(m map[string]map[string]struct{}, target, source string) {
+	if m[target] == nil {
+		m[target] = make(map[string]struct{})
+	}
+	m[target][source] = struct{}{}
+}
+
+// appendCollisions appends the targets with more than one distinct source.
+func appendCollisions(collisions []Collision, kind string, m map[string]map[string]struct{}) []Collision {
+	targets := make([]string, 0, len(m))
+	for target, sources := range m {
+		if len(sources) > 1 {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		sources := make([]string, 0, len(m[target]))
+		for source := range m[target] {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		collisions = append(collisions, Collision{Kind: kind, Target: target, Sources: sources})
+	}
+	return collisions
+}
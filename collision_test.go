@@ -0,0 +1,57 @@
+package prost
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRustModulePath(t *testing.T) {
+	cases := map[string]string{
+		"foo.bar.v1":       "foo::bar::v1",
+		"My.HTTPServer":    "my::http_server",
+		"fooBar.type":      "foo_bar::r#type",
+		"google.protobuf":  "google::protobuf",
+		"a.self":           "a::self_",
+		"Foo__Bar.Baz_Qux": "foo_bar::baz_qux",
+	}
+	for pkg, want := range cases {
+		if got := RustModulePath(pkg); got != want {
+			t.Errorf("RustModulePath(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestCheckCollisions(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/x.proto", "b/x.proto", "b/X.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("a/x.proto"), Package: proto.String("foo.bar")},
+			{Name: proto.String("b/x.proto"), Package: proto.String("Foo.Bar")},
+			{Name: proto.String("b/X.proto"), Package: proto.String("foo.bar")},
+		},
+	}
+
+	err := CheckCollisions(req)
+	var collisionErr *CollisionError
+	if !errors.As(err, &collisionErr) {
+		t.Fatalf("expected CollisionError, got %v", err)
+	}
+	if len(collisionErr.Collisions) != 2 {
+		t.Fatalf("expected 2 collisions, got:\n%v", err)
+	}
+	if c := collisionErr.Collisions[0]; c.Kind != "module" || c.Target != "foo::bar" || len(c.Sources) != 2 {
+		t.Errorf("unexpected module collision: %+v", c)
+	}
+	if c := collisionErr.Collisions[1]; c.Kind != "file" || c.Target != "b/x.pb.rs" || len(c.Sources) != 2 {
+		t.Errorf("unexpected file collision: %+v", c)
+	}
+
+	req.FileToGenerate = req.FileToGenerate[:1]
+	if err := CheckCollisions(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
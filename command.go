@@ -0,0 +1,85 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// exportStart is the entry point of a WASI command module.
+const exportStart = "_start"
+
+// IsCommandModule checks if a compiled module must run in command mode.
+//
+// Command modules lack all known variants of the reactor ABI (see
+// lowlevel.KnownExportNames) but export _start, like stock wasm32-wasi builds
+// of protoc plugins.
+func IsCommandModule(compiled wazero.CompiledModule) bool {
+	fns := compiled.ExportedFunctions()
+	if _, ok := lowlevel.DetectExportNames(fns); ok {
+		return false
+	}
+	_, ok := fns[exportStart]
+	return ok
+}
+
+// IsCommand returns true if the instance runs its module in command mode.
+//
+// In command mode each Execute instantiates a fresh module with the request
+// on stdin and reads the response from stdout.
+func (p *ProtocGenProst) IsCommand() bool {
+	return p.command
+}
+
+// executeCommand runs a command module once with input on stdin.
+// Appends the response to dst. Must be called with mu held.
+func (p *ProtocGenProst) executeCommand(ctx context.Context, input, dst []byte) ([]byte, error) {
+	stdout := bytes.NewBuffer(dst)
+	var stderr bytes.Buffer
+	var stderrOut io.Writer = &stderr
+	if p.stderr != nil {
+		stderrOut = io.MultiWriter(&stderr, p.stderr)
+	}
+	modCfg := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(ProtocGenProstWASMFilename).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(stdout).
+		WithStderr(stderrOut).
+		WithStartFunctions()
+	modCfg = p.pinModuleConfig(modCfg)
+	if p.opts.hardened {
+		modCfg = sandboxModuleConfig(modCfg)
+	}
+
+	mod, err := p.runtime.InstantiateModule(p.memoryContext(ctx), p.compiled, modCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	defer mod.Close(ctx)
+
+	// Publish the instance so Interrupt can close it
+	p.modMu.Lock()
+	p.mod = mod
+	p.modMu.Unlock()
+
+	_, err = mod.ExportedFunction(exportStart).Call(ctx)
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		err = nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, newTrapError(exportStart, err, mod.Memory())
+	}
+	return stdout.Bytes(), nil
+}
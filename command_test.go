@@ -0,0 +1,108 @@
+package prost
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// buildCommandPlugin builds testdata/cmdplugin as a WASI command.
+func buildCommandPlugin(t *testing.T) []byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping wasip1 build in short mode")
+	}
+	out := filepath.Join(t.TempDir(), "cmdplugin.wasm")
+	cmd := exec.Command("go", "build", "-ldflags=-s -w", "-o", out, "./testdata/cmdplugin")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build command plugin: %v\n%s", err, msg)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestProtocGenProst_CommandMode(t *testing.T) {
+	wasm := buildCommandPlugin(t)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	if !IsCommandModule(compiled) {
+		t.Fatal("expected a command module")
+	}
+
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+	if !p.IsCommand() {
+		t.Fatal("expected command mode")
+	}
+
+	// Each call runs in a fresh instance
+	for i := 0; i < 2; i++ {
+		resp, err := p.ExecuteRequest(ctx, &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			Parameter:      proto.String("hello"),
+		})
+		if err != nil {
+			t.Fatalf("ExecuteRequest failed: %v", err)
+		}
+		if len(resp.GetFile()) != 1 || resp.GetFile()[0].GetContent() != "hello" {
+			t.Fatalf("unexpected response: %v", resp)
+		}
+	}
+
+	_, err = p.ExecuteRequest(ctx, &pluginpb.CodeGeneratorRequest{Parameter: proto.String("fail")})
+	if err == nil || !strings.Contains(err.Error(), "requested failure") {
+		t.Fatalf("expected failure with stderr message, got %v", err)
+	}
+}
+
+func TestProtocGenProst_WithDefaultParameters(t *testing.T) {
+	wasm := buildCommandPlugin(t)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithDefaultParameters("flat_output_dir,default_package_filename=lib"), WithWellKnownTypes())
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	// The plugin echoes the parameter as the file content
+	for param, want := range map[string]string{
+		"":                           "flat_output_dir,default_package_filename=lib",
+		"default_package_filename=x": "flat_output_dir,default_package_filename=x",
+	} {
+		resp, err := p.ExecuteRequest(ctx, &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"a.proto"}, Parameter: proto.String(param)})
+		if err != nil {
+			t.Fatalf("ExecuteRequest failed: %v", err)
+		}
+		if got := resp.GetFile()[0].GetContent(); got != want {
+			t.Errorf("parameter %q: got %q, want %q", param, got, want)
+		}
+	}
+}
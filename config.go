@@ -0,0 +1,112 @@
+package prost
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFilename is the conventional name of the project configuration.
+const DefaultConfigFilename = "prost.yaml"
+
+// Config is the project configuration, usually read from prost.yaml:
+//
+//	out: gen
+//	routes:
+//	  - package: acme.internal.*
+//	    out: crates/internal/src
+//	    trim_prefix: acme/internal/
+//	  - package: acme.api.*
+//	    out: crates/api/src
+//	hooks:
+//	  - name: rustfmt
+//	    command: [rustfmt, --edition, "2021", "{files}"]
+//
+// Targets configure go-prost generate, which runs several generators on one
+// descriptor set:
+//
+//	targets:
+//	  - name: rust
+//	    out: crates/api/src
+//	  - name: go
+//	    plugin: [protoc-gen-go]
+//	    opt: [paths=source_relative]
+//	    out: go/api
+type Config struct {
+	// Out is the output root of files matching no route.
+	Out string `yaml:"out,omitempty"`
+	// Routes send proto packages to their own output roots.
+	Routes Routes `yaml:"routes,omitempty"`
+	// Hooks run in each output root after the files are written.
+	Hooks []*CommandHook `yaml:"hooks,omitempty"`
+	// Targets are the generators run by go-prost generate.
+	Targets []*TargetConfig `yaml:"targets,omitempty"`
+}
+
+// TargetConfig configures a Target of go-prost generate.
+type TargetConfig struct {
+	// Name identifies the target, e.g. rust or go.
+	Name string `yaml:"name"`
+	// Plugin is the command of a native protoc plugin, e.g. [protoc-gen-go].
+	// If empty, the embedded prost plugin is used.
+	Plugin []string `yaml:"plugin,omitempty"`
+	// Opt are the plugin options, joined with commas into the parameter.
+	Opt []string `yaml:"opt,omitempty"`
+	// Out is the output directory.
+	Out string `yaml:"out"`
+	// Hooks run in Out after the files are written.
+	Hooks []*CommandHook `yaml:"hooks,omitempty"`
+}
+
+// LoadConfig reads a configuration file. Unknown keys are rejected.
+// Relative output roots are resolved against the directory of the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	base := filepath.Dir(path)
+	resolve := func(dir string) string {
+		if dir == "" || filepath.IsAbs(dir) {
+			return dir
+		}
+		return filepath.Join(base, filepath.FromSlash(dir))
+	}
+	cfg.Out = resolve(cfg.Out)
+	for i := range cfg.Routes {
+		r := &cfg.Routes[i]
+		if r.Package == "" || r.Out == "" {
+			return nil, fmt.Errorf("%s: route %d requires package and out", path, i)
+		}
+		r.Out = resolve(r.Out)
+	}
+	for i, h := range cfg.Hooks {
+		if len(h.Command) == 0 {
+			return nil, fmt.Errorf("%s: hook %d requires command", path, i)
+		}
+	}
+	for i, t := range cfg.Targets {
+		if t.Name == "" || t.Out == "" {
+			return nil, fmt.Errorf("%s: target %d requires name and out", path, i)
+		}
+		t.Out = resolve(t.Out)
+		for j, h := range t.Hooks {
+			if len(h.Command) == 0 {
+				return nil, fmt.Errorf("%s: target %s: hook %d requires command", path, t.Name, j)
+			}
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,398 @@
+package prost
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// CrateIncludeFilename is the name of the include file SplitResponse
+// generates for each crate, at the root of its file set.
+const CrateIncludeFilename = "lib.rs"
+
+// CrateRule maps proto packages to a Rust crate.
+type CrateRule struct {
+	// Package selects proto packages, with the syntax of PathRule.Package.
+	Package string
+	// Crate is the crate name, e.g. "billing-proto".
+	Crate string
+}
+
+// CrateRules is an ordered list of crate rules. The first matching rule wins.
+type CrateRules []CrateRule
+
+// CrateFor returns the crate of a proto package.
+func (rs CrateRules) CrateFor(pkg string) (string, bool) {
+	for _, r := range rs {
+		if matchPackage(r.Package, pkg) {
+			return r.Crate, true
+		}
+	}
+	return "", false
+}
+
+// ExternPaths returns the extern_path parameters for generating crate on its
+// own: every package of req mapped to another crate is referenced through
+// that crate instead of a relative module path.
+func (rs CrateRules) ExternPaths(req *pluginpb.CodeGeneratorRequest, crate string) []PathValue {
+	seen := make(map[string]bool)
+	var paths []PathValue
+	for _, fd := range req.GetProtoFile() {
+		pkg := fd.GetPackage()
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		other, ok := rs.CrateFor(pkg)
+		if !ok || other == crate {
+			continue
+		}
+		paths = append(paths, PathValue{
+			Path:  "." + pkg,
+			Value: "::" + CrateIdent(other) + "::" + RustModulePath(pkg),
+		})
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].Path < paths[j].Path
+	})
+	return paths
+}
+
+// CrateFiles is the file set of one crate produced by SplitResponse.
+type CrateFiles struct {
+	// Crate is the crate name.
+	Crate string
+	// Packages lists the proto packages in the crate, sorted.
+	Packages []string
+	// Files are the generated files with insertion points applied, followed by
+	// the include file named CrateIncludeFilename.
+	Files []ResolvedFile
+}
+
+// SplitResponse partitions the files of a merged response into one file set
+// per crate, using the packages of the files in req.
+//
+// Each file set gets an include file declaring a module per package segment
+// and including the generated files, so it can serve as the crate's lib.rs.
+// Returns an error if a file's package is unknown or matches no rule. Crates
+// are returned sorted by name.
+//
+// References between crates still use relative module paths; generate each
+// crate with the parameters from CrateRules.ExternPaths to resolve them.
+func SplitResponse(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, rules CrateRules) ([]CrateFiles, error) {
+	if msg := resp.GetError(); msg != "" {
+		return nil, &PluginError{Message: msg}
+	}
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := OutputPackages(req)
+	crates := make(map[string]*CrateFiles)
+	filePackages := make(map[string]map[string][]string)
+	for _, f := range files {
+		pkg, ok := pkgs[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no proto file in the request generates this file", f.Name)
+		}
+		crate, ok := rules.CrateFor(pkg)
+		if !ok {
+			return nil, fmt.Errorf("%s: package %q matches no crate rule", f.Name, pkg)
+		}
+		c := crates[crate]
+		if c == nil {
+			c = &CrateFiles{Crate: crate}
+			crates[crate] = c
+			filePackages[crate] = make(map[string][]string)
+		}
+		if _, ok := filePackages[crate][pkg]; !ok {
+			c.Packages = append(c.Packages, pkg)
+		}
+		filePackages[crate][pkg] = append(filePackages[crate][pkg], f.Name)
+		c.Files = append(c.Files, f)
+	}
+
+	out := make([]CrateFiles, 0, len(crates))
+	for name, c := range crates {
+		sort.Strings(c.Packages)
+		c.Files = append(c.Files, ResolvedFile{
+			Name:    CrateIncludeFilename,
+			Content: includeFile(filePackages[name]),
+		})
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Crate < out[j].Crate
+	})
+	return out, nil
+}
+
+// moduleNode is a Rust module in an include file.
+type moduleNode struct {
+	files    []string
+	children map[string]*moduleNode
+}
+
+// includeFile renders nested modules for the packages including their files.
+func includeFile(filesByPackage map[string][]string) string {
+	root := &moduleNode{children: make(map[string]*moduleNode)}
+	for pkg, files := range filesByPackage {
+		node := root
+		for _, seg := range strings.Split(RustModulePath(pkg), "::") {
+			if seg == "" {
+				continue
+			}
+			child := node.children[seg]
+			if child == nil {
+				child = &moduleNode{children: make(map[string]*moduleNode)}
+				node.children[seg] = child
+			}
+			node = child
+		}
+		node.files = append(node.files, files...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// @generated\n")
+	writeModule(&sb, root, 0)
+	return sb.String()
+}
+
+// writeModule writes the includes and child modules of node.
+func writeModule(sb *strings.Builder, node *moduleNode, depth int) {
+	indent := strings.Repeat("    ", depth)
+	sort.Strings(node.files)
+	for _, f := range node.files {
+		fmt.Fprintf(sb, "%sinclude!(%q);\n", indent, path.Clean(f))
+	}
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(sb, "%spub mod %s {\n", indent, name)
+		writeModule(sb, node.children[name], depth+1)
+		fmt.Fprintf(sb, "%s}\n", indent)
+	}
+}
+
+// CrateIdent returns the Rust identifier of a crate name, e.g. billing_proto
+// for billing-proto.
+func CrateIdent(crate string) string {
+	return strings.ReplaceAll(crate, "-", "_")
+}
+
+// CrateConfig describes a publishable crate built by BuildCrate.
+type CrateConfig struct {
+	// Name is the crate name, e.g. "foo-proto". Required.
+	Name string
+	// Version is the crate version. Defaults to "0.1.0".
+	Version string
+	// Edition is the Rust edition. Defaults to "2021".
+	Edition string
+	// Description is the package description, if set.
+	Description string
+	// License is the SPDX license expression, if set.
+	License string
+	// Tonic adds the tonic dependencies required by gRPC services generated
+	// with protoc-gen-tonic.
+	Tonic bool
+	// README adds a README.md stub listing the proto packages.
+	README bool
+	// Dependencies are other generated crates this crate references, as
+	// sibling directories named after the crate.
+	Dependencies []string
+	// Workspace makes the crate a member of a Cargo workspace written by
+	// BuildWorkspace. Version and Edition are inherited from the workspace,
+	// as is License if set, and dependency versions come from the
+	// workspace dependencies.
+	Workspace bool
+}
+
+// BuildCrate lays out the files of resp as a crate: Cargo.toml at the root,
+// the generated files under src/, and src/lib.rs including them in a module
+// per package. Optionally a README.md stub is added.
+//
+// The returned response can be written with WriteResponse. prost-types is
+// added as a dependency if the request imports well-known types.
+func BuildCrate(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, cfg *CrateConfig) (*pluginpb.CodeGeneratorResponse, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("crate name is required")
+	}
+	if msg := resp.GetError(); msg != "" {
+		return nil, &PluginError{Message: msg}
+	}
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := OutputPackages(req)
+	filesByPackage := make(map[string][]string)
+	out := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: resp.SupportedFeatures,
+		File: []*pluginpb.CodeGeneratorResponse_File{{
+			Name:    proto.String("Cargo.toml"),
+			Content: proto.String(cfg.cargoToml(usesWellKnownTypes(req))),
+		}},
+	}
+	for _, f := range files {
+		pkg, ok := pkgs[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no proto file in the request generates this file", f.Name)
+		}
+		filesByPackage[pkg] = append(filesByPackage[pkg], f.Name)
+		out.File = append(out.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(path.Join("src", f.Name)),
+			Content: proto.String(f.Content),
+		})
+	}
+	out.File = append(out.File, &pluginpb.CodeGeneratorResponse_File{
+		Name:    proto.String(path.Join("src", CrateIncludeFilename)),
+		Content: proto.String(includeFile(filesByPackage)),
+	})
+	if cfg.README {
+		packages := make([]string, 0, len(filesByPackage))
+		for pkg := range filesByPackage {
+			packages = append(packages, pkg)
+		}
+		sort.Strings(packages)
+		out.File = append(out.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String("README.md"),
+			Content: proto.String(cfg.readme(packages)),
+		})
+	}
+	return out, nil
+}
+
+// cargoToml renders the crate manifest.
+func (c *CrateConfig) cargoToml(wkt bool) string {
+	var sb strings.Builder
+	sb.WriteString("[package]\n")
+	fmt.Fprintf(&sb, "name = %s\n", tomlString(c.Name))
+	if c.Workspace {
+		sb.WriteString("version.workspace = true\n")
+		sb.WriteString("edition.workspace = true\n")
+	} else {
+		version, edition := c.Version, c.Edition
+		if version == "" {
+			version = "0.1.0"
+		}
+		if edition == "" {
+			edition = "2021"
+		}
+		fmt.Fprintf(&sb, "version = %s\n", tomlString(version))
+		fmt.Fprintf(&sb, "edition = %s\n", tomlString(edition))
+	}
+	if c.Description != "" {
+		fmt.Fprintf(&sb, "description = %s\n", tomlString(c.Description))
+	}
+	switch {
+	case c.License != "" && c.Workspace:
+		sb.WriteString("license.workspace = true\n")
+	case c.License != "":
+		fmt.Fprintf(&sb, "license = %s\n", tomlString(c.License))
+	}
+	if c.README {
+		sb.WriteString("readme = \"README.md\"\n")
+	}
+
+	sb.WriteString("\n[dependencies]\n")
+	for _, dep := range crateDependencies(wkt, c.Tonic) {
+		if c.Workspace {
+			fmt.Fprintf(&sb, "%s.workspace = true\n", dep.name)
+		} else {
+			fmt.Fprintf(&sb, "%s = %s\n", dep.name, dep.spec)
+		}
+	}
+	deps := append([]string(nil), c.Dependencies...)
+	sort.Strings(deps)
+	for _, dep := range deps {
+		if c.Workspace {
+			fmt.Fprintf(&sb, "%s.workspace = true\n", dep)
+		} else {
+			fmt.Fprintf(&sb, "%s = { path = %s }\n", dep, tomlString("../"+dep))
+		}
+	}
+	return sb.String()
+}
+
+// crateDependency is a registry dependency of generated crates.
+type crateDependency struct {
+	name string
+	// spec is the TOML value of the dependency.
+	spec string
+}
+
+// crateDependencies returns the registry dependencies of generated code.
+func crateDependencies(wkt, tonic bool) []crateDependency {
+	deps := []crateDependency{{"prost", tomlString(ProstCrateVersion)}}
+	if wkt {
+		deps = append(deps, crateDependency{"prost-types", tomlString(ProstCrateVersion)})
+	}
+	if tonic {
+		deps = append(deps,
+			crateDependency{"tonic", fmt.Sprintf("{ version = %s, default-features = false, features = [\"codegen\"] }", tomlString(TonicCrateVersion))},
+			crateDependency{"tonic-prost", tomlString(TonicCrateVersion)},
+		)
+	}
+	return deps
+}
+
+// readme renders the README.md stub.
+func (c *CrateConfig) readme(packages []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", c.Name)
+	if c.Description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", c.Description)
+	}
+	sb.WriteString("Rust types generated with prost from the following proto packages:\n\n")
+	for _, pkg := range packages {
+		if pkg == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "- `%s` (`%s::%s`)\n", pkg, CrateIdent(c.Name), RustModulePath(pkg))
+	}
+	return sb.String()
+}
+
+// usesWellKnownTypes checks if the request imports google/protobuf files
+// without compiling them itself.
+func usesWellKnownTypes(req *pluginpb.CodeGeneratorRequest) bool {
+	if params, err := ParseParams(req.GetParameter()); err == nil && params.CompileWellKnownTypes {
+		return false
+	}
+	for _, fd := range FilesToGenerate(req) {
+		for _, dep := range fd.GetDependency() {
+			if strings.HasPrefix(dep, "google/protobuf/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, "\\u%04X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
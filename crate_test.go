@@ -0,0 +1,157 @@
+package prost
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestSplitResponse(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"billing/v1/invoice.proto", "users/user.proto", "users/type.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("billing/v1/invoice.proto"), Package: proto.String("acme.billing.v1")},
+			{Name: proto.String("users/user.proto"), Package: proto.String("acme.users")},
+			{Name: proto.String("users/type.proto"), Package: proto.String("acme.users.type")},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("acme/billing/v1/invoice.pb.rs"), Content: proto.String("// invoice\n")},
+			{Name: proto.String("acme/users/user.pb.rs"), Content: proto.String("// user\n")},
+			{Name: proto.String("acme/users/type/type.pb.rs"), Content: proto.String("// type\n")},
+		},
+	}
+	rules := CrateRules{
+		{Package: "acme.billing.*", Crate: "billing-proto"},
+		{Package: "*", Crate: "common-proto"},
+	}
+
+	crates, err := SplitResponse(resp, req, rules)
+	if err != nil {
+		t.Fatalf("SplitResponse failed: %v", err)
+	}
+	if len(crates) != 2 {
+		t.Fatalf("expected 2 crates, got %d", len(crates))
+	}
+
+	billing := crates[0]
+	if billing.Crate != "billing-proto" || !reflect.DeepEqual(billing.Packages, []string{"acme.billing.v1"}) {
+		t.Fatalf("unexpected crate %q with packages %v", billing.Crate, billing.Packages)
+	}
+	if len(billing.Files) != 2 || billing.Files[0].Name != "acme/billing/v1/invoice.pb.rs" {
+		t.Fatalf("unexpected files %v", billing.Files)
+	}
+
+	common := crates[1]
+	if !reflect.DeepEqual(common.Packages, []string{"acme.users", "acme.users.type"}) {
+		t.Fatalf("unexpected packages %v", common.Packages)
+	}
+	include := common.Files[len(common.Files)-1]
+	if include.Name != CrateIncludeFilename {
+		t.Fatalf("expected include file last, got %q", include.Name)
+	}
+	want := `// @generated
+pub mod acme {
+    pub mod users {
+        include!("acme/users/user.pb.rs");
+        pub mod r#type {
+            include!("acme/users/type/type.pb.rs");
+        }
+    }
+}
+`
+	if include.Content != want {
+		t.Fatalf("unexpected include file:\n%s", include.Content)
+	}
+}
+
+func TestSplitResponse_Unmapped(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("a.proto"), Package: proto.String("a")},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a/a.pb.rs")},
+		},
+	}
+	if _, err := SplitResponse(resp, req, CrateRules{{Package: "b", Crate: "b"}}); err == nil {
+		t.Fatal("expected error for unmapped package")
+	}
+}
+
+func TestCrateRules_ExternPaths(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("common.proto"), Package: proto.String("acme.common")},
+			{Name: proto.String("invoice.proto"), Package: proto.String("acme.billing")},
+		},
+	}
+	rules := CrateRules{
+		{Package: "acme.common", Crate: "common-proto"},
+		{Package: "acme.billing", Crate: "billing-proto"},
+	}
+	got := rules.ExternPaths(req, "billing-proto")
+	want := []PathValue{{Path: ".acme.common", Value: "::common_proto::acme::common"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestBuildCrate(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/v1/foo.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("google/protobuf/timestamp.proto"), Package: proto.String("google.protobuf")},
+			{
+				Name:       proto.String("foo/v1/foo.proto"),
+				Package:    proto.String("foo.v1"),
+				Dependency: []string{"google/protobuf/timestamp.proto"},
+			},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("foo/v1/foo.pb.rs"), Content: proto.String("// foo\n")},
+		},
+	}
+	crate, err := BuildCrate(resp, req, &CrateConfig{Name: "foo-proto", License: "MIT", README: true})
+	if err != nil {
+		t.Fatalf("BuildCrate failed: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range crate.GetFile() {
+		files[f.GetName()] = f.GetContent()
+	}
+	wantCargo := `[package]
+name = "foo-proto"
+version = "0.1.0"
+edition = "2021"
+license = "MIT"
+readme = "README.md"
+
+[dependencies]
+prost = "` + ProstCrateVersion + `"
+prost-types = "` + ProstCrateVersion + `"
+`
+	if files["Cargo.toml"] != wantCargo {
+		t.Fatalf("unexpected Cargo.toml:\n%s", files["Cargo.toml"])
+	}
+	if files["src/foo/v1/foo.pb.rs"] != "// foo\n" {
+		t.Fatal("expected generated file under src/")
+	}
+	if !strings.Contains(files["src/lib.rs"], `include!("foo/v1/foo.pb.rs");`) {
+		t.Fatalf("unexpected lib.rs:\n%s", files["src/lib.rs"])
+	}
+	if !strings.Contains(files["README.md"], "`foo.v1` (`foo_proto::foo::v1`)") {
+		t.Fatalf("unexpected README.md:\n%s", files["README.md"])
+	}
+}
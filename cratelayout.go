@@ -0,0 +1,169 @@
+package prost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Features of generated code reported in CrateLayout.Features.
+const (
+	// CrateFeatureWellKnownTypes is set if the code uses prost-types.
+	CrateFeatureWellKnownTypes = "well-known-types"
+	// CrateFeatureSerde is set if attributes reference serde.
+	CrateFeatureSerde = "serde"
+	// CrateFeatureTonic is set if gRPC services are generated with tonic.
+	CrateFeatureTonic = "tonic"
+)
+
+// CrateLayout describes the crate formed by the files of a response, so
+// tooling can reason about the generated code without parsing it. See
+// NewCrateLayout.
+type CrateLayout struct {
+	// Modules are the modules of the include file, one per proto package,
+	// sorted by path.
+	Modules []CrateModule `json:"modules"`
+	// Features lists what the generated code needs, e.g.
+	// CrateFeatureSerde, sorted.
+	Features []string `json:"features,omitempty"`
+	// Dependencies are the crates the generated code uses, sorted by name.
+	Dependencies []CrateDependency `json:"dependencies"`
+}
+
+// CrateModule is a Rust module of a CrateLayout.
+type CrateModule struct {
+	// Package is the proto package.
+	Package string `json:"package"`
+	// Path is the Rust module path, e.g. "acme::api::v1", or empty for
+	// files without a package.
+	Path string `json:"path"`
+	// Files are the generated files included in the module, sorted.
+	Files []string `json:"files"`
+}
+
+// CrateDependency is a crate used by generated code.
+type CrateDependency struct {
+	// Name is the crate name, e.g. "prost-types".
+	Name string `json:"name"`
+	// Version is the version requirement, or empty for crates only known
+	// from extern_path parameters.
+	Version string `json:"version,omitempty"`
+	// Features are the crate features the code needs.
+	Features []string `json:"features,omitempty"`
+	// ExternPaths lists the extern_path parameters referencing the crate.
+	ExternPaths []string `json:"externPaths,omitempty"`
+}
+
+// CrateLayoutOptions configures NewCrateLayout.
+type CrateLayoutOptions struct {
+	// Tonic reports the tonic dependencies if the files to generate declare
+	// services, which protoc-gen-tonic generates alongside.
+	Tonic bool
+}
+
+// NewCrateLayout computes the layout of the crate formed by resp, generated
+// for req. Features and dependencies are derived from the request parameter:
+// well-known types without compile_well_known_types need prost-types,
+// attributes mentioning serde need serde, and extern_path values name the
+// crates they point into. opts may be nil.
+func NewCrateLayout(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, opts *CrateLayoutOptions) (*CrateLayout, error) {
+	if opts == nil {
+		opts = &CrateLayoutOptions{}
+	}
+	if msg := resp.GetError(); msg != "" {
+		return nil, &PluginError{Message: msg}
+	}
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ParseParams(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &CrateLayout{Modules: []CrateModule{}}
+	pkgs := OutputPackages(req)
+	modules := make(map[string]*CrateModule)
+	for _, f := range files {
+		pkg, ok := pkgs[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no proto file in the request generates this file", f.Name)
+		}
+		m := modules[pkg]
+		if m == nil {
+			m = &CrateModule{Package: pkg, Path: RustModulePath(pkg)}
+			modules[pkg] = m
+		}
+		m.Files = append(m.Files, f.Name)
+	}
+	for _, m := range modules {
+		sort.Strings(m.Files)
+		layout.Modules = append(layout.Modules, *m)
+	}
+	sort.Slice(layout.Modules, func(i, j int) bool {
+		return layout.Modules[i].Path < layout.Modules[j].Path
+	})
+
+	deps := map[string]*CrateDependency{"prost": {Name: "prost", Version: ProstCrateVersion}}
+	if usesWellKnownTypes(req) {
+		layout.Features = append(layout.Features, CrateFeatureWellKnownTypes)
+		deps["prost-types"] = &CrateDependency{Name: "prost-types", Version: ProstCrateVersion}
+	}
+	if usesSerde(params) {
+		layout.Features = append(layout.Features, CrateFeatureSerde)
+		deps["serde"] = &CrateDependency{Name: "serde", Version: "1", Features: []string{"derive"}}
+	}
+	if opts.Tonic && len(ListServices(FilesToGenerate(req))) != 0 {
+		layout.Features = append(layout.Features, CrateFeatureTonic)
+		deps["tonic"] = &CrateDependency{Name: "tonic", Version: TonicCrateVersion, Features: []string{"codegen"}}
+		deps["tonic-prost"] = &CrateDependency{Name: "tonic-prost", Version: TonicCrateVersion}
+	}
+	for _, pv := range params.ExternPaths {
+		name, ok := externCrate(pv.Value)
+		if !ok {
+			continue
+		}
+		dep := deps[name]
+		if dep == nil {
+			dep = &CrateDependency{Name: name}
+			deps[name] = dep
+		}
+		dep.ExternPaths = append(dep.ExternPaths, pv.Path)
+	}
+	for _, dep := range deps {
+		layout.Dependencies = append(layout.Dependencies, *dep)
+	}
+	sort.Strings(layout.Features)
+	sort.Slice(layout.Dependencies, func(i, j int) bool {
+		return layout.Dependencies[i].Name < layout.Dependencies[j].Name
+	})
+	return layout, nil
+}
+
+// usesSerde checks if attribute parameters reference serde.
+func usesSerde(params *Params) bool {
+	for _, attrs := range [][]PathValue{params.TypeAttributes, params.FieldAttributes, params.MessageAttributes, params.EnumAttributes} {
+		for _, pv := range attrs {
+			if strings.Contains(pv.Value, "serde") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// externCrate returns the crate an extern_path value points into, e.g.
+// prost-types for ::prost_types::Timestamp. The crate name is assumed to use
+// dashes where the identifier has underscores, as most crates do. Paths into
+// the current crate or the standard library have none.
+func externCrate(value string) (string, bool) {
+	first, _, _ := strings.Cut(strings.TrimPrefix(value, "::"), "::")
+	switch first {
+	case "", "crate", "self", "super", "std", "core", "alloc":
+		return "", false
+	}
+	return strings.ReplaceAll(first, "_", "-"), true
+}
@@ -0,0 +1,58 @@
+package prost
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestNewCrateLayout(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"acme/api/v1/api.proto", "acme/api/v1/svc.proto", "acme/types.proto"},
+		Parameter:      proto.String(`type_attribute=.=#[derive(serde::Serialize)],extern_path=.common=::acme_common::common,extern_path=.local=crate::local`),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("google/protobuf/timestamp.proto"), Package: proto.String("google.protobuf")},
+			{Name: proto.String("acme/api/v1/api.proto"), Package: proto.String("acme.api.v1"), Dependency: []string{"google/protobuf/timestamp.proto"}},
+			{Name: proto.String("acme/api/v1/svc.proto"), Package: proto.String("acme.api.v1"), Service: []*descriptorpb.ServiceDescriptorProto{{Name: proto.String("Pets")}}},
+			{Name: proto.String("acme/types.proto"), Package: proto.String("acme")},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("acme/api/v1/api.pb.rs"), Content: proto.String("// api\n")},
+		{Name: proto.String("acme/api/v1/svc.pb.rs"), Content: proto.String("// svc\n")},
+		{Name: proto.String("acme/types.pb.rs"), Content: proto.String("// acme\n")},
+	}}
+
+	layout, err := NewCrateLayout(resp, req, &CrateLayoutOptions{Tonic: true})
+	if err != nil {
+		t.Fatalf("NewCrateLayout failed: %v", err)
+	}
+	wantModules := []CrateModule{
+		{Package: "acme", Path: "acme", Files: []string{"acme/types.pb.rs"}},
+		{Package: "acme.api.v1", Path: "acme::api::v1", Files: []string{"acme/api/v1/api.pb.rs", "acme/api/v1/svc.pb.rs"}},
+	}
+	if !reflect.DeepEqual(layout.Modules, wantModules) {
+		t.Fatalf("unexpected modules: %+v", layout.Modules)
+	}
+	wantFeatures := []string{CrateFeatureSerde, CrateFeatureTonic, CrateFeatureWellKnownTypes}
+	if !reflect.DeepEqual(layout.Features, wantFeatures) {
+		t.Fatalf("unexpected features: %v", layout.Features)
+	}
+	var names []string
+	for _, dep := range layout.Dependencies {
+		names = append(names, dep.Name)
+	}
+	if want := []string{"acme-common", "prost", "prost-types", "serde", "tonic", "tonic-prost"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected dependencies: %v", names)
+	}
+	if dep := layout.Dependencies[0]; !reflect.DeepEqual(dep.ExternPaths, []string{".common"}) || dep.Version != "" {
+		t.Fatalf("unexpected extern dependency: %+v", dep)
+	}
+
+	if _, err := NewCrateLayout(&pluginpb.CodeGeneratorResponse{Error: proto.String("boom")}, req, nil); err == nil {
+		t.Fatal("expected plugin error")
+	}
+}
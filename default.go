@@ -0,0 +1,67 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// Process-global default instance used by Execute.
+var (
+	defaultMu      sync.Mutex
+	defaultRuntime wazero.Runtime
+	defaultProst   *ProtocGenProst
+)
+
+// Execute runs the plugin on a lazily-initialized process-global instance.
+//
+// The input should be a serialized google.protobuf.compiler.CodeGeneratorRequest.
+// Returns a serialized google.protobuf.compiler.CodeGeneratorResponse.
+// Calls are serialized. Use CloseDefault to release the instance.
+func Execute(ctx context.Context, input []byte) ([]byte, error) {
+	p, err := getDefault(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.Execute(ctx, input)
+}
+
+// CloseDefault releases the process-global instance used by Execute.
+// Calls in flight on the instance finish first, as with ProtocGenProst.Close,
+// and calls that have not started fail with ErrClosed.
+// A later Execute creates a new instance.
+func CloseDefault(ctx context.Context) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultRuntime == nil {
+		return nil
+	}
+	err := defaultProst.Close(ctx)
+	err = errors.Join(err, defaultRuntime.Close(context.WithoutCancel(ctx)))
+	defaultRuntime, defaultProst = nil, nil
+	return err
+}
+
+// getDefault returns the process-global instance, creating it if necessary.
+func getDefault(ctx context.Context) (*ProtocGenProst, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultProst != nil {
+		return defaultProst, nil
+	}
+
+	// Not bound to the caller's context: the instance outlives the call
+	initCtx := context.WithoutCancel(ctx)
+	r := wazero.NewRuntime(initCtx)
+	p, err := NewProtocGenProst(initCtx, r)
+	if err != nil {
+		r.Close(initCtx)
+		return nil, err
+	}
+	defaultRuntime, defaultProst = r, p
+	return p, nil
+}
@@ -0,0 +1,72 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestExecute_Default(t *testing.T) {
+	ctx := context.Background()
+	defer CloseDefault(ctx)
+
+	for i := 0; i < 2; i++ {
+		output, err := Execute(ctx, minimalRequestInput(t))
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		resp := &pluginpb.CodeGeneratorResponse{}
+		if err := proto.Unmarshal(output, resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(resp.GetFile()) == 0 {
+			t.Fatal("expected generated files")
+		}
+
+		// A closed default instance is recreated on the next call
+		if err := CloseDefault(ctx); err != nil {
+			t.Fatalf("CloseDefault failed: %v", err)
+		}
+	}
+}
+
+func TestCloseDefault_WaitsForCalls(t *testing.T) {
+	ctx := context.Background()
+	defer CloseDefault(ctx)
+
+	p, err := getDefault(ctx)
+	if err != nil {
+		t.Fatalf("getDefault failed: %v", err)
+	}
+
+	// Calls racing CloseDefault either complete or fail with ErrClosed,
+	// never with the runtime torn down under them.
+	input := minimalRequestInput(t)
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			for {
+				if _, err := p.Execute(ctx, input); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for inFlight := false; !inFlight; {
+		p.stateMu.Lock()
+		inFlight = p.active != 0
+		p.stateMu.Unlock()
+	}
+	if err := CloseDefault(ctx); err != nil {
+		t.Fatalf("CloseDefault failed: %v", err)
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package prost
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DependencyStatus is the role of a file in a request.
+type DependencyStatus string
+
+const (
+	// DependencyGenerate marks a file listed in file_to_generate.
+	DependencyGenerate DependencyStatus = "generate"
+	// DependencyImported marks a file imported, directly or transitively, by
+	// a file to generate.
+	DependencyImported DependencyStatus = "dependency"
+	// DependencyUnused marks a file no file to generate depends on. Removing
+	// it, e.g. with PruneRequest, does not change the output.
+	DependencyUnused DependencyStatus = "unused"
+	// DependencyMissing marks an import not included in the request.
+	DependencyMissing DependencyStatus = "missing"
+)
+
+// DependencyGraph is the import graph of a request with the encoded size of
+// each file, to find out why a request is large and what pruning would
+// remove. See NewDependencyGraph.
+type DependencyGraph struct {
+	// Files are the proto files in request order, followed by the missing
+	// imports sorted by name.
+	Files []*DependencyFile `json:"files"`
+	// TotalSize is the encoded size of all proto files in bytes.
+	TotalSize int `json:"totalSize"`
+	// UnusedSize is the encoded size of the unused files in bytes.
+	UnusedSize int `json:"unusedSize"`
+}
+
+// DependencyFile is a node of a DependencyGraph.
+type DependencyFile struct {
+	// Name is the proto file name.
+	Name string `json:"name"`
+	// Package is the proto package.
+	Package string `json:"package,omitempty"`
+	// Status is the role of the file in the request.
+	Status DependencyStatus `json:"status"`
+	// Size is the encoded size of the FileDescriptorProto in bytes.
+	Size int `json:"size"`
+	// TransitiveSize is the size of the file and everything it imports,
+	// directly or transitively.
+	TransitiveSize int `json:"transitiveSize"`
+	// Imports are the direct imports.
+	Imports []string `json:"imports,omitempty"`
+}
+
+// NewDependencyGraph builds the import graph of req.
+func NewDependencyGraph(req *pluginpb.CodeGeneratorRequest) *DependencyGraph {
+	graph := ImportGraph(req)
+	used := make(map[string]bool)
+	for _, name := range TransitiveImports(graph, req.GetFileToGenerate()) {
+		used[name] = true
+	}
+	generate := make(map[string]bool, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		generate[name] = true
+	}
+
+	g := &DependencyGraph{}
+	sizes := make(map[string]int, len(req.GetProtoFile()))
+	missing := make(map[string]bool)
+	for _, fd := range req.GetProtoFile() {
+		f := &DependencyFile{
+			Name:    fd.GetName(),
+			Package: fd.GetPackage(),
+			Size:    proto.Size(fd),
+			Imports: graph[fd.GetName()],
+		}
+		switch {
+		case generate[f.Name]:
+			f.Status = DependencyGenerate
+		case used[f.Name]:
+			f.Status = DependencyImported
+		default:
+			f.Status = DependencyUnused
+			g.UnusedSize += f.Size
+		}
+		sizes[f.Name] = f.Size
+		g.TotalSize += f.Size
+		g.Files = append(g.Files, f)
+		for _, imp := range f.Imports {
+			missing[imp] = true
+		}
+	}
+	var missingNames []string
+	for name := range missing {
+		if _, ok := sizes[name]; !ok {
+			missingNames = append(missingNames, name)
+		}
+	}
+	sort.Strings(missingNames)
+	for _, name := range missingNames {
+		g.Files = append(g.Files, &DependencyFile{Name: name, Status: DependencyMissing})
+	}
+
+	for _, f := range g.Files {
+		for _, name := range TransitiveImports(graph, []string{f.Name}) {
+			f.TransitiveSize += sizes[name]
+		}
+	}
+	return g
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Edges point from a
+// file to its imports. Files to generate are filled, unused files are gray
+// and missing imports are dashed.
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph imports {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box, fontname=\"monospace\"];")
+	for _, f := range g.Files {
+		label := f.Name
+		if f.Status != DependencyMissing {
+			label += fmt.Sprintf("\n%d B (%d B with imports)", f.Size, f.TransitiveSize)
+		}
+		var attrs string
+		switch f.Status {
+		case DependencyGenerate:
+			attrs = `, style=filled, fillcolor="#cde4ff"`
+		case DependencyUnused:
+			attrs = `, color=gray, fontcolor=gray`
+		case DependencyMissing:
+			attrs = `, style=dashed`
+		}
+		fmt.Fprintf(bw, "  %s [label=%s%s];\n", strconv.Quote(f.Name), strconv.Quote(label), attrs)
+	}
+	for _, f := range g.Files {
+		for _, imp := range f.Imports {
+			fmt.Fprintf(bw, "  %s -> %s;\n", strconv.Quote(f.Name), strconv.Quote(imp))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
@@ -0,0 +1,59 @@
+package prost
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"api.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("common.proto"), Package: proto.String("common"), Dependency: []string{"vendor.proto"}},
+			{Name: proto.String("unused.proto"), Package: proto.String("unused")},
+			{Name: proto.String("api.proto"), Package: proto.String("api"), Dependency: []string{"common.proto"}},
+		},
+	}
+	g := NewDependencyGraph(req)
+
+	statuses := make(map[string]DependencyStatus)
+	files := make(map[string]*DependencyFile)
+	for _, f := range g.Files {
+		statuses[f.Name] = f.Status
+		files[f.Name] = f
+	}
+	want := map[string]DependencyStatus{
+		"api.proto":    DependencyGenerate,
+		"common.proto": DependencyImported,
+		"unused.proto": DependencyUnused,
+		"vendor.proto": DependencyMissing,
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Fatalf("%s: expected %s, got %s", name, status, statuses[name])
+		}
+	}
+	api, common, unused := files["api.proto"], files["common.proto"], files["unused.proto"]
+	if api.TransitiveSize != api.Size+common.Size {
+		t.Fatalf("unexpected transitive size %d", api.TransitiveSize)
+	}
+	if g.UnusedSize != unused.Size || g.TotalSize != api.Size+common.Size+unused.Size {
+		t.Fatalf("unexpected sizes: total %d, unused %d", g.TotalSize, g.UnusedSize)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, s := range []string{`"api.proto" -> "common.proto";`, `"vendor.proto" [label="vendor.proto", style=dashed];`} {
+		if !strings.Contains(dot, s) {
+			t.Fatalf("expected %q in:\n%s", s, dot)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package prost
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RequestFromFiles builds a CodeGeneratorRequest from linked descriptors.
+//
+// toGenerate lists the paths of the files to generate. Their transitive
+// imports are looked up in files and included in proto_file in dependency
+// order. params is the plugin parameter string and may be empty.
+func RequestFromFiles(files *protoregistry.Files, toGenerate []string, params string) (*pluginpb.CodeGeneratorRequest, error) {
+	fds := make([]protoreflect.FileDescriptor, 0, len(toGenerate))
+	for _, name := range toGenerate {
+		fd, err := files.FindFileByPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		fds = append(fds, fd)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: append([]string(nil), toGenerate...),
+		ProtoFile:      FileDescriptorProtos(fds...),
+	}
+	if params != "" {
+		req.Parameter = proto.String(params)
+	}
+	return req, nil
+}
+
+// FileDescriptorProtos converts files and their transitive imports to
+// FileDescriptorProtos in dependency order, each file appearing once.
+func FileDescriptorProtos(files ...protoreflect.FileDescriptor) []*descriptorpb.FileDescriptorProto {
+	var out []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]struct{})
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if _, ok := seen[fd.Path()]; ok {
+			return
+		}
+		seen[fd.Path()] = struct{}{}
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		out = append(out, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range files {
+		add(fd)
+	}
+	return out
+}
+
+// RequestFiles links the proto_file descriptors of a request.
+//
+// Dependencies are re-resolved by path among the request's files, so every
+// import must be present in proto_file as protoc guarantees.
+func RequestFiles(req *pluginpb.CodeGeneratorRequest) (*protoregistry.Files, error) {
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: req.GetProtoFile()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link request files: %w", err)
+	}
+	return files, nil
+}
+
+// RequestFileDescriptors returns the linked descriptors of the files to
+// generate, in file_to_generate order.
+func RequestFileDescriptors(req *pluginpb.CodeGeneratorRequest) ([]protoreflect.FileDescriptor, error) {
+	files, err := RequestFiles(req)
+	if err != nil {
+		return nil, err
+	}
+	fds := make([]protoreflect.FileDescriptor, 0, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		fd, err := files.FindFileByPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
@@ -0,0 +1,66 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
+	_ "google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestRequestFromFiles(t *testing.T) {
+	req, err := RequestFromFiles(protoregistry.GlobalFiles, []string{"google/protobuf/api.proto"}, "compile_well_known_types")
+	if err != nil {
+		t.Fatalf("RequestFromFiles failed: %v", err)
+	}
+
+	// api.proto imports source_context.proto and type.proto, which imports any.proto
+	var names []string
+	pos := make(map[string]int)
+	for i, fd := range req.GetProtoFile() {
+		names = append(names, fd.GetName())
+		pos[fd.GetName()] = i
+	}
+	if pos["google/protobuf/api.proto"] != len(names)-1 {
+		t.Fatalf("expected api.proto last, got %v", names)
+	}
+	if _, ok := pos["google/protobuf/any.proto"]; !ok || pos["google/protobuf/any.proto"] > pos["google/protobuf/type.proto"] {
+		t.Fatalf("expected any.proto before type.proto, got %v", names)
+	}
+	if req.GetParameter() != "compile_well_known_types" {
+		t.Fatalf("unexpected parameter: %q", req.GetParameter())
+	}
+
+	if _, err := RequestFromFiles(protoregistry.GlobalFiles, []string{"missing.proto"}, ""); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestRequestFileDescriptors(t *testing.T) {
+	req, err := RequestFromFiles(protoregistry.GlobalFiles, []string{"google/protobuf/api.proto"}, "")
+	if err != nil {
+		t.Fatalf("RequestFromFiles failed: %v", err)
+	}
+
+	fds, err := RequestFileDescriptors(req)
+	if err != nil {
+		t.Fatalf("RequestFileDescriptors failed: %v", err)
+	}
+	if len(fds) != 1 || fds[0].Path() != "google/protobuf/api.proto" {
+		t.Fatalf("unexpected descriptors: %v", fds)
+	}
+	api := fds[0].Messages().ByName("Api")
+	if api == nil || api.Fields().ByName("methods").Message().FullName() != "google.protobuf.Method" {
+		t.Fatal("expected linked message references")
+	}
+
+	// Round trip back to the request's FileDescriptorProtos
+	if got := FileDescriptorProtos(fds...); len(got) != len(req.GetProtoFile()) {
+		t.Fatalf("expected %d files, got %d", len(req.GetProtoFile()), len(got))
+	}
+
+	// Dropping an import fails to link
+	req.ProtoFile = req.ProtoFile[1:]
+	if _, err := RequestFileDescriptors(req); err == nil {
+		t.Fatal("expected error for missing import")
+	}
+}
@@ -0,0 +1,213 @@
+package prost
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// NondeterminismError is returned by CheckDeterministic if two runs of the
+// same request produced different output.
+type NondeterminismError struct {
+	// Files lists the files that differ between the runs, sorted by name.
+	// Files generated by only one run are included.
+	Files []string
+	// ErrorDiffers is set if the plugin error message differs.
+	ErrorDiffers bool
+}
+
+// Error returns the error message.
+func (e *NondeterminismError) Error() string {
+	msg := "nondeterministic output"
+	if e.ErrorDiffers {
+		msg += ": plugin error differs"
+	}
+	if len(e.Files) != 0 {
+		msg += ": " + strings.Join(e.Files, ", ")
+	}
+	return msg
+}
+
+// CheckDeterministic runs req on a and then on b and compares the outputs.
+// a and b may be the same Executor. Returns the first response, or a
+// *NondeterminismError listing the differing files.
+//
+// Executors with a result cache (see WithCache) return the cached response
+// for the second run and must not be used.
+func CheckDeterministic(ctx context.Context, a, b Executor, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	first, err := a.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	second, err := b.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if diff := compareResponses(first, second); diff != nil {
+		return first, diff
+	}
+	return first, nil
+}
+
+// CheckDeterministicFresh runs req on two new instances, each in its own
+// runtime, and compares the outputs with CheckDeterministic.
+func CheckDeterministicFresh(ctx context.Context, req *pluginpb.CodeGeneratorRequest, opts ...Option) (*pluginpb.CodeGeneratorResponse, error) {
+	var instances [2]*ProtocGenProst
+	for i := range instances {
+		r := wazero.NewRuntime(ctx)
+		defer r.Close(ctx)
+		p, err := NewProtocGenProst(ctx, r, opts...)
+		if err != nil {
+			return nil, err
+		}
+		instances[i] = p
+	}
+	return CheckDeterministic(ctx, instances[0], instances[1], req)
+}
+
+// compareResponses returns the differences between two responses, or nil.
+func compareResponses(a, b *pluginpb.CodeGeneratorResponse) *NondeterminismError {
+	diff := &NondeterminismError{ErrorDiffers: a.GetError() != b.GetError()}
+	contents := func(resp *pluginpb.CodeGeneratorResponse) (map[string]string, error) {
+		files, err := ResolveFiles(resp)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]string, len(files))
+		for _, f := range files {
+			m[f.Name] = f.Content
+		}
+		return m, nil
+	}
+	filesA, errA := contents(a)
+	filesB, errB := contents(b)
+	if errA != nil || errB != nil {
+		// Compare the raw file lists if insertion points cannot be applied
+		filesA, filesB = rawContents(a), rawContents(b)
+	}
+
+	for name, content := range filesA {
+		if other, ok := filesB[name]; !ok || other != content {
+			diff.Files = append(diff.Files, name)
+		}
+	}
+	for name := range filesB {
+		if _, ok := filesA[name]; !ok {
+			diff.Files = append(diff.Files, name)
+		}
+	}
+	if len(diff.Files) == 0 && !diff.ErrorDiffers {
+		return nil
+	}
+	sort.Strings(diff.Files)
+	return diff
+}
+
+// rawContents concatenates the entries of each file name in a response.
+func rawContents(resp *pluginpb.CodeGeneratorResponse) map[string]string {
+	m := make(map[string]string)
+	for _, f := range resp.GetFile() {
+		m[f.GetName()] += fmt.Sprintf("%s\x00%s\x00", f.GetInsertionPoint(), f.GetContent())
+	}
+	return m
+}
+
+// WithFixedWalltime pins the guest wall clock to t. Every read returns t, so
+// generated code embedding timestamps is byte-identical across machines and
+// runs. Without this option the guest reads a fake clock that advances by
+// 1ms per read over the life of the instance.
+//
+// Ignored with WithHardenedSandbox, which denies clock access.
+func WithFixedWalltime(t time.Time) Option {
+	return func(o *options) {
+		o.walltime = &t
+	}
+}
+
+// WithFixedNanotime pins the guest monotonic clock to ns nanoseconds, so
+// elapsed times measured by the guest are always zero. Sleeps return
+// immediately, as without this option.
+//
+// Ignored with WithHardenedSandbox, which denies clock access.
+func WithFixedNanotime(ns int64) Option {
+	return func(o *options) {
+		o.nanotime = &ns
+	}
+}
+
+// WithRandomSeed replaces the guest random source with a ChaCha8 stream
+// derived from seed and restarted before each plugin run, so every run sees
+// the same bytes regardless of the calls before it. Without this option the
+// stream continues across the runs of an instance.
+//
+// Ignored with WithHardenedSandbox, which denies random access.
+func WithRandomSeed(seed uint64) Option {
+	return func(o *options) {
+		o.randomSeed = &seed
+	}
+}
+
+// WithDeterministicHost pins the guest clocks and random source to fixed
+// values: the wall clock to the Unix epoch, the monotonic clock to zero, and
+// the random source to seed 0. Combine with WithCache or CheckDeterministic
+// for reproducible-build audits.
+func WithDeterministicHost() Option {
+	return func(o *options) {
+		WithFixedWalltime(time.Unix(0, 0).UTC())(o)
+		WithFixedNanotime(0)(o)
+		WithRandomSeed(0)(o)
+	}
+}
+
+// seededReader is a random source restarting from its seed on reset.
+type seededReader struct {
+	seed [32]byte
+	mu   sync.Mutex
+	r    *rand.ChaCha8
+}
+
+// newSeededReader creates a seededReader for seed.
+func newSeededReader(seed uint64) *seededReader {
+	s := &seededReader{}
+	binary.LittleEndian.PutUint64(s.seed[:], seed)
+	s.reset()
+	return s
+}
+
+// Read fills p with the next bytes of the stream.
+func (s *seededReader) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Read(p)
+}
+
+// reset restarts the stream.
+func (s *seededReader) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r = rand.NewChaCha8(s.seed)
+}
+
+// pinModuleConfig applies the fixed clocks and random source to cfg.
+func (p *ProtocGenProst) pinModuleConfig(cfg wazero.ModuleConfig) wazero.ModuleConfig {
+	if t := p.opts.walltime; t != nil {
+		sec, nsec := t.Unix(), int32(t.Nanosecond())
+		cfg = cfg.WithWalltime(func() (int64, int32) { return sec, nsec }, 1)
+	}
+	if ns := p.opts.nanotime; ns != nil {
+		v := *ns
+		cfg = cfg.WithNanotime(func() int64 { return v }, 1)
+	}
+	if p.random != nil {
+		cfg = cfg.WithRandSource(p.random)
+	}
+	return cfg
+}
@@ -0,0 +1,141 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// counterExecutor generates a file whose content changes on every call.
+type counterExecutor struct {
+	calls int
+}
+
+func (e *counterExecutor) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *counterExecutor) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	e.calls++
+	return &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("stable.rs"), Content: proto.String("// stable\n")},
+			{Name: proto.String("unstable.rs"), Content: proto.String(strconv.Itoa(e.calls))},
+		},
+	}, nil
+}
+
+func TestCheckDeterministic(t *testing.T) {
+	ctx := context.Background()
+	e := &counterExecutor{}
+	_, err := CheckDeterministic(ctx, e, e, &pluginpb.CodeGeneratorRequest{})
+	var nd *NondeterminismError
+	if !errors.As(err, &nd) {
+		t.Fatalf("expected NondeterminismError, got %v", err)
+	}
+	if !reflect.DeepEqual(nd.Files, []string{"unstable.rs"}) || nd.ErrorDiffers {
+		t.Fatalf("unexpected differences %+v", nd)
+	}
+}
+
+func TestCheckDeterministicFresh(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:        proto.String("test.proto"),
+			Package:     proto.String("test"),
+			Syntax:      proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Msg")}},
+		}},
+	}
+	resp, err := CheckDeterministicFresh(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CheckDeterministicFresh failed: %v", err)
+	}
+	if len(resp.GetFile()) == 0 {
+		t.Fatal("expected generated files")
+	}
+}
+
+// hostEntropyModule encodes a module whose prost_execute outputs 8 bytes from
+// random_get followed by the realtime clock in nanoseconds.
+func hostEntropyModule() []byte {
+	i32, i64 := wasmtest.I32, wasmtest.I64
+	stub := abiStubModule(0)
+	stub.Imports = []wasmtest.Import{
+		{Module: "wasi_snapshot_preview1", Name: "random_get", Params: []byte{i32, i32}, Results: []byte{i32}},
+		{Module: "wasi_snapshot_preview1", Name: "clock_time_get", Params: []byte{i32, i64, i32}, Results: []byte{i32}},
+	}
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Code(
+				wasmtest.I32Const(0), wasmtest.I32Const(8), wasmtest.Call(0), wasmtest.Drop,
+				wasmtest.I32Const(0), wasmtest.I64Const(0), wasmtest.I32Const(8), wasmtest.Call(1), wasmtest.Drop,
+				wasmtest.I32Const(0), wasmtest.GlobalSet(0),
+				wasmtest.I32Const(16), wasmtest.GlobalSet(1),
+				wasmtest.I32Const(16),
+			)
+		}
+	}
+	return stub.Encode()
+}
+
+func TestProtocGenProst_DeterministicHost(t *testing.T) {
+	ctx := context.Background()
+	wasm := hostEntropyModule()
+	run := func(opts ...Option) [2][]byte {
+		r := wazero.NewRuntime(ctx)
+		defer r.Close(ctx)
+		compiled, err := r.CompileModule(ctx, wasm)
+		if err != nil {
+			t.Fatalf("CompileModule failed: %v", err)
+		}
+		p, err := NewProtocGenProstWithModule(ctx, r, compiled, opts...)
+		if err != nil {
+			t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+		}
+		defer p.Close(ctx)
+		var outs [2][]byte
+		for i := range outs {
+			if outs[i], err = p.Execute(ctx, []byte{0x0a, 0x01, 'x'}); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+		}
+		return outs
+	}
+
+	if outs := run(); bytes.Equal(outs[0], outs[1]) {
+		t.Fatal("expected the default clock and random source to advance between runs")
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	outs := run(WithFixedWalltime(at), WithRandomSeed(7))
+	if !bytes.Equal(outs[0], outs[1]) {
+		t.Fatalf("expected identical runs, got %x and %x", outs[0], outs[1])
+	}
+	if ns := int64(binary.LittleEndian.Uint64(outs[0][8:])); ns != at.UnixNano() {
+		t.Fatalf("expected the pinned wall clock, got %d", ns)
+	}
+	if other := run(WithFixedWalltime(at), WithRandomSeed(7)); !bytes.Equal(other[0], outs[0]) {
+		t.Fatal("expected identical output across instances")
+	}
+	if other := run(WithFixedWalltime(at), WithRandomSeed(8)); bytes.Equal(other[0][:8], outs[0][:8]) {
+		t.Fatal("expected the random bytes to depend on the seed")
+	}
+	if outs := run(WithDeterministicHost()); !bytes.Equal(outs[0], outs[1]) || binary.LittleEndian.Uint64(outs[0][8:]) != 0 {
+		t.Fatalf("unexpected output with WithDeterministicHost: %x", outs[0])
+	}
+}
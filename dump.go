@@ -0,0 +1,116 @@
+package prost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DumpFieldLimit is the length above which string and bytes fields are
+// truncated in dumps.
+const DumpFieldLimit = 4096
+
+// DumpRequest formats a request as prototext for debugging.
+// SourceCodeInfo is elided and fields longer than DumpFieldLimit are truncated.
+func DumpRequest(req *pluginpb.CodeGeneratorRequest) []byte {
+	req = proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	for _, fd := range req.GetProtoFile() {
+		fd.SourceCodeInfo = nil
+	}
+	for _, fd := range req.GetSourceFileDescriptors() {
+		fd.SourceCodeInfo = nil
+	}
+	return dumpMessage(req)
+}
+
+// DumpResponse formats a response as prototext for debugging.
+// Fields longer than DumpFieldLimit, e.g. generated content, are truncated.
+func DumpResponse(resp *pluginpb.CodeGeneratorResponse) []byte {
+	return dumpMessage(proto.Clone(resp))
+}
+
+// dumpMessage truncates long fields of m in place and formats it.
+func dumpMessage(m proto.Message) []byte {
+	truncateFields(m.ProtoReflect(), DumpFieldLimit)
+	out, err := prototext.MarshalOptions{Multiline: true}.Marshal(m)
+	if err != nil {
+		return []byte(fmt.Sprintf("# failed to format: %v\n", err))
+	}
+	return out
+}
+
+// truncateFields shortens string and bytes fields longer than limit.
+func truncateFields(m protoreflect.Message, limit int) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				truncateFields(list.Get(i).Message(), limit)
+			}
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if tv, ok := truncateValue(fd, list.Get(i), limit); ok {
+					list.Set(i, tv)
+				}
+			}
+		case fd.IsMap():
+			// No map fields in the plugin protocol
+		case fd.Message() != nil:
+			truncateFields(v.Message(), limit)
+		default:
+			if tv, ok := truncateValue(fd, v, limit); ok {
+				m.Set(fd, tv)
+			}
+		}
+		return true
+	})
+}
+
+// truncateValue shortens a scalar string or bytes value longer than limit.
+func truncateValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, limit int) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if s := v.String(); len(s) > limit {
+			return protoreflect.ValueOfString(fmt.Sprintf("%s... (%d bytes truncated)", s[:limit], len(s)-limit)), true
+		}
+	case protoreflect.BytesKind:
+		if b := v.Bytes(); len(b) > limit {
+			return protoreflect.ValueOfBytes(b[:limit:limit]), true
+		}
+	}
+	return v, false
+}
+
+// dumpCall writes the dumps of one call to dir.
+// The request is written as <digest>.request.binpb if it cannot be decoded.
+func dumpCall(dir string, input, output []byte, callErr error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, RequestDigest(input).String())
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(input, req); err != nil {
+		if err := os.WriteFile(base+".request.binpb", input, 0o644); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(base+".request.txtpb", DumpRequest(req), 0o644); err != nil {
+		return err
+	}
+
+	if callErr != nil {
+		return os.WriteFile(base+".error.txt", []byte(callErr.Error()+"\n"), 0o644)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".response.txtpb", DumpResponse(resp), 0o644)
+}
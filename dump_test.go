@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestDumpRequest(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		Parameter:      proto.String(strings.Repeat("x", DumpFieldLimit+10)),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:           proto.String("test.proto"),
+			SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+		}},
+	}
+	dump := string(DumpRequest(req))
+	if strings.Contains(dump, "source_code_info") {
+		t.Fatalf("expected SourceCodeInfo to be elided:\n%s", dump)
+	}
+	if !strings.Contains(dump, "(10 bytes truncated)") {
+		t.Fatalf("expected parameter to be truncated:\n%s", dump)
+	}
+	if req.ProtoFile[0].SourceCodeInfo == nil || len(req.GetParameter()) != DumpFieldLimit+10 {
+		t.Fatal("DumpRequest modified its argument")
+	}
+}
+
+func TestProtocGenProst_DumpDir(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	dir := t.TempDir()
+	p, err := NewProtocGenProst(ctx, r, WithDumpDir(dir))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input := minimalRequestInput(t)
+	if _, err := p.Execute(ctx, input); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	base := filepath.Join(dir, RequestDigest(input).String())
+	reqDump, err := os.ReadFile(base + ".request.txtpb")
+	if err != nil {
+		t.Fatalf("expected request dump: %v", err)
+	}
+	// prototext output is deliberately unstable, so parse it back
+	dumped := &pluginpb.CodeGeneratorRequest{}
+	if err := prototext.Unmarshal(reqDump, dumped); err != nil {
+		t.Fatalf("failed to parse request dump: %v", err)
+	}
+	if dumped.GetFileToGenerate()[0] != "test.proto" {
+		t.Fatalf("unexpected request dump:\n%s", reqDump)
+	}
+	if _, err := os.Stat(base + ".response.txtpb"); err != nil {
+		t.Fatalf("expected response dump: %v", err)
+	}
+}
@@ -1,45 +1,79 @@
 // Package prost provides a Go wrapper for running protoc-gen-prost via WASI/wazero.
 package prost
 
-import _ "embed"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 
-// ProtocGenProstWASM contains the binary contents of the protoc-gen-prost WASI build.
-//
-// This is a WASM binary that exports functions for executing the Prost protobuf
-// code generator. The module uses the standard WASI preview1 interface.
-//
-//go:embed protoc-gen-prost.wasm
-var ProtocGenProstWASM []byte
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+)
 
 // ProtocGenProstWASMFilename is the filename for ProtocGenProstWASM.
 const ProtocGenProstWASMFilename = "protoc-gen-prost.wasm"
 
+// VerifyEmbeddedWASM checks the embedded build against WASMSHA256, e.g. to
+// detect a corrupted module cache. Returns ErrNoEmbeddedWASM if built with
+// the prost_noembed tag.
+func VerifyEmbeddedWASM() error {
+	wasm := ProtocGenProstWASM()
+	if wasm == nil {
+		return ErrNoEmbeddedWASM
+	}
+	sum := sha256.Sum256(wasm)
+	if got := hex.EncodeToString(sum[:]); got != WASMSHA256 {
+		return fmt.Errorf("embedded wasm checksum mismatch: got %s, want %s", got, WASMSHA256)
+	}
+	return nil
+}
+
 // Prost plugin exports
 const (
 	// ExportProstExecute executes the prost plugin.
 	// Signature: prost_execute(input_ptr: i32, input_len: i32) -> i32 (output_len)
-	ExportProstExecute = "prost_execute"
+	ExportProstExecute = lowlevel.ExportExecute
 
 	// ExportProstGetOutputPtr returns the pointer to the output buffer.
 	// Signature: prost_get_output_ptr() -> i32 (ptr)
-	ExportProstGetOutputPtr = "prost_get_output_ptr"
+	ExportProstGetOutputPtr = lowlevel.ExportGetOutputPtr
 
 	// ExportProstGetOutputLen returns the length of the output buffer.
 	// Signature: prost_get_output_len() -> i32 (len)
-	ExportProstGetOutputLen = "prost_get_output_len"
+	ExportProstGetOutputLen = lowlevel.ExportGetOutputLen
 
 	// ExportProstClearOutput clears the output buffer.
 	// Signature: prost_clear_output() -> void
-	ExportProstClearOutput = "prost_clear_output"
+	ExportProstClearOutput = lowlevel.ExportClearOutput
 )
 
 // Memory management exports
 const (
 	// ExportProstMalloc allocates memory in WASM linear memory.
 	// Signature: prost_malloc(size: i32) -> i32 (pointer)
-	ExportProstMalloc = "prost_malloc"
+	ExportProstMalloc = lowlevel.ExportMalloc
 
 	// ExportProstFree frees memory in WASM linear memory.
 	// Signature: prost_free(ptr: i32, size: i32) -> void
-	ExportProstFree = "prost_free"
+	ExportProstFree = lowlevel.ExportFree
+)
+
+// Optional allocator statistics exports
+//
+// These are not required. When present, AllocatorStats reports their values.
+const (
+	// ExportProstAllocLiveCount returns the number of live allocations.
+	// Signature: prost_alloc_live_count() -> i32
+	ExportProstAllocLiveCount = lowlevel.ExportAllocLiveCount
+
+	// ExportProstAllocPeakCount returns the peak number of live allocations.
+	// Signature: prost_alloc_peak_count() -> i32
+	ExportProstAllocPeakCount = lowlevel.ExportAllocPeakCount
+
+	// ExportProstAllocLiveBytes returns the number of live allocated bytes.
+	// Signature: prost_alloc_live_bytes() -> i32
+	ExportProstAllocLiveBytes = lowlevel.ExportAllocLiveBytes
+
+	// ExportProstAllocPeakBytes returns the peak number of live allocated bytes.
+	// Signature: prost_alloc_peak_bytes() -> i32
+	ExportProstAllocPeakBytes = lowlevel.ExportAllocPeakBytes
 )
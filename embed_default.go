@@ -0,0 +1,20 @@
+//go:build !prost_noembed
+
+package prost
+
+import "github.com/aperturerobotics/go-protoc-gen-prost/embedded"
+
+// embeddedWASM reports whether ProtocGenProstWASM returns the embedded build.
+const embeddedWASM = true
+
+// ProtocGenProstWASM returns the binary contents of the protoc-gen-prost WASI build.
+//
+// This is a WASM binary that exports functions for executing the Prost protobuf
+// code generator. The module uses the standard WASI preview1 interface.
+//
+// The binary lives in the nested embedded module. It is embedded
+// gzip-compressed and decompressed on the first call. The returned slice is
+// shared and must not be modified.
+func ProtocGenProstWASM() []byte {
+	return embedded.WASM()
+}
@@ -0,0 +1,15 @@
+//go:build !prost_noembed
+
+package prost
+
+import (
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/embedded"
+)
+
+func TestVersionMatchesEmbedded(t *testing.T) {
+	if Version != embedded.Version {
+		t.Fatalf("Version %s does not match the embedded build %s", Version, embedded.Version)
+	}
+}
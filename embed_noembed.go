@@ -0,0 +1,13 @@
+//go:build prost_noembed
+
+package prost
+
+// embeddedWASM reports whether ProtocGenProstWASM returns the embedded build.
+const embeddedWASM = false
+
+// ProtocGenProstWASM returns nil: the prost_noembed build tag excludes the
+// embedded module. Load a build with CompileVerifiedWASM or compile one
+// yourself and use NewProtocGenProstWithModule.
+func ProtocGenProstWASM() []byte {
+	return nil
+}
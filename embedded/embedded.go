@@ -0,0 +1,46 @@
+// Package embedded contains the protoc-gen-prost WASI build.
+//
+// It is a separate module so programs that always load external WASM can
+// depend on the host package, built with the prost_noembed tag, without
+// downloading the artifact.
+package embedded
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"io"
+	"sync"
+)
+
+// wasmGzip is the gzip-compressed protoc-gen-prost WASI build.
+//
+//go:embed protoc-gen-prost.wasm.gz
+var wasmGzip []byte
+
+// wasm decompresses the embedded build once.
+var wasm = sync.OnceValue(func() []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(wasmGzip))
+	if err != nil {
+		panic("embedded: corrupt wasm: " + err.Error())
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		panic("embedded: corrupt wasm: " + err.Error())
+	}
+	return data
+})
+
+// WASM returns the protoc-gen-prost WASI build.
+//
+// The binary is embedded gzip-compressed and decompressed on the first call.
+// The returned slice is shared and must not be modified.
+func WASM() []byte {
+	return wasm()
+}
+
+// Compressed returns the gzip-compressed build as embedded.
+// The returned slice is shared and must not be modified.
+func Compressed() []byte {
+	return wasmGzip
+}
@@ -0,0 +1,16 @@
+package embedded
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWASM(t *testing.T) {
+	data := WASM()
+	if !bytes.HasPrefix(data, []byte("\x00asm")) {
+		t.Fatal("expected a WebAssembly binary")
+	}
+	if len(Compressed()) >= len(data) {
+		t.Fatalf("expected compressed size %d below %d", len(Compressed()), len(data))
+	}
+}
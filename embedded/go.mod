@@ -0,0 +1,3 @@
+module github.com/aperturerobotics/go-protoc-gen-prost/embedded
+
+go 1.24.0
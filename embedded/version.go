@@ -0,0 +1,4 @@
+package embedded
+
+// Version is the protoc-gen-prost release of the embedded build.
+const Version = "v0.5.0-wasi"
@@ -0,0 +1,120 @@
+package prost
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+)
+
+// ErrAllocatorStatsUnsupported is returned by AllocatorStats if the module
+// does not export the allocator statistics functions.
+var ErrAllocatorStatsUnsupported = errors.New("allocator statistics not supported by module")
+
+// ErrClosed is returned by calls made after Close.
+var ErrClosed = errors.New("instance is closed")
+
+// ErrNoEmbeddedWASM is returned when compiling the embedded module in a build
+// using the prost_noembed tag.
+var ErrNoEmbeddedWASM = errors.New("embedded wasm excluded by the prost_noembed build tag")
+
+// ErrInterrupted is returned by Execute if the call was aborted by Interrupt.
+var ErrInterrupted = errors.New("execution interrupted")
+
+// TrapError is returned when a call into the guest module fails.
+//
+// This indicates a runtime failure of the plugin (e.g. a panic or memory
+// fault), not a semantic error reported in the CodeGeneratorResponse.
+type TrapError struct {
+	// Function is the name of the exported function that failed.
+	Function string
+	// Err is the error returned by the runtime.
+	Err error
+	// Reason is the trap reason reported by the runtime, e.g. "unreachable"
+	// for a Rust panic or "out of bounds memory access".
+	Reason string
+	// MemoryPages is the size of guest memory at the trap in 64KiB pages,
+	// or 0 if unknown.
+	MemoryPages uint32
+	// Stack is the guest stack trace, innermost frame first, if the runtime
+	// reported one. Frames are followed by their source positions if debug
+	// info is enabled in the runtime config (the default) and the module
+	// has DWARF sections.
+	Stack []string
+}
+
+// Error returns the error message, including the guest stack trace and
+// memory size if known.
+func (e *TrapError) Error() string {
+	msg := e.Function + " failed: " + e.Err.Error()
+	if e.MemoryPages != 0 {
+		msg += fmt.Sprintf("\nguest memory: %d pages", e.MemoryPages)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying runtime error.
+func (e *TrapError) Unwrap() error {
+	return e.Err
+}
+
+// PluginError is returned when the plugin reports an error in the
+// CodeGeneratorResponse, e.g. due to an invalid parameter.
+type PluginError struct {
+	// Message is the error reported by the plugin.
+	Message string
+}
+
+// Error returns the error message.
+func (e *PluginError) Error() string {
+	return "plugin error: " + e.Message
+}
+
+// ExecuteError is returned when prost_execute reports a failure with a
+// negative status code. See the Status constants in package lowlevel.
+type ExecuteError = lowlevel.ExecuteError
+
+// ErrorInfo is the structured failure detail attached to an ExecuteError by
+// modules exporting the error buffer (see lowlevel.ExportGetErrorPtr).
+type ErrorInfo = lowlevel.ErrorInfo
+
+// ErrOutOfMemory matches an *OutOfMemoryError with errors.Is.
+var ErrOutOfMemory = errors.New("guest out of memory")
+
+// OutOfMemoryError is returned when the guest runs out of memory: the input
+// allocation returned null, the plugin reported lowlevel.StatusOutOfMemory,
+// or the plugin trapped while its memory was close to the limit.
+//
+// Raise the limit with wazero.RuntimeConfig.WithMemoryLimitPages, or split
+// the request into smaller ones.
+type OutOfMemoryError struct {
+	// Pages is the size of guest memory in 64KiB pages at the failure.
+	Pages uint32
+	// LimitPages is the maximum size guest memory can grow to, in pages.
+	LimitPages uint32
+	// RequestSize is the size of the serialized request in bytes.
+	RequestSize int
+	// Err is the underlying failure.
+	Err error
+}
+
+// Error returns the error message.
+func (e *OutOfMemoryError) Error() string {
+	return fmt.Sprintf("%s: using %d of %d pages (%s of %s) with a %d byte request: %v; raise the memory limit with wazero.RuntimeConfig.WithMemoryLimitPages or split the request",
+		ErrOutOfMemory, e.Pages, e.LimitPages, formatPages(e.Pages), formatPages(e.LimitPages), e.RequestSize, e.Err)
+}
+
+// Is reports whether target is ErrOutOfMemory.
+func (e *OutOfMemoryError) Is(target error) bool {
+	return target == ErrOutOfMemory
+}
+
+// Unwrap returns the underlying failure.
+func (e *OutOfMemoryError) Unwrap() error {
+	return e.Err
+}
+
+// formatPages formats a number of 64KiB pages as MiB.
+func formatPages(pages uint32) string {
+	return fmt.Sprintf("%dMiB", uint64(pages)*wasmPageSize>>20)
+}
@@ -0,0 +1,49 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestExecuteError_Info(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	info := `{"code":"unresolved_type","message":"unknown type Foo","file":"a.proto","line":3,"column":5}`
+	i32 := []byte{wasmtest.I32}
+	stub := abiStubModule(lowlevel.StatusInvalidRequest,
+		wasmtest.Func{Name: lowlevel.ExportGetErrorPtr, Results: i32, Code: wasmtest.I32Const(0)},
+		wasmtest.Func{Name: lowlevel.ExportGetErrorLen, Results: i32, Code: wasmtest.I32Const(int32(len(info)))},
+	)
+	stub.Data = []byte(info)
+
+	compiled, err := r.CompileModule(ctx, stub.Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	_, err = p.Execute(ctx, []byte{0x0a, 0x01, 'x'})
+	var execErr *ExecuteError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("expected *ExecuteError, got %v", err)
+	}
+	want := ErrorInfo{Code: "unresolved_type", Message: "unknown type Foo", File: "a.proto", Line: 3, Column: 5}
+	if execErr.Info == nil || *execErr.Info != want {
+		t.Fatalf("unexpected error info: %+v", execErr.Info)
+	}
+	if !strings.Contains(err.Error(), "a.proto:3:5: unknown type Foo") {
+		t.Fatalf("expected location in error message: %v", err)
+	}
+}
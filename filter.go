@@ -0,0 +1,91 @@
+package prost
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FileFilter selects generated files by name.
+//
+// Patterns use path.Match syntax with an additional "**" element matching
+// any number of path elements. Like buf's --exclude-path, a pattern without
+// wildcards also matches all files below it when it names a directory.
+type FileFilter struct {
+	// Include selects the files to keep. If empty, all files are included.
+	Include []string
+	// Exclude removes files from the included set.
+	Exclude []string
+}
+
+// Match checks if the file name is selected by the filter.
+func (f *FileFilter) Match(name string) bool {
+	if len(f.Include) != 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+// FilterFiles removes the response files not selected by the filter.
+// Insertion points are kept or removed together with the file they target.
+func FilterFiles(resp *pluginpb.CodeGeneratorResponse, f *FileFilter) {
+	files := resp.File[:0]
+	for _, file := range resp.GetFile() {
+		if f.Match(file.GetName()) {
+			files = append(files, file)
+		}
+	}
+	clear(resp.File[len(files):])
+	resp.File = files
+}
+
+// matchAny checks if name matches any of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob checks if the slash-separated name matches the pattern.
+//
+// Patterns use path.Match syntax per path element. A "**" element matches zero
+// or more elements. A pattern also matches all names below a matching directory.
+func MatchGlob(pattern, name string) bool {
+	return matchElems(splitPath(pattern), splitPath(name))
+}
+
+// matchElems matches pattern elements against name elements.
+// Trailing name elements are allowed once the pattern is exhausted.
+func matchElems(pattern, name []string) bool {
+	for len(pattern) != 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return true
+}
+
+// splitPath splits a slash-separated path into elements.
+func splitPath(p string) []string {
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "" || p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
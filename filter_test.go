@@ -0,0 +1,53 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"google", "google/protobuf/any.pb.rs", true},
+		{"google/**/*.rs", "google/protobuf/any.pb.rs", true},
+		{"**/*.pb.rs", "a.pb.rs", true},
+		{"*.rs", "a/b.rs", false},
+		{"third_party/*", "third_party/x/y.rs", true},
+		{"googleapis", "google/api.rs", false},
+	}
+	for _, c := range cases {
+		if got := MatchGlob(c.pattern, c.name); got != c.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestProtocGenProst_FileFilter(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithFileFilter(&FileFilter{Exclude: []string{"test"}}))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	output, err := p.Execute(ctx, minimalRequestInput(t))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.GetFile()) != 0 {
+		t.Fatalf("expected all files to be excluded, got %d", len(resp.GetFile()))
+	}
+}
@@ -0,0 +1,185 @@
+package prost
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize is the largest frame accepted by the framed protocol.
+const maxFrameSize = 1 << 30
+
+// defaultMaxRequestSize is the largest request accepted by the servers of
+// ServeListener and the remote plugin handler unless ServeLimits sets
+// MaxRequestSize.
+const defaultMaxRequestSize = 64 << 20
+
+// frameChunk is the initial buffer size of a frame body.
+const frameChunk = 64 << 10
+
+// Frame status codes sent before each response frame.
+const (
+	frameOK        byte = 0
+	frameErr       byte = 1
+	frameExhausted byte = 2
+)
+
+// RemoteError is an error reported by the server side of the framed protocol.
+type RemoteError struct {
+	// Message is the error message reported by the server.
+	Message string
+	// Exhausted is set if the server rejected the request because a
+	// ServeLimits limit was reached.
+	Exhausted bool
+}
+
+// Error returns the error message.
+func (e *RemoteError) Error() string {
+	return "remote error: " + e.Message
+}
+
+// Is reports whether target is ErrResourceExhausted and the server rejected
+// the request over a limit.
+func (e *RemoteError) Is(target error) bool {
+	return e.Exhausted && target == ErrResourceExhausted
+}
+
+// ServeFrames serves the framed protocol on p until r is closed.
+//
+// Each request is a little-endian uint32 length followed by a serialized
+// CodeGeneratorRequest. Each reply is a status byte (0 for success, 1 for
+// error, 2 for a request rejected by ServeLimits) followed by a
+// length-prefixed CodeGeneratorResponse or UTF-8 error message. Used by
+// Subprocess and ServeListener.
+func ServeFrames(ctx context.Context, p *ProtocGenProst, r io.Reader, w io.Writer) error {
+	return serveFrames(ctx, p.ExecuteInto, r, w, nil, maxFrameSize)
+}
+
+// executeIntoFunc runs a serialized request, appending the serialized
+// response to out.
+type executeIntoFunc func(ctx context.Context, input, out []byte) ([]byte, error)
+
+// serveFrames implements ServeFrames on exec, admitting each request with
+// admit if not nil. Requests are admitted before their body is read, and
+// requests larger than maxSize are rejected; the bodies of rejected requests
+// are discarded so the connection stays usable.
+func serveFrames(ctx context.Context, exec executeIntoFunc, r io.Reader, w io.Writer, admit frameAdmitter, maxSize uint32) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	var out []byte
+	for {
+		n, err := readFrameHeader(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		status := frameOK
+		release := func() {}
+		switch {
+		case n > maxSize:
+			status, out = frameErr, fmt.Appendf(out[:0], "request of %d bytes exceeds the limit of %d bytes", n, maxSize)
+		case admit != nil:
+			if release, err = admit(); err != nil {
+				status, out = frameExhausted, []byte(err.Error())
+			}
+		}
+		if status != frameOK {
+			if _, err := io.CopyN(io.Discard, br, int64(n)); err != nil {
+				return err
+			}
+		} else {
+			input, err := readFrameBody(br, n)
+			if err != nil {
+				release()
+				return err
+			}
+			out, err = exec(ctx, input, out[:0])
+			release()
+			if err != nil {
+				status, out = frameErr, []byte(err.Error())
+			}
+		}
+		if err := bw.WriteByte(status); err != nil {
+			return err
+		}
+		if err := writeFrame(bw, out); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// roundTripFrame sends one request and reads the reply.
+func roundTripFrame(w io.Writer, r *bufio.Reader, input []byte) ([]byte, error) {
+	if err := writeFrame(w, input); err != nil {
+		return nil, fmt.Errorf("write failed: %w", err)
+	}
+	status, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	out, err := readFrame(r)
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	if status != frameOK {
+		return nil, &RemoteError{Message: string(out), Exhausted: status == frameExhausted}
+	}
+	return out, nil
+}
+
+// writeFrame writes a length-prefixed frame.
+func writeFrame(w io.Writer, data []byte) error {
+	var hdr [4]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(len(data)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readFrame reads a length-prefixed frame.
+func readFrame(r io.Reader) ([]byte, error) {
+	n, err := readFrameHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return readFrameBody(r, n)
+}
+
+// readFrameHeader reads the length prefix of a frame.
+func readFrameHeader(r io.Reader) (uint32, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, err
+	}
+	n := binary.LittleEndian.Uint32(hdr[:])
+	if n > maxFrameSize {
+		return 0, fmt.Errorf("frame too large: %d bytes", n)
+	}
+	return n, nil
+}
+
+// readFrameBody reads a frame body of n bytes. The buffer grows as data
+// arrives instead of being sized from the length prefix, so a header alone
+// cannot force a large allocation.
+func readFrameBody(r io.Reader, n uint32) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, min(n, frameChunk)))
+	if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RequestFromFS compiles .proto sources from fsys into a CodeGeneratorRequest,
+// so codegen tools can embed their sources with embed.FS and need no protoc
+// or external files.
+//
+// includeRoots are slash-separated directories of fsys that imports and
+// toGenerate are resolved against, like protoc -I; if empty, the root of
+// fsys is used. Like protoc, a root of the form "virtual=dir" maps the import
+// path prefix virtual to dir, so vendored files can keep their canonical
+// import paths: "google/api=third_party/googleapis/google/api" serves
+// third_party/googleapis/google/api/http.proto as google/api/http.proto.
+// The first root containing a file wins.
+//
+// If toGenerate is empty, every .proto file under the include roots is
+// generated. The well-known types may be imported without adding them.
+// Comments are kept in source_code_info for generated docs.
+func RequestFromFS(fsys fs.FS, includeRoots []string, toGenerate []string) (*pluginpb.CodeGeneratorRequest, error) {
+	roots, err := parseIncludeRoots(includeRoots)
+	if err != nil {
+		return nil, err
+	}
+	if len(toGenerate) == 0 {
+		if toGenerate, err = findProtoFiles(fsys, roots); err != nil {
+			return nil, err
+		}
+		if len(toGenerate) == 0 {
+			return nil, errors.New("no .proto files found")
+		}
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: func(name string) (io.ReadCloser, error) {
+				return openInclude(fsys, roots, name)
+			},
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), toGenerate...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile proto sources: %w", err)
+	}
+
+	b := NewRequestBuilder()
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor) error
+	add = func(fd protoreflect.FileDescriptor) error {
+		if seen[fd.Path()] {
+			return nil
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			if err := add(imports.Get(i).FileDescriptor); err != nil {
+				return err
+			}
+		}
+		return b.AddFile(protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, f := range files {
+		if err := add(f); err != nil {
+			return nil, err
+		}
+	}
+	return b.Generate(toGenerate...).Build()
+}
+
+// includeRoot maps the import paths below virtual to dir.
+type includeRoot struct {
+	// virtual is the import path prefix, or empty for all paths.
+	virtual string
+	// dir is the directory of the fs.FS the paths are resolved in.
+	dir string
+}
+
+// parseIncludeRoots parses "dir" and "virtual=dir" include roots.
+// Returns the root of the file system if roots is empty.
+func parseIncludeRoots(roots []string) ([]includeRoot, error) {
+	if len(roots) == 0 {
+		return []includeRoot{{dir: "."}}, nil
+	}
+	parsed := make([]includeRoot, len(roots))
+	for i, root := range roots {
+		virtual, dir, ok := strings.Cut(root, "=")
+		if !ok {
+			virtual, dir = "", root
+		}
+		r := includeRoot{virtual: path.Clean(virtual), dir: path.Clean(dir)}
+		if !ok || r.virtual == "." {
+			r.virtual = ""
+		}
+		if !fs.ValidPath(r.dir) || (r.virtual != "" && !fs.ValidPath(r.virtual)) {
+			return nil, fmt.Errorf("invalid include root: %q", root)
+		}
+		parsed[i] = r
+	}
+	return parsed, nil
+}
+
+// resolve returns the path of the import name in the file system.
+// Returns false if name is not below the virtual prefix.
+func (r includeRoot) resolve(name string) (string, bool) {
+	if r.virtual == "" {
+		return path.Join(r.dir, name), true
+	}
+	rest, ok := strings.CutPrefix(name, r.virtual+"/")
+	if !ok {
+		return "", false
+	}
+	return path.Join(r.dir, rest), true
+}
+
+// openInclude opens name in the first include root containing it.
+func openInclude(fsys fs.FS, roots []includeRoot, name string) (io.ReadCloser, error) {
+	for _, root := range roots {
+		p, ok := root.resolve(name)
+		if !ok {
+			continue
+		}
+		f, err := fsys.Open(p)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// findProtoFiles lists the import paths of the .proto files under the include
+// roots, sorted. Files found under several roots are listed once.
+func findProtoFiles(fsys fs.FS, roots []includeRoot) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, root := range roots {
+		err := fs.WalkDir(fsys, root.dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(p, ".proto") {
+				return err
+			}
+			name := p
+			if root.dir != "." {
+				name = strings.TrimPrefix(p, root.dir+"/")
+			}
+			name = path.Join(root.virtual, name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,80 @@
+package prost
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestRequestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"proto/a/a.proto": {Data: []byte(`syntax = "proto3";
+package a;
+import "b/b.proto";
+import "google/protobuf/timestamp.proto";
+// A is documented.
+message A { b.B b = 1; google.protobuf.Timestamp at = 2; }
+`)},
+		"proto/b/b.proto": {Data: []byte(`syntax = "proto3"; package b; message B {}`)},
+		"README.md":       {Data: []byte("not a proto")},
+	}
+
+	req, err := RequestFromFS(fsys, []string{"proto"}, nil)
+	if err != nil {
+		t.Fatalf("RequestFromFS failed: %v", err)
+	}
+	if !slices.Equal(req.GetFileToGenerate(), []string{"a/a.proto", "b/b.proto"}) {
+		t.Fatalf("unexpected files to generate: %v", req.GetFileToGenerate())
+	}
+	var names []string
+	for _, fd := range req.GetProtoFile() {
+		names = append(names, fd.GetName())
+	}
+	if !slices.Contains(names, "google/protobuf/timestamp.proto") || slices.Index(names, "b/b.proto") > slices.Index(names, "a/a.proto") {
+		t.Fatalf("expected the imports in dependency order, got %v", names)
+	}
+	a := FilesByName(req)["a/a.proto"]
+	if loc := a.GetSourceCodeInfo().GetLocation(); !slices.ContainsFunc(loc, func(l *descriptorpb.SourceCodeInfo_Location) bool {
+		return strings.Contains(l.GetLeadingComments(), "A is documented.")
+	}) {
+		t.Fatal("expected comments in source_code_info")
+	}
+
+	req, err = RequestFromFS(fsys, []string{"proto"}, []string{"b/b.proto"})
+	if err != nil || !slices.Equal(req.GetFileToGenerate(), []string{"b/b.proto"}) {
+		t.Fatalf("unexpected request %v: %v", req.GetFileToGenerate(), err)
+	}
+	if _, err := RequestFromFS(fsys, nil, []string{"a/a.proto"}); err == nil {
+		t.Fatal("expected error for an import outside the include roots")
+	}
+}
+
+func TestRequestFromFS_VirtualRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"proto/api.proto": {Data: []byte(`syntax = "proto3";
+package api;
+import "google/api/http.proto";
+message Rule { google.api.HttpRule rule = 1; }
+`)},
+		"third_party/googleapis/google/api/http.proto": {Data: []byte(`syntax = "proto3"; package google.api; message HttpRule { string get = 2; }`)},
+	}
+	roots := []string{"proto", "google/api=third_party/googleapis/google/api"}
+	req, err := RequestFromFS(fsys, roots, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("RequestFromFS failed: %v", err)
+	}
+	if _, ok := FilesByName(req)["google/api/http.proto"]; !ok {
+		t.Fatalf("expected the vendored file under its import path, got %v", NewDependencyGraph(req).Files)
+	}
+
+	req, err = RequestFromFS(fsys, roots[1:], nil)
+	if err != nil || !slices.Equal(req.GetFileToGenerate(), []string{"google/api/http.proto"}) {
+		t.Fatalf("unexpected files to generate %v: %v", req.GetFileToGenerate(), err)
+	}
+	if _, err := RequestFromFS(fsys, []string{"google/api=../googleapis"}, nil); err == nil {
+		t.Fatal("expected error for an invalid include root")
+	}
+}
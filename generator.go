@@ -0,0 +1,83 @@
+package prost
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Executor runs CodeGeneratorRequests.
+//
+// It is implemented by ProtocGenProst, Client, PluginCommand and HandlerFunc,
+// and by prosttest.FakeGenerator for tests of code that orchestrates
+// generation.
+type Executor interface {
+	// Execute runs a serialized CodeGeneratorRequest and returns the
+	// serialized CodeGeneratorResponse.
+	Execute(ctx context.Context, input []byte) ([]byte, error)
+	// ExecuteRequest runs a CodeGeneratorRequest and returns the response.
+	ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+}
+
+var (
+	_ Executor = (*ProtocGenProst)(nil)
+	_ Executor = (*Client)(nil)
+	_ Executor = (*PluginCommand)(nil)
+	_ Executor = (*Pool)(nil)
+	_ Executor = HandlerFunc(nil)
+)
+
+// GeneratorName is the name of the protoc-gen-prost generator.
+const GeneratorName = "prost"
+
+// Generator is a named and versioned code generator running decoded
+// requests. Pipelines, pools, caches and servers can be written once against
+// it and work with any plugin wrapper.
+//
+// Executor.Execute takes the serialized request, so Executor implementations
+// provide a Generator through an adapter: ProtocGenProst.Generator,
+// GeneratorRegistry.Generator, or NewGenerator for any other Executor.
+type Generator interface {
+	// Execute runs a CodeGeneratorRequest and returns the response.
+	Execute(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+	// Name identifies the generator, e.g. "prost".
+	Name() string
+	// Version is the version of the generator, or empty if unknown.
+	Version() string
+}
+
+// NewGenerator returns a Generator running requests on e.
+func NewGenerator(name, version string, e Executor) Generator {
+	return &executorGenerator{name: name, version: version, exec: e}
+}
+
+// executorGenerator adapts an Executor to Generator.
+type executorGenerator struct {
+	name, version string
+	exec          Executor
+}
+
+// Execute runs req on the executor.
+func (g *executorGenerator) Execute(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	return g.exec.ExecuteRequest(ctx, req)
+}
+
+// Name returns the generator name.
+func (g *executorGenerator) Name() string {
+	return g.name
+}
+
+// Version returns the generator version.
+func (g *executorGenerator) Version() string {
+	return g.version
+}
+
+// Generator returns p as a Generator named GeneratorName.
+//
+// The version is Version for the embedded plugin. For other builds it is the
+// content of the VersionSectionName custom section, which is only available
+// if the runtime keeps custom sections (wazero.RuntimeConfig.WithCustomSections),
+// and empty otherwise.
+func (p *ProtocGenProst) Generator() Generator {
+	return NewGenerator(GeneratorName, p.version, p)
+}
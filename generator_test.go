@@ -0,0 +1,58 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_Generator(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	g := p.Generator()
+	if g.Name() != GeneratorName || g.Version() != Version {
+		t.Fatalf("unexpected generator %s %s", g.Name(), g.Version())
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(minimalRequestInput(t), req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := g.Execute(ctx, req)
+	if err != nil || len(resp.GetFile()) == 0 {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+}
+
+func TestProtocGenProst_GeneratorCustomVersion(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCustomSections(true))
+	defer r.Close(ctx)
+
+	// Custom section: id 0, size, name, data
+	name, data := VersionSectionName, "v1.2.3-fork\n"
+	wasm := abiStubModule(0).Encode()
+	wasm = append(wasm, 0, byte(1+len(name)+len(data)), byte(len(name)))
+	wasm = append(append(wasm, name...), data...)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+	if v := p.Generator().Version(); v != "v1.2.3-fork" {
+		t.Fatalf("unexpected version %q", v)
+	}
+}
@@ -0,0 +1,221 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ErrGeneratorNotFound is returned by GeneratorRegistry if no generator is
+// registered under a key.
+var ErrGeneratorNotFound = errors.New("generator not found")
+
+// GeneratorRegistry holds several generators keyed by name and version, e.g.
+// prost@0.4 and prost@0.5, and dispatches calls by key. This lets one
+// service serve requests pinned to different generator versions.
+//
+// Keys have the form name@version. A key without a version selects the
+// highest registered version of the name, comparing dot-separated numeric
+// components numerically. GeneratorRegistry is safe for concurrent use.
+type GeneratorRegistry struct {
+	mu         sync.RWMutex
+	generators map[string]*registeredGenerator
+}
+
+// registeredGenerator is a generator with the resources owned by the registry.
+type registeredGenerator struct {
+	name, version string
+	exec          Executor
+	// close releases the instance and runtime created by Load, if any.
+	close func(ctx context.Context) error
+}
+
+// NewGeneratorRegistry creates an empty GeneratorRegistry.
+func NewGeneratorRegistry() *GeneratorRegistry {
+	return &GeneratorRegistry{generators: make(map[string]*registeredGenerator)}
+}
+
+// Register adds a generator under name@version.
+// The caller keeps ownership of e. Returns an error if the key is taken.
+func (r *GeneratorRegistry) Register(name, version string, e Executor) error {
+	return r.add(&registeredGenerator{name: name, version: version, exec: e})
+}
+
+// Load compiles a WASM plugin in a new runtime and registers it under
+// name@version. The registry owns the instance and closes it on Unregister
+// or Close.
+func (r *GeneratorRegistry) Load(ctx context.Context, name, version string, wasm []byte, opts ...Option) error {
+	rt := wazero.NewRuntime(ctx)
+	compiled, err := compileWASM(ctx, rt, wasm)
+	if err != nil {
+		rt.Close(ctx)
+		return fmt.Errorf("%s@%s: %w", name, version, err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, rt, compiled, opts...)
+	if err != nil {
+		rt.Close(ctx)
+		return fmt.Errorf("%s@%s: %w", name, version, err)
+	}
+	p.moduleID = wasmModuleID(wasm)
+	g := &registeredGenerator{
+		name:    name,
+		version: version,
+		exec:    p,
+		close: func(ctx context.Context) error {
+			err := p.Close(ctx)
+			if cerr := rt.Close(ctx); err == nil {
+				err = cerr
+			}
+			return err
+		},
+	}
+	if err := r.add(g); err != nil {
+		g.close(ctx)
+		return err
+	}
+	return nil
+}
+
+// add registers g.
+func (r *GeneratorRegistry) add(g *registeredGenerator) error {
+	if g.name == "" || g.version == "" || strings.Contains(g.name, "@") {
+		return fmt.Errorf("invalid generator key: %s@%s", g.name, g.version)
+	}
+	key := g.name + "@" + g.version
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.generators[key]; ok {
+		return fmt.Errorf("generator already registered: %s", key)
+	}
+	r.generators[key] = g
+	return nil
+}
+
+// Lookup returns the generator for a key.
+// Returns an error wrapping ErrGeneratorNotFound if there is none.
+func (r *GeneratorRegistry) Lookup(key string) (Executor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g := r.lookup(key)
+	if g == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGeneratorNotFound, key)
+	}
+	return g.exec, nil
+}
+
+// lookup resolves a key. Must be called with mu held.
+func (r *GeneratorRegistry) lookup(key string) *registeredGenerator {
+	if strings.Contains(key, "@") {
+		return r.generators[key]
+	}
+	var best *registeredGenerator
+	for _, g := range r.generators {
+		if g.name == key && (best == nil || compareVersions(g.version, best.version) > 0) {
+			best = g
+		}
+	}
+	return best
+}
+
+// Generator returns the generator for a key as a Generator reporting its
+// registered name and version.
+// Returns an error wrapping ErrGeneratorNotFound if there is none.
+func (r *GeneratorRegistry) Generator(key string) (Generator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g := r.lookup(key)
+	if g == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGeneratorNotFound, key)
+	}
+	return NewGenerator(g.name, g.version, g.exec), nil
+}
+
+// Keys returns the registered keys, sorted.
+func (r *GeneratorRegistry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.generators))
+	for key := range r.generators {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Execute runs a serialized CodeGeneratorRequest on the generator for key.
+func (r *GeneratorRegistry) Execute(ctx context.Context, key string, input []byte) ([]byte, error) {
+	e, err := r.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return e.Execute(ctx, input)
+}
+
+// ExecuteRequest runs a CodeGeneratorRequest on the generator for key.
+func (r *GeneratorRegistry) ExecuteRequest(ctx context.Context, key string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	e, err := r.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return e.ExecuteRequest(ctx, req)
+}
+
+// Unregister removes the generator registered under name@version, closing it
+// if it was added with Load. Calls already dispatched to it are waited for.
+func (r *GeneratorRegistry) Unregister(ctx context.Context, key string) error {
+	r.mu.Lock()
+	g, ok := r.generators[key]
+	delete(r.generators, key)
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrGeneratorNotFound, key)
+	}
+	if g.close != nil {
+		return g.close(ctx)
+	}
+	return nil
+}
+
+// Close unregisters all generators, closing the ones added with Load.
+func (r *GeneratorRegistry) Close(ctx context.Context) error {
+	r.mu.Lock()
+	generators := r.generators
+	r.generators = make(map[string]*registeredGenerator)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, g := range generators {
+		if g.close != nil {
+			errs = append(errs, g.close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compareVersions compares dot-separated versions, numerically where both
+// components are numbers. A leading "v" is ignored.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
+}
@@ -0,0 +1,79 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestGeneratorRegistry(t *testing.T) {
+	ctx := context.Background()
+	reg := NewGeneratorRegistry()
+	defer reg.Close(ctx)
+
+	if err := reg.Load(ctx, "prost", "0.5", ProtocGenProstWASM()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	old := &counterExecutor{}
+	if err := reg.Register("prost", "0.4.10", old); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := reg.Register("prost", "0.4.10", old); err == nil {
+		t.Fatal("expected error for duplicate key")
+	}
+	if got := reg.Keys(); !reflect.DeepEqual(got, []string{"prost@0.4.10", "prost@0.5"}) {
+		t.Fatalf("unexpected keys %v", got)
+	}
+
+	if _, err := reg.ExecuteRequest(ctx, "prost@0.4.10", &pluginpb.CodeGeneratorRequest{}); err != nil || old.calls != 1 {
+		t.Fatalf("expected call on the pinned generator: %v", err)
+	}
+	g, err := reg.Generator("prost@0.4.10")
+	if err != nil || g.Name() != "prost" || g.Version() != "0.4.10" {
+		t.Fatalf("unexpected generator %v: %v", g, err)
+	}
+	if _, err := g.Execute(ctx, &pluginpb.CodeGeneratorRequest{}); err != nil || old.calls != 2 {
+		t.Fatalf("expected call through the generator: %v", err)
+	}
+	latest, err := reg.Lookup("prost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := latest.(*ProtocGenProst); !ok {
+		t.Fatalf("expected prost to resolve to 0.5, got %T", latest)
+	}
+	if _, err := reg.Execute(ctx, "prost", minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if err := reg.Unregister(ctx, "prost@0.5"); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+	if _, err := reg.Lookup("prost@0.5"); !errors.Is(err, ErrGeneratorNotFound) {
+		t.Fatalf("expected ErrGeneratorNotFound, got %v", err)
+	}
+	if _, err := latest.Execute(ctx, minimalRequestInput(t)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected loaded generator to be closed, got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.5", "0.4.10", 1},
+		{"0.4.9", "0.4.10", -1},
+		{"v1.2", "1.2", 0},
+		{"1.2", "1.2.1", -1},
+	}
+	for _, tt := range tests {
+		got := compareVersions(tt.a, tt.b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compareVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
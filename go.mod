@@ -3,8 +3,31 @@ module github.com/aperturerobotics/go-protoc-gen-prost
 go 1.24.0
 
 require (
+	github.com/aperturerobotics/go-protoc-gen-prost/embedded v0.1.0
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/prometheus/client_golang v1.23.2
 	github.com/tetratelabs/wazero v1.11.0
+	golang.org/x/crypto v0.45.0
+	golang.org/x/sys v0.38.0
 	google.golang.org/protobuf v1.36.11
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require golang.org/x/sys v0.38.0 // indirect
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.66.1 // indirect
+	github.com/prometheus/procfs v0.16.1 // indirect
+	go.yaml.in/yaml/v2 v2.4.2 // indirect
+	golang.org/x/sync v0.13.0 // indirect
+)
+
+// The embedded module is developed in this repository. The replace only
+// applies to builds of this module; consumers resolve the required version,
+// which must be published as the embedded/vX.Y.Z tag before this module is
+// tagged.
+replace github.com/aperturerobotics/go-protoc-gen-prost/embedded => ./embedded
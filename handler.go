@@ -0,0 +1,75 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// HandlerFunc is the handler signature used by Go protoc plugin frameworks and multiplexers.
+type HandlerFunc func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+
+// ExecuteRequest runs the plugin with a decoded CodeGeneratorRequest.
+// It marshals the request, calls Execute, and unmarshals the response.
+// The intermediate encodings use pooled buffers.
+func (p *ProtocGenProst) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	if len(p.opts.interceptors) != 0 {
+		return p.intercept(ctx, req)
+	}
+	return p.executeRequest(ctx, req)
+}
+
+// executeRequest is ExecuteRequest without the interceptors.
+func (p *ProtocGenProst) executeRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	inBuf := p.getBuffer()
+	defer p.putBuffer(inBuf)
+	input, err := proto.MarshalOptions{}.MarshalAppend(*inBuf, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	*inBuf = input
+
+	outBuf := p.getBuffer()
+	defer p.putBuffer(outBuf)
+	output, err := p.executeCall(ctx, input, *outBuf)
+	if err != nil {
+		return nil, err
+	}
+	*outBuf = output
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp, nil
+}
+
+// Handler returns a HandlerFunc executing requests with p.
+// The context is used for every call made through the handler.
+func (p *ProtocGenProst) Handler(ctx context.Context) HandlerFunc {
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		return p.ExecuteRequest(ctx, req)
+	}
+}
+
+// Execute runs a serialized CodeGeneratorRequest with h, so a HandlerFunc
+// can be used as an Executor.
+func (h HandlerFunc) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(input, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+	resp, err := h(req)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(resp)
+}
+
+// ExecuteRequest runs a CodeGeneratorRequest with h.
+// The context is not passed to h.
+func (h HandlerFunc) ExecuteRequest(_ context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	return h(req)
+}
@@ -0,0 +1,38 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_Handler(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(minimalRequestInput(t), req); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	resp, err := p.Handler(ctx)(req)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+	if len(resp.GetFile()) != 1 || resp.GetFile()[0].GetName() != "test/test.pb.rs" {
+		t.Fatalf("expected test/test.pb.rs, got %v", resp.GetFile())
+	}
+}
@@ -0,0 +1,61 @@
+package prost
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// commentPrefixes maps file extensions to their line comment prefix.
+var commentPrefixes = map[string]string{
+	".rs":   "//",
+	".toml": "#",
+	".yaml": "#",
+	".yml":  "#",
+}
+
+// DefaultHeader returns a "do not edit" banner naming the generator version.
+func DefaultHeader() string {
+	return "Code generated by protoc-gen-prost " + Version + ". DO NOT EDIT."
+}
+
+// CommentHeader formats header as a line comment block for the file name.
+// Returns false if the comment syntax for the file type is unknown.
+func CommentHeader(name, header string) (string, bool) {
+	prefix, ok := commentPrefixes[path.Ext(name)]
+	if !ok {
+		return "", false
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(header, "\n"), "\n") {
+		sb.WriteString(prefix)
+		if line != "" {
+			sb.WriteByte(' ')
+			sb.WriteString(line)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), true
+}
+
+// ApplyHeader prepends header as a comment to every file in the response.
+//
+// The header is formatted with the comment syntax of each file type; files of
+// unknown type and insertion point fragments are left unchanged.
+func ApplyHeader(resp *pluginpb.CodeGeneratorResponse, header string) {
+	if header == "" {
+		return
+	}
+	for _, f := range resp.GetFile() {
+		if f.GetInsertionPoint() != "" {
+			continue
+		}
+		comment, ok := CommentHeader(f.GetName(), header)
+		if !ok {
+			continue
+		}
+		f.Content = proto.String(comment + f.GetContent())
+	}
+}
@@ -0,0 +1,30 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestApplyHeader(t *testing.T) {
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a.rs"), Content: proto.String("// @generated\n")},
+			{Name: proto.String("a.rs"), InsertionPoint: proto.String("module"), Content: proto.String("x")},
+			{Name: proto.String("data.bin"), Content: proto.String("raw")},
+		},
+	}
+	ApplyHeader(resp, "Copyright Example\n\nSPDX-License-Identifier: MIT")
+
+	want := "// Copyright Example\n//\n// SPDX-License-Identifier: MIT\n// @generated\n"
+	if got := resp.GetFile()[0].GetContent(); got != want {
+		t.Fatalf("unexpected content:\n%s", got)
+	}
+	if got := resp.GetFile()[1].GetContent(); got != "x" {
+		t.Fatalf("insertion point fragment was modified: %q", got)
+	}
+	if got := resp.GetFile()[2].GetContent(); got != "raw" {
+		t.Fatalf("unknown file type was modified: %q", got)
+	}
+}
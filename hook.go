@@ -0,0 +1,152 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HookFilesArg is expanded by CommandHook to the names of the generated files.
+const HookFilesArg = "{files}"
+
+// Hook runs after generated files are written, e.g. to format them or check
+// that they compile.
+type Hook interface {
+	// RunHook runs the hook against the output directory dir containing the
+	// generated files, given as slash-separated paths relative to dir.
+	RunHook(ctx context.Context, dir string, files []string) *HookResult
+}
+
+// HookResult reports the outcome of a hook.
+type HookResult struct {
+	// Name identifies the hook.
+	Name string `json:"name"`
+	// OK is set if the hook succeeded.
+	OK bool `json:"ok"`
+	// ExitCode is the exit code of a command hook, or -1 if it did not exit.
+	ExitCode int `json:"exitCode"`
+	// Output is the combined stdout and stderr of a command hook.
+	Output string `json:"output,omitempty"`
+	// Error describes the failure, if any.
+	Error string `json:"error,omitempty"`
+	// Duration is the time the hook took.
+	Duration time.Duration `json:"duration"`
+}
+
+// HookError is returned by RunHooks if a hook failed.
+type HookError struct {
+	// Results are the results of all hooks that ran.
+	Results []*HookResult
+}
+
+// Error returns the error message.
+func (e *HookError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if !r.OK {
+			failed = append(failed, r.Name)
+		}
+	}
+	return "hooks failed: " + strings.Join(failed, ", ")
+}
+
+// RunHooks runs the hooks in order against dir. Stops at the first failing
+// hook and returns a *HookError. The results of the hooks that ran are
+// returned in both cases.
+func RunHooks(ctx context.Context, dir string, files []string, hooks []Hook) ([]*HookResult, error) {
+	results := make([]*HookResult, 0, len(hooks))
+	for _, h := range hooks {
+		res := h.RunHook(ctx, dir, files)
+		results = append(results, res)
+		if !res.OK {
+			return results, &HookError{Results: results}
+		}
+	}
+	return results, nil
+}
+
+// CommandHook runs an external command in the output directory.
+type CommandHook struct {
+	// Name identifies the hook in results. Defaults to the command line.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Command is the program and its arguments. An argument equal to
+	// HookFilesArg is replaced by the generated file names.
+	Command []string `yaml:"command" json:"command"`
+	// Dir is the working directory relative to the output directory.
+	// The file names passed for HookFilesArg are relative to it.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// Env are extra environment variables as KEY=value.
+	Env []string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// CargoCheckHook checks that the crate containing the output compiles.
+func CargoCheckHook() *CommandHook {
+	return &CommandHook{Name: "cargo check", Command: []string{"cargo", "check", "--quiet"}}
+}
+
+// RustfmtHook formats the generated files with rustfmt.
+func RustfmtHook() *CommandHook {
+	return &CommandHook{Name: "rustfmt", Command: []string{"rustfmt", "--edition", "2021", HookFilesArg}}
+}
+
+// RunHook runs the command.
+func (h *CommandHook) RunHook(ctx context.Context, dir string, files []string) *HookResult {
+	res := &HookResult{Name: h.Name, ExitCode: -1}
+	if res.Name == "" {
+		res.Name = strings.Join(h.Command, " ")
+	}
+	if len(h.Command) == 0 {
+		res.Error = "empty command"
+		return res
+	}
+
+	workDir := filepath.Join(dir, filepath.FromSlash(h.Dir))
+	var args []string
+	for _, arg := range h.Command[1:] {
+		if arg != HookFilesArg {
+			args = append(args, arg)
+			continue
+		}
+		for _, f := range files {
+			rel, err := filepath.Rel(workDir, filepath.Join(dir, filepath.FromSlash(f)))
+			if err != nil {
+				rel = filepath.Join(dir, filepath.FromSlash(f))
+			}
+			args = append(args, rel)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Command[0], args...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), h.Env...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	start := time.Now()
+	err := cmd.Run()
+	res.Duration = time.Since(start)
+	res.Output = out.String()
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.OK = true
+	case errors.As(err, &exitErr):
+		res.Error = fmt.Sprintf("exit status %d", res.ExitCode)
+	default:
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// _ is a type assertion
+var _ Hook = ((*CommandHook)(nil))
@@ -0,0 +1,46 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHooks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "a.pb.rs"), []byte("// a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := []Hook{
+		&CommandHook{Name: "list", Command: []string{"ls", HookFilesArg}},
+		&CommandHook{Command: []string{"sh", "-c", "echo broken; exit 3"}},
+		&CommandHook{Name: "never", Command: []string{"true"}},
+	}
+	results, err := RunHooks(ctx, dir, []string{"a/a.pb.rs"}, hooks)
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected HookError, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected to stop after the failing hook, got %d results", len(results))
+	}
+	if !results[0].OK || strings.TrimSpace(results[0].Output) != "a/a.pb.rs" {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+	if res := results[1]; res.OK || res.ExitCode != 3 || res.Name != "sh -c echo broken; exit 3" || strings.TrimSpace(res.Output) != "broken" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	res := (&CommandHook{Command: []string{"go-prost-no-such-command"}}).RunHook(ctx, dir, nil)
+	if res.OK || res.ExitCode != -1 || res.Error == "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
@@ -0,0 +1,161 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HostModuleName is the import module of the host functions a plugin build
+// may call. NewProtocGenProst and the other constructors instantiate it on
+// the runtime if the plugin imports it.
+//
+// Functions:
+//
+//   - host_log(level, ptr, len): logs the UTF-8 message at ptr, see HostLogLevel
+//   - host_cache_get(key_ptr, key_len) -> i32: looks up a key in the guest
+//     cache, see WithGuestCache. Returns the length of the value, or -1 if
+//     it is not cached.
+//   - host_cache_read(dst_ptr) -> i32: copies the value found by the last
+//     host_cache_get to dst_ptr. Returns its length, or -1 if there is none.
+//   - host_cache_put(key_ptr, key_len, value_ptr, value_len) -> i32: stores a
+//     value in the guest cache. Returns 0, or -1 if it was not stored.
+const HostModuleName = "prost_host"
+
+// HostLogLevel is the level passed to host_log, numbered like the levels of
+// the Rust log crate.
+type HostLogLevel int32
+
+// Levels accepted by host_log.
+const (
+	HostLogError HostLogLevel = 1
+	HostLogWarn  HostLogLevel = 2
+	HostLogInfo  HostLogLevel = 3
+	HostLogDebug HostLogLevel = 4
+	HostLogTrace HostLogLevel = 5
+)
+
+// SlogLevel maps the level to a slog level. Trace maps below slog.LevelDebug
+// and unknown levels to slog.LevelInfo.
+func (l HostLogLevel) SlogLevel() slog.Level {
+	switch l {
+	case HostLogError:
+		return slog.LevelError
+	case HostLogWarn:
+		return slog.LevelWarn
+	case HostLogDebug:
+		return slog.LevelDebug
+	case HostLogTrace:
+		return slog.LevelDebug - 4
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithLogger routes messages the plugin logs with host_log to logger.
+// Messages are dropped if no logger is configured.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// InstantiateHost instantiates the HostModuleName module on r. Only needed
+// for modules instantiated without the constructors of this package.
+// Host functions find their configuration in the context passed to the
+// guest call, so one instance serves every plugin on r.
+func InstantiateHost(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	i32 := api.ValueTypeI32
+	b := r.NewHostModuleBuilder(HostModuleName)
+	b.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(hostLog), []api.ValueType{i32, i32, i32}, nil).
+		WithParameterNames("level", "ptr", "len").
+		Export("host_log")
+	b.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(hostCacheGet), []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		WithParameterNames("key_ptr", "key_len").
+		Export("host_cache_get")
+	b.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(hostCacheRead), []api.ValueType{i32}, []api.ValueType{i32}).
+		WithParameterNames("dst_ptr").
+		Export("host_cache_read")
+	b.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(hostCachePut), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}).
+		WithParameterNames("key_ptr", "key_len", "value_ptr", "value_len").
+		Export("host_cache_put")
+	return b.Instantiate(ctx)
+}
+
+// importsHost checks if compiled imports any function of HostModuleName.
+func importsHost(compiled wazero.CompiledModule) bool {
+	for _, def := range compiled.ImportedFunctions() {
+		if module, _, _ := def.Import(); module == HostModuleName {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureHost instantiates the host module on r if compiled imports it and
+// it is not instantiated yet.
+func ensureHost(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule) error {
+	if !importsHost(compiled) || r.Module(HostModuleName) != nil {
+		return nil
+	}
+	if _, err := InstantiateHost(ctx, r); err != nil {
+		return fmt.Errorf("failed to instantiate %s: %w", HostModuleName, err)
+	}
+	return nil
+}
+
+// hostState is the configuration of the host functions during a guest call.
+type hostState struct {
+	logger *slog.Logger
+	cache  Cache
+	// moduleID identifies the module for the guest cache keys.
+	moduleID string
+	// staged is the value found by the last host_cache_get.
+	staged []byte
+}
+
+// hostContextKey is the context key of the *hostState.
+type hostContextKey struct{}
+
+// hostContext attaches the host function configuration of p to ctx.
+// Must be used for every call into the guest.
+func (p *ProtocGenProst) hostContext(ctx context.Context) context.Context {
+	if p.opts.logger == nil && p.opts.guestCache == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, hostContextKey{}, &hostState{
+		logger:   p.opts.logger,
+		cache:    p.opts.guestCache,
+		moduleID: p.moduleID,
+	})
+}
+
+// hostFromContext returns the host function configuration, or nil.
+func hostFromContext(ctx context.Context) *hostState {
+	h, _ := ctx.Value(hostContextKey{}).(*hostState)
+	return h
+}
+
+// hostLog implements host_log.
+func hostLog(ctx context.Context, mod api.Module, stack []uint64) {
+	h := hostFromContext(ctx)
+	if h == nil || h.logger == nil {
+		return
+	}
+	level := HostLogLevel(int32(stack[0])).SlogLevel()
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+	msg, ok := mod.Memory().Read(uint32(stack[1]), uint32(stack[2]))
+	if !ok {
+		return
+	}
+	h.logger.Log(ctx, level, string(msg), slog.String("module", mod.Name()))
+}
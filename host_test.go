@@ -0,0 +1,57 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestWithLogger(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	msg := "resolving types"
+	stub := abiStubModule(0)
+	stub.Imports = []wasmtest.Import{{
+		Module: HostModuleName,
+		Name:   "host_log",
+		Params: []byte{wasmtest.I32, wasmtest.I32, wasmtest.I32},
+	}}
+	stub.Data = []byte(msg)
+	// Log before returning an empty response
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Code(
+				wasmtest.I32Const(int32(HostLogWarn)), wasmtest.I32Const(0), wasmtest.I32Const(int32(len(msg))),
+				wasmtest.Call(0),
+				wasmtest.I32Const(0),
+			)
+		}
+	}
+	compiled, err := r.CompileModule(ctx, stub.Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if _, err := p.Execute(ctx, nil); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, `msg="resolving types"`) {
+		t.Fatalf("expected guest message in log, got %q", out)
+	}
+}
@@ -0,0 +1,115 @@
+package prost
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// WithGuestCache lets the plugin store and look up its own intermediate
+// results, e.g. per-file generation output, in c through the host_cache_*
+// functions of HostModuleName. This allows incremental generation within a
+// single large request, which the request-level WithCache cannot.
+//
+// Keys chosen by the guest are hashed with GuestCacheKey, so c may be
+// shared with WithCache. Cache failures are reported to the guest as misses
+// and logged to the WithLogger logger.
+func WithGuestCache(c Cache) Option {
+	return func(o *options) {
+		o.guestCache = c
+	}
+}
+
+// GuestCacheKey returns the digest a guest cache key is stored under. Like
+// CacheKey it identifies the module the instance runs, so different plugin
+// builds never read each other's entries, and it never collides with a
+// CacheKey.
+func (p *ProtocGenProst) GuestCacheKey(key []byte) Digest {
+	return guestCacheDigest(p.moduleID, key)
+}
+
+// guestCacheDigest returns the digest of a guest cache key of the module
+// identified by moduleID.
+func guestCacheDigest(moduleID string, key []byte) Digest {
+	h := sha256.New()
+	h.Write([]byte("prost_host cache"))
+	h.Write([]byte{0})
+	h.Write([]byte(moduleID))
+	h.Write([]byte{0})
+	h.Write(key)
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// hostCacheGet implements host_cache_get.
+func hostCacheGet(ctx context.Context, mod api.Module, stack []uint64) {
+	keyPtr, keyLen := uint32(stack[0]), uint32(stack[1])
+	stack[0] = api.EncodeI32(-1)
+	h := hostFromContext(ctx)
+	if h == nil || h.cache == nil {
+		return
+	}
+	h.staged = nil
+	key, ok := mod.Memory().Read(keyPtr, keyLen)
+	if !ok {
+		return
+	}
+	value, found, err := h.cache.Get(ctx, guestCacheDigest(h.moduleID, key))
+	if err != nil {
+		h.logCacheError(ctx, "get", err)
+		return
+	}
+	if found {
+		h.staged = value
+		stack[0] = api.EncodeI32(int32(len(value)))
+	}
+}
+
+// hostCacheRead implements host_cache_read.
+func hostCacheRead(ctx context.Context, mod api.Module, stack []uint64) {
+	dst := uint32(stack[0])
+	stack[0] = api.EncodeI32(-1)
+	h := hostFromContext(ctx)
+	if h == nil || h.staged == nil {
+		return
+	}
+	value := h.staged
+	h.staged = nil
+	if mod.Memory().Write(dst, value) {
+		stack[0] = api.EncodeI32(int32(len(value)))
+	}
+}
+
+// hostCachePut implements host_cache_put.
+func hostCachePut(ctx context.Context, mod api.Module, stack []uint64) {
+	keyPtr, keyLen := uint32(stack[0]), uint32(stack[1])
+	valuePtr, valueLen := uint32(stack[2]), uint32(stack[3])
+	stack[0] = api.EncodeI32(-1)
+	h := hostFromContext(ctx)
+	if h == nil || h.cache == nil {
+		return
+	}
+	key, ok := mod.Memory().Read(keyPtr, keyLen)
+	if !ok {
+		return
+	}
+	value, ok := mod.Memory().Read(valuePtr, valueLen)
+	if !ok {
+		return
+	}
+	if err := h.cache.Put(ctx, guestCacheDigest(h.moduleID, key), value); err != nil {
+		h.logCacheError(ctx, "put", err)
+		return
+	}
+	stack[0] = 0
+}
+
+// logCacheError logs a failed guest cache operation.
+func (h *hostState) logCacheError(ctx context.Context, op string, err error) {
+	if h.logger != nil {
+		h.logger.LogAttrs(ctx, slog.LevelWarn, "guest cache "+op+" failed", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,77 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+// mapCache is an in-memory Cache.
+type mapCache map[Digest][]byte
+
+func (c mapCache) Get(ctx context.Context, digest Digest) ([]byte, bool, error) {
+	v, ok := c[digest]
+	return v, ok, nil
+}
+
+func (c mapCache) Put(ctx context.Context, digest Digest, resp []byte) error {
+	c[digest] = append([]byte(nil), resp...)
+	return nil
+}
+
+func TestWithGuestCache(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	i32 := wasmtest.I32
+	stub := abiStubModule(0)
+	stub.Imports = []wasmtest.Import{
+		{Module: HostModuleName, Name: "host_cache_get", Params: []byte{i32, i32}, Results: []byte{i32}},
+		{Module: HostModuleName, Name: "host_cache_read", Params: []byte{i32}, Results: []byte{i32}},
+		{Module: HostModuleName, Name: "host_cache_put", Params: []byte{i32, i32, i32, i32}, Results: []byte{i32}},
+	}
+	// Key "k" at 0, value "v1" at 1
+	stub.Data = []byte("kv1")
+	// Store the value, then read it back as the output
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Code(
+				wasmtest.I32Const(0), wasmtest.I32Const(1), wasmtest.I32Const(1), wasmtest.I32Const(2), wasmtest.Call(2), wasmtest.Drop,
+				wasmtest.I32Const(0), wasmtest.I32Const(1), wasmtest.Call(0), wasmtest.GlobalSet(1),
+				wasmtest.I32Const(100), wasmtest.Call(1), wasmtest.Drop,
+				wasmtest.I32Const(100), wasmtest.GlobalSet(0),
+				wasmtest.GlobalGet(1),
+			)
+		}
+	}
+	compiled, err := r.CompileModule(ctx, stub.Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+
+	cache := mapCache{}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithGuestCache(cache))
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	out, err := p.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != "v1" {
+		t.Fatalf("expected cached value as output, got %q", out)
+	}
+	if v, ok := cache[p.GuestCacheKey([]byte("k"))]; !ok || string(v) != "v1" {
+		t.Fatalf("expected value stored under the guest digest, got %q", v)
+	}
+	// Other builds of the plugin use their own keys.
+	if p.GuestCacheKey([]byte("k")) == guestCacheDigest(wasmModuleID([]byte("other")), []byte("k")) {
+		t.Fatal("expected guest cache keys to depend on the module")
+	}
+}
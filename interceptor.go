@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ExecuteFunc runs a decoded request. It is the next step passed to an
+// Interceptor.
+type ExecuteFunc func(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+
+// Interceptor runs around every Execute of an instance, for cross-cutting
+// concerns like caching, logging, request mutation or authorization. It calls
+// next to continue with the following interceptor and finally the plugin, or
+// returns without calling it to short-circuit the call. req may be modified
+// before passing it on.
+//
+//	logging := func(ctx context.Context, req *pluginpb.CodeGeneratorRequest, next prost.ExecuteFunc) (*pluginpb.CodeGeneratorResponse, error) {
+//		start := time.Now()
+//		resp, err := next(ctx, req)
+//		slog.Info("generated", "files", len(resp.GetFile()), "elapsed", time.Since(start))
+//		return resp, err
+//	}
+type Interceptor func(ctx context.Context, req *pluginpb.CodeGeneratorRequest, next ExecuteFunc) (*pluginpb.CodeGeneratorResponse, error)
+
+// WithInterceptors runs the interceptors around every Execute, ExecuteInto
+// and ExecuteRequest. The first interceptor is the outermost. Repeated
+// options append to the chain.
+//
+// Interceptors see decoded messages, so serialized requests passed to
+// Execute are decoded and the responses re-encoded. Everything else the
+// instance does for a call, e.g. request options, the result cache and
+// dumps, runs inside the chain.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// intercept runs req through the interceptor chain.
+func (p *ProtocGenProst) intercept(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	next := p.executeRequest
+	for i := len(p.opts.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := p.opts.interceptors[i], next
+		next = func(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+			return interceptor(ctx, req, inner)
+		}
+	}
+	return next(ctx, req)
+}
+
+// interceptInto runs a serialized request through the interceptor chain and
+// appends the serialized response to dst.
+func (p *ProtocGenProst) interceptInto(ctx context.Context, input, dst []byte) ([]byte, error) {
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(input, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+	resp, err := p.intercept(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := proto.MarshalOptions{}.MarshalAppend(dst, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return out, nil
+}
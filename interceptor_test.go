@@ -0,0 +1,68 @@
+package prost
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_WithInterceptors(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, abiStubModule(0).Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, req *pluginpb.CodeGeneratorRequest, next ExecuteFunc) (*pluginpb.CodeGeneratorResponse, error) {
+			calls = append(calls, name+" "+req.GetParameter())
+			if name == "outer" {
+				req.Parameter = proto.String("mutated")
+			}
+			return next(ctx, req)
+		}
+	}
+	cached := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{{Name: proto.String("cached.rs")}}}
+	cache := func(ctx context.Context, req *pluginpb.CodeGeneratorRequest, next ExecuteFunc) (*pluginpb.CodeGeneratorResponse, error) {
+		if req.GetParameter() == "mutated" && len(req.GetFileToGenerate()) != 0 {
+			return cached, nil
+		}
+		return next(ctx, req)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithInterceptors(trace("outer")), WithInterceptors(trace("inner"), cache))
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	resp, err := p.ExecuteRequest(ctx, &pluginpb.CodeGeneratorRequest{Parameter: proto.String("original")})
+	if err != nil || len(resp.GetFile()) != 0 {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+	if want := []string{"outer original", "inner mutated"}; !slices.Equal(calls, want) {
+		t.Fatalf("unexpected calls: %q", calls)
+	}
+
+	input, err := proto.Marshal(&pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"a.proto"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := p.ExecuteInto(ctx, input, []byte("prefix"))
+	if err != nil {
+		t.Fatalf("ExecuteInto failed: %v", err)
+	}
+	resp = &pluginpb.CodeGeneratorResponse{}
+	if string(output[:6]) != "prefix" || proto.Unmarshal(output[6:], resp) != nil || !proto.Equal(resp, cached) {
+		t.Fatalf("expected the cached response, got %q", output)
+	}
+	if _, err := p.Execute(ctx, []byte{0xff}); err == nil {
+		t.Fatal("expected an error for an invalid request")
+	}
+}
@@ -0,0 +1,196 @@
+// Package diff implements line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// opKind is the kind of an edit operation.
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op is a single line edit.
+type op struct {
+	kind opKind
+	// aLine and bLine are the 0-based line indexes in a and b
+	aLine, bLine int
+	text         string
+}
+
+// Unified returns a unified diff transforming a into b.
+// Returns an empty string if a and b are equal.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	aLines, bLines := splitLines(a), splitLines(b)
+	ops := edits(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until there is a run of more than 2*context equal lines
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+
+		lo := max(start-context, 0)
+		hi := min(end+context, len(ops))
+		writeHunk(&sb, ops[lo:hi])
+		start = hi
+	}
+	return sb.String()
+}
+
+// writeHunk writes a single hunk with its header.
+func writeHunk(sb *strings.Builder, ops []op) {
+	var aStart, bStart, aCount, bCount int
+	aStart, bStart = -1, -1
+	for _, o := range ops {
+		if o.kind != opInsert {
+			if aStart < 0 {
+				aStart = o.aLine
+			}
+			aCount++
+		}
+		if o.kind != opDelete {
+			if bStart < 0 {
+				bStart = o.bLine
+			}
+			bCount++
+		}
+	}
+	// Empty ranges are reported as the line before the hunk
+	if aStart < 0 {
+		aStart = ops[0].aLine - 1
+	}
+	if bStart < 0 {
+		bStart = ops[0].bLine - 1
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.text)
+		if !strings.HasSuffix(o.text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk range from a 0-based start line.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s into lines, keeping the line terminators.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits computes the shortest edit script from a to b using Myers' algorithm.
+func edits(a, b []string) []op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+	// Forward pass: record V for each edit distance d
+found:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break found
+			}
+		}
+	}
+
+	// Backward pass: walk the trace from the end
+	var rev []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0 && (x > 0 || y > 0); d-- {
+		vd := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[offset+k-1] < vd[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, op{kind: opEqual, aLine: x, bLine: y, text: a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, op{kind: opInsert, aLine: x, bLine: y, text: b[y]})
+		} else {
+			x--
+			rev = append(rev, op{kind: opDelete, aLine: x, bLine: y, text: a[x]})
+		}
+	}
+
+	ops := make([]op, len(rev))
+	for i := range rev {
+		ops[i] = rev[len(rev)-1-i]
+	}
+	return ops
+}
@@ -0,0 +1,38 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	b := "one\ntwo\nthree\nFOUR\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+	want := `--- a.rs
++++ b.rs
+@@ -1,7 +1,7 @@
+ one
+ two
+ three
+-four
++FOUR
+ five
+ six
+ seven
+@@ -9,3 +9,4 @@
+ nine
+ ten
+ eleven
++twelve
+`
+	if got := Unified("a.rs", "b.rs", a, b); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := Unified("a", "b", a, a); got != "" {
+		t.Fatalf("expected empty diff, got:\n%s", got)
+	}
+}
+
+func TestUnified_Empty(t *testing.T) {
+	want := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := Unified("a", "b", "", "x\ny\n"); got != want {
+		t.Fatalf("unexpected diff:\n%q", got)
+	}
+}
@@ -0,0 +1,180 @@
+// Package wasmtest encodes small WebAssembly modules for tests.
+package wasmtest
+
+// Value types.
+const (
+	I32 byte = 0x7f
+	I64 byte = 0x7e
+)
+
+// Import is a function imported by the module.
+type Import struct {
+	Module, Name    string
+	Params, Results []byte
+}
+
+// Func is a function defined by the module.
+type Func struct {
+	// Name is the export name, or empty if the function is not exported.
+	Name            string
+	Params, Results []byte
+	// Code is the body, without locals and the final end opcode.
+	Code []byte
+}
+
+// Module describes a module. Function indices start with the imports,
+// followed by Funcs in order.
+type Module struct {
+	Imports []Import
+	Funcs   []Func
+	// MemoryPages is the initial size of the memory exported as "memory".
+	// No memory is defined if 0.
+	MemoryPages uint32
+	// Globals is the number of mutable i32 globals, initialized to 0.
+	Globals int
+	// Data is copied to memory at DataOffset on instantiation.
+	Data       []byte
+	DataOffset int32
+}
+
+// Encode returns the binary encoding of the module.
+func (m *Module) Encode() []byte {
+	var types, imports, funcs, exports, codes [][]byte
+	funcType := func(params, results []byte) byte {
+		types = append(types, append(append(append([]byte{0x60}, vec(params)...), uleb(uint32(len(results)))...), results...))
+		return byte(len(types) - 1)
+	}
+	for _, imp := range m.Imports {
+		typ := funcType(imp.Params, imp.Results)
+		imports = append(imports, append(append(name(imp.Module), name(imp.Name)...), 0x00, typ))
+	}
+	for i, fn := range m.Funcs {
+		funcs = append(funcs, uleb(uint32(funcType(fn.Params, fn.Results))))
+		if fn.Name != "" {
+			exports = append(exports, append(append(name(fn.Name), 0x00), uleb(uint32(len(m.Imports)+i))...))
+		}
+		body := append(append([]byte{0x00}, fn.Code...), 0x0b)
+		codes = append(codes, append(uleb(uint32(len(body))), body...))
+	}
+
+	wasm := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, section(1, vecItems(types))...)
+	if len(imports) != 0 {
+		wasm = append(wasm, section(2, vecItems(imports))...)
+	}
+	wasm = append(wasm, section(3, vecItems(funcs))...)
+	if m.MemoryPages != 0 {
+		wasm = append(wasm, section(5, vecItems([][]byte{append([]byte{0x00}, uleb(m.MemoryPages)...)}))...)
+		exports = append(exports, append(name("memory"), 0x02, 0x00))
+	}
+	if m.Globals != 0 {
+		globals := make([][]byte, m.Globals)
+		for i := range globals {
+			globals[i] = []byte{I32, 0x01, 0x41, 0x00, 0x0b}
+		}
+		wasm = append(wasm, section(6, vecItems(globals))...)
+	}
+	wasm = append(wasm, section(7, vecItems(exports))...)
+	wasm = append(wasm, section(10, vecItems(codes))...)
+	if len(m.Data) != 0 {
+		seg := append(append([]byte{0x00}, I32Const(m.DataOffset)...), 0x0b)
+		seg = append(append(seg, uleb(uint32(len(m.Data)))...), m.Data...)
+		wasm = append(wasm, section(11, vecItems([][]byte{seg}))...)
+	}
+	return wasm
+}
+
+// I32Const returns an i32.const instruction.
+func I32Const(v int32) []byte {
+	return append([]byte{0x41}, sleb(int64(v))...)
+}
+
+// I64Const returns an i64.const instruction.
+func I64Const(v int64) []byte {
+	return append([]byte{0x42}, sleb(v)...)
+}
+
+// LocalGet returns a local.get instruction.
+func LocalGet(i uint32) []byte {
+	return append([]byte{0x20}, uleb(i)...)
+}
+
+// GlobalGet returns a global.get instruction.
+func GlobalGet(i uint32) []byte {
+	return append([]byte{0x23}, uleb(i)...)
+}
+
+// GlobalSet returns a global.set instruction.
+func GlobalSet(i uint32) []byte {
+	return append([]byte{0x24}, uleb(i)...)
+}
+
+// Call returns a call instruction.
+func Call(i uint32) []byte {
+	return append([]byte{0x10}, uleb(i)...)
+}
+
+// Drop is the drop instruction.
+var Drop = []byte{0x1a}
+
+// Unreachable is the unreachable instruction, which traps.
+var Unreachable = []byte{0x00}
+
+// Code concatenates instructions.
+func Code(instrs ...[]byte) []byte {
+	var code []byte
+	for _, instr := range instrs {
+		code = append(code, instr...)
+	}
+	return code
+}
+
+// section encodes a section with the given id.
+func section(id byte, body []byte) []byte {
+	return append(append([]byte{id}, uleb(uint32(len(body)))...), body...)
+}
+
+// vec encodes a vector of bytes.
+func vec(b []byte) []byte {
+	return append(uleb(uint32(len(b))), b...)
+}
+
+// vecItems encodes a vector of encoded items.
+func vecItems(items [][]byte) []byte {
+	out := uleb(uint32(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// name encodes a name.
+func name(s string) []byte {
+	return vec([]byte(s))
+}
+
+// uleb encodes v as unsigned LEB128.
+func uleb(v uint32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// sleb encodes v as signed LEB128.
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,37 @@
+package prost
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// InterruptExitCode is the exit code used to close the module on Interrupt.
+const InterruptExitCode uint32 = 130
+
+// Interrupt aborts an in-flight Execute by closing the module with InterruptExitCode.
+//
+// The interrupted Execute returns ErrInterrupted. The module is re-instantiated
+// automatically on the next Execute. Calling Interrupt while idle discards the
+// current instance.
+//
+// Aborting a guest that is busy computing (rather than calling a host function)
+// requires the runtime to be configured with WithCloseOnContextDone(true).
+// Safe to call from any goroutine.
+func (p *ProtocGenProst) Interrupt(ctx context.Context) error {
+	p.modMu.Lock()
+	mod := p.mod
+	p.modMu.Unlock()
+
+	if mod == nil || mod.IsClosed() {
+		return nil
+	}
+	return mod.CloseWithExitCode(ctx, InterruptExitCode)
+}
+
+// isInterruptExit checks if err is the exit caused by Interrupt.
+func isInterruptExit(err error) bool {
+	var exitErr *sys.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == InterruptExitCode
+}
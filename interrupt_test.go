@@ -0,0 +1,87 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_InterruptReinstantiates(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	oldMod := p.mod
+	if err := p.Interrupt(ctx); err != nil {
+		t.Fatalf("Interrupt failed: %v", err)
+	}
+	if !oldMod.IsClosed() {
+		t.Fatal("expected module to be closed after Interrupt")
+	}
+
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute after Interrupt failed: %v", err)
+	}
+	if p.mod == oldMod {
+		t.Fatal("expected module to be re-instantiated")
+	}
+}
+
+func TestProtocGenProst_InterruptInFlight(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// The guest blocks in a host call until the test releases it.
+	entered, release := make(chan struct{}), make(chan struct{})
+	_, err := r.NewHostModuleBuilder("block").
+		NewFunctionBuilder().WithFunc(func(context.Context) {
+		close(entered)
+		<-release
+	}).Export("wait").
+		Instantiate(ctx)
+	if err != nil {
+		t.Fatalf("failed to instantiate host module: %v", err)
+	}
+
+	stub := abiStubModule(0)
+	stub.Imports = []wasmtest.Import{{Module: "block", Name: "wait"}}
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Code(wasmtest.Call(0), wasmtest.I32Const(0))
+		}
+	}
+	compiled, err := r.CompileModule(ctx, stub.Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Execute(ctx, minimalRequestInput(t))
+		done <- err
+	}()
+	<-entered
+	if err := p.Interrupt(ctx); err != nil {
+		t.Fatalf("Interrupt failed: %v", err)
+	}
+	close(release)
+	if err := <-done; !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package prost
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Symbol is a message, enum, or service declared in a proto file.
+type Symbol struct {
+	// FullName is the fully-qualified name without a leading dot.
+	FullName string
+	// File is the name of the proto file declaring the symbol.
+	File string
+}
+
+// FilesToGenerate returns the descriptors of the files listed in file_to_generate.
+// Files missing from proto_file are skipped.
+func FilesToGenerate(req *pluginpb.CodeGeneratorRequest) []*descriptorpb.FileDescriptorProto {
+	byName := FilesByName(req)
+	files := make([]*descriptorpb.FileDescriptorProto, 0, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		if fd, ok := byName[name]; ok {
+			files = append(files, fd)
+		}
+	}
+	return files
+}
+
+// FilesByName indexes the proto files of the request by name.
+func FilesByName(req *pluginpb.CodeGeneratorRequest) map[string]*descriptorpb.FileDescriptorProto {
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(req.GetProtoFile()))
+	for _, fd := range req.GetProtoFile() {
+		byName[fd.GetName()] = fd
+	}
+	return byName
+}
+
+// ListMessages returns the messages declared in files, including nested messages.
+// Synthetic map entry messages are omitted.
+func ListMessages(files []*descriptorpb.FileDescriptorProto) []Symbol {
+	var syms []Symbol
+	for _, fd := range files {
+		walkMessages(fd.GetPackage(), fd.GetMessageType(), func(fullName string, _ *descriptorpb.DescriptorProto) {
+			syms = append(syms, Symbol{FullName: fullName, File: fd.GetName()})
+		})
+	}
+	return syms
+}
+
+// ListEnums returns the enums declared in files, including nested enums.
+func ListEnums(files []*descriptorpb.FileDescriptorProto) []Symbol {
+	var syms []Symbol
+	for _, fd := range files {
+		for _, e := range fd.GetEnumType() {
+			syms = append(syms, Symbol{FullName: joinName(fd.GetPackage(), e.GetName()), File: fd.GetName()})
+		}
+		walkMessages(fd.GetPackage(), fd.GetMessageType(), func(fullName string, msg *descriptorpb.DescriptorProto) {
+			for _, e := range msg.GetEnumType() {
+				syms = append(syms, Symbol{FullName: joinName(fullName, e.GetName()), File: fd.GetName()})
+			}
+		})
+	}
+	return syms
+}
+
+// ListServices returns the services declared in files.
+func ListServices(files []*descriptorpb.FileDescriptorProto) []Symbol {
+	var syms []Symbol
+	for _, fd := range files {
+		for _, svc := range fd.GetService() {
+			syms = append(syms, Symbol{FullName: joinName(fd.GetPackage(), svc.GetName()), File: fd.GetName()})
+		}
+	}
+	return syms
+}
+
+// ImportGraph maps each proto file in the request to its direct imports.
+func ImportGraph(req *pluginpb.CodeGeneratorRequest) map[string][]string {
+	graph := make(map[string][]string, len(req.GetProtoFile()))
+	for _, fd := range req.GetProtoFile() {
+		graph[fd.GetName()] = append([]string(nil), fd.GetDependency()...)
+	}
+	return graph
+}
+
+// TransitiveImports returns the files reachable from roots in the import graph.
+// The roots are included. The result is sorted.
+func TransitiveImports(graph map[string][]string, roots []string) []string {
+	seen := make(map[string]struct{})
+	stack := append([]string(nil), roots...)
+	for len(stack) != 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		stack = append(stack, graph[name]...)
+	}
+	files := make([]string, 0, len(seen))
+	for name := range seen {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// walkMessages calls fn for each message in msgs and their nested messages.
+func walkMessages(scope string, msgs []*descriptorpb.DescriptorProto, fn func(fullName string, msg *descriptorpb.DescriptorProto)) {
+	for _, msg := range msgs {
+		if msg.GetOptions().GetMapEntry() {
+			continue
+		}
+		fullName := joinName(scope, msg.GetName())
+		fn(fullName, msg)
+		walkMessages(fullName, msg.GetNestedType(), fn)
+	}
+}
+
+// joinName joins a scope and a name with a dot.
+func joinName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
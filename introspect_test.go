@@ -0,0 +1,48 @@
+package prost
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestIntrospect(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("c.proto")},
+			{Name: proto.String("b.proto"), Dependency: []string{"c.proto"}},
+			{
+				Name:       proto.String("a.proto"),
+				Package:    proto.String("pkg"),
+				Dependency: []string{"b.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Outer"),
+					NestedType: []*descriptorpb.DescriptorProto{
+						{Name: proto.String("Inner")},
+						{Name: proto.String("LabelsEntry"), Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}},
+					},
+					EnumType: []*descriptorpb.EnumDescriptorProto{{Name: proto.String("Kind")}},
+				}},
+				Service: []*descriptorpb.ServiceDescriptorProto{{Name: proto.String("Svc")}},
+			},
+		},
+	}
+
+	files := FilesToGenerate(req)
+	if got := ListMessages(files); !reflect.DeepEqual(got, []Symbol{{"pkg.Outer", "a.proto"}, {"pkg.Outer.Inner", "a.proto"}}) {
+		t.Errorf("unexpected messages: %v", got)
+	}
+	if got := ListEnums(files); !reflect.DeepEqual(got, []Symbol{{"pkg.Outer.Kind", "a.proto"}}) {
+		t.Errorf("unexpected enums: %v", got)
+	}
+	if got := ListServices(files); !reflect.DeepEqual(got, []Symbol{{"pkg.Svc", "a.proto"}}) {
+		t.Errorf("unexpected services: %v", got)
+	}
+	if got := TransitiveImports(ImportGraph(req), []string{"b.proto"}); !reflect.DeepEqual(got, []string{"b.proto", "c.proto"}) {
+		t.Errorf("unexpected transitive imports: %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RequestFormat is the encoding of a serialized CodeGeneratorRequest.
+type RequestFormat string
+
+const (
+	// FormatAuto detects the encoding with IsJSON.
+	FormatAuto RequestFormat = "auto"
+	// FormatBinary is the protobuf wire encoding.
+	FormatBinary RequestFormat = "binary"
+	// FormatJSON is the protojson encoding.
+	FormatJSON RequestFormat = "json"
+)
+
+// IsJSON checks if data looks like a JSON object.
+//
+// A CodeGeneratorRequest in wire encoding never starts with '{', which would
+// be a group tag for field 15.
+func IsJSON(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) != 0 && data[0] == '{'
+}
+
+// UnmarshalRequest decodes a CodeGeneratorRequest in the given format.
+// With FormatAuto, data that looks like JSON is decoded as protojson and
+// falls back to the wire encoding if that fails.
+func UnmarshalRequest(data []byte, format RequestFormat) (*pluginpb.CodeGeneratorRequest, error) {
+	req := &pluginpb.CodeGeneratorRequest{}
+	switch format {
+	case FormatBinary:
+		if err := proto.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+		}
+	case FormatJSON:
+		if err := protojson.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json request: %w", err)
+		}
+	case FormatAuto, "":
+		if IsJSON(data) {
+			if err := protojson.Unmarshal(data, req); err == nil {
+				return req, nil
+			}
+			proto.Reset(req)
+		}
+		return UnmarshalRequest(data, FormatBinary)
+	default:
+		return nil, fmt.Errorf("unknown request format: %q", format)
+	}
+	return req, nil
+}
+
+// ExecuteJSON runs the plugin with a protojson-encoded CodeGeneratorRequest
+// and returns the protojson-encoded CodeGeneratorResponse.
+func (p *ProtocGenProst) ExecuteJSON(ctx context.Context, input []byte) ([]byte, error) {
+	req, err := UnmarshalRequest(input, FormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+}
@@ -0,0 +1,77 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const testJSONRequest = `{
+  "fileToGenerate": ["test.proto"],
+  "protoFile": [{"name": "test.proto", "package": "test", "syntax": "proto3",
+    "messageType": [{"name": "Msg"}]}]
+}`
+
+func TestUnmarshalRequest(t *testing.T) {
+	fromJSON, err := UnmarshalRequest([]byte(testJSONRequest), FormatAuto)
+	if err != nil {
+		t.Fatalf("UnmarshalRequest failed: %v", err)
+	}
+	if fromJSON.GetFileToGenerate()[0] != "test.proto" {
+		t.Fatalf("unexpected request: %v", fromJSON)
+	}
+
+	binary, err := proto.Marshal(fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary, err := UnmarshalRequest(binary, FormatAuto)
+	if err != nil {
+		t.Fatalf("UnmarshalRequest failed: %v", err)
+	}
+	if !proto.Equal(fromJSON, fromBinary) {
+		t.Fatal("expected identical requests")
+	}
+
+	// A file name of length '{' makes a binary request look like JSON
+	name := string(make([]byte, '{'))
+	tricky, err := proto.Marshal(&pluginpb.CodeGeneratorRequest{FileToGenerate: []string{name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req, err := UnmarshalRequest(tricky, FormatAuto); err != nil || req.GetFileToGenerate()[0] != name {
+		t.Fatalf("expected binary fallback, got %v: %v", req, err)
+	}
+
+	if _, err := UnmarshalRequest(binary, "yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestProtocGenProst_ExecuteJSON(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	output, err := p.ExecuteJSON(ctx, []byte(testJSONRequest))
+	if err != nil {
+		t.Fatalf("ExecuteJSON failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := protojson.Unmarshal(output, resp); err != nil {
+		t.Fatalf("failed to unmarshal json response: %v", err)
+	}
+	if len(resp.GetFile()) == 0 {
+		t.Fatal("expected generated files")
+	}
+}
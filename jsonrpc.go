@@ -0,0 +1,205 @@
+package prost
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// JSON-RPC 2.0 error codes used by ServeJSONRPC.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	// JSONRPCGenerateError reports a failed generation, including plugin errors.
+	JSONRPCGenerateError = -32000
+)
+
+// jsonrpcRequest is an incoming JSON-RPC message.
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// jsonrpcResponse is an outgoing JSON-RPC message.
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// JSONRPCError is a JSON-RPC error object.
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message.
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// GenerateParams are the parameters of the generate and verify methods.
+type GenerateParams struct {
+	// Request is the protojson-encoded CodeGeneratorRequest.
+	Request json.RawMessage `json:"request"`
+	// Dir is the output directory. If empty, generate returns the response
+	// instead of writing it. Required by verify.
+	Dir string `json:"dir,omitempty"`
+	// RemoveStale removes (generate) or reports (verify) stale files.
+	RemoveStale bool `json:"removeStale,omitempty"`
+	// Header is prepended as a comment to each generated file if set.
+	Header string `json:"header,omitempty"`
+}
+
+// GenerateResult is the result of the generate method.
+type GenerateResult struct {
+	// Files lists the generated file names.
+	Files []string `json:"files"`
+	// Response is the protojson-encoded CodeGeneratorResponse.
+	// Only set if no output directory was given.
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// VerifyResult is the result of the verify method.
+type VerifyResult struct {
+	// UpToDate is set if the output directory matches the generated files.
+	UpToDate bool `json:"upToDate"`
+	// Diffs lists the out of date files.
+	Diffs []FileDiff `json:"diffs"`
+}
+
+// ServeJSONRPC serves a JSON-RPC 2.0 protocol over r and w for editor tooling.
+//
+// Messages are newline-delimited JSON objects. Requests are processed in
+// order on the warm instance p. Supported methods:
+//
+//   - generate: runs GenerateParams.Request and writes the files to Dir
+//     with WriteResponse, or returns the response if Dir is empty.
+//   - verify: runs the request and reports files in Dir that are out of date.
+//   - shutdown: replies with null and stops serving.
+//
+// Returns nil when r is closed or after shutdown.
+func ServeJSONRPC(ctx context.Context, p *ProtocGenProst, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxFrameSize)
+	enc := json.NewEncoder(w)
+	send := func(resp *jsonrpcResponse) error {
+		resp.JSONRPC = "2.0"
+		return enc.Encode(resp)
+	}
+
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req jsonrpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := send(&jsonrpcResponse{ID: json.RawMessage("null"), Error: &JSONRPCError{Code: JSONRPCParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rpcErr := handleJSONRPC(ctx, p, &req)
+		// Notifications have no id and get no reply
+		if len(req.ID) != 0 {
+			if err := send(&jsonrpcResponse{ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+				return err
+			}
+		}
+		if req.Method == "shutdown" && rpcErr == nil {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// handleJSONRPC runs one JSON-RPC method.
+func handleJSONRPC(ctx context.Context, p *ProtocGenProst, req *jsonrpcRequest) (any, *JSONRPCError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "expected jsonrpc 2.0"}
+	}
+
+	switch req.Method {
+	case "shutdown":
+		return json.RawMessage("null"), nil
+	case "generate", "verify":
+	default:
+		return nil, &JSONRPCError{Code: JSONRPCMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	var params GenerateParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+	}
+	genReq, err := UnmarshalRequest(params.Request, FormatJSON)
+	if err != nil {
+		return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+	}
+	if req.Method == "verify" && params.Dir == "" {
+		return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: "verify requires dir"}
+	}
+
+	resp, err := p.ExecuteRequest(ctx, genReq)
+	if err != nil {
+		return nil, generateError(err)
+	}
+	opts := &WriteOptions{
+		RemoveStale: params.RemoveStale,
+		Header:      params.Header,
+		Request:     genReq,
+	}
+
+	if req.Method == "verify" {
+		diffs, err := CheckDir(params.Dir, resp, opts)
+		if err != nil {
+			return nil, generateError(err)
+		}
+		return &VerifyResult{UpToDate: len(diffs) == 0, Diffs: append([]FileDiff{}, diffs...)}, nil
+	}
+
+	result := &GenerateResult{Files: responseFileNames(resp)}
+	if params.Dir == "" {
+		if msg := resp.GetError(); msg != "" {
+			return nil, generateError(&PluginError{Message: msg})
+		}
+		data, err := protojson.Marshal(resp)
+		if err != nil {
+			return nil, generateError(err)
+		}
+		result.Response = data
+		return result, nil
+	}
+	if err := WriteResponse(params.Dir, resp, opts); err != nil {
+		return nil, generateError(err)
+	}
+	return result, nil
+}
+
+// generateError converts a generation failure to a JSON-RPC error.
+func generateError(err error) *JSONRPCError {
+	return &JSONRPCError{Code: JSONRPCGenerateError, Message: err.Error()}
+}
+
+// responseFileNames lists the names of the complete files in a response.
+func responseFileNames(resp *pluginpb.CodeGeneratorResponse) []string {
+	names := []string{}
+	for _, f := range resp.GetFile() {
+		if f.GetInsertionPoint() == "" {
+			names = append(names, f.GetName())
+		}
+	}
+	return names
+}
@@ -0,0 +1,86 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestServeJSONRPC(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	dir := t.TempDir()
+	params := func(dir string) string {
+		data, _ := json.Marshal(&GenerateParams{Request: json.RawMessage(testJSONRequest), Dir: dir})
+		return string(data)
+	}
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"verify","params":` + params(dir) + `}`,
+		`{"jsonrpc":"2.0","id":2,"method":"generate","params":` + params(dir) + `}`,
+		`{"jsonrpc":"2.0","id":3,"method":"verify","params":` + params(dir) + `}`,
+		`{"jsonrpc":"2.0","id":4,"method":"generate","params":` + params("") + `}`,
+		`{"jsonrpc":"2.0","id":5,"method":"frobnicate"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"generate","params":` + params("") + `}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := ServeJSONRPC(ctx, p, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("ServeJSONRPC failed: %v", err)
+	}
+
+	type reply struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+	}
+	var replies []reply
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var rep reply
+		if err := dec.Decode(&rep); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, rep)
+	}
+	if len(replies) != 7 {
+		t.Fatalf("expected 7 replies before shutdown, got %d", len(replies))
+	}
+
+	var verify VerifyResult
+	if err := json.Unmarshal(replies[0].Result, &verify); err != nil || verify.UpToDate {
+		t.Fatalf("expected out of date dir before generate: %s", replies[0].Result)
+	}
+	var gen GenerateResult
+	if err := json.Unmarshal(replies[1].Result, &gen); err != nil || len(gen.Files) == 0 || gen.Response != nil {
+		t.Fatalf("unexpected generate result: %s", replies[1].Result)
+	}
+	if err := json.Unmarshal(replies[2].Result, &verify); err != nil || !verify.UpToDate {
+		t.Fatalf("expected up to date dir after generate: %s", replies[2].Result)
+	}
+	if err := json.Unmarshal(replies[3].Result, &gen); err != nil || gen.Response == nil {
+		t.Fatalf("expected inline response: %s", replies[3].Result)
+	}
+	if replies[4].Error == nil || replies[4].Error.Code != JSONRPCMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", replies[4].Error)
+	}
+	if replies[5].Error == nil || replies[5].Error.Code != JSONRPCParseError {
+		t.Fatalf("expected parse error, got %+v", replies[5].Error)
+	}
+	if string(replies[6].ID) != "6" || replies[6].Error != nil {
+		t.Fatalf("unexpected shutdown reply: %+v", replies[6])
+	}
+}
@@ -0,0 +1,85 @@
+package lowlevel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Optional structured error export names.
+//
+// After prost_execute (or a session function) returns a negative status, the
+// error buffer holds an ErrorInfo encoded as a JSON object. Its length is 0 if
+// the guest recorded no detail. prost_clear_output clears it.
+const (
+	// ExportGetErrorPtr returns the pointer to the error buffer.
+	ExportGetErrorPtr = "prost_get_error_ptr"
+	// ExportGetErrorLen returns the length of the error buffer.
+	ExportGetErrorLen = "prost_get_error_len"
+)
+
+// ErrorInfo is the structured detail of a failure reported by the guest.
+type ErrorInfo struct {
+	// Code identifies the kind of failure, e.g. "unresolved_type".
+	Code string `json:"code,omitempty"`
+	// Message describes the failure.
+	Message string `json:"message"`
+	// File is the proto file the failure was found in, if any.
+	File string `json:"file,omitempty"`
+	// Line is the 1-based line in File, or 0 if unknown.
+	Line int `json:"line,omitempty"`
+	// Column is the 1-based column in Line, or 0 if unknown.
+	Column int `json:"column,omitempty"`
+}
+
+// String formats the detail as "file:line:column: message", omitting
+// unknown location parts.
+func (i *ErrorInfo) String() string {
+	if i.File == "" {
+		return i.Message
+	}
+	loc := i.File
+	if i.Line != 0 {
+		loc += ":" + strconv.Itoa(i.Line)
+		if i.Column != 0 {
+			loc += ":" + strconv.Itoa(i.Column)
+		}
+	}
+	return loc + ": " + i.Message
+}
+
+// HasErrorInfo returns true if the module exports the structured error
+// buffer functions.
+func (m *Module) HasErrorInfo() bool {
+	return m.getErrorPtr != nil && m.getErrorLen != nil
+}
+
+// ReadErrorInfo decodes the error buffer.
+// Returns nil without error if the buffer is empty.
+func (m *Module) ReadErrorInfo(ctx context.Context) (*ErrorInfo, error) {
+	if !m.HasErrorInfo() {
+		return nil, errors.New("module does not export the error buffer functions")
+	}
+	results, err := m.getErrorLen.Call(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n := uint32(results[0])
+	if n == 0 {
+		return nil, nil
+	}
+	if results, err = m.getErrorPtr.Call(ctx); err != nil {
+		return nil, err
+	}
+	data, ok := m.mod.Memory().Read(uint32(results[0]), n)
+	if !ok {
+		return nil, errors.New("failed to read error buffer from memory")
+	}
+	info := &ErrorInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("failed to decode error buffer: %w", err)
+	}
+	return info, nil
+}
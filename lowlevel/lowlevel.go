@@ -0,0 +1,280 @@
+// Package lowlevel exposes the raw protoc-gen-prost WASM ABI.
+//
+// Most users should use the high-level ProtocGenProst type in the parent
+// package. This package is intended for advanced users building custom
+// protocols on top of the same WASM module.
+//
+// Module is not safe for concurrent use: WASI modules are single-threaded.
+package lowlevel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ErrMallocNull is returned by Malloc if the guest allocator returned a null
+// pointer, usually because guest memory could not grow.
+var ErrMallocNull = errors.New("malloc returned null")
+
+// Export names of the protoc-gen-prost ABI.
+const (
+	// ExportExecute executes the prost plugin.
+	ExportExecute = "prost_execute"
+	// ExportGetOutputPtr returns the pointer to the output buffer.
+	ExportGetOutputPtr = "prost_get_output_ptr"
+	// ExportGetOutputLen returns the length of the output buffer.
+	ExportGetOutputLen = "prost_get_output_len"
+	// ExportClearOutput clears the output buffer.
+	ExportClearOutput = "prost_clear_output"
+	// ExportMalloc allocates memory in WASM linear memory.
+	ExportMalloc = "prost_malloc"
+	// ExportFree frees memory in WASM linear memory.
+	ExportFree = "prost_free"
+)
+
+// Optional allocator statistics export names.
+const (
+	// ExportAllocLiveCount returns the number of live allocations.
+	ExportAllocLiveCount = "prost_alloc_live_count"
+	// ExportAllocPeakCount returns the peak number of live allocations.
+	ExportAllocPeakCount = "prost_alloc_peak_count"
+	// ExportAllocLiveBytes returns the number of live allocated bytes.
+	ExportAllocLiveBytes = "prost_alloc_live_bytes"
+	// ExportAllocPeakBytes returns the peak number of live allocated bytes.
+	ExportAllocPeakBytes = "prost_alloc_peak_bytes"
+)
+
+// Module wraps the exported functions of an instantiated protoc-gen-prost module.
+type Module struct {
+	mod   api.Module
+	names ExportNames
+
+	malloc       api.Function
+	free         api.Function
+	execute      api.Function
+	getOutputPtr api.Function
+	getOutputLen api.Function
+	clearOutput  api.Function
+
+	// Optional allocator statistics
+	allocLiveCount api.Function
+	allocPeakCount api.Function
+	allocLiveBytes api.Function
+	allocPeakBytes api.Function
+
+	// Optional per-file session
+	sessionBegin    api.Function
+	sessionAdd      api.Function
+	sessionGenerate api.Function
+	sessionEnd      api.Function
+
+	// Optional structured error buffer
+	getErrorPtr api.Function
+	getErrorLen api.Function
+}
+
+// AllocatorStats contains guest allocator statistics.
+type AllocatorStats struct {
+	// LiveAllocs is the number of allocations not yet freed.
+	LiveAllocs uint32
+	// PeakAllocs is the highest LiveAllocs value observed.
+	PeakAllocs uint32
+	// LiveBytes is the number of bytes allocated and not yet freed.
+	LiveBytes uint32
+	// PeakBytes is the highest LiveBytes value observed.
+	PeakBytes uint32
+}
+
+// Bind looks up the protoc-gen-prost exports on an instantiated module.
+//
+// The export names are detected with DetectExportNames. Returns an error
+// naming the first missing default export if no known variant matches.
+// The caller retains ownership of mod.
+func Bind(mod api.Module) (*Module, error) {
+	names, ok := DetectExportNames(mod.ExportedFunctionDefinitions())
+	if !ok {
+		names = DefaultExportNames
+	}
+	return BindNames(mod, names)
+}
+
+// BindNames looks up the ABI exports on an instantiated module using the
+// given export names. Returns an error if any required export is missing,
+// or ErrMemory64Unsupported if the ABI uses 64-bit pointers.
+// The caller retains ownership of mod.
+func BindNames(mod api.Module, names ExportNames) (*Module, error) {
+	exports := mod.ExportedFunctionDefinitions()
+	if missing := names.Missing(exports); missing != "" {
+		return nil, errors.New("missing export: " + missing)
+	}
+	width, err := PointerWidth(exports, names)
+	if err != nil {
+		return nil, err
+	}
+	if width != 32 {
+		return nil, ErrMemory64Unsupported
+	}
+
+	return &Module{
+		mod:          mod,
+		names:        names,
+		malloc:       mod.ExportedFunction(names.Malloc),
+		free:         mod.ExportedFunction(names.Free),
+		execute:      mod.ExportedFunction(names.Execute),
+		getOutputPtr: mod.ExportedFunction(names.GetOutputPtr),
+		getOutputLen: mod.ExportedFunction(names.GetOutputLen),
+		clearOutput:  mod.ExportedFunction(names.ClearOutput),
+
+		allocLiveCount: mod.ExportedFunction(ExportAllocLiveCount),
+		allocPeakCount: mod.ExportedFunction(ExportAllocPeakCount),
+		allocLiveBytes: mod.ExportedFunction(ExportAllocLiveBytes),
+		allocPeakBytes: mod.ExportedFunction(ExportAllocPeakBytes),
+
+		sessionBegin:    mod.ExportedFunction(ExportSessionBegin),
+		sessionAdd:      mod.ExportedFunction(ExportSessionAdd),
+		sessionGenerate: mod.ExportedFunction(ExportSessionGenerate),
+		sessionEnd:      mod.ExportedFunction(ExportSessionEnd),
+
+		getErrorPtr: mod.ExportedFunction(ExportGetErrorPtr),
+		getErrorLen: mod.ExportedFunction(ExportGetErrorLen),
+	}, nil
+}
+
+// Names returns the export names bound by the module.
+func (m *Module) Names() ExportNames {
+	return m.names
+}
+
+// Module returns the underlying wazero module.
+func (m *Module) Module() api.Module {
+	return m.mod
+}
+
+// Memory returns the linear memory of the module.
+func (m *Module) Memory() api.Memory {
+	return m.mod.Memory()
+}
+
+// Malloc allocates size bytes in guest memory and returns the pointer.
+func (m *Module) Malloc(ctx context.Context, size uint32) (uint32, error) {
+	results, err := m.malloc.Call(ctx, uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	ptr := uint32(results[0])
+	if ptr == 0 {
+		return 0, ErrMallocNull
+	}
+	return ptr, nil
+}
+
+// AllocBytes allocates guest memory and copies data into it.
+// Returns pointer 0 without allocating if data is empty.
+func (m *Module) AllocBytes(ctx context.Context, data []byte) (uint32, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	ptr, err := m.Malloc(ctx, uint32(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	if !m.mod.Memory().Write(ptr, data) {
+		_ = m.Free(ctx, ptr, uint32(len(data)))
+		return 0, errors.New("failed to write to memory")
+	}
+	return ptr, nil
+}
+
+// Free releases guest memory previously returned by Malloc or AllocBytes.
+// Freeing pointer 0 is a no-op.
+func (m *Module) Free(ctx context.Context, ptr, size uint32) error {
+	if ptr == 0 {
+		return nil
+	}
+	_, err := m.free.Call(ctx, uint64(ptr), uint64(size))
+	return err
+}
+
+// CallExecuteRaw calls prost_execute with a request already in guest memory.
+// Returns the length of the output buffer.
+func (m *Module) CallExecuteRaw(ctx context.Context, inputPtr, inputLen uint32) (uint32, error) {
+	results, err := m.execute.Call(ctx, uint64(inputPtr), uint64(inputLen))
+	if err != nil {
+		return 0, err
+	}
+	return uint32(results[0]), nil
+}
+
+// OutputPtr calls prost_get_output_ptr.
+func (m *Module) OutputPtr(ctx context.Context) (uint32, error) {
+	results, err := m.getOutputPtr.Call(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(results[0]), nil
+}
+
+// OutputLen calls prost_get_output_len.
+func (m *Module) OutputLen(ctx context.Context) (uint32, error) {
+	results, err := m.getOutputLen.Call(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(results[0]), nil
+}
+
+// ClearOutput calls prost_clear_output.
+func (m *Module) ClearOutput(ctx context.Context) error {
+	_, err := m.clearOutput.Call(ctx)
+	return err
+}
+
+// ReadOutput returns a view of the output buffer in guest memory.
+// The returned slice aliases guest memory and is invalidated by ClearOutput
+// or any further call into the module; copy it if it must be retained.
+func (m *Module) ReadOutput(ctx context.Context, outputLen uint32) ([]byte, error) {
+	outputPtr, err := m.OutputPtr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output, ok := m.mod.Memory().Read(outputPtr, outputLen)
+	if !ok {
+		return nil, errors.New("failed to read output from memory")
+	}
+	return output, nil
+}
+
+// HasAllocatorStats returns true if the module exports the allocator statistics functions.
+func (m *Module) HasAllocatorStats() bool {
+	return m.allocLiveCount != nil &&
+		m.allocPeakCount != nil &&
+		m.allocLiveBytes != nil &&
+		m.allocPeakBytes != nil
+}
+
+// AllocatorStats reads the guest allocator statistics.
+// Returns an error if HasAllocatorStats is false.
+func (m *Module) AllocatorStats(ctx context.Context) (AllocatorStats, error) {
+	var stats AllocatorStats
+	if !m.HasAllocatorStats() {
+		return stats, errors.New("module does not export allocator statistics")
+	}
+	for _, f := range []struct {
+		fn  api.Function
+		dst *uint32
+	}{
+		{m.allocLiveCount, &stats.LiveAllocs},
+		{m.allocPeakCount, &stats.PeakAllocs},
+		{m.allocLiveBytes, &stats.LiveBytes},
+		{m.allocPeakBytes, &stats.PeakBytes},
+	} {
+		results, err := f.fn.Call(ctx)
+		if err != nil {
+			return stats, err
+		}
+		*f.dst = uint32(results[0])
+	}
+	return stats, nil
+}
@@ -0,0 +1,201 @@
+package lowlevel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestModule_ExecuteRaw(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		t.Fatal(err.Error())
+	}
+	mod, err := r.InstantiateWithConfig(ctx, prost.ProtocGenProstWASM(), wazero.NewModuleConfig())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer mod.Close(ctx)
+
+	m, err := lowlevel.Bind(mod)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+		}},
+	}
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ptr, err := m.AllocBytes(ctx, input)
+	if err != nil {
+		t.Fatalf("AllocBytes failed: %v", err)
+	}
+	defer m.Free(ctx, ptr, uint32(len(input)))
+
+	outputLen, err := m.CallExecuteRaw(ctx, ptr, uint32(len(input)))
+	if err != nil {
+		t.Fatalf("CallExecuteRaw failed: %v", err)
+	}
+	if n, err := m.OutputLen(ctx); err != nil || n != outputLen {
+		t.Fatalf("OutputLen mismatch: %d != %d (err=%v)", n, outputLen, err)
+	}
+
+	output, err := m.ReadOutput(ctx, outputLen)
+	if err != nil {
+		t.Fatalf("ReadOutput failed: %v", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.GetFile()) == 0 {
+		t.Fatal("expected at least one generated file")
+	}
+	if err := m.ClearOutput(ctx); err != nil {
+		t.Fatalf("ClearOutput failed: %v", err)
+	}
+}
+
+// stubModule encodes a WebAssembly module exporting stub functions with the
+// signatures of the ABI under the given names, using ptr (0x7f for i32 or
+// 0x7e for i64) for pointers and lengths. The execute stub returns status,
+// a single-byte signed LEB128 value.
+func stubModule(names lowlevel.ExportNames, ptr, status byte) []byte {
+	section := func(id byte, items ...[]byte) []byte {
+		body := []byte{byte(len(items))}
+		for _, item := range items {
+			body = append(body, item...)
+		}
+		return append([]byte{id, byte(len(body))}, body...)
+	}
+	// Types: 0 (ptr)->ptr, 1 (ptr,ptr)->(), 2 ()->ptr, 3 ()->(), 4 (ptr,ptr)->ptr
+	types := section(1,
+		[]byte{0x60, 1, ptr, 1, ptr},
+		[]byte{0x60, 2, ptr, ptr, 0},
+		[]byte{0x60, 0, 1, ptr},
+		[]byte{0x60, 0, 0},
+		[]byte{0x60, 2, ptr, ptr, 1, ptr},
+	)
+	fns := []struct {
+		name string
+		typ  byte
+	}{
+		{names.Malloc, 0},
+		{names.Free, 1},
+		{names.Execute, 4},
+		{names.GetOutputPtr, 2},
+		{names.GetOutputLen, 2},
+		{names.ClearOutput, 3},
+	}
+	var funcs, exports, codes [][]byte
+	for i, fn := range fns {
+		funcs = append(funcs, []byte{fn.typ})
+		exp := append([]byte{byte(len(fn.name))}, fn.name...)
+		exports = append(exports, append(exp, 0x00, byte(i)))
+		body := []byte{0x00} // no locals
+		if fn.typ == 0 || fn.typ == 2 || fn.typ == 4 {
+			constOp := byte(0x41) // i32.const
+			if ptr == 0x7e {
+				constOp = 0x42 // i64.const
+			}
+			value := byte(0x00)
+			if fn.typ == 4 {
+				value = status
+			}
+			body = append(body, constOp, value)
+		}
+		body = append(body, 0x0b)
+		codes = append(codes, append([]byte{byte(len(body))}, body...))
+	}
+	wasm := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, types...)
+	wasm = append(wasm, section(3, funcs...)...)
+	wasm = append(wasm, section(7, exports...)...)
+	wasm = append(wasm, section(10, codes...)...)
+	return wasm
+}
+
+func TestBind_AlternativeNames(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// plugin_* variant with the libc allocator
+	names := lowlevel.KnownExportNames[3]
+	mod, err := r.Instantiate(ctx, stubModule(names, 0x7f, 0))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	m, err := lowlevel.Bind(mod)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if m.Names() != names {
+		t.Fatalf("unexpected names: %+v", m.Names())
+	}
+
+	if _, err := lowlevel.BindNames(mod, lowlevel.DefaultExportNames); err == nil {
+		t.Fatal("expected error binding default names")
+	}
+}
+
+func TestBind_Memory64(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	mod, err := r.Instantiate(ctx, stubModule(lowlevel.DefaultExportNames, 0x7e, 0))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	width, err := lowlevel.PointerWidth(mod.ExportedFunctionDefinitions(), lowlevel.DefaultExportNames)
+	if err != nil || width != 64 {
+		t.Fatalf("expected 64-bit pointers, got %d: %v", width, err)
+	}
+	if _, err := lowlevel.Bind(mod); !errors.Is(err, lowlevel.ErrMemory64Unsupported) {
+		t.Fatalf("expected ErrMemory64Unsupported, got %v", err)
+	}
+}
+
+func TestCallExecute_Status(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// 0x7e is -2 in signed LEB128
+	mod, err := r.Instantiate(ctx, stubModule(lowlevel.DefaultExportNames, 0x7f, 0x7e))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	m, err := lowlevel.Bind(mod)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	_, err = m.CallExecute(ctx, 0, 0)
+	var execErr *lowlevel.ExecuteError
+	if !errors.As(err, &execErr) || execErr.Code != lowlevel.StatusPanic {
+		t.Fatalf("expected ExecuteError with StatusPanic, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package lowlevel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ExportNames is a set of export names implementing the ABI.
+type ExportNames struct {
+	Malloc       string
+	Free         string
+	Execute      string
+	GetOutputPtr string
+	GetOutputLen string
+	ClearOutput  string
+}
+
+// DefaultExportNames are the export names of the embedded build.
+var DefaultExportNames = ExportNames{
+	Malloc:       ExportMalloc,
+	Free:         ExportFree,
+	Execute:      ExportExecute,
+	GetOutputPtr: ExportGetOutputPtr,
+	GetOutputLen: ExportGetOutputLen,
+	ClearOutput:  ExportClearOutput,
+}
+
+// KnownExportNames lists the export name variants probed by Bind, in order.
+//
+// Forks and alternative builds may rename the ABI functions or use the libc
+// allocator directly. The first complete set found on a module is used.
+var KnownExportNames = []ExportNames{
+	DefaultExportNames,
+	{
+		Malloc:       "malloc",
+		Free:         "free",
+		Execute:      ExportExecute,
+		GetOutputPtr: ExportGetOutputPtr,
+		GetOutputLen: ExportGetOutputLen,
+		ClearOutput:  ExportClearOutput,
+	},
+	{
+		Malloc:       "plugin_malloc",
+		Free:         "plugin_free",
+		Execute:      "plugin_execute",
+		GetOutputPtr: "plugin_get_output_ptr",
+		GetOutputLen: "plugin_get_output_len",
+		ClearOutput:  "plugin_clear_output",
+	},
+	{
+		Malloc:       "malloc",
+		Free:         "free",
+		Execute:      "plugin_execute",
+		GetOutputPtr: "plugin_get_output_ptr",
+		GetOutputLen: "plugin_get_output_len",
+		ClearOutput:  "plugin_clear_output",
+	},
+}
+
+// all returns the names in the set.
+func (n ExportNames) all() []string {
+	return []string{n.Malloc, n.Free, n.Execute, n.GetOutputPtr, n.GetOutputLen, n.ClearOutput}
+}
+
+// Missing returns the first name in the set not present in exports.
+// Returns an empty string if all are present.
+func (n ExportNames) Missing(exports map[string]api.FunctionDefinition) string {
+	for _, name := range n.all() {
+		if _, ok := exports[name]; !ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// DetectExportNames returns the first entry of KnownExportNames fully present
+// in exports. Accepts the result of ExportedFunctions on a compiled module or
+// ExportedFunctionDefinitions on an instance.
+func DetectExportNames(exports map[string]api.FunctionDefinition) (ExportNames, bool) {
+	for _, names := range KnownExportNames {
+		if names.Missing(exports) == "" {
+			return names, true
+		}
+	}
+	return ExportNames{}, false
+}
+
+// ErrMemory64Unsupported is returned when binding a build using 64-bit
+// pointers. The runtime cannot host memory64 modules yet.
+var ErrMemory64Unsupported = errors.New("memory64 builds are not supported by the runtime")
+
+// PointerWidth returns the pointer width in bits (32 or 64) of the ABI
+// exported under names, inferred from the parameter type of the allocator.
+//
+// A memory64 build passes i64 pointers and lengths to every ABI function.
+// Returns an error if the allocator is missing or has an unexpected signature.
+func PointerWidth(exports map[string]api.FunctionDefinition, names ExportNames) (int, error) {
+	def, ok := exports[names.Malloc]
+	if !ok {
+		return 0, errors.New("missing export: " + names.Malloc)
+	}
+	params := def.ParamTypes()
+	if len(params) != 1 {
+		return 0, fmt.Errorf("unexpected signature for %s: %d params", names.Malloc, len(params))
+	}
+	switch params[0] {
+	case api.ValueTypeI32:
+		return 32, nil
+	case api.ValueTypeI64:
+		return 64, nil
+	default:
+		return 0, fmt.Errorf("unexpected pointer type for %s: %s", names.Malloc, api.ValueTypeName(params[0]))
+	}
+}
@@ -0,0 +1,93 @@
+package lowlevel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Optional per-file session export names.
+//
+// A session accumulates serialized FileDescriptorProtos one at a time, in
+// dependency order, and generates each file to generate as it is added. All
+// functions return a status code (see StatusInvalidRequest) on failure, with
+// the detail message in the output buffer.
+const (
+	// ExportSessionBegin starts a session, discarding any previous one:
+	// (parameter_ptr, parameter_len) -> status.
+	ExportSessionBegin = "prost_session_begin"
+	// ExportSessionAdd adds a dependency without generating it:
+	// (file_ptr, file_len) -> status.
+	ExportSessionAdd = "prost_session_add"
+	// ExportSessionGenerate adds a file and generates it. The output buffer
+	// holds a CodeGeneratorResponse for the file:
+	// (file_ptr, file_len) -> output length or status.
+	ExportSessionGenerate = "prost_session_generate"
+	// ExportSessionEnd discards the session state: () -> ().
+	ExportSessionEnd = "prost_session_end"
+)
+
+// errNoSession is returned by the session methods if HasSession is false.
+var errNoSession = errors.New("module does not export the session functions")
+
+// HasSession returns true if the module exports the per-file session functions.
+func (m *Module) HasSession() bool {
+	return m.sessionBegin != nil &&
+		m.sessionAdd != nil &&
+		m.sessionGenerate != nil &&
+		m.sessionEnd != nil
+}
+
+// SessionBegin calls prost_session_begin with the plugin parameter.
+func (m *Module) SessionBegin(ctx context.Context, parameter []byte) error {
+	if !m.HasSession() {
+		return errNoSession
+	}
+	_, err := m.callBytes(ctx, m.sessionBegin, ExportSessionBegin, parameter)
+	return err
+}
+
+// SessionAdd calls prost_session_add with a serialized FileDescriptorProto.
+func (m *Module) SessionAdd(ctx context.Context, file []byte) error {
+	if !m.HasSession() {
+		return errNoSession
+	}
+	_, err := m.callBytes(ctx, m.sessionAdd, ExportSessionAdd, file)
+	return err
+}
+
+// SessionGenerate calls prost_session_generate with a serialized
+// FileDescriptorProto. Returns the length of the output buffer; read it with
+// ReadOutput and release it with ClearOutput.
+func (m *Module) SessionGenerate(ctx context.Context, file []byte) (uint32, error) {
+	if !m.HasSession() {
+		return 0, errNoSession
+	}
+	return m.callBytes(ctx, m.sessionGenerate, ExportSessionGenerate, file)
+}
+
+// SessionEnd calls prost_session_end.
+func (m *Module) SessionEnd(ctx context.Context) error {
+	if !m.HasSession() {
+		return errNoSession
+	}
+	_, err := m.sessionEnd.Call(ctx)
+	return err
+}
+
+// callBytes copies data into guest memory, calls fn with its pointer and
+// length, and frees it again. Status codes are translated with checkStatus.
+func (m *Module) callBytes(ctx context.Context, fn api.Function, name string, data []byte) (uint32, error) {
+	ptr, err := m.AllocBytes(ctx, data)
+	if err != nil {
+		return 0, err
+	}
+	defer m.Free(ctx, ptr, uint32(len(data)))
+
+	results, err := fn.Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	return m.checkStatus(ctx, name, uint32(results[0]))
+}
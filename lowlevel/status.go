@@ -0,0 +1,99 @@
+package lowlevel
+
+import (
+	"context"
+	"fmt"
+)
+
+// Status codes returned by prost_execute in place of an output length.
+//
+// A negative return value (as int32) reports a failure. The output buffer
+// then holds a UTF-8 detail message instead of a CodeGeneratorResponse.
+// Outputs are therefore limited to 2GiB.
+const (
+	// StatusInvalidRequest reports that the request could not be decoded.
+	StatusInvalidRequest int32 = -1
+	// StatusPanic reports that the plugin panicked during generation.
+	StatusPanic int32 = -2
+	// StatusOutOfMemory reports that the guest allocator failed.
+	StatusOutOfMemory int32 = -3
+)
+
+// statusNames are the names of the known status codes.
+var statusNames = map[int32]string{
+	StatusInvalidRequest: "invalid request",
+	StatusPanic:          "panic",
+	StatusOutOfMemory:    "out of memory",
+}
+
+// ExecuteError is a failure reported by a negative prost_execute return value.
+type ExecuteError struct {
+	// Function is the export that returned the status.
+	// An empty value means prost_execute.
+	Function string
+	// Code is the status code returned by prost_execute.
+	Code int32
+	// Detail is the message provided by the guest, if any.
+	Detail string
+	// Info is the structured detail from the error buffer, if the module
+	// exports it and the guest recorded one.
+	Info *ErrorInfo
+}
+
+// Error returns the error message.
+func (e *ExecuteError) Error() string {
+	name, ok := statusNames[e.Code]
+	if !ok {
+		name = "unknown status"
+	}
+	fn := e.Function
+	if fn == "" {
+		fn = ExportExecute
+	}
+	msg := fmt.Sprintf("%s: %s (%d)", fn, name, e.Code)
+	switch {
+	case e.Info != nil && (e.Detail == "" || e.Detail == e.Info.Message):
+		msg += ": " + e.Info.String()
+	case e.Detail != "":
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// CallExecute calls prost_execute and translates negative status codes.
+//
+// Returns the output length on success. On a status code, reads the detail
+// message, clears the output buffer, and returns an *ExecuteError. Any other
+// error is a runtime failure from the call itself.
+func (m *Module) CallExecute(ctx context.Context, inputPtr, inputLen uint32) (uint32, error) {
+	n, err := m.CallExecuteRaw(ctx, inputPtr, inputLen)
+	if err != nil {
+		return 0, err
+	}
+	return m.checkStatus(ctx, "", n)
+}
+
+// checkStatus translates a negative status code returned by function.
+// Returns n if it is not a status code. Otherwise reads the detail message,
+// clears the output buffer, and returns an *ExecuteError.
+func (m *Module) checkStatus(ctx context.Context, function string, n uint32) (uint32, error) {
+	code := int32(n)
+	if code >= 0 {
+		return n, nil
+	}
+
+	execErr := &ExecuteError{Function: function, Code: code}
+	if detailLen, err := m.OutputLen(ctx); err == nil && detailLen != 0 {
+		if detail, err := m.ReadOutput(ctx, detailLen); err == nil {
+			execErr.Detail = string(detail)
+		}
+	}
+	if m.HasErrorInfo() {
+		// A malformed error buffer must not hide the status itself
+		execErr.Info, _ = m.ReadErrorInfo(ctx)
+	}
+	if err := m.ClearOutput(ctx); err != nil {
+		return 0, err
+	}
+	return 0, execErr
+}
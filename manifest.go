@@ -0,0 +1,89 @@
+package prost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// DefaultManifestFilename is the conventional name of the generation manifest.
+const DefaultManifestFilename = "prost-manifest.json"
+
+// Manifest describes the output of a generation run.
+type Manifest struct {
+	// PluginVersion is the protoc-gen-prost version that generated the files.
+	PluginVersion string `json:"pluginVersion"`
+	// RequestDigest is the RequestDigest of the CodeGeneratorRequest, if known.
+	RequestDigest string `json:"requestDigest,omitempty"`
+	// Files lists the generated files sorted by name.
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes a single generated file.
+type ManifestFile struct {
+	// Name is the slash-separated path relative to the output directory.
+	Name string `json:"name"`
+	// SHA256 is the hex-encoded SHA-256 of the file content.
+	SHA256 string `json:"sha256"`
+	// Size is the file size in bytes.
+	Size int `json:"size"`
+}
+
+// NewManifest builds a manifest for the resolved files.
+// If digest is the zero value, RequestDigest is omitted.
+func NewManifest(digest Digest, files []ResolvedFile) *Manifest {
+	entries := make([]ManifestFile, len(files))
+	for i, f := range files {
+		entries[i] = newManifestFile(f.Name, []byte(f.Content))
+	}
+	return newManifest(digest, entries)
+}
+
+// newManifest builds a manifest listing files, sorting them by name.
+func newManifest(digest Digest, files []ManifestFile) *Manifest {
+	m := &Manifest{
+		PluginVersion: Version,
+		Files:         files,
+	}
+	if digest != (Digest{}) {
+		m.RequestDigest = digest.String()
+	}
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Name < m.Files[j].Name
+	})
+	return m
+}
+
+// newManifestFile describes a file with the given content.
+func newManifestFile(name string, content []byte) ManifestFile {
+	sum := sha256.Sum256(content)
+	return ManifestFile{
+		Name:   name,
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   len(content),
+	}
+}
+
+// Marshal encodes the manifest as indented JSON.
+func (m *Manifest) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadManifest reads a manifest from a JSON file.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
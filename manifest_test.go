@@ -0,0 +1,39 @@
+package prost
+
+import (
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWriteResponse_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("b.rs"), Content: proto.String("b")},
+			{Name: proto.String("a.rs"), Content: proto.String("a")},
+		},
+	}
+	digest := RequestDigest([]byte("request"))
+	opts := &WriteOptions{ManifestFilename: DefaultManifestFilename, RequestDigest: digest}
+	if err := WriteResponse(dir, resp, opts); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+
+	m, err := ReadManifest(filepath.Join(dir, DefaultManifestFilename))
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	if m.PluginVersion != Version || m.RequestDigest != digest.String() {
+		t.Fatalf("unexpected manifest header: %+v", m)
+	}
+	if len(m.Files) != 2 || m.Files[0].Name != "a.rs" || m.Files[1].Name != "b.rs" {
+		t.Fatalf("unexpected manifest files: %+v", m.Files)
+	}
+	// sha256("a")
+	if m.Files[0].SHA256 != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" || m.Files[0].Size != 1 {
+		t.Fatalf("unexpected manifest entry: %+v", m.Files[0])
+	}
+}
@@ -0,0 +1,86 @@
+package prost
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// memoryContext attaches the configured memory allocator to ctx.
+// Must be used for every module instantiation.
+func (p *ProtocGenProst) memoryContext(ctx context.Context) context.Context {
+	if p.opts.memoryCapacity == 0 {
+		return ctx
+	}
+	capacity := p.opts.memoryCapacity
+	return experimental.WithMemoryAllocator(ctx, experimental.MemoryAllocatorFunc(func(capHint, maxSize uint64) experimental.LinearMemory {
+		return newPreallocatedMemory(capHint, maxSize, capacity)
+	}))
+}
+
+// preallocatedMemory is a LinearMemory with capacity reserved up front.
+// Grows within the reserved capacity do not copy.
+type preallocatedMemory struct {
+	buf []byte
+	max uint64
+}
+
+// newPreallocatedMemory allocates a backing buffer with capacity bytes,
+// clamped to maxSize, or capHint if larger.
+func newPreallocatedMemory(capHint, maxSize, capacity uint64) *preallocatedMemory {
+	return &preallocatedMemory{
+		buf: make([]byte, 0, max(capHint, min(capacity, maxSize))),
+		max: maxSize,
+	}
+}
+
+// Reallocate implements experimental.LinearMemory.
+func (m *preallocatedMemory) Reallocate(size uint64) []byte {
+	if size > m.max {
+		return nil
+	}
+	if size <= uint64(cap(m.buf)) {
+		// Memory never shrinks so the bytes past len are still zero
+		m.buf = m.buf[:size]
+		return m.buf
+	}
+	buf := make([]byte, size, min(max(size, 2*uint64(cap(m.buf))), m.max))
+	copy(buf, m.buf)
+	m.buf = buf
+	return buf
+}
+
+// Free implements experimental.LinearMemory.
+func (m *preallocatedMemory) Free() {
+	m.buf = nil
+}
+
+// wasmPageSize is the size of a WebAssembly memory page in bytes.
+const wasmPageSize = 65536
+
+// outOfMemory wraps err in an *OutOfMemoryError describing the current
+// guest memory. Must be called with mu held.
+func (p *ProtocGenProst) outOfMemory(requestSize int, err error) error {
+	mem := p.ll.Memory()
+	limit, _ := mem.Definition().Max()
+	return &OutOfMemoryError{
+		Pages:       mem.Size() / wasmPageSize,
+		LimitPages:  limit,
+		RequestSize: requestSize,
+		Err:         err,
+	}
+}
+
+// trappedOutOfMemory guesses whether a trap was caused by a failed memory
+// grow. Rust aborts with an unreachable trap when allocation fails, and
+// allocators grow geometrically, so a trap with memory past half of the
+// limit is most likely an allocation failure. Must be called with mu held.
+func (p *ProtocGenProst) trappedOutOfMemory(err error) bool {
+	if !strings.Contains(err.Error(), "unreachable") {
+		return false
+	}
+	mem := p.ll.Memory()
+	limit, _ := mem.Definition().Max()
+	return uint64(mem.Size())*2 > uint64(limit)*wasmPageSize
+}
@@ -0,0 +1,82 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_MemoryCapacity(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithMemoryCapacity(64<<20))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	mem := p.mod.Memory()
+	before, _ := mem.Read(0, 1)
+	initialSize := mem.Size()
+
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if mem.Size() <= initialSize {
+		t.Skip("memory did not grow during Execute")
+	}
+
+	// Growth within the reserved capacity keeps the backing buffer
+	after, _ := mem.Read(0, 1)
+	if &before[0] != &after[0] {
+		t.Fatal("expected memory growth without reallocation")
+	}
+}
+
+func TestPreallocatedMemory_Reallocate(t *testing.T) {
+	m := newPreallocatedMemory(0, 4<<16, 2<<16)
+	if cap(m.buf) != 2<<16 {
+		t.Fatalf("unexpected capacity %d", cap(m.buf))
+	}
+	buf := m.Reallocate(1 << 16)
+	buf[0] = 1
+	if grown := m.Reallocate(3 << 16); len(grown) != 3<<16 || grown[0] != 1 {
+		t.Fatal("expected contents to be preserved when growing past capacity")
+	}
+	if m.Reallocate(5<<16) != nil {
+		t.Fatal("expected nil when exceeding max")
+	}
+}
+
+func TestProtocGenProst_OutOfMemory(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithMemoryLimitPages(64))
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Skipf("module does not fit in 64 pages: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input := make([]byte, 8<<20)
+	_, err = p.Execute(ctx, input)
+	if !errors.Is(err, ErrOutOfMemory) {
+		t.Fatalf("expected ErrOutOfMemory, got %v", err)
+	}
+	var oom *OutOfMemoryError
+	if !errors.As(err, &oom) {
+		t.Fatalf("expected *OutOfMemoryError, got %T", err)
+	}
+	if oom.LimitPages != 64 || oom.RequestSize != len(input) || oom.Pages == 0 || oom.Pages > oom.LimitPages {
+		t.Fatalf("unexpected error fields: %+v", oom)
+	}
+	if !strings.Contains(err.Error(), "WithMemoryLimitPages") {
+		t.Fatalf("expected guidance in error message: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package metrics exports Prometheus metrics of protoc-gen-prost instances.
+//
+// Attach a Collector to each instance with its Option and register it with
+// a prometheus.Registerer:
+//
+//	c := metrics.NewCollector()
+//	prometheus.MustRegister(c)
+//	p, err := prost.NewProtocGenProst(ctx, r, c.Option())
+//
+// Servers built on the instance, e.g. prost.ServeListener, are then covered
+// without further changes.
+package metrics
+
+import (
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes the names of all metrics.
+const Namespace = "prost"
+
+// Collector records the events of the instances it is attached to and
+// exports them as Prometheus metrics. One Collector may be shared by several
+// instances, e.g. the members of a pool.
+type Collector struct {
+	executions  *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	duration    prometheus.Histogram
+	inFlight    prometheus.Gauge
+	instances   prometheus.Counter
+	recycles    prometheus.Counter
+	memoryPages prometheus.Gauge
+}
+
+var (
+	_ prost.Observer       = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// NewCollector creates a Collector.
+func NewCollector() *Collector {
+	return &Collector{
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "executions_total",
+			Help:      "Execute calls by result (ok or error).",
+		}, []string{"result"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "execution_errors_total",
+			Help:      "Failed Execute calls by error class, see prost.ErrorClass.",
+		}, []string{"class"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "execution_duration_seconds",
+			Help:      "Duration of Execute calls, including waiting for the instance.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "executions_in_flight",
+			Help:      "Execute calls running or waiting for an instance.",
+		}),
+		instances: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "instantiations_total",
+			Help:      "Module instances created.",
+		}),
+		recycles: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "instance_recycles_total",
+			Help:      "Module instances replaced, e.g. after a trap or interrupt.",
+		}),
+		memoryPages: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "guest_memory_pages",
+			Help:      "Guest memory size in 64KiB pages after the most recent plugin run.",
+		}),
+	}
+}
+
+// Option returns the option attaching c to an instance.
+func (c *Collector) Option() prost.Option {
+	return prost.WithObserver(c)
+}
+
+// ExecuteStarted implements prost.Observer.
+func (c *Collector) ExecuteStarted() {
+	c.inFlight.Inc()
+}
+
+// ExecuteDone implements prost.Observer.
+func (c *Collector) ExecuteDone(ev prost.ExecuteEvent) {
+	c.inFlight.Dec()
+	c.duration.Observe(ev.Duration.Seconds())
+	if ev.Err != nil {
+		c.executions.WithLabelValues("error").Inc()
+		c.errors.WithLabelValues(prost.ErrorClass(ev.Err)).Inc()
+	} else {
+		c.executions.WithLabelValues("ok").Inc()
+	}
+	if ev.MemoryPages != 0 {
+		c.memoryPages.Set(float64(ev.MemoryPages))
+	}
+}
+
+// Instantiated implements prost.Observer.
+func (c *Collector) Instantiated(recycled bool) {
+	c.instances.Inc()
+	if recycled {
+		c.recycles.Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors() {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+}
+
+// collectors lists the metrics of c.
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.executions, c.errors, c.duration, c.inFlight, c.instances, c.recycles, c.memoryPages}
+}
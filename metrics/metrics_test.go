@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	c := NewCollector()
+	p, err := prost.NewProtocGenProst(ctx, r, c.Option())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+
+	if _, err := p.Execute(ctx, nil); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	p.Close(ctx)
+	if _, err := p.Execute(ctx, nil); err == nil {
+		t.Fatal("expected error after Close")
+	}
+
+	if n := testutil.ToFloat64(c.executions.WithLabelValues("ok")); n != 1 {
+		t.Fatalf("expected 1 successful execution, got %v", n)
+	}
+	if n := testutil.ToFloat64(c.errors.WithLabelValues("closed")); n != 1 {
+		t.Fatalf("expected 1 closed error, got %v", n)
+	}
+	if n := testutil.ToFloat64(c.inFlight); n != 0 {
+		t.Fatalf("expected no executions in flight, got %v", n)
+	}
+	if n := testutil.ToFloat64(c.instances); n != 1 {
+		t.Fatalf("expected 1 instantiation, got %v", n)
+	}
+	if n := testutil.ToFloat64(c.memoryPages); n == 0 {
+		t.Fatal("expected guest memory pages to be recorded")
+	}
+	if n := testutil.CollectAndCount(c); n == 0 {
+		t.Fatal("expected collected metrics")
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	ctx := context.Background()
+	pool, err := prost.NewPool(ctx, &prost.PoolOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Close(ctx)
+
+	want := `
+# HELP prost_pool_instances Instances of the pool.
+# TYPE prost_pool_instances gauge
+prost_pool_instances 1
+# HELP prost_pool_queue_depth Calls queued on the pool and not yet running, by priority.
+# TYPE prost_pool_queue_depth gauge
+prost_pool_queue_depth{priority="bulk"} 0
+prost_pool_queue_depth{priority="interactive"} 0
+`
+	if err := testutil.CollectAndCompare(NewPoolCollector(pool), strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
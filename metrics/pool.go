@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports the queue of a prost.Pool as Prometheus metrics.
+// Attach a Collector to the instances of the pool for the call metrics:
+//
+//	c := metrics.NewCollector()
+//	pool, err := prost.NewPool(ctx, &prost.PoolOptions{Options: []prost.Option{c.Option()}})
+//	prometheus.MustRegister(c, metrics.NewPoolCollector(pool))
+type PoolCollector struct {
+	pool       *prost.Pool
+	queueDepth *prometheus.Desc
+	size       *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*PoolCollector)(nil)
+
+// NewPoolCollector creates a PoolCollector for pool.
+func NewPoolCollector(pool *prost.Pool) *PoolCollector {
+	return &PoolCollector{
+		pool: pool,
+		queueDepth: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "pool", "queue_depth"),
+			"Calls queued on the pool and not yet running, by priority.",
+			[]string{"priority"}, nil,
+		),
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "pool", "instances"),
+			"Instances of the pool.",
+			nil, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDepth
+	ch <- c.size
+}
+
+// Collect implements prometheus.Collector.
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, pri := range prost.Priorities() {
+		ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(c.pool.QueueDepth(pri)), pri.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(c.pool.Size()))
+}
@@ -0,0 +1,248 @@
+package prost
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// VersionSectionName is the custom section a plugin build may use to record
+// its version as a UTF-8 string, reported by InspectWASM.
+const VersionSectionName = "prost_version"
+
+// ModuleInfo describes a plugin WASM binary. See InspectWASM.
+type ModuleInfo struct {
+	// ABI is the binary format.
+	ABI ABI `json:"abi"`
+	// Size is the size of the binary in bytes.
+	Size int `json:"size"`
+	// SHA256 is the hex SHA-256 checksum of the binary.
+	SHA256 string `json:"sha256"`
+	// Version is the content of the VersionSectionName custom section.
+	Version string `json:"version,omitempty"`
+	// Producers lists the toolchains recorded in the producers section,
+	// e.g. "processed-by: rustc 1.80.0".
+	Producers []string `json:"producers,omitempty"`
+	// Names is the detected ABI export name variant, or nil if the module
+	// does not implement the ABI.
+	Names *lowlevel.ExportNames `json:"names,omitempty"`
+	// PointerWidth is 32 or 64 if Names is set.
+	PointerWidth int `json:"pointerWidth,omitempty"`
+	// Command is set if the module runs as a WASI command, see IsCommandModule.
+	Command bool `json:"command"`
+	// Extensions lists the optional ABI extensions the module exports:
+	// "allocator-stats", "session" and "error-info".
+	Extensions []string `json:"extensions,omitempty"`
+	// MemoryMinPages is the initial memory size in 64KiB pages.
+	MemoryMinPages uint32 `json:"memoryMinPages"`
+	// MemoryMaxPages is the maximum memory size in pages, or 0 if the module
+	// declares none.
+	MemoryMaxPages uint32 `json:"memoryMaxPages,omitempty"`
+	// Exports lists the exported functions with their signatures.
+	Exports []FunctionInfo `json:"exports"`
+	// Imports lists the imported functions with their signatures.
+	Imports []FunctionInfo `json:"imports"`
+}
+
+// FunctionInfo describes an exported or imported function.
+type FunctionInfo struct {
+	// Module is the import module, empty for exports.
+	Module string `json:"module,omitempty"`
+	// Name is the export or import name.
+	Name string `json:"name"`
+	// Signature is formatted like "(i32, i32) -> i32".
+	Signature string `json:"signature"`
+}
+
+// InspectWASM describes a plugin WASM binary without instantiating it, to
+// vet custom or forked builds. Component-model binaries are only identified,
+// since the runtime cannot compile them.
+func InspectWASM(ctx context.Context, wasm []byte) (*ModuleInfo, error) {
+	abi, err := DetectABI(wasm)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(wasm)
+	info := &ModuleInfo{ABI: abi, Size: len(wasm), SHA256: hex.EncodeToString(sum[:])}
+	if abi != ABICoreModule {
+		return info, nil
+	}
+
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCustomSections(true))
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := compiled.ExportedFunctions()
+	for name, def := range exports {
+		info.Exports = append(info.Exports, FunctionInfo{Name: name, Signature: signature(def)})
+	}
+	sort.Slice(info.Exports, func(i, j int) bool { return info.Exports[i].Name < info.Exports[j].Name })
+	for _, def := range compiled.ImportedFunctions() {
+		module, name, _ := def.Import()
+		info.Imports = append(info.Imports, FunctionInfo{Module: module, Name: name, Signature: signature(def)})
+	}
+
+	if names, ok := lowlevel.DetectExportNames(exports); ok {
+		info.Names = &names
+		info.PointerWidth, _ = lowlevel.PointerWidth(exports, names)
+	}
+	info.Command = IsCommandModule(compiled)
+	for _, ext := range []struct {
+		name    string
+		exports []string
+	}{
+		{"allocator-stats", []string{lowlevel.ExportAllocLiveCount, lowlevel.ExportAllocPeakCount, lowlevel.ExportAllocLiveBytes, lowlevel.ExportAllocPeakBytes}},
+		{"session", []string{lowlevel.ExportSessionBegin, lowlevel.ExportSessionAdd, lowlevel.ExportSessionGenerate, lowlevel.ExportSessionEnd}},
+		{"error-info", []string{lowlevel.ExportGetErrorPtr, lowlevel.ExportGetErrorLen}},
+	} {
+		if hasExports(exports, ext.exports) {
+			info.Extensions = append(info.Extensions, ext.name)
+		}
+	}
+
+	for _, mem := range compiled.ExportedMemories() {
+		info.MemoryMinPages = mem.Min()
+		if maxPages, ok := mem.Max(); ok {
+			info.MemoryMaxPages = maxPages
+		}
+	}
+	for _, section := range compiled.CustomSections() {
+		if section.Name() == "producers" {
+			info.Producers = parseProducers(section.Data())
+		}
+	}
+	info.Version = moduleVersion(compiled)
+	return info, nil
+}
+
+// moduleVersion returns the content of the VersionSectionName custom section,
+// or an empty string if the compiled module does not keep it.
+func moduleVersion(compiled wazero.CompiledModule) string {
+	for _, section := range compiled.CustomSections() {
+		if section.Name() == VersionSectionName {
+			return strings.TrimSpace(string(section.Data()))
+		}
+	}
+	return ""
+}
+
+// moduleFingerprint identifies a compiled module whose bytes are unknown by
+// hashing its version, custom sections and function signatures. Builds that
+// differ only in code bodies share a fingerprint unless they record
+// different versions.
+func moduleFingerprint(compiled wazero.CompiledModule) string {
+	h := sha256.New()
+	field := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	field(moduleVersion(compiled))
+	for _, section := range compiled.CustomSections() {
+		field(section.Name())
+		field(string(section.Data()))
+	}
+	exports := compiled.ExportedFunctions()
+	names := make([]string, 0, len(exports))
+	for name := range exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field(name + signature(exports[name]))
+	}
+	for _, def := range compiled.ImportedFunctions() {
+		module, name, _ := def.Import()
+		field(module + "." + name + signature(def))
+	}
+	return "module:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// hasExports checks if all names are exported.
+func hasExports(exports map[string]api.FunctionDefinition, names []string) bool {
+	for _, name := range names {
+		if _, ok := exports[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// signature formats the type of a function.
+func signature(def api.FunctionDefinition) string {
+	types := func(ts []api.ValueType) string {
+		names := make([]string, len(ts))
+		for i, t := range ts {
+			names[i] = api.ValueTypeName(t)
+		}
+		return strings.Join(names, ", ")
+	}
+	sig := "(" + types(def.ParamTypes()) + ")"
+	switch results := def.ResultTypes(); len(results) {
+	case 0:
+		return sig
+	case 1:
+		return sig + " -> " + types(results)
+	default:
+		return sig + " -> (" + types(results) + ")"
+	}
+}
+
+// parseProducers decodes the producers custom section into "field: name
+// version" entries. Returns the entries decoded before any malformed data.
+func parseProducers(data []byte) []string {
+	var out []string
+	readUint := func() (int, bool) {
+		var v, shift uint
+		for len(data) != 0 && shift < 35 {
+			b := data[0]
+			data = data[1:]
+			v |= uint(b&0x7f) << shift
+			if b&0x80 == 0 {
+				return int(v), true
+			}
+			shift += 7
+		}
+		return 0, false
+	}
+	readName := func() (string, bool) {
+		n, ok := readUint()
+		if !ok || n > len(data) {
+			return "", false
+		}
+		s := string(data[:n])
+		data = data[n:]
+		return s, true
+	}
+
+	fields, ok := readUint()
+	for i := 0; ok && i < fields; i++ {
+		var field string
+		var values int
+		if field, ok = readName(); !ok {
+			break
+		}
+		if values, ok = readUint(); !ok {
+			break
+		}
+		for j := 0; ok && j < values; j++ {
+			var name, version string
+			if name, ok = readName(); !ok {
+				break
+			}
+			if version, ok = readName(); !ok {
+				break
+			}
+			out = append(out, strings.TrimSpace(field+": "+name+" "+version))
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package prost
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+)
+
+func TestInspectWASM(t *testing.T) {
+	ctx := context.Background()
+
+	info, err := InspectWASM(ctx, ProtocGenProstWASM())
+	if err != nil {
+		t.Fatalf("InspectWASM failed: %v", err)
+	}
+	if info.ABI != ABICoreModule || info.SHA256 != WASMSHA256 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.Names == nil || *info.Names != lowlevel.DefaultExportNames || info.PointerWidth != 32 {
+		t.Fatalf("expected the default ABI, got %+v", info.Names)
+	}
+	if info.MemoryMinPages == 0 || len(info.Exports) == 0 {
+		t.Fatalf("expected memory and exports, got %+v", info)
+	}
+
+	info, err = InspectWASM(ctx, sessionStubModule())
+	if err != nil {
+		t.Fatalf("InspectWASM failed: %v", err)
+	}
+	if !slices.Equal(info.Extensions, []string{"session"}) {
+		t.Fatalf("expected the session extension, got %v", info.Extensions)
+	}
+
+	component := []byte{0x00, 'a', 's', 'm', 0x0d, 0x00, 0x01, 0x00}
+	if info, err := InspectWASM(ctx, component); err != nil || info.ABI != ABIComponent {
+		t.Fatalf("expected a component, got %+v: %v", info, err)
+	}
+}
+
+func TestParseProducers(t *testing.T) {
+	// 1 field "language" with 1 value "Rust" ""
+	data := []byte{1, 8, 'l', 'a', 'n', 'g', 'u', 'a', 'g', 'e', 1, 4, 'R', 'u', 's', 't', 0}
+	if got := parseProducers(data); !slices.Equal(got, []string{"language: Rust"}) {
+		t.Fatalf("unexpected producers: %q", got)
+	}
+	if got := parseProducers(data[:5]); len(got) != 0 {
+		t.Fatalf("expected no producers from truncated data, got %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Observer receives events of a ProtocGenProst instance, e.g. to export
+// metrics (see package metrics). Methods are called synchronously from the
+// calling goroutine and must be safe for concurrent use.
+type Observer interface {
+	// ExecuteStarted is called when an Execute call starts, before it waits
+	// for the instance.
+	ExecuteStarted()
+	// ExecuteDone is called when an Execute call returns.
+	ExecuteDone(ExecuteEvent)
+	// Instantiated is called after a module instance was created. recycled
+	// is true if it replaces a previous instance, e.g. after a trap.
+	Instantiated(recycled bool)
+}
+
+// ExecuteEvent describes a finished Execute call.
+type ExecuteEvent struct {
+	// Duration is the time the call took, including waiting for the instance.
+	Duration time.Duration
+	// Err is the error the call returned, if any.
+	Err error
+	// MemoryPages is the size of guest memory in 64KiB pages after the most
+	// recent plugin run, or 0 in command mode.
+	MemoryPages uint32
+}
+
+// WithObserver reports the Execute calls and instantiations of the instance
+// to o.
+func WithObserver(o Observer) Option {
+	return func(opts *options) {
+		opts.observer = o
+	}
+}
+
+// ErrorClass returns a short, stable name for the kind of err, suitable as a
+// metric label. Returns an empty string for nil.
+func ErrorClass(err error) string {
+	var oom *OutOfMemoryError
+	var execErr *ExecuteError
+	var trapErr *TrapError
+	var collisionErr *CollisionError
+	var sandboxErr *SandboxError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ErrInterrupted):
+		return "interrupted"
+	case errors.Is(err, ErrClosed):
+		return "closed"
+	case errors.As(err, &oom):
+		return "out_of_memory"
+	case errors.As(err, &sandboxErr):
+		return "sandbox"
+	case errors.As(err, &execErr):
+		return "status"
+	case errors.As(err, &trapErr):
+		return "trap"
+	case errors.As(err, &collisionErr):
+		return "collision"
+	default:
+		return "other"
+	}
+}
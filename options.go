@@ -0,0 +1,240 @@
+package prost
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// Option configures a ProtocGenProst instance.
+type Option func(*options)
+
+// options contains the configuration applied by Option values.
+type options struct {
+	// cache stores generation results keyed by request digest.
+	cache Cache
+	// retries is the number of times a trapped Execute is retried
+	retries int
+	// retryBackoff is the delay before the first retry
+	retryBackoff time.Duration
+	// fileFilter selects the response files returned by Execute
+	fileFilter *FileFilter
+	// sortResponse enables SortResponse on each response
+	sortResponse bool
+	// noBufferPool disables pooling of host-side buffers
+	noBufferPool bool
+	// checkCollisions enables CheckCollisions before generation
+	checkCollisions bool
+	// checkSyntax enables CheckSyntax before generation
+	checkSyntax bool
+	// injectWKT enables InjectWellKnownTypes before generation
+	injectWKT bool
+	// prune enables PruneRequest before generation
+	prune bool
+	// defaultParams are merged into the parameter of each request
+	defaultParams string
+	// pristine restores the post-init guest state after each Execute
+	pristine bool
+	// maxExecutions re-instantiates the module after this many calls
+	maxExecutions int
+	// instanceTTL re-instantiates the module once it is older
+	instanceTTL time.Duration
+	// memoryCapacity is the guest memory capacity reserved at instantiation
+	memoryCapacity uint64
+	// listenerFactory is attached to guest functions at compile time
+	listenerFactory experimental.FunctionListenerFactory
+	// dumpDir receives prototext dumps of each call
+	dumpDir string
+	// failureDir receives the artifacts of failed calls
+	failureDir string
+	// hardened denies the guest clock, random, filesystem and network access
+	hardened bool
+	// walltime pins the guest wall clock if non-nil
+	walltime *time.Time
+	// nanotime pins the guest monotonic clock if non-nil
+	nanotime *int64
+	// randomSeed seeds a guest random source restarted per run if non-nil
+	randomSeed *uint64
+	// wasiAudit is attached to the WASI host module if the constructor
+	// instantiates it
+	wasiAudit *WASIAuditor
+	// logger receives the messages logged by the guest with host_log
+	logger *slog.Logger
+	// guestCache is exposed to the guest through the host_cache functions
+	guestCache Cache
+	// observer receives execution and instantiation events
+	observer Observer
+	// progress receives progress reports of each plugin run
+	progress ProgressFunc
+	// progressInterval is the period of the ProgressRunning reports
+	progressInterval time.Duration
+	// interceptors run around every Execute, outermost first
+	interceptors []Interceptor
+}
+
+// hasRequestOptions checks if any option requires decoding the request.
+func (o *options) hasRequestOptions() bool {
+	return o.checkCollisions || o.checkSyntax || o.injectWKT || o.prune || o.defaultParams != ""
+}
+
+// hasResponseOptions checks if any option requires processing the response.
+func (o *options) hasResponseOptions() bool {
+	return o.fileFilter != nil || o.sortResponse
+}
+
+// newOptions builds the options from the given Option list.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// WithCache configures a content-addressed result cache.
+// Execute consults the cache before running the plugin and stores results after,
+// keyed by CacheKey.
+func WithCache(c Cache) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
+
+// WithRetry retries Execute up to n times if the guest traps.
+//
+// The trapped instance is discarded and a fresh one is instantiated before
+// each retry. The delay before retry i (starting at 0) is backoff << i,
+// capped at one minute. Errors reported by the plugin in the
+// CodeGeneratorResponse, interrupts, and context cancellation are not
+// retried.
+func WithRetry(n int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = n
+		o.retryBackoff = backoff
+	}
+}
+
+// WithFileFilter removes response files not selected by the filter.
+// The filter is applied after the cache, so cached results are unfiltered.
+func WithFileFilter(f *FileFilter) Option {
+	return func(o *options) {
+		o.fileFilter = f
+	}
+}
+
+// WithDefaultParameters merges params into the parameter of every request
+// with MergeParameters, so organization-wide options like
+// "extern_path=.google.protobuf=::pbjson_types" apply even where a call site
+// forgets them. Values set by the request win. Repeated options merge, the
+// later winning.
+func WithDefaultParameters(params string) Option {
+	return func(o *options) {
+		o.defaultParams = MergeParameters(o.defaultParams, params)
+	}
+}
+
+// WithCollisionCheck runs CheckCollisions on each request before generation.
+// Execute returns a *CollisionError instead of running the plugin on conflicts.
+func WithCollisionCheck() Option {
+	return func(o *options) {
+		o.checkCollisions = true
+	}
+}
+
+// WithSortedResponse sorts and normalizes each response with SortResponse.
+// Responses are re-encoded deterministically, so identical results are
+// byte-identical regardless of guest-side iteration order.
+func WithSortedResponse() Option {
+	return func(o *options) {
+		o.sortResponse = true
+	}
+}
+
+// WithoutBufferPool disables pooling of the host-side buffers used for
+// intermediate request and response encodings.
+func WithoutBufferPool() Option {
+	return func(o *options) {
+		o.noBufferPool = true
+	}
+}
+
+// WithPristineState restores the guest to its post-initialization state after
+// every Execute, so one request can never influence the output of the next.
+//
+// If guest memory did not grow during the call its contents are restored from
+// a snapshot taken after instantiation. Otherwise the instance is discarded
+// and re-instantiated, since linear memory cannot shrink. Instances that
+// trapped are always re-instantiated, with or without this option.
+func WithPristineState() Option {
+	return func(o *options) {
+		o.pristine = true
+	}
+}
+
+// WithMemoryCapacity reserves capacity bytes for guest memory when the module
+// is instantiated, so memory grows without reallocation until it exceeds
+// capacity. This avoids grow-related latency spikes on large requests.
+//
+// The module declares no memory maximum, so the runtime-wide alternative
+// wazero.RuntimeConfig.WithMemoryCapacityFromMax must be combined with
+// WithMemoryLimitPages to avoid reserving 4GB per instance.
+func WithMemoryCapacity(capacity uint64) Option {
+	return func(o *options) {
+		o.memoryCapacity = capacity
+	}
+}
+
+// WithFunctionListenerFactory attaches a wazero FunctionListenerFactory to the
+// guest functions, e.g. a Profiler or a custom tracer.
+//
+// Listeners are bound when the module is compiled, so this only applies to
+// constructors that compile the embedded module. For a pre-compiled module,
+// pass experimental.WithFunctionListenerFactory in the ctx given to
+// CompileProtocGenProst instead.
+func WithFunctionListenerFactory(f experimental.FunctionListenerFactory) Option {
+	return func(o *options) {
+		o.listenerFactory = f
+	}
+}
+
+// WithDumpDir writes a prototext dump of every request and response to dir,
+// as produced by DumpRequest and DumpResponse. Files are named by the request
+// digest: <digest>.request.txtpb, <digest>.response.txtpb, and
+// <digest>.error.txt if the call failed. Intended for debugging and filing
+// upstream bugs; failures to write dumps are ignored.
+func WithDumpDir(dir string) Option {
+	return func(o *options) {
+		o.dumpDir = dir
+	}
+}
+
+// WithHardenedSandbox runs the guest without clock, random, filesystem,
+// network, or environment access, for untrusted or third-party plugin builds.
+//
+// Modules importing WASI clock, filesystem, or socket functions are rejected
+// at construction. random_get is commonly imported but unused, so it traps
+// when called instead. Either way the error is a *SandboxError. The guest
+// sees an empty environment.
+//
+// The embedded protoc-gen-prost seeds its hash maps from random_get, so it
+// fails under this option.
+func WithHardenedSandbox() Option {
+	return func(o *options) {
+		o.hardened = true
+	}
+}
+
+// WithWASIAudit logs every WASI host call made by the guest to logger, for
+// verifying what the plugin actually does. See WASIAuditor.
+//
+// Only takes effect with NewProtocGenProst and NewProtocGenProstWithModule,
+// which instantiate WASI. On a shared runtime, instantiate WASI with a
+// WASIAuditor attached instead.
+func WithWASIAudit(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.wasiAudit = NewWASIAuditor(logger)
+	}
+}
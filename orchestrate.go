@@ -0,0 +1,243 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// PluginCommand runs a native protoc plugin executable, e.g. protoc-gen-go,
+// as an Executor. Each call starts a new process.
+type PluginCommand struct {
+	// Command is the program and its arguments.
+	Command []string
+	// Dir is the working directory, or empty for the current directory.
+	Dir string
+	// Env are extra environment variables as KEY=value.
+	Env []string
+}
+
+// PluginCommandError is returned by PluginCommand if the plugin process failed.
+type PluginCommandError struct {
+	// Command is the program that failed.
+	Command string
+	// Stderr is the error output of the process.
+	Stderr string
+	// Err is the error returned by the process.
+	Err error
+}
+
+// Error returns the error message.
+func (e *PluginCommandError) Error() string {
+	msg := fmt.Sprintf("plugin %s failed: %v", e.Command, e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *PluginCommandError) Unwrap() error {
+	return e.Err
+}
+
+// Execute runs the plugin with a serialized CodeGeneratorRequest on stdin and
+// returns the serialized CodeGeneratorResponse written to stdout.
+// Returns a *PluginCommandError if the process fails.
+func (c *PluginCommand) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	if len(c.Command) == 0 {
+		return nil, errors.New("plugin command is empty")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &PluginCommandError{Command: c.Command[0], Stderr: stderr.String(), Err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// ExecuteRequest runs the plugin with a CodeGeneratorRequest.
+func (c *PluginCommand) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	output, err := c.Execute(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response of %s: %w", c.Command[0], err)
+	}
+	return resp, nil
+}
+
+// RequestFromDescriptorSet builds a CodeGeneratorRequest from a descriptor
+// set, e.g. written by buf build or protoc --include_imports. The set must
+// be in dependency order. files lists the files to generate; if empty, all
+// files of the set are generated.
+func RequestFromDescriptorSet(set *descriptorpb.FileDescriptorSet, files []string) (*pluginpb.CodeGeneratorRequest, error) {
+	req := &pluginpb.CodeGeneratorRequest{ProtoFile: set.GetFile()}
+	known := make(map[string]bool, len(set.GetFile()))
+	for _, fd := range set.GetFile() {
+		known[fd.GetName()] = true
+		if len(files) == 0 {
+			req.FileToGenerate = append(req.FileToGenerate, fd.GetName())
+		}
+	}
+	for _, name := range files {
+		if !known[name] {
+			return nil, fmt.Errorf("file not in descriptor set: %s", name)
+		}
+		req.FileToGenerate = append(req.FileToGenerate, name)
+	}
+	return req, nil
+}
+
+// Target is one generator run by GenerateTargets, e.g. the embedded prost
+// plugin for Rust or protoc-gen-go for Go.
+type Target struct {
+	// Name identifies the target in results and errors, e.g. "rust".
+	Name string
+	// Executor runs the generator: a ProtocGenProst, a PluginCommand, or a
+	// HandlerFunc of an in-process Go generator.
+	Executor Executor
+	// Parameter is the plugin parameter passed in the request.
+	Parameter string
+	// Out is the directory the files are written to.
+	Out string
+	// WriteOptions configures writing the files. Request is set by
+	// GenerateTargets.
+	WriteOptions WriteOptions
+	// Hooks run in Out after the files are written.
+	Hooks []Hook
+}
+
+// TargetResult reports the outcome of one Target.
+type TargetResult struct {
+	// Name is the name of the target.
+	Name string `json:"name"`
+	// Out is the output directory.
+	Out string `json:"out"`
+	// Files lists the files written, after the filter and path rules of
+	// the target's WriteOptions.
+	Files []string `json:"files"`
+	// Hooks are the results of the hooks that ran.
+	Hooks []*HookResult `json:"hooks,omitempty"`
+	// Duration is the time the target took, including hooks.
+	Duration time.Duration `json:"duration"`
+	// Err is the error the target failed with, if any.
+	Err error `json:"-"`
+}
+
+// TargetError is returned by GenerateTargets for a failed target.
+type TargetError struct {
+	// Name is the name of the target.
+	Name string
+	// Err is the error the target failed with.
+	Err error
+}
+
+// Error returns the error message.
+func (e *TargetError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// GenerateTargets runs every target on req and writes each response to the
+// target's output directory, so one descriptor set regenerates the code of
+// all languages of a repository. Each target receives a copy of req with its
+// own Parameter.
+//
+// Targets run concurrently and independently: a failing target does not
+// stop the others. Output directories must differ, since each has its own
+// marker file. Returns the results in target order and the failures as
+// *TargetError values joined with errors.Join.
+func GenerateTargets(ctx context.Context, req *pluginpb.CodeGeneratorRequest, targets []*Target) ([]*TargetResult, error) {
+	names := make(map[string]bool, len(targets))
+	outs := make(map[string]string, len(targets))
+	for i, t := range targets {
+		out := filepath.Clean(t.Out)
+		switch {
+		case t.Name == "" || t.Executor == nil || t.Out == "":
+			return nil, fmt.Errorf("target %d requires a name, executor and output directory", i)
+		case names[t.Name]:
+			return nil, fmt.Errorf("duplicate target: %s", t.Name)
+		case outs[out] != "":
+			// Each output root has one marker file listing its files
+			return nil, fmt.Errorf("targets %s and %s write to the same directory", outs[out], t.Name)
+		}
+		names[t.Name] = true
+		outs[out] = t.Name
+	}
+
+	results := make([]*TargetResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = t.generate(ctx, req)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, &TargetError{Name: res.Name, Err: res.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// generate runs the target and writes its output.
+func (t *Target) generate(ctx context.Context, req *pluginpb.CodeGeneratorRequest) *TargetResult {
+	res := &TargetResult{Name: t.Name, Out: t.Out, Files: []string{}}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	treq := proto.CloneOf(req)
+	treq.Parameter = nil
+	if t.Parameter != "" {
+		treq.Parameter = proto.String(t.Parameter)
+	}
+	resp, err := t.Executor.ExecuteRequest(ctx, treq)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	opts := t.WriteOptions
+	opts.Request = treq
+	files, err := writeResponse(t.Out, resp, &opts)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for _, f := range files {
+		res.Files = append(res.Files, f.Name)
+	}
+	res.Hooks, res.Err = RunHooks(ctx, t.Out, res.Files, t.Hooks)
+	return res
+}
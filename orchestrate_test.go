@@ -0,0 +1,122 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// buildNativePlugin builds testdata/cmdplugin for the host platform.
+func buildNativePlugin(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping plugin build in short mode")
+	}
+	out := filepath.Join(t.TempDir(), "protoc-gen-txt")
+	if msg, err := exec.Command("go", "build", "-o", out, "./testdata/cmdplugin").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build plugin: %v\n%s", err, msg)
+	}
+	return out
+}
+
+func TestGenerateTargets(t *testing.T) {
+	plugin := buildNativePlugin(t)
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		{Name: proto.String("test.proto"), Package: proto.String("test"), Syntax: proto.String("proto3")},
+	}}
+	req, err := RequestFromDescriptorSet(set, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RequestFromDescriptorSet(set, []string{"missing.proto"}); err == nil {
+		t.Fatal("expected error for a file not in the set")
+	}
+
+	root := t.TempDir()
+	var handlerParam string
+	targets := []*Target{
+		{Name: "rust", Executor: p, Out: filepath.Join(root, "rust")},
+		{Name: "txt", Executor: &PluginCommand{Command: []string{plugin}}, Parameter: "hello", Out: filepath.Join(root, "txt")},
+		{Name: "handler", Executor: HandlerFunc(func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+			handlerParam = req.GetParameter()
+			return &pluginpb.CodeGeneratorResponse{}, nil
+		}), Parameter: "a=b", Out: filepath.Join(root, "handler")},
+		{Name: "broken", Executor: &PluginCommand{Command: []string{plugin}}, Parameter: "fail", Out: filepath.Join(root, "broken")},
+	}
+	results, err := GenerateTargets(ctx, req, targets)
+
+	var targetErr *TargetError
+	var cmdErr *PluginCommandError
+	if !errors.As(err, &targetErr) || targetErr.Name != "broken" || !errors.As(err, &cmdErr) || cmdErr.Stderr != "requested failure" {
+		t.Fatalf("expected failure of the broken target, got %v", err)
+	}
+	if len(results) != 4 || results[0].Err != nil || len(results[0].Files) == 0 || results[1].Err != nil || results[3].Err == nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "txt", "test.proto.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected plugin output %q: %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "rust", filepath.FromSlash(results[0].Files[0]))); err != nil {
+		t.Fatalf("expected rust output: %v", err)
+	}
+	if handlerParam != "a=b" || req.Parameter != nil {
+		t.Fatalf("unexpected parameters: handler %q, request %v", handlerParam, req.Parameter)
+	}
+
+	targets[1].Out = targets[0].Out
+	if _, err := GenerateTargets(ctx, req, targets); err == nil {
+		t.Fatal("expected error for a shared output directory")
+	}
+}
+
+func TestGenerateTargets_WrittenFiles(t *testing.T) {
+	if _, err := exec.LookPath("ls"); err != nil {
+		t.Skip("ls not available")
+	}
+	gen := HandlerFunc(func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		return &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a/x.rs"), Content: proto.String("x")},
+			{Name: proto.String("b/y.rs"), Content: proto.String("y")},
+		}}, nil
+	})
+	target := &Target{
+		Name:     "remapped",
+		Executor: gen,
+		Out:      t.TempDir(),
+		WriteOptions: WriteOptions{
+			Filter:    &FileFilter{Include: []string{"a"}},
+			PathRules: PathRules{{Path: "a/*", TrimPrefix: "a/", Dir: "src"}},
+		},
+		Hooks: []Hook{&CommandHook{Name: "list", Command: []string{"ls", HookFilesArg}}},
+	}
+	results, err := GenerateTargets(context.Background(), &pluginpb.CodeGeneratorRequest{}, []*Target{target})
+	if err != nil {
+		t.Fatalf("GenerateTargets failed: %v", err)
+	}
+	// Files and hooks see the names written, not those of the response.
+	res := results[0]
+	if len(res.Files) != 1 || res.Files[0] != "src/x.rs" {
+		t.Fatalf("expected [src/x.rs], got %v", res.Files)
+	}
+	if len(res.Hooks) != 1 || !res.Hooks[0].OK || res.Hooks[0].Output != filepath.FromSlash("src/x.rs")+"\n" {
+		t.Fatalf("unexpected hook result: %+v", res.Hooks)
+	}
+}
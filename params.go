@@ -0,0 +1,373 @@
+package prost
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Params are the typed protoc-gen-prost parameters.
+//
+// Path arguments use prost-build matching: "." matches everything, a path
+// starting with "." matches a fully-qualified name and everything nested in
+// it, and any other path matches whole segments of fully-qualified names,
+// e.g. "Message.field" or "field".
+type Params struct {
+	// BTreeMap lists map fields generated as BTreeMap instead of HashMap.
+	BTreeMap []string
+	// Bytes lists bytes fields generated as bytes::Bytes instead of Vec<u8>.
+	Bytes []string
+	// Boxed lists fields wrapped in Box.
+	Boxed []string
+	// DisableComments lists paths whose comments are not emitted.
+	DisableComments []string
+	// SkipDebug lists paths without a derived Debug implementation.
+	SkipDebug []string
+	// TypeAttributes are added to generated messages and enums.
+	TypeAttributes []PathValue
+	// FieldAttributes are added to generated fields.
+	FieldAttributes []PathValue
+	// MessageAttributes are added to generated messages.
+	MessageAttributes []PathValue
+	// EnumAttributes are added to generated enums.
+	EnumAttributes []PathValue
+	// ExternPaths map proto paths to existing Rust types.
+	// Value is the Rust path.
+	ExternPaths []PathValue
+	// DefaultPackageFilename names the output file of files without a package.
+	DefaultPackageFilename string
+	// CompileWellKnownTypes generates the well-known types instead of
+	// using prost-types.
+	CompileWellKnownTypes bool
+	// RetainEnumPrefix keeps the enum name prefix on variant names.
+	RetainEnumPrefix bool
+	// EnableTypeNames implements prost::Name for generated messages.
+	EnableTypeNames bool
+	// FileDescriptorSet embeds the encoded file descriptor set.
+	FileDescriptorSet bool
+	// FlatOutputDir writes all files to the output root.
+	FlatOutputDir bool
+}
+
+// PathValue is a parameter taking a path and a value, as path=value.
+type PathValue struct {
+	// Path selects the proto elements.
+	Path string
+	// Value is the attribute or Rust path.
+	Value string
+}
+
+// ParseParams parses a CodeGeneratorRequest parameter string.
+//
+// Parameters are separated by commas. A comma inside a value is escaped as
+// "\,". Boolean parameters may be given bare or as name=true or name=false.
+// Returns an error for unknown parameters.
+func ParseParams(s string) (*Params, error) {
+	p := &Params{}
+	for _, param := range splitParams(s) {
+		name, value, hasValue := strings.Cut(param, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if b, ok := p.boolParam(name); ok {
+			switch {
+			case !hasValue || value == "true":
+				*b = true
+			case value == "false":
+				*b = false
+			default:
+				return nil, fmt.Errorf("invalid parameter: %s: expected true or false, got %q", name, value)
+			}
+			continue
+		}
+		if !hasValue || value == "" {
+			return nil, fmt.Errorf("invalid parameter: %s: missing value", name)
+		}
+
+		if list, ok := p.pathParam(name); ok {
+			*list = append(*list, value)
+			continue
+		}
+		if list, ok := p.pathValueParam(name); ok {
+			path, v, ok := strings.Cut(value, "=")
+			if !ok || path == "" {
+				return nil, fmt.Errorf("invalid parameter: %s: expected PATH=VALUE, got %q", name, value)
+			}
+			*list = append(*list, PathValue{Path: path, Value: v})
+			continue
+		}
+		if name == "default_package_filename" {
+			p.DefaultPackageFilename = value
+			continue
+		}
+		return nil, fmt.Errorf("invalid parameter: %s", name)
+	}
+	return p, nil
+}
+
+// String encodes the parameters as a CodeGeneratorRequest parameter string.
+func (p *Params) String() string {
+	var params []string
+	add := func(name, value string) {
+		params = append(params, name+"="+strings.ReplaceAll(value, ",", `\,`))
+	}
+	for _, name := range pathParamNames {
+		list, _ := p.pathParam(name)
+		for _, path := range *list {
+			add(name, path)
+		}
+	}
+	for _, name := range pathValueParamNames {
+		list, _ := p.pathValueParam(name)
+		for _, pv := range *list {
+			add(name, pv.Path+"="+pv.Value)
+		}
+	}
+	if p.DefaultPackageFilename != "" {
+		add("default_package_filename", p.DefaultPackageFilename)
+	}
+	for _, name := range boolParamNames {
+		if b, _ := p.boolParam(name); *b {
+			params = append(params, name)
+		}
+	}
+	return strings.Join(params, ",")
+}
+
+// MergeParameters merges default parameters into a request parameter string,
+// with the request winning where both set a value: a boolean parameter,
+// default_package_filename or an unknown parameter in param replaces the
+// default of the same name, and an extern_path in param replaces the default
+// for the same proto path. Path lists and attributes add up, since prost
+// applies every entry; duplicates are dropped. Defaults come first.
+func MergeParameters(defaults, param string) string {
+	key := func(entry string) string {
+		name, value, _ := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "extern_path":
+			path, _, _ := strings.Cut(value, "=")
+			return name + "=" + path
+		case slices.Contains(pathParamNames, name), slices.Contains(pathValueParamNames, name):
+			return entry
+		}
+		return name
+	}
+
+	var entries []string
+	set := make(map[string]bool)
+	for _, entry := range splitParams(param) {
+		if strings.TrimSpace(entry) != "" {
+			set[key(entry)] = true
+		}
+	}
+	for _, entry := range splitParams(defaults) {
+		if k := key(entry); strings.TrimSpace(entry) != "" && !set[k] {
+			set[k] = true
+			entries = append(entries, entry)
+		}
+	}
+	for _, entry := range splitParams(param) {
+		if strings.TrimSpace(entry) != "" && !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	for i, entry := range entries {
+		entries[i] = strings.ReplaceAll(entry, ",", `\,`)
+	}
+	return strings.Join(entries, ",")
+}
+
+// Parameter names by kind, in encoding order.
+var (
+	pathParamNames      = []string{"btree_map", "bytes", "boxed", "disable_comments", "skip_debug"}
+	pathValueParamNames = []string{"extern_path", "type_attribute", "field_attribute", "message_attribute", "enum_attribute"}
+	boolParamNames      = []string{"compile_well_known_types", "retain_enum_prefix", "enable_type_names", "file_descriptor_set", "flat_output_dir"}
+)
+
+// pathParam returns the list of a path parameter.
+func (p *Params) pathParam(name string) (*[]string, bool) {
+	switch name {
+	case "btree_map":
+		return &p.BTreeMap, true
+	case "bytes":
+		return &p.Bytes, true
+	case "boxed":
+		return &p.Boxed, true
+	case "disable_comments":
+		return &p.DisableComments, true
+	case "skip_debug":
+		return &p.SkipDebug, true
+	}
+	return nil, false
+}
+
+// pathValueParam returns the list of a path=value parameter.
+func (p *Params) pathValueParam(name string) (*[]PathValue, bool) {
+	switch name {
+	case "extern_path":
+		return &p.ExternPaths, true
+	case "type_attribute":
+		return &p.TypeAttributes, true
+	case "field_attribute":
+		return &p.FieldAttributes, true
+	case "message_attribute":
+		return &p.MessageAttributes, true
+	case "enum_attribute":
+		return &p.EnumAttributes, true
+	}
+	return nil, false
+}
+
+// boolParam returns the field of a boolean parameter.
+func (p *Params) boolParam(name string) (*bool, bool) {
+	switch name {
+	case "compile_well_known_types":
+		return &p.CompileWellKnownTypes, true
+	case "retain_enum_prefix":
+		return &p.RetainEnumPrefix, true
+	case "enable_type_names":
+		return &p.EnableTypeNames, true
+	case "file_descriptor_set":
+		return &p.FileDescriptorSet, true
+	case "flat_output_dir":
+		return &p.FlatOutputDir, true
+	}
+	return nil, false
+}
+
+// splitParams splits a parameter string on commas not escaped as "\,".
+func splitParams(s string) []string {
+	var params []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case s[i] == ',':
+			params = append(params, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return append(params, sb.String())
+}
+
+// UnmatchedPath is a path parameter matching no field of the kind it
+// configures.
+type UnmatchedPath struct {
+	// Param is the parameter name, e.g. "btree_map".
+	Param string
+	// Path is the unmatched path.
+	Path string
+}
+
+// String returns the path as name=path.
+func (u UnmatchedPath) String() string {
+	return u.Param + "=" + u.Path
+}
+
+// CheckPaths reports btree_map paths matching no map field and bytes paths
+// matching no bytes field in the files to generate of req, which usually
+// means the configuration is stale after a schema refactor. The catch-all
+// path "." is never reported.
+func (p *Params) CheckPaths(req *pluginpb.CodeGeneratorRequest) []UnmatchedPath {
+	var mapFields, bytesFields []string
+	generate := make(map[string]bool, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		generate[name] = true
+	}
+	for _, fd := range req.GetProtoFile() {
+		if !generate[fd.GetName()] {
+			continue
+		}
+		scope := ""
+		if pkg := fd.GetPackage(); pkg != "" {
+			scope = "." + pkg
+		}
+		for _, msg := range fd.GetMessageType() {
+			collectFields(scope, msg, &mapFields, &bytesFields)
+		}
+	}
+
+	var unmatched []UnmatchedPath
+	check := func(param string, paths, fields []string) {
+		for _, path := range paths {
+			if !matchesAny(path, fields) {
+				unmatched = append(unmatched, UnmatchedPath{Param: param, Path: path})
+			}
+		}
+	}
+	check("btree_map", p.BTreeMap, mapFields)
+	check("bytes", p.Bytes, bytesFields)
+	sort.SliceStable(unmatched, func(i, j int) bool {
+		return unmatched[i].Param < unmatched[j].Param
+	})
+	return unmatched
+}
+
+// collectFields appends the fully-qualified names of the map and bytes fields
+// of msg and its nested messages.
+func collectFields(scope string, msg *descriptorpb.DescriptorProto, mapFields, bytesFields *[]string) {
+	name := scope + "." + msg.GetName()
+	entries := make(map[string]bool)
+	for _, nested := range msg.GetNestedType() {
+		if nested.GetOptions().GetMapEntry() {
+			entries[name+"."+nested.GetName()] = true
+			continue
+		}
+		collectFields(name, nested, mapFields, bytesFields)
+	}
+	for _, f := range msg.GetField() {
+		path := name + "." + f.GetName()
+		switch {
+		case f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+			*bytesFields = append(*bytesFields, path)
+		case f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE && entries[f.GetTypeName()]:
+			*mapFields = append(*mapFields, path)
+		}
+	}
+}
+
+// matchesAny checks if path matches any of the fully-qualified names.
+func matchesAny(path string, names []string) bool {
+	if path == "." {
+		return true
+	}
+	for _, name := range names {
+		if matchPath(path, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath checks if a prost-build path matches a fully-qualified name.
+// Relative paths match any run of whole segments, so "Message" matches
+// ".pkg.Message.field".
+func matchPath(path, name string) bool {
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+		for i := strings.Index(name, path); i >= 0; {
+			rest := name[i+len(path):]
+			if rest == "" || rest[0] == '.' {
+				return true
+			}
+			next := strings.Index(name[i+1:], path)
+			if next < 0 {
+				break
+			}
+			i += 1 + next
+		}
+		return false
+	}
+	return name == path || strings.HasPrefix(name, path+".")
+}
@@ -0,0 +1,108 @@
+package prost
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParseParams(t *testing.T) {
+	s := `btree_map=.,bytes=.pkg.Msg.data,type_attribute=.pkg.Msg=#[derive(Eq\, Hash)],compile_well_known_types,flat_output_dir=false,default_package_filename=lib`
+	p, err := ParseParams(s)
+	if err != nil {
+		t.Fatalf("ParseParams failed: %v", err)
+	}
+	want := &Params{
+		BTreeMap:               []string{"."},
+		Bytes:                  []string{".pkg.Msg.data"},
+		TypeAttributes:         []PathValue{{Path: ".pkg.Msg", Value: "#[derive(Eq, Hash)]"}},
+		CompileWellKnownTypes:  true,
+		DefaultPackageFilename: "lib",
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+
+	// String round-trips.
+	again, err := ParseParams(p.String())
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Fatalf("round trip of %q: got %+v, %v", p.String(), again, err)
+	}
+
+	for _, bad := range []string{"frobnicate", "btree_map", "type_attribute=.pkg", "retain_enum_prefix=maybe"} {
+		if _, err := ParseParams(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestMergeParameters(t *testing.T) {
+	defaults := `extern_path=.google.protobuf=::pbjson_types,extern_path=.common=::common,type_attribute=.=#[derive(Eq\, Hash)],compile_well_known_types,btree_map=.`
+	for _, tc := range []struct {
+		param, want string
+	}{
+		{"", defaults},
+		{
+			"extern_path=.google.protobuf=::prost_types,compile_well_known_types=false",
+			`extern_path=.common=::common,type_attribute=.=#[derive(Eq\, Hash)],btree_map=.,extern_path=.google.protobuf=::prost_types,compile_well_known_types=false`,
+		},
+		{
+			"type_attribute=.=#[derive(Default)],btree_map=.,bytes=.",
+			`extern_path=.google.protobuf=::pbjson_types,extern_path=.common=::common,type_attribute=.=#[derive(Eq\, Hash)],compile_well_known_types,type_attribute=.=#[derive(Default)],btree_map=.,bytes=.`,
+		},
+	} {
+		if got := MergeParameters(defaults, tc.param); got != tc.want {
+			t.Errorf("MergeParameters(%q):\ngot  %s\nwant %s", tc.param, got, tc.want)
+		}
+	}
+	if got := MergeParameters("", "flat_output_dir"); got != "flat_output_dir" {
+		t.Errorf("unexpected merge without defaults: %q", got)
+	}
+}
+
+func TestParams_CheckPaths(t *testing.T) {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("a.proto"),
+			Package: proto.String("pkg"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Msg"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("data"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()},
+					{Name: proto.String("labels"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".pkg.Msg.LabelsEntry")},
+					{Name: proto.String("name"), Number: proto.Int32(3), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("LabelsEntry"),
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{Name: proto.String("key"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+						{Name: proto.String("value"), Number: proto.Int32(2), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+					},
+				}},
+			}},
+		}},
+	}
+
+	p := &Params{
+		BTreeMap: []string{".", ".pkg", "Msg", "Msg.labels", ".pkg.Msg.name", "Other.labels", "sg.labels"},
+		Bytes:    []string{"data", ".pkg.Msg.data", "labels", ".other"},
+	}
+	got := p.CheckPaths(req)
+	want := []UnmatchedPath{
+		{Param: "btree_map", Path: ".pkg.Msg.name"},
+		{Param: "btree_map", Path: "Other.labels"},
+		{Param: "btree_map", Path: "sg.labels"},
+		{Param: "bytes", Path: "labels"},
+		{Param: "bytes", Path: ".other"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
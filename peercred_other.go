@@ -0,0 +1,10 @@
+//go:build !linux
+
+package prost
+
+import "net"
+
+// peerUID returns false: peer credentials are only read on Linux.
+func peerUID(conn net.Conn) (uint32, bool) {
+	return 0, false
+}
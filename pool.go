@@ -0,0 +1,282 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ErrPoolClosed is returned by Pool calls after Close or Shutdown, and by
+// calls still queued when the pool is closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// PoolOptions configures NewPool.
+type PoolOptions struct {
+	// Size is the number of instances, each executing one request at a
+	// time. Defaults to runtime.GOMAXPROCS(0).
+	Size int
+	// RuntimeConfig configures the runtime of each instance. A compilation
+	// cache shared by the instances is added, so the module is compiled once.
+	// Defaults to wazero.NewRuntimeConfig().
+	RuntimeConfig wazero.RuntimeConfig
+	// Options are applied to each instance, e.g. WithInstanceTTL and
+	// WithMaxExecutions to recycle instances in long-running services.
+	Options []Option
+	// InteractiveBurst is the number of interactive calls run in a row while
+	// bulk calls are queued before one bulk call runs. Defaults to 8.
+	InteractiveBurst int
+}
+
+// Result is the outcome of a call queued with Pool.ExecuteAsync.
+type Result struct {
+	// Output is the serialized CodeGeneratorResponse.
+	Output []byte
+	// Err is the error of the call.
+	Err error
+}
+
+// Pool runs requests on several instances of the embedded plugin in
+// parallel. Calls are queued and run by the first idle instance, in the
+// order they were made within each Priority. Interactive calls run before
+// bulk calls, see WithPriority.
+//
+// Each instance has its own runtime. Pool is safe for concurrent use.
+type Pool struct {
+	queue     *jobQueue
+	cache     wazero.CompilationCache
+	instances []*poolInstance
+	workers   sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// poolJob is a call queued on a Pool.
+type poolJob struct {
+	ctx      context.Context
+	priority Priority
+	input    []byte
+	result   chan Result
+	// stop unregisters the removal of the job when ctx is done.
+	stop func() bool
+}
+
+// NewPool creates a pool of instances of the embedded plugin.
+func NewPool(ctx context.Context, opts *PoolOptions) (*Pool, error) {
+	if opts == nil {
+		opts = &PoolOptions{}
+	}
+	size := opts.Size
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+	cfg := opts.RuntimeConfig
+	if cfg == nil {
+		cfg = wazero.NewRuntimeConfig()
+	}
+	burst := opts.InteractiveBurst
+	if burst <= 0 {
+		burst = defaultInteractiveBurst
+	}
+	pool := &Pool{queue: newJobQueue(burst), cache: wazero.NewCompilationCache()}
+	cfg = cfg.WithCompilationCache(pool.cache)
+
+	for range size {
+		inst, err := newPoolInstance(ctx, cfg, opts.Options)
+		if err != nil {
+			_ = pool.closeInstances(ctx)
+			return nil, fmt.Errorf("failed to create pool instance: %w", err)
+		}
+		pool.instances = append(pool.instances, inst)
+	}
+	for _, inst := range pool.instances {
+		pool.workers.Add(1)
+		go pool.work(inst)
+	}
+	return pool, nil
+}
+
+// ExecuteAsync queues a serialized CodeGeneratorRequest at the priority of
+// ctx and returns immediately. The channel receives the Result once an
+// instance has run the request, or ctx is done while it is still queued. The
+// input must not be modified until then.
+func (p *Pool) ExecuteAsync(ctx context.Context, input []byte) <-chan Result {
+	job := &poolJob{ctx: ctx, priority: PriorityFromContext(ctx), input: input, result: make(chan Result, 1)}
+	if err := ctx.Err(); err != nil {
+		job.result <- Result{Err: err}
+		return job.result
+	}
+	// Registered before pushing, so a worker can always unregister it. If ctx
+	// is done before the push, the worker fails the job instead.
+	job.stop = context.AfterFunc(ctx, func() {
+		if p.queue.remove(job) {
+			job.result <- Result{Err: ctx.Err()}
+		}
+	})
+	if !p.queue.push(job) {
+		job.stop()
+		job.result <- Result{Err: ErrPoolClosed}
+	}
+	return job.result
+}
+
+// Execute runs a serialized CodeGeneratorRequest on the next idle instance
+// and returns the serialized CodeGeneratorResponse.
+func (p *Pool) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	res := <-p.ExecuteAsync(ctx, input)
+	return res.Output, res.Err
+}
+
+// ExecuteRequest runs a CodeGeneratorRequest on the next idle instance and
+// returns the parsed CodeGeneratorResponse.
+func (p *Pool) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	output, err := p.Execute(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp, nil
+}
+
+// ServePoolOptions configures ServePool.
+type ServePoolOptions struct {
+	// Limits rejects requests over its limits like ServeListenerWithLimits.
+	Limits ServeLimits
+	// Priority returns the priority of the requests of a connection, e.g.
+	// PriorityBulk for a listener used by backfills. Defaults to the priority
+	// of ctx.
+	Priority func(net.Conn) Priority
+}
+
+// ServePool serves the framed protocol of ServeFrames on each connection
+// accepted from ln like ServeListener, queuing the requests on pool so
+// requests from different connections run in parallel.
+func ServePool(ctx context.Context, pool *Pool, ln net.Listener, opts *ServePoolOptions) error {
+	if opts == nil {
+		opts = &ServePoolOptions{}
+	}
+	exec := func(ctx context.Context, input, _ []byte) ([]byte, error) {
+		return pool.Execute(ctx, input)
+	}
+	return serveListener(ctx, exec, ln, newServeLimiter(opts.Limits), opts.Priority)
+}
+
+// Size returns the number of instances.
+func (p *Pool) Size() int {
+	return len(p.instances)
+}
+
+// QueueDepth returns the number of calls queued at pri and not yet running.
+func (p *Pool) QueueDepth(pri Priority) int {
+	if pri < 0 || pri >= numPriorities {
+		return 0
+	}
+	return p.queue.depth(pri)
+}
+
+// Close fails the queued calls with ErrPoolClosed, waits for the running
+// ones, and closes the instances. If ctx is done first, the running calls
+// are interrupted as with ProtocGenProst.Close.
+func (p *Pool) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		for _, job := range p.queue.close() {
+			job.stop()
+			job.result <- Result{Err: ErrPoolClosed}
+		}
+		p.closeErr = p.closeInstances(ctx)
+		p.workers.Wait()
+	})
+	return p.closeErr
+}
+
+// Shutdown stops accepting calls and waits for the queued and running ones
+// to finish, then closes the pool. If ctx is done first, the remaining calls
+// fail as with Close and the error of ctx is returned. Calls made after
+// Shutdown fail with ErrPoolClosed.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.queue.stop()
+	drained := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return errors.Join(err, p.Close(ctx))
+}
+
+// closeInstances closes the instances concurrently, then the compilation
+// cache.
+func (p *Pool) closeInstances(ctx context.Context) error {
+	errs := make([]error, len(p.instances)+1)
+	var wg sync.WaitGroup
+	for i, inst := range p.instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = inst.close(ctx)
+		}()
+	}
+	wg.Wait()
+	errs[len(p.instances)] = p.cache.Close(context.WithoutCancel(ctx))
+	return errors.Join(errs...)
+}
+
+// work runs queued jobs on inst until the queue is closed.
+func (p *Pool) work(inst *poolInstance) {
+	defer p.workers.Done()
+	for {
+		job, ok := p.queue.pop()
+		if !ok {
+			return
+		}
+		job.stop()
+		if err := job.ctx.Err(); err != nil {
+			job.result <- Result{Err: err}
+			continue
+		}
+		out, err := inst.p.Execute(job.ctx, job.input)
+		job.result <- Result{Output: out, Err: err}
+	}
+}
+
+// poolInstance is an instance of a Pool with its runtime.
+type poolInstance struct {
+	runtime wazero.Runtime
+	p       *ProtocGenProst
+}
+
+// newPoolInstance creates an instance in a new runtime.
+func newPoolInstance(ctx context.Context, cfg wazero.RuntimeConfig, opts []Option) (*poolInstance, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, cfg)
+	p, err := NewProtocGenProst(ctx, r, opts...)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	return &poolInstance{runtime: r, p: p}, nil
+}
+
+// close closes the instance and its runtime.
+func (i *poolInstance) close(ctx context.Context) error {
+	err := i.p.Close(ctx)
+	return errors.Join(err, i.runtime.Close(context.WithoutCancel(ctx)))
+}
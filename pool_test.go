@@ -0,0 +1,210 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPool_ExecuteAsync(t *testing.T) {
+	ctx := context.Background()
+	pool, err := NewPool(ctx, &PoolOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Close(ctx)
+
+	// Queue more calls than instances without blocking.
+	results := make([]<-chan Result, 5)
+	for i := range results {
+		results[i] = pool.ExecuteAsync(ctx, minimalRequestInput(t))
+	}
+	for i, ch := range results {
+		res := <-ch
+		if res.Err != nil {
+			t.Fatalf("call %d failed: %v", i, res.Err)
+		}
+		if resp := mustUnmarshalResponse(t, res.Output); resp.Error != nil {
+			t.Fatalf("call %d: plugin returned error: %s", i, resp.GetError())
+		}
+	}
+
+	// Canceled calls fail with the context error.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := pool.Execute(canceled, minimalRequestInput(t)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if err := pool.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if res := <-pool.ExecuteAsync(ctx, minimalRequestInput(t)); !errors.Is(res.Err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", res.Err)
+	}
+}
+
+// afterFuncContext counts the context.AfterFunc registrations still active.
+type afterFuncContext struct {
+	context.Context
+	mu     sync.Mutex
+	active int
+}
+
+func (c *afterFuncContext) AfterFunc(f func()) func() bool {
+	c.mu.Lock()
+	c.active++
+	c.mu.Unlock()
+	var once sync.Once
+	return func() bool {
+		stopped := false
+		once.Do(func() {
+			c.mu.Lock()
+			c.active--
+			c.mu.Unlock()
+			stopped = true
+		})
+		return stopped
+	}
+}
+
+func TestPool_ExecuteAsyncReleasesContext(t *testing.T) {
+	ctx := context.Background()
+	pool, err := NewPool(ctx, &PoolOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Close(ctx)
+
+	// A long-lived context keeps no registration once its calls ran.
+	long := &afterFuncContext{Context: ctx}
+	for range 3 {
+		if _, err := pool.Execute(long, minimalRequestInput(t)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	long.mu.Lock()
+	defer long.mu.Unlock()
+	if long.active != 0 {
+		t.Fatalf("expected no active AfterFunc registrations, got %d", long.active)
+	}
+}
+
+func TestPool_Shutdown(t *testing.T) {
+	ctx := context.Background()
+	pool, err := NewPool(ctx, &PoolOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+
+	// Queued calls run before the pool closes.
+	results := make([]<-chan Result, 3)
+	for i := range results {
+		results[i] = pool.ExecuteAsync(ctx, minimalRequestInput(t))
+	}
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	for i, ch := range results {
+		if res := <-ch; res.Err != nil {
+			t.Fatalf("call %d failed: %v", i, res.Err)
+		}
+	}
+	if _, err := pool.Execute(ctx, minimalRequestInput(t)); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestPool_ShutdownDeadline(t *testing.T) {
+	ctx := context.Background()
+	pool, err := NewPool(ctx, &PoolOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+
+	results := make([]<-chan Result, 8)
+	for i := range results {
+		results[i] = pool.ExecuteAsync(ctx, minimalRequestInput(t))
+	}
+	expired, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := pool.Shutdown(expired); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	// Every call completes, the ones still queued with ErrPoolClosed.
+	var closed int
+	for _, ch := range results {
+		if res := <-ch; errors.Is(res.Err, ErrPoolClosed) {
+			closed++
+		}
+	}
+	if closed == 0 {
+		t.Fatal("expected queued calls to fail with ErrPoolClosed")
+	}
+}
+
+func TestServePool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool, err := NewPool(ctx, &PoolOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Close(context.Background())
+
+	sock := filepath.Join(t.TempDir(), "prost.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	opts := &ServePoolOptions{Priority: func(net.Conn) Priority { return PriorityBulk }}
+	served := make(chan error, 1)
+	go func() { served <- ServePool(ctx, pool, ln, opts) }()
+
+	c, err := DialSocket(ctx, sock)
+	if err != nil {
+		t.Fatalf("DialSocket failed: %v", err)
+	}
+	defer c.Close()
+	output, err := c.Execute(ctx, minimalRequestInput(t))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp := mustUnmarshalResponse(t, output); resp.Error != nil {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("ServePool failed: %v", err)
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	q := newJobQueue(defaultInteractiveBurst)
+	a, b, c := &poolJob{}, &poolJob{}, &poolJob{}
+	for _, job := range []*poolJob{a, b, c} {
+		if !q.push(job) {
+			t.Fatal("push failed")
+		}
+	}
+	if !q.remove(b) || q.remove(b) {
+		t.Fatal("expected b to be removed once")
+	}
+	if job, ok := q.pop(); !ok || job != a {
+		t.Fatal("expected a first")
+	}
+	if queued := q.close(); len(queued) != 1 || queued[0] != c {
+		t.Fatalf("unexpected queued jobs %v", queued)
+	}
+	if q.push(a) {
+		t.Fatal("push after close succeeded")
+	}
+	if _, ok := q.pop(); ok {
+		t.Fatal("pop after close succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+package prost
+
+// Preset is a named bundle of attributes added to selected messages and
+// enums with Params.ApplyPreset.
+type Preset struct {
+	// Name identifies the preset, e.g. "serde".
+	Name string
+	// Description is a one-line summary.
+	Description string
+	// TypeAttributes are added to messages and enums.
+	TypeAttributes []string
+	// MessageAttributes are added to messages only.
+	MessageAttributes []string
+	// EnumAttributes are added to enums only.
+	EnumAttributes []string
+}
+
+// Curated presets.
+//
+// prost already derives Eq and Hash for messages without floating point
+// fields, and Eq, Hash, PartialOrd, and Ord for enums, so the presets never
+// repeat those derives.
+var (
+	// PresetSerde derives serde traits behind the crate's "serde" feature.
+	// Messages deserialize missing fields as their default value.
+	PresetSerde = &Preset{
+		Name:           "serde",
+		Description:    `serde Serialize and Deserialize behind the "serde" feature`,
+		TypeAttributes: []string{`#[cfg_attr(feature = "serde", derive(::serde::Serialize, ::serde::Deserialize))]`},
+		MessageAttributes: []string{
+			`#[cfg_attr(feature = "serde", serde(default))]`,
+		},
+	}
+	// PresetOrd derives PartialOrd and Ord for messages.
+	// The selected messages must have no floating point fields.
+	PresetOrd = &Preset{
+		Name:              "ord",
+		Description:       "PartialOrd and Ord for messages",
+		MessageAttributes: []string{"#[derive(PartialOrd, Ord)]"},
+	}
+	// PresetNonExhaustive marks messages and enums #[non_exhaustive], so
+	// adding fields or variants is not a breaking change for dependents.
+	PresetNonExhaustive = &Preset{
+		Name:           "non_exhaustive",
+		Description:    "#[non_exhaustive] on messages and enums",
+		TypeAttributes: []string{"#[non_exhaustive]"},
+	}
+)
+
+// Presets lists the curated presets.
+var Presets = []*Preset{PresetSerde, PresetOrd, PresetNonExhaustive}
+
+// LookupPreset returns the curated preset with the given name.
+func LookupPreset(name string) (*Preset, bool) {
+	for _, preset := range Presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return nil, false
+}
+
+// ApplyPreset adds the attributes of preset to the elements selected by
+// paths. With no paths, the preset applies to everything (".").
+func (p *Params) ApplyPreset(preset *Preset, paths ...string) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, path := range paths {
+		for _, attr := range preset.TypeAttributes {
+			p.TypeAttributes = append(p.TypeAttributes, PathValue{Path: path, Value: attr})
+		}
+		for _, attr := range preset.MessageAttributes {
+			p.MessageAttributes = append(p.MessageAttributes, PathValue{Path: path, Value: attr})
+		}
+		for _, attr := range preset.EnumAttributes {
+			p.EnumAttributes = append(p.EnumAttributes, PathValue{Path: path, Value: attr})
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package prost
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParams_ApplyPreset(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	params := &Params{}
+	for _, name := range []string{"serde", "ord", "non_exhaustive"} {
+		preset, ok := LookupPreset(name)
+		if !ok {
+			t.Fatalf("preset %s not found", name)
+		}
+		params.ApplyPreset(preset, ".test")
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		Parameter:      proto.String(params.String()),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:        proto.String("test.proto"),
+			Package:     proto.String("test"),
+			Syntax:      proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Msg")}},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name:  proto.String("Kind"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+			}},
+		}},
+	}
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	if resp.GetError() != "" {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+
+	content := resp.GetFile()[0].GetContent()
+	for _, attr := range []string{
+		`derive(::serde::Serialize, ::serde::Deserialize)`,
+		`serde(default)`,
+		`#[derive(PartialOrd, Ord)]`,
+		`#[non_exhaustive]`,
+	} {
+		if !strings.Contains(content, attr) {
+			t.Errorf("expected %s in output:\n%s", attr, content)
+		}
+	}
+	// Message-only attributes are not applied to the enum.
+	for _, attr := range []string{"#[derive(PartialOrd, Ord)]", "serde(default)"} {
+		if n := strings.Count(content, attr); n != 1 {
+			t.Errorf("expected %s once, got %d times:\n%s", attr, n, content)
+		}
+	}
+}
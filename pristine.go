@@ -0,0 +1,41 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// takeSnapshot copies the linear memory of mod.
+func takeSnapshot(mod api.Module) []byte {
+	mem := mod.Memory()
+	data, _ := mem.Read(0, mem.Size())
+	return append([]byte(nil), data...)
+}
+
+// restoreLocked returns the current instance to its post-init state.
+//
+// Restores memory from the snapshot if it has not grown, otherwise replaces
+// the instance. Mutable globals are not exported by the module; the only one
+// in use (the shadow stack pointer) is balanced when prost_execute returns
+// normally. Instances that trapped are replaced instead of restored, see
+// executeOnceLocked. Must be called with mu held.
+func (p *ProtocGenProst) restoreLocked(ctx context.Context) error {
+	if p.mod.IsClosed() {
+		// re-instantiated on the next Execute
+		return nil
+	}
+	mem := p.mod.Memory()
+	if mem.Size() == uint32(len(p.snapshot)) {
+		if !mem.Write(0, p.snapshot) {
+			return fmt.Errorf("failed to restore memory snapshot")
+		}
+		return nil
+	}
+	p.mod.Close(ctx)
+	if err := p.instantiate(ctx); err != nil {
+		return fmt.Errorf("failed to re-instantiate module: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_PristineState(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithPristineState())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	first, err := p.Execute(ctx, minimalRequestInput(t))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	second, err := p.Execute(ctx, minimalRequestInput(t))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected identical output for identical requests")
+	}
+
+	mem := p.mod.Memory()
+	data, _ := mem.Read(0, mem.Size())
+	if !bytes.Equal(data, p.snapshot) {
+		t.Fatal("expected memory to match the post-init snapshot")
+	}
+}
+
+func TestProtocGenProst_RestoreInPlace(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithPristineState())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	mod := p.mod
+	addr := uint32(len(p.snapshot) - 1)
+	if !mod.Memory().WriteByte(addr, p.snapshot[addr]+1) {
+		t.Fatal("failed to write memory")
+	}
+
+	p.mu.Lock()
+	err = p.restoreLocked(ctx)
+	p.mu.Unlock()
+	if err != nil {
+		t.Fatalf("restoreLocked failed: %v", err)
+	}
+	if p.mod != mod {
+		t.Fatal("expected the instance to be restored in place")
+	}
+	if b, _ := mod.Memory().ReadByte(addr); b != p.snapshot[addr] {
+		t.Fatal("expected memory to be restored")
+	}
+}
+
+// stackStubModule encodes a module whose prost_execute moves a stack pointer
+// global down and back up around the call, like the shadow stack of a Rust
+// guest, and outputs its depth. An empty request traps with the stack
+// pointer moved.
+func stackStubModule() []byte {
+	stub := abiStubModule(0)
+	stub.Globals = 3
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Code(
+				wasmtest.GlobalGet(2), wasmtest.I32Const(1), []byte{0x6a}, wasmtest.GlobalSet(2), // sp++
+				wasmtest.LocalGet(1), []byte{0x45, 0x04, 0x40}, wasmtest.Unreachable, []byte{0x0b}, // if len == 0 trap
+				wasmtest.I32Const(200), wasmtest.GlobalGet(2), []byte{0x3a, 0x00, 0x00}, // mem[200] = sp
+				wasmtest.I32Const(200), wasmtest.GlobalSet(0), wasmtest.I32Const(1), wasmtest.GlobalSet(1),
+				wasmtest.GlobalGet(2), wasmtest.I32Const(1), []byte{0x6b}, wasmtest.GlobalSet(2), // sp--
+				wasmtest.GlobalGet(1),
+			)
+		}
+	}
+	return stub.Encode()
+}
+
+func TestProtocGenProst_ReinstantiatesAfterTrap(t *testing.T) {
+	ctx := context.Background()
+	// Each instance has its own runtime, which hosts one WASI module.
+	newStub := func(opts ...Option) *ProtocGenProst {
+		t.Helper()
+		r := wazero.NewRuntime(ctx)
+		t.Cleanup(func() { r.Close(ctx) })
+		compiled, err := r.CompileModule(ctx, stackStubModule())
+		if err != nil {
+			t.Fatalf("CompileModule failed: %v", err)
+		}
+		p, err := NewProtocGenProstWithModule(ctx, r, compiled, opts...)
+		if err != nil {
+			t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+		}
+		return p
+	}
+
+	input := minimalRequestInput(t)
+	want, err := newStub().Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, opts := range [][]Option{nil, {WithPristineState()}} {
+		p := newStub(opts...)
+		var trapErr *TrapError
+		if _, err := p.Execute(ctx, nil); !errors.As(err, &trapErr) {
+			t.Fatalf("expected TrapError, got %v", err)
+		}
+		got, err := p.Execute(ctx, input)
+		if err != nil {
+			t.Fatalf("Execute after trap failed: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("pristine=%v: expected output %x of a fresh instance after a trap, got %x", opts != nil, want, got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Features describes the capabilities reported and exhibited by the plugin.
+type Features struct {
+	// SupportedFeatures is the supported_features bitmask of the response.
+	SupportedFeatures uint64 `json:"supportedFeatures"`
+	// Proto3Optional is set if the plugin supports proto3 optional fields.
+	Proto3Optional bool `json:"proto3Optional"`
+	// Editions is set if the plugin supports editions.
+	Editions bool `json:"editions"`
+	// MinimumEdition and MaximumEdition are the supported edition range.
+	MinimumEdition int32 `json:"minimumEdition,omitempty"`
+	MaximumEdition int32 `json:"maximumEdition,omitempty"`
+	// Parameters lists the entries of KnownParameters accepted by the plugin.
+	Parameters []string `json:"parameters"`
+	// OutputSuffix is the suffix appended to the proto file base name to form
+	// output file names, e.g. ".pb.rs".
+	OutputSuffix string `json:"outputSuffix"`
+	// PackageDirs is set if outputs are placed in directories derived from
+	// the proto package rather than the proto file path.
+	PackageDirs bool `json:"packageDirs"`
+}
+
+// HasParameter checks if the plugin accepted the named parameter.
+func (f *Features) HasParameter(name string) bool {
+	return slices.Contains(f.Parameters, name)
+}
+
+// KnownParameter is a plugin parameter probed by ProbeFeatures.
+type KnownParameter struct {
+	// Name is the parameter name.
+	Name string
+	// Value is a valid value used for probing, or empty for flags.
+	Value string
+}
+
+// KnownParameters are the protoc-gen-prost parameters probed by ProbeFeatures.
+var KnownParameters = []KnownParameter{
+	{Name: "btree_map", Value: "."},
+	{Name: "bytes", Value: "."},
+	{Name: "boxed", Value: ".probe.v1.Probe.probe"},
+	{Name: "default_package_filename", Value: "_"},
+	{Name: "disable_comments", Value: "."},
+	{Name: "skip_debug", Value: "."},
+	{Name: "extern_path", Value: ".probe.extern=::probe_extern"},
+	{Name: "compile_well_known_types"},
+	{Name: "retain_enum_prefix"},
+	{Name: "enable_type_names"},
+	{Name: "type_attribute", Value: ".=#[probe]"},
+	{Name: "field_attribute", Value: ".=#[probe]"},
+	{Name: "message_attribute", Value: ".=#[probe]"},
+	{Name: "enum_attribute", Value: ".=#[probe]"},
+	{Name: "file_descriptor_set"},
+	{Name: "flat_output_dir"},
+	{Name: "include_file", Value: "mod.rs"},
+}
+
+// probePackage is the package of the probe request.
+const probePackage = "probe.v1"
+
+// probeRequest builds the minimal request used by ProbeFeatures.
+func probeRequest(param string) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"probe_dir/probe.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("probe_dir/probe.proto"),
+			Package: proto.String(probePackage),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Probe"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("probe"),
+					JsonName: proto.String("probe"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				}},
+			}},
+		}},
+	}
+	if param != "" {
+		req.Parameter = proto.String(param)
+	}
+	return req
+}
+
+// newFeatures returns the capabilities declared by a response.
+func newFeatures(resp *pluginpb.CodeGeneratorResponse) *Features {
+	f := &Features{
+		SupportedFeatures: resp.GetSupportedFeatures(),
+		MinimumEdition:    resp.GetMinimumEdition(),
+		MaximumEdition:    resp.GetMaximumEdition(),
+	}
+	f.Proto3Optional = f.SupportedFeatures&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) != 0
+	f.Editions = f.SupportedFeatures&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) != 0
+	return f
+}
+
+// ProbeFeatures runs minimal requests to report the plugin's capabilities.
+//
+// The result is cached per instance after the first successful probe.
+// Each entry of KnownParameters is tried in a separate request.
+func (p *ProtocGenProst) ProbeFeatures(ctx context.Context) (*Features, error) {
+	p.featuresMu.Lock()
+	defer p.featuresMu.Unlock()
+	if p.features != nil {
+		return p.features, nil
+	}
+
+	resp, err := p.ExecuteRequest(ctx, probeRequest(""))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, &PluginError{Message: resp.GetError()}
+	}
+
+	f := newFeatures(resp)
+
+	for _, file := range resp.GetFile() {
+		if file.GetInsertionPoint() != "" {
+			continue
+		}
+		dir, base := path.Split(file.GetName())
+		if !strings.HasPrefix(base, "probe") {
+			return nil, fmt.Errorf("unexpected output file name: %s", file.GetName())
+		}
+		f.OutputSuffix = strings.TrimPrefix(base, "probe")
+		f.PackageDirs = strings.TrimSuffix(dir, "/") == strings.ReplaceAll(probePackage, ".", "/")
+		break
+	}
+
+	for _, param := range KnownParameters {
+		value := param.Name
+		if param.Value != "" {
+			value += "=" + param.Value
+		}
+		resp, err := p.ExecuteRequest(ctx, probeRequest(value))
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error == nil {
+			f.Parameters = append(f.Parameters, param.Name)
+		}
+	}
+
+	p.features = f
+	return f, nil
+}
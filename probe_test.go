@@ -0,0 +1,39 @@
+package prost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_ProbeFeatures(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	f, err := p.ProbeFeatures(ctx)
+	if err != nil {
+		t.Fatalf("ProbeFeatures failed: %v", err)
+	}
+	if !f.Proto3Optional {
+		t.Fatal("expected proto3 optional support")
+	}
+	if f.OutputSuffix != ".pb.rs" || !f.PackageDirs {
+		t.Fatalf("unexpected output conventions: %+v", f)
+	}
+	if !f.HasParameter("btree_map") || !f.HasParameter("file_descriptor_set") {
+		t.Fatalf("expected common parameters, got %v", f.Parameters)
+	}
+
+	again, err := p.ProbeFeatures(ctx)
+	if err != nil || again != f {
+		t.Fatal("expected cached features")
+	}
+}
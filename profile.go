@@ -0,0 +1,164 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// Profiler is a FunctionListenerFactory aggregating time per guest function.
+//
+// Attach it with WithFunctionListenerFactory. Recursive calls count their
+// time once per active frame in Total, but only once in Self.
+// Safe for concurrent use by multiple instances.
+type Profiler struct {
+	mu     sync.Mutex
+	stacks map[api.Module][]profileFrame
+	funcs  map[string]*FunctionProfile
+}
+
+// FunctionProfile contains the aggregated timings of a guest function.
+type FunctionProfile struct {
+	// Name is the export, debug, or import name of the function,
+	// or $index if it has none.
+	Name string `json:"name"`
+	// Calls is the number of calls.
+	Calls uint64 `json:"calls"`
+	// Total is the time spent in the function including callees.
+	Total time.Duration `json:"totalNs"`
+	// Self is the time spent in the function excluding callees.
+	Self time.Duration `json:"selfNs"`
+}
+
+// profileFrame is an active call on a module's stack.
+type profileFrame struct {
+	name  string
+	start time.Time
+	// children is the time spent in callees
+	children time.Duration
+}
+
+// NewProfiler creates an empty Profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		stacks: make(map[api.Module][]profileFrame),
+		funcs:  make(map[string]*FunctionProfile),
+	}
+}
+
+// NewFunctionListener implements experimental.FunctionListenerFactory.
+func (p *Profiler) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return p
+}
+
+// Before implements experimental.FunctionListener.
+func (p *Profiler) Before(_ context.Context, mod api.Module, def api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+	p.mu.Lock()
+	p.stacks[mod] = append(p.stacks[mod], profileFrame{name: functionName(def), start: time.Now()})
+	p.mu.Unlock()
+}
+
+// After implements experimental.FunctionListener.
+func (p *Profiler) After(_ context.Context, mod api.Module, _ api.FunctionDefinition, _ []uint64) {
+	p.pop(mod)
+}
+
+// Abort implements experimental.FunctionListener.
+func (p *Profiler) Abort(_ context.Context, mod api.Module, _ api.FunctionDefinition, _ error) {
+	p.pop(mod)
+}
+
+// pop ends the innermost call on the stack of mod.
+func (p *Profiler) pop(mod api.Module) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stack := p.stacks[mod]
+	if len(stack) == 0 {
+		return
+	}
+	frame := stack[len(stack)-1]
+	stack = stack[:len(stack)-1]
+	elapsed := now.Sub(frame.start)
+	if len(stack) != 0 {
+		stack[len(stack)-1].children += elapsed
+		p.stacks[mod] = stack
+	} else {
+		delete(p.stacks, mod)
+	}
+
+	fp := p.funcs[frame.name]
+	if fp == nil {
+		fp = &FunctionProfile{Name: frame.name}
+		p.funcs[frame.name] = fp
+	}
+	fp.Calls++
+	fp.Total += elapsed
+	fp.Self += elapsed - frame.children
+}
+
+// functionName returns a readable name for a function.
+// The embedded module is stripped, so most functions only have an index.
+func functionName(def api.FunctionDefinition) string {
+	if names := def.ExportNames(); len(names) != 0 {
+		return names[0]
+	}
+	if name := def.Name(); name != "" {
+		return name
+	}
+	if mod, name, ok := def.Import(); ok {
+		return mod + "." + name
+	}
+	return fmt.Sprintf("$%d", def.Index())
+}
+
+// Profile returns the aggregated timings sorted by Self descending.
+func (p *Profiler) Profile() []FunctionProfile {
+	p.mu.Lock()
+	out := make([]FunctionProfile, 0, len(p.funcs))
+	for _, fp := range p.funcs {
+		out = append(out, *fp)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Self != out[j].Self {
+			return out[i].Self > out[j].Self
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Reset discards the aggregated timings.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	clear(p.funcs)
+	p.mu.Unlock()
+}
+
+// WriteTable writes the top n functions by Self time as a table.
+// Writes all functions if n <= 0.
+func (p *Profiler) WriteTable(w io.Writer, n int) error {
+	prof := p.Profile()
+	if n > 0 && len(prof) > n {
+		prof = prof[:n]
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FUNCTION\tCALLS\tSELF\tTOTAL\n")
+	for _, fp := range prof {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", fp.Name, fp.Calls, fp.Self, fp.Total)
+	}
+	return tw.Flush()
+}
+
+// _ is a type assertion
+var _ experimental.FunctionListenerFactory = ((*Profiler)(nil))
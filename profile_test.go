@@ -0,0 +1,54 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProfiler(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	prof := NewProfiler()
+	p, err := NewProtocGenProst(ctx, r, WithFunctionListenerFactory(prof))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var execute *FunctionProfile
+	for _, fp := range prof.Profile() {
+		if fp.Name == ExportProstExecute {
+			execute = &fp
+			break
+		}
+	}
+	if execute == nil {
+		t.Fatal("expected a profile entry for prost_execute")
+	}
+	if execute.Calls != 1 || execute.Total < execute.Self {
+		t.Fatalf("unexpected profile: %+v", execute)
+	}
+
+	var buf bytes.Buffer
+	if err := prof.WriteTable(&buf, 5); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 6 {
+		t.Fatalf("expected header and 5 rows, got %d lines:\n%s", lines, buf.String())
+	}
+
+	prof.Reset()
+	if len(prof.Profile()) != 0 {
+		t.Fatal("expected empty profile after Reset")
+	}
+}
@@ -0,0 +1,129 @@
+package prost
+
+import (
+	"time"
+)
+
+// ProgressStage identifies the step of an Execute call reported to a
+// ProgressFunc.
+type ProgressStage int
+
+const (
+	// ProgressStarted is reported before the request is passed to the plugin.
+	ProgressStarted ProgressStage = iota
+	// ProgressRunning is reported periodically while the plugin runs.
+	ProgressRunning
+	// ProgressDone is reported after the plugin returned or failed.
+	ProgressDone
+)
+
+// String returns the name of the stage.
+func (s ProgressStage) String() string {
+	switch s {
+	case ProgressStarted:
+		return "started"
+	case ProgressRunning:
+		return "running"
+	case ProgressDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// Progress describes the state of an Execute call.
+type Progress struct {
+	// Stage is the step being reported.
+	Stage ProgressStage
+	// Elapsed is the time since the plugin was started.
+	Elapsed time.Duration
+	// RequestSize is the size of the encoded request in bytes.
+	RequestSize int
+	// ResponseSize is the size of the encoded response in bytes.
+	// Only set for ProgressDone without Err.
+	ResponseSize int
+	// Err is the error the call failed with. Only set for ProgressDone.
+	Err error
+}
+
+// ProgressFunc receives progress reports of Execute calls.
+type ProgressFunc func(Progress)
+
+// WithProgress reports the progress of each plugin run to f: once when it
+// starts, every interval while it runs, and once when it is done. An interval
+// of 0 disables the periodic reports.
+//
+// The plugin ABI has no progress reporting, so periodic reports only carry
+// the elapsed time; they let CLIs and UIs show that a long generation is
+// still running. Calls answered from the cache are not reported, and each
+// retry is reported as a separate run. Reports of one call are never
+// concurrent, but f may be called from another goroutine.
+func WithProgress(interval time.Duration, f ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = f
+		o.progressInterval = interval
+	}
+}
+
+// progressReporter reports the progress of one plugin run.
+type progressReporter struct {
+	fn          ProgressFunc
+	requestSize int
+	start       time.Time
+	// stop is closed to stop the periodic reports.
+	stop chan struct{}
+	// stopped is closed once the periodic reports stopped.
+	stopped chan struct{}
+}
+
+// startProgress reports the start of a plugin run.
+// Returns nil if no ProgressFunc is configured.
+func (p *ProtocGenProst) startProgress(requestSize int) *progressReporter {
+	if p.opts.progress == nil {
+		return nil
+	}
+	r := &progressReporter{
+		fn:          p.opts.progress,
+		requestSize: requestSize,
+		start:       time.Now(),
+	}
+	r.fn(Progress{Stage: ProgressStarted, RequestSize: requestSize})
+	if interval := p.opts.progressInterval; interval > 0 {
+		r.stop = make(chan struct{})
+		r.stopped = make(chan struct{})
+		go r.tick(interval)
+	}
+	return r
+}
+
+// tick sends periodic reports until stop is closed.
+func (r *progressReporter) tick(interval time.Duration) {
+	defer close(r.stopped)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			r.fn(Progress{Stage: ProgressRunning, Elapsed: time.Since(r.start), RequestSize: r.requestSize})
+		}
+	}
+}
+
+// finish stops the periodic reports and reports the end of the run.
+// Does nothing on a nil reporter.
+func (r *progressReporter) finish(responseSize int, err error) {
+	if r == nil {
+		return
+	}
+	if r.stop != nil {
+		close(r.stop)
+		<-r.stopped
+	}
+	prog := Progress{Stage: ProgressDone, Elapsed: time.Since(r.start), RequestSize: r.requestSize, Err: err}
+	if err == nil {
+		prog.ResponseSize = responseSize
+	}
+	r.fn(prog)
+}
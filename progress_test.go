@@ -0,0 +1,46 @@
+package prost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestWithProgress(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	var reports []Progress
+	p, err := NewProtocGenProst(ctx, r, WithProgress(time.Millisecond, func(prog Progress) {
+		reports = append(reports, prog)
+	}))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input := minimalRequestInput(t)
+	out, err := p.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("expected at least 2 reports, got %d", len(reports))
+	}
+	if first := reports[0]; first.Stage != ProgressStarted || first.RequestSize != len(input) {
+		t.Fatalf("unexpected first report: %+v", first)
+	}
+	for _, prog := range reports[1 : len(reports)-1] {
+		if prog.Stage != ProgressRunning {
+			t.Fatalf("unexpected intermediate report: %+v", prog)
+		}
+	}
+	last := reports[len(reports)-1]
+	if last.Stage != ProgressDone || last.Err != nil || last.ResponseSize != len(out) {
+		t.Fatalf("unexpected last report: %+v", last)
+	}
+}
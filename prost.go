@@ -5,27 +5,77 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 // ProtocGenProst wraps a protoc-gen-prost WASI module providing a high-level API
 // for executing the Prost protobuf code generator.
 type ProtocGenProst struct {
-	runtime wazero.Runtime
-	mod     api.Module
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
 
-	// Memory management
-	malloc api.Function
-	free   api.Function
+	// Current module instance and its low-level ABI bindings.
+	// Replaced when the module is re-instantiated after Interrupt.
+	// Writers hold both mu and modMu; readers hold either.
+	mod   api.Module
+	ll    *lowlevel.Module
+	modMu sync.Mutex
 
-	// Prost plugin functions
-	prostExecute      api.Function
-	prostGetOutputPtr api.Function
-	prostGetOutputLen api.Function
-	prostClearOutput  api.Function
+	// Lifecycle state guarded by stateMu.
+	// closed is set by Close; active counts in-flight calls; idle is closed
+	// when active drops to zero while Close is waiting.
+	stateMu sync.Mutex
+	closed  bool
+	active  int
+	idle    chan struct{}
+
+	// command is set if the module runs as a WASI command (see IsCommandModule).
+	command bool
+
+	// version is the plugin version reported by Generator.
+	version string
+	// moduleID identifies the module in cache keys, see CacheKey.
+	moduleID string
+
+	// instantiatedAt is when the current instance was created and runs the
+	// number of calls it ran, for WithInstanceTTL and WithMaxExecutions.
+	// Guarded by mu once the instance is in use.
+	instantiatedAt time.Time
+	runs           int
+
+	// snapshot is the post-init memory of the current instance.
+	// Only captured with WithPristineState.
+	snapshot []byte
+
+	// Options applied at construction
+	opts *options
+
+	// Cached result of ProbeFeatures
+	features   *Features
+	featuresMu sync.Mutex
+
+	// Capabilities probed by WithSyntaxCheck
+	syntax   *Features
+	syntaxMu sync.Mutex
+
+	// stderr captures the guest stderr of the latest run.
+	// Only set with WithFailureArtifacts.
+	stderr *stderrBuffer
+
+	// random is the guest random source restarted before each run.
+	// Only set with WithRandomSeed.
+	random *seededReader
+
+	// memoryPages is the guest memory size after the latest plugin run.
+	// Only tracked with WithObserver.
+	memoryPages atomic.Uint32
 
 	// Mutex for thread-safe Execute calls (WASI is single-threaded)
 	mu sync.Mutex
@@ -33,99 +83,102 @@ type ProtocGenProst struct {
 
 // CompileProtocGenProst compiles the embedded protoc-gen-prost WASM module.
 // The compiled module can be reused across multiple ProtocGenProst instances.
+// The binding is selected from the ABI reported by DetectABI.
+// Returns ErrNoEmbeddedWASM if built with the prost_noembed tag.
 func CompileProtocGenProst(ctx context.Context, r wazero.Runtime) (wazero.CompiledModule, error) {
-	return r.CompileModule(ctx, ProtocGenProstWASM)
+	wasm := ProtocGenProstWASM()
+	if wasm == nil {
+		return nil, ErrNoEmbeddedWASM
+	}
+	return compileWASM(ctx, r, wasm)
 }
 
 // NewProtocGenProst creates a new ProtocGenProst instance using the embedded WASM.
 // This instantiates WASI on the runtime. For shared runtimes where WASI is already
 // instantiated, use NewProtocGenProstWithWASI instead.
 // Call Close() when done to release resources.
-func NewProtocGenProst(ctx context.Context, r wazero.Runtime) (*ProtocGenProst, error) {
-	// Instantiate WASI
+func NewProtocGenProst(ctx context.Context, r wazero.Runtime, opts ...Option) (*ProtocGenProst, error) {
+	if err := instantiateWASI(ctx, r, newOptions(opts)); err != nil {
+		return nil, err
+	}
+	return NewProtocGenProstWithWASI(ctx, r, opts...)
+}
+
+// instantiateWASI instantiates WASI on r, attaching the WASI auditor if set.
+func instantiateWASI(ctx context.Context, r wazero.Runtime, o *options) error {
+	if o.wasiAudit != nil {
+		ctx = experimental.WithFunctionListenerFactory(ctx, o.wasiAudit)
+	}
 	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
-		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+		return fmt.Errorf("failed to instantiate WASI: %w", err)
 	}
-	return NewProtocGenProstWithWASI(ctx, r)
+	return nil
 }
 
 // NewProtocGenProstWithWASI creates a new ProtocGenProst instance on a runtime
 // that already has WASI instantiated. Use this when sharing a runtime with other
 // WASM modules (e.g., protoc).
-func NewProtocGenProstWithWASI(ctx context.Context, r wazero.Runtime) (*ProtocGenProst, error) {
-	compiled, err := CompileProtocGenProst(ctx, r)
+func NewProtocGenProstWithWASI(ctx context.Context, r wazero.Runtime, opts ...Option) (*ProtocGenProst, error) {
+	compileCtx := ctx
+	if f := newOptions(opts).listenerFactory; f != nil {
+		compileCtx = experimental.WithFunctionListenerFactory(ctx, f)
+	}
+	compiled, err := CompileProtocGenProst(compileCtx, r)
 	if err != nil {
 		return nil, err
 	}
-	return NewProtocGenProstWithWASIAndModule(ctx, r, compiled)
+	p, err := NewProtocGenProstWithWASIAndModule(ctx, r, compiled, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.version, p.moduleID = Version, "sha256:"+WASMSHA256
+	return p, nil
 }
 
 // NewProtocGenProstWithModule creates a new ProtocGenProst instance using a pre-compiled module.
 // This instantiates WASI on the runtime. For shared runtimes where WASI is already
 // instantiated, use NewProtocGenProstWithWASIAndModule instead.
-func NewProtocGenProstWithModule(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule) (*ProtocGenProst, error) {
-	// Instantiate WASI
-	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
-		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+func NewProtocGenProstWithModule(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule, opts ...Option) (*ProtocGenProst, error) {
+	if err := instantiateWASI(ctx, r, newOptions(opts)); err != nil {
+		return nil, err
 	}
-	return NewProtocGenProstWithWASIAndModule(ctx, r, compiled)
+	return NewProtocGenProstWithWASIAndModule(ctx, r, compiled, opts...)
 }
 
 // NewProtocGenProstWithWASIAndModule creates a new ProtocGenProst instance using
 // a pre-compiled module on a runtime that already has WASI instantiated.
-func NewProtocGenProstWithWASIAndModule(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule) (*ProtocGenProst, error) {
-	// Build module config
-	modCfg := wazero.NewModuleConfig().WithName(ProtocGenProstWASMFilename)
-
-	// Instantiate the module
-	mod, err := r.InstantiateModule(ctx, compiled, modCfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate module: %w", err)
-	}
-
-	// Call _initialize if present (reactor mode)
-	if initFn := mod.ExportedFunction("_initialize"); initFn != nil {
-		if _, err := initFn.Call(ctx); err != nil {
-			mod.Close(ctx)
-			return nil, fmt.Errorf("_initialize failed: %w", err)
-		}
-	}
-
+//
+// Modules without the prost_execute ABI that export _start are run in command
+// mode; see IsCommandModule.
+func NewProtocGenProstWithWASIAndModule(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule, opts ...Option) (*ProtocGenProst, error) {
 	p := &ProtocGenProst{
-		runtime:           r,
-		mod:               mod,
-		malloc:            mod.ExportedFunction(ExportProstMalloc),
-		free:              mod.ExportedFunction(ExportProstFree),
-		prostExecute:      mod.ExportedFunction(ExportProstExecute),
-		prostGetOutputPtr: mod.ExportedFunction(ExportProstGetOutputPtr),
-		prostGetOutputLen: mod.ExportedFunction(ExportProstGetOutputLen),
-		prostClearOutput:  mod.ExportedFunction(ExportProstClearOutput),
-	}
-
-	// Validate required exports
-	if p.malloc == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstMalloc)
+		runtime:  r,
+		compiled: compiled,
+		opts:     newOptions(opts),
+		command:  IsCommandModule(compiled),
+		version:  moduleVersion(compiled),
+		moduleID: moduleFingerprint(compiled),
 	}
-	if p.free == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstFree)
+	if p.opts.failureDir != "" {
+		p.stderr = &stderrBuffer{}
 	}
-	if p.prostExecute == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstExecute)
+	if seed := p.opts.randomSeed; seed != nil {
+		p.random = newSeededReader(*seed)
 	}
-	if p.prostGetOutputPtr == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstGetOutputPtr)
+	if p.opts.hardened {
+		if err := checkSandboxImports(compiled); err != nil {
+			return nil, err
+		}
 	}
-	if p.prostGetOutputLen == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstGetOutputLen)
+	if err := ensureHost(ctx, r, compiled); err != nil {
+		return nil, err
 	}
-	if p.prostClearOutput == nil {
-		mod.Close(ctx)
-		return nil, errors.New("missing export: " + ExportProstClearOutput)
+	if p.command {
+		// Instantiated per call
+		return p, nil
+	}
+	if err := p.instantiate(ctx); err != nil {
+		return nil, err
 	}
 
 	return p, nil
@@ -134,83 +187,363 @@ func NewProtocGenProstWithWASIAndModule(ctx context.Context, r wazero.Runtime, c
 // Execute runs the protoc-gen-prost plugin with the given CodeGeneratorRequest.
 // The input should be a serialized google.protobuf.compiler.CodeGeneratorRequest.
 // Returns a serialized google.protobuf.compiler.CodeGeneratorResponse.
+//
+// An empty input is a valid empty request: the response lists the supported
+// features and contains no files.
+//
+// If a Cache is configured the result is looked up by RequestDigest first.
+// Request options (e.g. WithCollisionCheck) are applied before generation and
+// response options (e.g. WithFileFilter) are applied to the result. All of
+// this runs inside the interceptors configured with WithInterceptors.
 func (p *ProtocGenProst) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	return p.ExecuteInto(ctx, input, nil)
+}
+
+// ExecuteInto is like Execute but appends the response to dst.
+// Returns the extended buffer. Reusing a buffer with enough capacity avoids
+// allocating a new result on every call. Returns ErrClosed after Close.
+func (p *ProtocGenProst) ExecuteInto(ctx context.Context, input, dst []byte) ([]byte, error) {
+	if len(p.opts.interceptors) != 0 {
+		return p.interceptInto(ctx, input, dst)
+	}
+	return p.executeCall(ctx, input, dst)
+}
+
+// executeCall is ExecuteInto without the interceptors.
+func (p *ProtocGenProst) executeCall(ctx context.Context, input, dst []byte) (out []byte, err error) {
+	if o := p.opts.observer; o != nil {
+		o.ExecuteStarted()
+		start := time.Now()
+		defer func() {
+			o.ExecuteDone(ExecuteEvent{Duration: time.Since(start), Err: err, MemoryPages: p.memoryPages.Load()})
+		}()
+	}
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	var capture *failureCapture
+	if p.opts.failureDir != "" {
+		capture = &failureCapture{}
+		ctx = context.WithValue(ctx, failureContextKey{}, capture)
+	}
+	out, err = p.executeInto(ctx, input, dst)
+	if p.opts.dumpDir != "" {
+		var output []byte
+		if err == nil {
+			output = out[len(dst):]
+		}
+		// Dumps are a debugging aid and never fail the call
+		_ = dumpCall(p.opts.dumpDir, input, output, err)
+	}
+	if err != nil && capture != nil {
+		err = p.writeFailureArtifacts(input, capture, err)
+	}
+	return out, err
+}
+
+// executeInto applies the request and response options around executeCached.
+func (p *ProtocGenProst) executeInto(ctx context.Context, input, dst []byte) ([]byte, error) {
+	input, err := p.processRequest(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.executeCached(ctx, input, dst)
+	if err != nil {
+		return nil, err
+	}
+	if !p.opts.hasResponseOptions() {
+		return out, nil
+	}
+	return p.processResponse(out[:len(dst)], out[len(dst):])
+}
+
+// executeCached runs the plugin, consulting the cache if one is configured.
+// Appends the response to dst.
+func (p *ProtocGenProst) executeCached(ctx context.Context, input, dst []byte) ([]byte, error) {
+	if p.opts.cache == nil {
+		return p.execute(ctx, input, dst)
+	}
+
+	digest := p.CacheKey(input)
+	cached, ok, err := p.opts.cache.Get(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("cache get failed: %w", err)
+	}
+	if ok {
+		return append(dst, cached...), nil
+	}
+
+	out, err := p.execute(ctx, input, dst)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.opts.cache.Put(ctx, digest, out[len(dst):]); err != nil {
+		return nil, fmt.Errorf("cache put failed: %w", err)
+	}
+	return out, nil
+}
+
+// execute runs the plugin with the given serialized request.
+// Applies the retry policy if one is configured.
+func (p *ProtocGenProst) execute(ctx context.Context, input, dst []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := p.executeOnce(ctx, input, dst)
+		if err == nil || !p.shouldRetry(err, attempt) {
+			return result, err
+		}
+		if err := p.retryBackoff(ctx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// executeOnce runs the plugin with the given serialized request once.
+func (p *ProtocGenProst) executeOnce(ctx context.Context, input, dst []byte) ([]byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Allocate memory for input
-	inputPtr, err := p.allocBytes(ctx, input)
+	ctx = p.hostContext(ctx)
+	progress := p.startProgress(len(input))
+	if p.stderr != nil {
+		p.stderr.reset()
+	}
+	if p.random != nil {
+		p.random.reset()
+	}
+	result, err := p.executeOnceLocked(ctx, input, dst)
+	progress.finish(len(result)-len(dst), err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to allocate input: %w", err)
+		p.captureStderr(ctx)
+	}
+	if p.opts.observer != nil && p.ll != nil {
+		p.memoryPages.Store(p.ll.Memory().Size() / wasmPageSize)
 	}
-	defer p.freePtr(ctx, inputPtr, uint32(len(input)))
+	return result, err
+}
 
-	// Call prost_execute
-	results, err := p.prostExecute.Call(ctx, uint64(inputPtr), uint64(len(input)))
+// executeOnceLocked runs the plugin once. Must be called with mu held.
+func (p *ProtocGenProst) executeOnceLocked(ctx context.Context, input, dst []byte) ([]byte, error) {
+	if p.command {
+		result, err := p.executeCommand(ctx, input, dst)
+		if err != nil && isInterruptExit(err) {
+			return nil, ErrInterrupted
+		}
+		return result, err
+	}
+
+	// Re-instantiate if the module was closed by Interrupt or after a trap,
+	// or is due for recycling. Close never tears down the module while a
+	// call is in flight.
+	if p.mod.IsClosed() || p.recycleDueLocked() {
+		p.mod.Close(ctx)
+		if err := p.instantiate(ctx); err != nil {
+			return nil, fmt.Errorf("failed to re-instantiate module: %w", err)
+		}
+	}
+
+	result, err := p.executeLocked(ctx, input, dst)
+	p.runs++
+	if err != nil && isInterruptExit(err) {
+		return nil, ErrInterrupted
+	}
+	// A trap unwinds without restoring the guest's globals, such as the
+	// shadow stack pointer, so the instance is discarded and re-instantiated
+	// on the next call rather than restored.
+	var trapErr *TrapError
+	if errors.As(err, &trapErr) {
+		p.mod.Close(ctx)
+		return result, err
+	}
+	if p.opts.pristine {
+		if rerr := p.restoreLocked(ctx); rerr != nil && err == nil {
+			return nil, rerr
+		}
+	}
+	return result, err
+}
+
+// executeLocked runs the plugin on the current module instance.
+// Appends the response to dst. Must be called with mu held.
+func (p *ProtocGenProst) executeLocked(ctx context.Context, input, dst []byte) ([]byte, error) {
+	// Allocate memory for input.
+	// An empty request is passed as a 1-byte allocation with length 0, since
+	// the guest may treat pointer 0 as an error.
+	inputPtr, allocSize, err := p.allocInput(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("prost_execute failed: %w", err)
+		err = fmt.Errorf("failed to allocate input: %w", err)
+		if errors.Is(err, lowlevel.ErrMallocNull) {
+			return nil, p.outOfMemory(len(input), err)
+		}
+		return nil, err
 	}
-	outputLen := uint32(results[0])
+	defer p.ll.Free(ctx, inputPtr, allocSize)
 
-	// Get output pointer
-	results, err = p.prostGetOutputPtr.Call(ctx)
+	// Call prost_execute
+	outputLen, err := p.ll.CallExecute(ctx, inputPtr, uint32(len(input)))
 	if err != nil {
-		return nil, fmt.Errorf("prost_get_output_ptr failed: %w", err)
+		var execErr *ExecuteError
+		if errors.As(err, &execErr) {
+			if execErr.Code == lowlevel.StatusOutOfMemory {
+				return nil, p.outOfMemory(len(input), err)
+			}
+			return nil, err
+		}
+		err = newTrapError(p.ll.Names().Execute, err, p.ll.Memory())
+		if p.trappedOutOfMemory(err) {
+			return nil, p.outOfMemory(len(input), err)
+		}
+		return nil, err
 	}
-	outputPtr := uint32(results[0])
 
 	// Read output from WASM memory
-	output, ok := p.mod.Memory().Read(outputPtr, outputLen)
-	if !ok {
-		return nil, errors.New("failed to read output from memory")
+	output, err := p.ll.ReadOutput(ctx, outputLen)
+	if err != nil {
+		return nil, err
 	}
 
-	// Make a copy since we're about to clear the buffer
-	result := make([]byte, len(output))
-	copy(result, output)
+	// Make a copy since we're about to clear the buffer, unless the output
+	// is consumed in place
+	var result []byte
+	var sinkErr error
+	if sink, ok := ctx.Value(outputSinkKey{}).(*outputSink); ok {
+		sinkErr = sink.consumeOutput(output)
+		result = dst
+	} else {
+		result = append(dst, output...)
+	}
 
 	// Clear output buffer
-	if _, err := p.prostClearOutput.Call(ctx); err != nil {
-		return nil, fmt.Errorf("prost_clear_output failed: %w", err)
+	if err := p.ll.ClearOutput(ctx); err != nil {
+		return nil, newTrapError(p.ll.Names().ClearOutput, err, p.ll.Memory())
+	}
+	if sinkErr != nil {
+		return nil, sinkErr
 	}
 
 	return result, nil
 }
 
+// allocInput copies input into guest memory.
+// Returns the pointer and the allocation size to pass to Free.
+func (p *ProtocGenProst) allocInput(ctx context.Context, input []byte) (uint32, uint32, error) {
+	if len(input) != 0 {
+		ptr, err := p.ll.AllocBytes(ctx, input)
+		return ptr, uint32(len(input)), err
+	}
+	ptr, err := p.ll.Malloc(ctx, 1)
+	return ptr, 1, err
+}
+
 // Close releases resources associated with the ProtocGenProst instance.
+//
+// New calls fail with ErrClosed once Close begins. Close waits for in-flight
+// calls to finish; if ctx is done first they are aborted with Interrupt.
+// Calling Close more than once is a no-op.
 func (p *ProtocGenProst) Close(ctx context.Context) error {
+	p.stateMu.Lock()
+	if p.closed {
+		p.stateMu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var idle chan struct{}
+	if p.active != 0 {
+		idle = make(chan struct{})
+		p.idle = idle
+	}
+	p.stateMu.Unlock()
+
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			_ = p.Interrupt(context.WithoutCancel(ctx))
+			<-idle
+		}
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
-
 	if p.mod != nil {
 		return p.mod.Close(ctx)
 	}
 	return nil
 }
 
-// Memory helpers
+// acquire registers an in-flight call.
+// Returns ErrClosed if Close has been called.
+func (p *ProtocGenProst) acquire() error {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	p.active++
+	return nil
+}
+
+// release unregisters an in-flight call registered by acquire.
+func (p *ProtocGenProst) release() {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+
+	p.active--
+	if p.active == 0 && p.idle != nil {
+		close(p.idle)
+		p.idle = nil
+	}
+}
+
+// instantiate creates a new module instance from the compiled module.
+// Must be called with mu held or before p is shared.
+func (p *ProtocGenProst) instantiate(ctx context.Context) error {
+	ctx = p.hostContext(ctx)
 
-func (p *ProtocGenProst) allocBytes(ctx context.Context, data []byte) (uint32, error) {
-	if len(data) == 0 {
-		return 0, nil
+	// Build module config
+	modCfg := wazero.NewModuleConfig().WithName(ProtocGenProstWASMFilename)
+	if p.stderr != nil {
+		modCfg = modCfg.WithStderr(p.stderr)
 	}
-	results, err := p.malloc.Call(ctx, uint64(len(data)))
+	modCfg = p.pinModuleConfig(modCfg)
+	if p.opts.hardened {
+		modCfg = sandboxModuleConfig(modCfg)
+	}
+
+	// Instantiate the module
+	mod, err := p.runtime.InstantiateModule(p.memoryContext(ctx), p.compiled, modCfg)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to instantiate module: %w", err)
 	}
-	ptr := uint32(results[0])
-	if ptr == 0 {
-		return 0, errors.New("malloc returned null")
+
+	// Call _initialize if present (reactor mode)
+	if initFn := mod.ExportedFunction("_initialize"); initFn != nil {
+		if _, err := initFn.Call(ctx); err != nil {
+			mod.Close(ctx)
+			return fmt.Errorf("_initialize failed: %w", err)
+		}
 	}
-	if !p.mod.Memory().Write(ptr, data) {
-		p.free.Call(ctx, uint64(ptr), uint64(len(data)))
-		return 0, errors.New("failed to write to memory")
+
+	ll, err := lowlevel.Bind(mod)
+	if err != nil {
+		mod.Close(ctx)
+		return err
+	}
+
+	var snapshot []byte
+	if p.opts.pristine {
+		snapshot = takeSnapshot(mod)
 	}
-	return ptr, nil
-}
 
-func (p *ProtocGenProst) freePtr(ctx context.Context, ptr, size uint32) {
-	if ptr != 0 {
-		p.free.Call(ctx, uint64(ptr), uint64(size))
+	p.modMu.Lock()
+	recycled := p.mod != nil
+	p.mod, p.ll, p.snapshot = mod, ll, snapshot
+	p.instantiatedAt, p.runs = time.Now(), 0
+	p.modMu.Unlock()
+	if p.opts.observer != nil {
+		p.opts.observer.Instantiated(recycled)
 	}
+	return nil
 }
@@ -160,3 +160,100 @@ func TestProtocGenProst_CompiledModule(t *testing.T) {
 		t.Fatalf("Execute failed: %v", err)
 	}
 }
+
+// minimalRequestInput returns a serialized CodeGeneratorRequest for a minimal proto file.
+func minimalRequestInput(t testing.TB) []byte {
+	t.Helper()
+	protoFileName := "test.proto"
+	packageName := "test"
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{protoFileName},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    &protoFileName,
+				Package: &packageName,
+				Syntax:  proto.String("proto3"),
+			},
+		},
+	}
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	return input
+}
+
+func TestProtocGenProst_ExecuteInto(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input := minimalRequestInput(t)
+	want, err := p.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	buf := make([]byte, 0, 4096)
+	buf = append(buf, "prefix"...)
+	out, err := p.ExecuteInto(ctx, input, buf)
+	if err != nil {
+		t.Fatalf("ExecuteInto failed: %v", err)
+	}
+	if string(out[:6]) != "prefix" || string(out[6:]) != string(want) {
+		t.Fatal("ExecuteInto did not append the response")
+	}
+	if &out[0] != &buf[:1][0] {
+		t.Fatal("ExecuteInto reallocated a buffer with enough capacity")
+	}
+}
+
+func TestProtocGenProst_ExecuteEmpty(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	first, err := p.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	resp := mustUnmarshalResponse(t, first)
+	if resp.Error != nil || len(resp.GetFile()) != 0 {
+		t.Fatalf("expected an empty response, got %v", resp)
+	}
+
+	second, err := p.Execute(ctx, []byte{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !proto.Equal(resp, mustUnmarshalResponse(t, second)) {
+		t.Fatal("expected identical responses for empty input")
+	}
+}
+
+func mustUnmarshalResponse(t *testing.T, data []byte) *pluginpb.CodeGeneratorResponse {
+	t.Helper()
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(data, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	return resp
+}
+
+func TestVerifyEmbeddedWASM(t *testing.T) {
+	if err := VerifyEmbeddedWASM(); err != nil {
+		t.Fatalf("VerifyEmbeddedWASM failed: %v", err)
+	}
+}
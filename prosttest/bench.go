@@ -0,0 +1,231 @@
+package prosttest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// BenchOptions configures Bench.
+type BenchOptions struct {
+	// Request is used for the cold start and warm benchmarks.
+	// Defaults to SampleRequest.
+	Request *pluginpb.CodeGeneratorRequest
+	// LargeRequest is used for the large request benchmark.
+	// Defaults to LargeRequest(100).
+	LargeRequest *pluginpb.CodeGeneratorRequest
+	// Instances lists the instance counts for the pool scaling benchmark.
+	// Defaults to 1, 2, and 4.
+	Instances []int
+	// RuntimeConfig configures the wazero runtimes.
+	// Each instance uses its own runtime. Defaults to wazero.NewRuntimeConfig
+	// with a shared compilation cache.
+	RuntimeConfig wazero.RuntimeConfig
+	// Options are passed to each ProtocGenProst instance.
+	Options []prost.Option
+}
+
+// Bench runs the standard benchmark suite as sub-benchmarks of b.
+//
+//	ColdStart:        new runtime, compile, instantiate, and execute
+//	Instantiate:      instantiate a pre-compiled module and execute
+//	WarmExecute:      execute on a warm instance
+//	LargeRequest:     execute a large request on a warm instance
+//	PoolScaling/N:    parallel execution across N instances
+//
+// Use it from a benchmark in your own package:
+//
+//	func BenchmarkProst(b *testing.B) {
+//		prosttest.Bench(b, nil)
+//	}
+func Bench(b *testing.B, opts *BenchOptions) {
+	if opts == nil {
+		opts = &BenchOptions{}
+	}
+	req := opts.Request
+	if req == nil {
+		req = SampleRequest()
+	}
+	large := opts.LargeRequest
+	if large == nil {
+		large = LargeRequest(100)
+	}
+	instances := opts.Instances
+	if len(instances) == 0 {
+		instances = []int{1, 2, 4}
+	}
+	cfg := opts.RuntimeConfig
+	if cfg == nil {
+		cache := wazero.NewCompilationCache()
+		defer cache.Close(context.Background())
+		cfg = wazero.NewRuntimeConfig().WithCompilationCache(cache)
+	}
+
+	input := marshalRequest(b, req)
+	largeInput := marshalRequest(b, large)
+	ctx := context.Background()
+
+	b.Run("ColdStart", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r := wazero.NewRuntimeWithConfig(ctx, cfg)
+			p, err := prost.NewProtocGenProst(ctx, r, opts.Options...)
+			if err != nil {
+				b.Fatalf("NewProtocGenProst failed: %v", err)
+			}
+			benchExecute(b, p, input)
+			r.Close(ctx)
+		}
+	})
+
+	b.Run("Instantiate", func(b *testing.B) {
+		r := wazero.NewRuntimeWithConfig(ctx, cfg)
+		defer r.Close(ctx)
+		compiled, err := prost.CompileProtocGenProst(ctx, r)
+		if err != nil {
+			b.Fatalf("CompileProtocGenProst failed: %v", err)
+		}
+		p, err := prost.NewProtocGenProstWithModule(ctx, r, compiled, opts.Options...)
+		if err != nil {
+			b.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+		}
+		p.Close(ctx)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p, err := prost.NewProtocGenProstWithWASIAndModule(ctx, r, compiled, opts.Options...)
+			if err != nil {
+				b.Fatalf("NewProtocGenProstWithWASIAndModule failed: %v", err)
+			}
+			benchExecute(b, p, input)
+			p.Close(ctx)
+		}
+	})
+
+	b.Run("WarmExecute", func(b *testing.B) {
+		p := newBenchInstances(b, cfg, opts.Options, 1)[0]
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			benchExecute(b, p, input)
+		}
+	})
+
+	b.Run("LargeRequest", func(b *testing.B) {
+		p := newBenchInstances(b, cfg, opts.Options, 1)[0]
+		b.SetBytes(int64(len(largeInput)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			benchExecute(b, p, largeInput)
+		}
+	})
+
+	for _, n := range instances {
+		b.Run("PoolScaling/"+strconv.Itoa(n), func(b *testing.B) {
+			pool := make(chan *prost.ProtocGenProst, n)
+			for _, p := range newBenchInstances(b, cfg, opts.Options, n) {
+				pool <- p
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p := <-pool
+					benchExecute(b, p, input)
+					pool <- p
+				}
+			})
+		})
+	}
+}
+
+// LargeRequest builds a synthetic request with the given number of files.
+// Each file declares 50 messages with 10 fields each and an enum.
+func LargeRequest(files int) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{}
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("large/v1/file%d.proto", i)
+		pkg := fmt.Sprintf("large.v1.file%d", i)
+		fd := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(name),
+			Package: proto.String(pkg),
+			Syntax:  proto.String("proto3"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Kind"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("KIND_OTHER"), Number: proto.Int32(1)},
+				},
+			}},
+		}
+		for m := 0; m < 50; m++ {
+			msg := &descriptorpb.DescriptorProto{Name: proto.String(fmt.Sprintf("Message%d", m))}
+			for f := 0; f < 10; f++ {
+				field := &descriptorpb.FieldDescriptorProto{
+					Name:     proto.String(fmt.Sprintf("field_%d", f)),
+					JsonName: proto.String(fmt.Sprintf("field%d", f)),
+					Number:   proto.Int32(int32(f + 1)),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				}
+				switch f % 3 {
+				case 1:
+					field.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+					field.TypeName = proto.String("." + pkg + ".Kind")
+				case 2:
+					if m != 0 {
+						field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+						field.TypeName = proto.String("." + pkg + ".Message0")
+					}
+				}
+				msg.Field = append(msg.Field, field)
+			}
+			fd.MessageType = append(fd.MessageType, msg)
+		}
+		req.FileToGenerate = append(req.FileToGenerate, name)
+		req.ProtoFile = append(req.ProtoFile, fd)
+	}
+	return req
+}
+
+// newBenchInstances creates n instances, each on its own runtime closed with b.
+func newBenchInstances(b *testing.B, cfg wazero.RuntimeConfig, opts []prost.Option, n int) []*prost.ProtocGenProst {
+	ctx := context.Background()
+	instances := make([]*prost.ProtocGenProst, n)
+	for i := range instances {
+		r := wazero.NewRuntimeWithConfig(ctx, cfg)
+		b.Cleanup(func() { r.Close(ctx) })
+		p, err := prost.NewProtocGenProst(ctx, r, opts...)
+		if err != nil {
+			b.Fatalf("NewProtocGenProst failed: %v", err)
+		}
+		instances[i] = p
+	}
+	return instances
+}
+
+// benchExecute runs a single Execute and fails the benchmark on error.
+func benchExecute(b *testing.B, p *prost.ProtocGenProst, input []byte) {
+	if _, err := p.Execute(context.Background(), input); err != nil {
+		b.Fatalf("Execute failed: %v", err)
+	}
+}
+
+// marshalRequest serializes a request for benchmarking.
+func marshalRequest(b *testing.B, req *pluginpb.CodeGeneratorRequest) []byte {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		b.Fatalf("failed to marshal request: %v", err)
+	}
+	return input
+}
@@ -0,0 +1,7 @@
+package prosttest
+
+import "testing"
+
+func BenchmarkProst(b *testing.B) {
+	Bench(b, &BenchOptions{LargeRequest: LargeRequest(10)})
+}
@@ -0,0 +1,134 @@
+package prosttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FakeGenerator is a prost.Executor returning canned or scripted responses
+// without loading WASM, for unit tests of code that orchestrates generation.
+//
+// Scripted results queued with Respond and Fail are returned in order. When
+// the queue is empty the handler set with RespondFunc is called, or
+// DefaultResponse is returned. Safe for concurrent use.
+type FakeGenerator struct {
+	mu       sync.Mutex
+	queue    []fakeResult
+	handler  func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+	requests []*pluginpb.CodeGeneratorRequest
+}
+
+// fakeResult is a scripted result of a FakeGenerator call.
+type fakeResult struct {
+	resp *pluginpb.CodeGeneratorResponse
+	err  error
+}
+
+var _ prost.Executor = (*FakeGenerator)(nil)
+
+// NewFakeGenerator creates a FakeGenerator with an empty script.
+func NewFakeGenerator() *FakeGenerator {
+	return &FakeGenerator{}
+}
+
+// Respond queues resp as the result of the next unscripted call.
+func (f *FakeGenerator) Respond(resp *pluginpb.CodeGeneratorResponse) *FakeGenerator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queue = append(f.queue, fakeResult{resp: resp})
+	return f
+}
+
+// Fail queues err as the result of the next unscripted call.
+func (f *FakeGenerator) Fail(err error) *FakeGenerator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queue = append(f.queue, fakeResult{err: err})
+	return f
+}
+
+// RespondFunc sets the handler called once the script is exhausted.
+func (f *FakeGenerator) RespondFunc(fn func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)) *FakeGenerator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handler = fn
+	return f
+}
+
+// Requests returns the requests received so far, in order.
+func (f *FakeGenerator) Requests() []*pluginpb.CodeGeneratorRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*pluginpb.CodeGeneratorRequest(nil), f.requests...)
+}
+
+// Execute implements prost.Executor.
+func (f *FakeGenerator) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(input, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+	resp, err := f.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(resp)
+}
+
+// ExecuteRequest implements prost.Executor.
+// The returned response is a copy, so callers may modify it.
+func (f *FakeGenerator) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, proto.Clone(req).(*pluginpb.CodeGeneratorRequest))
+	var res fakeResult
+	scripted := len(f.queue) != 0
+	if scripted {
+		res, f.queue = f.queue[0], f.queue[1:]
+	}
+	handler := f.handler
+	f.mu.Unlock()
+
+	switch {
+	case scripted:
+	case handler != nil:
+		res.resp, res.err = handler(req)
+	default:
+		res.resp = DefaultResponse(req)
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return proto.Clone(res.resp).(*pluginpb.CodeGeneratorResponse), nil
+}
+
+// DefaultResponse returns the canned response of a FakeGenerator: one file
+// per file to generate, named after the proto file with a .pb.rs extension.
+// The content names the proto file, package, and parameter.
+func DefaultResponse(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	packages := make(map[string]string, len(req.GetProtoFile()))
+	for _, fd := range req.GetProtoFile() {
+		packages[fd.GetName()] = fd.GetPackage()
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+	for _, name := range req.GetFileToGenerate() {
+		content := fmt.Sprintf("// Fake output for %s (package %q, parameter %q).\n", name, packages[name], req.GetParameter())
+		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(strings.TrimSuffix(name, ".proto") + ".pb.rs"),
+			Content: proto.String(content),
+		})
+	}
+	return resp
+}
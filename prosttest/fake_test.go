@@ -0,0 +1,52 @@
+package prosttest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestFakeGenerator(t *testing.T) {
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+	scripted := &pluginpb.CodeGeneratorResponse{Error: proto.String("bad parameter")}
+	f := NewFakeGenerator().Respond(scripted).Fail(errBoom)
+
+	req := NewRequest().File("a/a.proto", `syntax = "proto3"; package a; message A {}`).Build(t)
+
+	resp, err := f.ExecuteRequest(ctx, req)
+	if err != nil || resp.GetError() != "bad parameter" {
+		t.Fatalf("expected scripted response, got %v, %v", resp, err)
+	}
+	if _, err := f.ExecuteRequest(ctx, req); !errors.Is(err, errBoom) {
+		t.Fatalf("expected scripted error, got %v", err)
+	}
+
+	// The default response has one file per file to generate.
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := f.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	out := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, out); err != nil {
+		t.Fatal(err)
+	}
+	NewOutput(t, out).FileContains(t, "a/a.pb.rs", `package "a"`)
+
+	f.RespondFunc(func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		return nil, errBoom
+	})
+	if _, err := f.ExecuteRequest(ctx, req); !errors.Is(err, errBoom) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if n := len(f.Requests()); n != 4 {
+		t.Fatalf("expected 4 recorded requests, got %d", n)
+	}
+}
@@ -0,0 +1,208 @@
+// Package prosttest provides testing helpers for programs using go-protoc-gen-prost.
+package prosttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FuzzFileName is the name of the proto file generated by FuzzRequest.
+const FuzzFileName = "fuzz.proto"
+
+// fuzzNames contains identifiers that are valid in protobuf but unusual in Rust.
+var fuzzNames = []string{
+	"Msg", "type", "Self", "self", "super", "crate", "match", "_", "__x",
+	"A", "aB_c", "HTTPServer", "Message", "Option", "Result", "Box", "Vec",
+	"String", "async", "await", "dyn", "impl", "loop", "fn", "mod", "use",
+	"struct", "enum", "trait", "where", "Z9",
+}
+
+// fuzzReader consumes fuzz input bytes, returning zero once exhausted.
+type fuzzReader struct {
+	data []byte
+}
+
+// byte returns the next byte of input.
+func (r *fuzzReader) byte() byte {
+	if len(r.data) == 0 {
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+// intn returns a value in [0, n).
+func (r *fuzzReader) intn(n int) int {
+	return int(r.byte()) % n
+}
+
+// name returns an identifier from fuzzNames.
+func (r *fuzzReader) name() string {
+	return fuzzNames[r.intn(len(fuzzNames))]
+}
+
+// FuzzRequest builds a structurally valid CodeGeneratorRequest from fuzz input.
+//
+// The request contains a single proto file with deeply nested messages, unusual
+// identifiers, and enums with up to several thousand values. The same input
+// always produces the same request.
+func FuzzRequest(data []byte) *pluginpb.CodeGeneratorRequest {
+	r := &fuzzReader{data: data}
+
+	pkgDepth := r.intn(4)
+	var pkg string
+	for i := 0; i < pkgDepth; i++ {
+		if i != 0 {
+			pkg += "."
+		}
+		pkg += r.name() + strconv.Itoa(i)
+	}
+	scope := ""
+	if pkg != "" {
+		scope = "." + pkg
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String(FuzzFileName),
+		Syntax: proto.String("proto3"),
+	}
+	if pkg != "" {
+		file.Package = proto.String(pkg)
+	}
+
+	// Giant enum at the file level
+	enumName := r.name() + "Enum"
+	file.EnumType = append(file.EnumType, buildFuzzEnum(r, enumName, 1+int(r.byte())*16))
+	enumRef := scope + "." + enumName
+
+	// Deeply nested message chain
+	depth := 1 + int(r.byte())%64
+	file.MessageType = append(file.MessageType, buildFuzzMessage(r, scope, enumRef, depth, 0))
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{FuzzFileName},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	}
+}
+
+// buildFuzzEnum builds an enum with n values.
+func buildFuzzEnum(r *fuzzReader, name string, n int) *descriptorpb.EnumDescriptorProto {
+	e := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i := 0; i < n; i++ {
+		e.Value = append(e.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(fmt.Sprintf("%s_%s_%d", name, r.name(), i)),
+			Number: proto.Int32(int32(i)),
+		})
+	}
+	return e
+}
+
+// buildFuzzMessage builds a message nested depth levels deep.
+func buildFuzzMessage(r *fuzzReader, scope, enumRef string, depth, level int) *descriptorpb.DescriptorProto {
+	name := r.name() + strconv.Itoa(level)
+	fullName := scope + "." + name
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+
+	fieldTypes := []descriptorpb.FieldDescriptorProto_Type{
+		descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+	}
+	nfields := r.intn(8)
+	for i := 0; i < nfields; i++ {
+		typ := fieldTypes[r.intn(len(fieldTypes))]
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(fmt.Sprintf("%s_%d", r.name(), i)),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			JsonName: proto.String(fmt.Sprintf("f%d", i)),
+		}
+		if r.intn(2) == 1 {
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}
+		switch typ {
+		case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+			field.TypeName = proto.String(enumRef)
+		case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+			// Self-reference keeps the type resolvable at any depth
+			field.TypeName = proto.String(fullName)
+		}
+		msg.Field = append(msg.Field, field)
+	}
+
+	if level+1 < depth {
+		msg.NestedType = append(msg.NestedType, buildFuzzMessage(r, fullName, enumRef, depth, level+1))
+	}
+	return msg
+}
+
+// CheckExecute executes req and checks the host contract.
+//
+// The call must either return a decodable CodeGeneratorResponse or an error
+// of a type defined by the prost package. Panics fail the test.
+func CheckExecute(t testing.TB, p *prost.ProtocGenProst, req *pluginpb.CodeGeneratorRequest) {
+	t.Helper()
+
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	output, err := p.Execute(context.Background(), input)
+	if err != nil {
+		var trapErr *prost.TrapError
+		var execErr *prost.ExecuteError
+		if !errors.As(err, &trapErr) && !errors.As(err, &execErr) && !errors.Is(err, prost.ErrInterrupted) {
+			t.Fatalf("Execute returned untyped error: %v", err)
+		}
+		return
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+}
+
+// Fuzz registers a fuzz target on f exercising p with FuzzRequest inputs.
+//
+// Use it from a fuzz test in your own package:
+//
+//	func FuzzProst(f *testing.F) {
+//		prosttest.Fuzz(f, p)
+//	}
+//
+// Run with `go test -fuzz=FuzzProst`.
+func Fuzz(f *testing.F, p *prost.ProtocGenProst) {
+	for _, seed := range FuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		CheckExecute(t, p, FuzzRequest(data))
+	})
+}
+
+// FuzzSeeds returns the seed corpus used by Fuzz.
+func FuzzSeeds() [][]byte {
+	return [][]byte{
+		{},
+		{0, 0, 0, 0},
+		{3, 1, 2, 3, 4, 255, 63},
+		{1, 7, 250, 10, 7, 1, 6, 1, 5, 0, 6, 1},
+		[]byte("the quick brown fox jumps over the lazy dog"),
+	}
+}
@@ -0,0 +1,32 @@
+package prosttest
+
+import (
+	"context"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFuzzRequest_Valid(t *testing.T) {
+	for _, seed := range FuzzSeeds() {
+		req := FuzzRequest(seed)
+		if _, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: req.GetProtoFile()}); err != nil {
+			t.Fatalf("seed %v produced invalid descriptors: %v", seed, err)
+		}
+	}
+}
+
+func FuzzExecute(f *testing.F) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	f.Cleanup(func() { r.Close(ctx) })
+
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		f.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	Fuzz(f, p)
+}
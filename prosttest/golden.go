@@ -0,0 +1,149 @@
+package prosttest
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/diff"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// update is the -update flag used to regenerate golden files.
+var update = flag.Bool("update", false, "update golden files")
+
+// GenerateGolden executes req and writes the generated files into dir.
+//
+// The directory is dedicated to the golden output: files in dir that are not
+// part of the response are removed.
+func GenerateGolden(t testing.TB, req *pluginpb.CodeGeneratorRequest, dir string) {
+	t.Helper()
+
+	files := generateFiles(t, req)
+	existing := readGoldenDir(t, dir)
+	for name := range existing {
+		if _, ok := files[name]; !ok {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				t.Fatalf("failed to remove stale golden file: %v", err)
+			}
+		}
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+	}
+}
+
+// CompareGolden executes req and compares the generated files with dir.
+//
+// Mismatches are reported with a unified diff. When the test binary is run
+// with -update, the golden files are regenerated instead.
+func CompareGolden(t testing.TB, req *pluginpb.CodeGeneratorRequest, dir string) {
+	t.Helper()
+
+	if *update {
+		GenerateGolden(t, req, dir)
+		return
+	}
+
+	files := generateFiles(t, req)
+	existing := readGoldenDir(t, dir)
+
+	names := make([]string, 0, len(files)+len(existing))
+	for name := range files {
+		names = append(names, name)
+	}
+	for name := range existing {
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want, wantOk := existing[name]
+		got, gotOk := files[name]
+		switch {
+		case !wantOk:
+			t.Errorf("%s: generated file missing from golden dir (run with -update)", name)
+		case !gotOk:
+			t.Errorf("%s: golden file not generated (run with -update)", name)
+		case want != got:
+			t.Errorf("%s: generated output differs from golden file (run with -update):\n%s",
+				name, diff.Unified("golden/"+name, "generated/"+name, want, got))
+		}
+	}
+}
+
+// generateFiles executes req on a fresh instance and returns the files by name.
+func generateFiles(t testing.TB, req *pluginpb.CodeGeneratorRequest) map[string]string {
+	t.Helper()
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetError() != "" {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+
+	files := make(map[string]string, len(resp.GetFile()))
+	for _, f := range resp.GetFile() {
+		if f.GetInsertionPoint() != "" {
+			t.Fatalf("%s: insertion points are not supported in golden files", f.GetName())
+		}
+		files[f.GetName()] = f.GetContent()
+	}
+	return files
+}
+
+// readGoldenDir reads all files under dir keyed by slash-separated relative path.
+// Returns an empty map if dir does not exist.
+func readGoldenDir(t testing.TB, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to read golden dir: %v", err)
+	}
+	return files
+}
@@ -0,0 +1,32 @@
+package prosttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestGolden(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+		}},
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stale.rs"), []byte("stale"), 0o644); err != nil {
+		t.Fatal(err.Error())
+	}
+	GenerateGolden(t, req, dir)
+	if _, err := os.Stat(filepath.Join(dir, "stale.rs")); !os.IsNotExist(err) {
+		t.Fatal("expected stale golden file to be removed")
+	}
+	CompareGolden(t, req, dir)
+}
@@ -0,0 +1,178 @@
+package prosttest
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/diff"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Request builds a CodeGeneratorRequest from proto source for tests:
+//
+//	out := prosttest.NewRequest().
+//		File("a.proto", `syntax = "proto3"; package a; message A {}`).
+//		Generate(t, p)
+//	out.FileExists(t, "a.pb.rs")
+//
+// Sources are compiled in memory. The well-known types may be imported
+// without adding them.
+type Request struct {
+	sources   map[string]string
+	generate  []string
+	parameter string
+}
+
+// NewRequest creates an empty Request.
+func NewRequest() *Request {
+	return &Request{sources: make(map[string]string)}
+}
+
+// File adds a proto source file and marks it for generation.
+func (r *Request) File(name, source string) *Request {
+	r.Import(name, source)
+	r.generate = append(r.generate, name)
+	return r
+}
+
+// Import adds a proto source file that is only imported by other files.
+func (r *Request) Import(name, source string) *Request {
+	r.sources[name] = source
+	return r
+}
+
+// Parameter sets the plugin parameter string.
+func (r *Request) Parameter(params string) *Request {
+	r.parameter = params
+	return r
+}
+
+// Build compiles the sources and returns the request.
+// Fails the test if the sources do not compile.
+func (r *Request) Build(t testing.TB) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+
+	fsys := make(fstest.MapFS, len(r.sources))
+	for name, source := range r.sources {
+		fsys[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+	req, err := prost.RequestFromFS(fsys, nil, r.generate)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if r.parameter != "" {
+		req.Parameter = proto.String(r.parameter)
+	}
+	return req
+}
+
+// Generate builds the request and executes it on p.
+// Fails the test if execution fails or the plugin reports an error.
+func (r *Request) Generate(t testing.TB, p *prost.ProtocGenProst) *Output {
+	t.Helper()
+
+	resp, err := p.ExecuteRequest(context.Background(), r.Build(t))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetError() != "" {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+	return NewOutput(t, resp)
+}
+
+// Output holds generated files in memory for assertions.
+type Output struct {
+	// Response is the plugin response.
+	Response *pluginpb.CodeGeneratorResponse
+	// FS contains the generated files with insertion points applied.
+	FS fstest.MapFS
+}
+
+// NewOutput resolves the files of resp into an Output.
+// Fails the test if the response is invalid.
+func NewOutput(t testing.TB, resp *pluginpb.CodeGeneratorResponse) *Output {
+	t.Helper()
+
+	files, err := prost.ResolveFiles(resp)
+	if err != nil {
+		t.Fatalf("failed to resolve response files: %v", err)
+	}
+	out := &Output{Response: resp, FS: make(fstest.MapFS, len(files))}
+	for _, f := range files {
+		out.FS[f.Name] = &fstest.MapFile{Data: []byte(f.Content), Mode: 0o644}
+	}
+	return out
+}
+
+// Content returns the content of a generated file.
+// Fails the test if the file was not generated.
+func (o *Output) Content(t testing.TB, name string) string {
+	t.Helper()
+
+	data, err := fs.ReadFile(o.FS, name)
+	if err != nil {
+		t.Fatalf("%s: not generated (generated: %s)", name, strings.Join(o.names(), ", "))
+	}
+	return string(data)
+}
+
+// FileExists fails the test if name was not generated.
+func (o *Output) FileExists(t testing.TB, name string) {
+	t.Helper()
+	o.Content(t, name)
+}
+
+// FileContains fails the test if name was not generated or does not
+// contain substr.
+func (o *Output) FileContains(t testing.TB, name, substr string) {
+	t.Helper()
+
+	if content := o.Content(t, name); !strings.Contains(content, substr) {
+		t.Errorf("%s: does not contain %q:\n%s", name, substr, content)
+	}
+}
+
+// NoDrift fails the test if the files checked in under dir differ from the
+// generated files, reporting a unified diff for each changed file. Files in
+// dir that are not generated are ignored.
+func (o *Output) NoDrift(t testing.TB, dir string) {
+	t.Helper()
+
+	diffs, err := prost.CheckDir(dir, o.Response, nil)
+	if err != nil {
+		t.Fatalf("failed to check %s: %v", dir, err)
+	}
+	for _, d := range diffs {
+		if d.Status != prost.FileChanged {
+			t.Errorf("%s: generated file %s in %s", d.Name, d.Status, dir)
+			continue
+		}
+		want, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(d.Name)))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", d.Name, err)
+		}
+		got := o.Content(t, d.Name)
+		t.Errorf("%s: checked in file differs from generated output:\n%s",
+			d.Name, diff.Unified("checked-in/"+d.Name, "generated/"+d.Name, string(want), got))
+	}
+}
+
+// names returns the sorted names of the generated files.
+func (o *Output) names() []string {
+	var names []string
+	_ = fs.WalkDir(o.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names
+}
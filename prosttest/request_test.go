@@ -0,0 +1,72 @@
+package prosttest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestRequest_Generate(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	out := NewRequest().
+		Import("common/v1/common.proto", `syntax = "proto3";
+package common.v1;
+message Id { string value = 1; }`).
+		File("app/v1/app.proto", `syntax = "proto3";
+package app.v1;
+import "common/v1/common.proto";
+import "google/protobuf/timestamp.proto";
+// A user of the app.
+message User {
+  common.v1.Id id = 1;
+  google.protobuf.Timestamp created = 2;
+}`).
+		Generate(t, p)
+
+	out.FileExists(t, "app/v1/app.pb.rs")
+	out.FileContains(t, "app/v1/app.pb.rs", "pub struct User")
+	out.FileContains(t, "app/v1/app.pb.rs", "A user of the app.")
+	if _, err := out.FS.Open("common/v1/common.pb.rs"); err == nil {
+		t.Fatal("expected imported file not to be generated")
+	}
+
+	dir := t.TempDir()
+	if err := prost.WriteResponse(dir, out.Response, nil); err != nil {
+		t.Fatal(err)
+	}
+	out.NoDrift(t, dir)
+
+	// Drift is reported as a test failure.
+	if err := os.WriteFile(filepath.Join(dir, "app/v1/app.pb.rs"), []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ft := &fakeT{TB: t}
+	out.NoDrift(ft, dir)
+	if !ft.failed {
+		t.Fatal("expected NoDrift to fail")
+	}
+}
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(string, ...any) { f.failed = true }
+
+func (f *fakeT) Fatalf(string, ...any) { f.failed = true }
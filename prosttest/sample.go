@@ -0,0 +1,49 @@
+package prosttest
+
+import (
+	_ "embed"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+//go:generate protoc -I sampledata --include_imports --descriptor_set_out=sampledata/sample.binpb prosttest/sample/v1/types.proto prosttest/sample/v1/service.proto
+
+// sampleDescriptorSet is the compiled FileDescriptorSet of the sample protos.
+// It includes the imported well-known types.
+//
+//go:embed sampledata/sample.binpb
+var sampleDescriptorSet []byte
+
+// SamplePackage is the proto package of the sample files.
+const SamplePackage = "prosttest.sample.v1"
+
+// SampleFiles contains the names of the sample proto files to generate.
+//
+// The files cover messages, nested types, enums, maps, oneofs, proto3
+// optional fields, streaming services, and well-known type usage.
+var SampleFiles = []string{
+	"prosttest/sample/v1/types.proto",
+	"prosttest/sample/v1/service.proto",
+}
+
+// SampleFileDescriptorSet returns the compiled sample descriptors.
+// Files are in dependency order, including the well-known types.
+// Returns a new copy on each call.
+func SampleFileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(sampleDescriptorSet, set); err != nil {
+		panic("prosttest: invalid embedded sample descriptors: " + err.Error())
+	}
+	return set
+}
+
+// SampleRequest returns a CodeGeneratorRequest generating SampleFiles.
+// Returns a new copy on each call.
+func SampleRequest() *pluginpb.CodeGeneratorRequest {
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: append([]string(nil), SampleFiles...),
+		ProtoFile:      SampleFileDescriptorSet().GetFile(),
+	}
+}
@@ -0,0 +1,40 @@
+package prosttest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestSampleRequest(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := prost.NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	resp, err := p.ExecuteRequest(ctx, SampleRequest())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetError() != "" {
+		t.Fatalf("plugin returned error: %s", resp.GetError())
+	}
+
+	var content string
+	for _, f := range resp.GetFile() {
+		content += f.GetContent()
+	}
+	for _, want := range []string{"pub struct Widget", "pub enum Status", "pub struct GetWidgetRequest"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated code does not contain %q", want)
+		}
+	}
+}
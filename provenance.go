@@ -0,0 +1,67 @@
+package prost
+
+import (
+	"runtime/debug"
+)
+
+// wazeroModulePath is the module path of the WebAssembly runtime.
+const wazeroModulePath = "github.com/tetratelabs/wazero"
+
+// ArtifactProvenance describes where the protoc-gen-prost WASI build used by
+// this package comes from, for SBOM generators and build attestations.
+type ArtifactProvenance struct {
+	// Name is the artifact file name.
+	Name string `json:"name"`
+	// Version is the protoc-gen-prost release, see Version.
+	Version string `json:"version"`
+	// DownloadURL is the release asset the artifact was downloaded from.
+	DownloadURL string `json:"downloadUrl"`
+	// SHA256 is the hex SHA-256 checksum of the uncompressed artifact.
+	SHA256 string `json:"sha256"`
+	// UpstreamCommit is the protoc-gen-prost commit the release was built
+	// from, or empty if it was not recorded.
+	UpstreamCommit string `json:"upstreamCommit,omitempty"`
+	// SelfTestDigest is the response digest of the Verify self-test.
+	SelfTestDigest string `json:"selfTestDigest"`
+	// Embedded is set if the artifact is embedded in this build, i.e. it was
+	// not built with the prost_noembed tag.
+	Embedded bool `json:"embedded"`
+	// WazeroVersion is the version of the wazero module linked into the
+	// program, or empty if the build information is unavailable.
+	WazeroVersion string `json:"wazeroVersion,omitempty"`
+}
+
+// Provenance returns metadata about the protoc-gen-prost artifact of this
+// package. It does not decompress or hash the embedded build; use
+// VerifyEmbeddedWASM to check it against SHA256.
+func Provenance() *ArtifactProvenance {
+	return &ArtifactProvenance{
+		Name:           ProtocGenProstWASMFilename,
+		Version:        Version,
+		DownloadURL:    DownloadURL,
+		SHA256:         WASMSHA256,
+		UpstreamCommit: UpstreamCommit,
+		SelfTestDigest: SelfTestDigest,
+		Embedded:       embeddedWASM,
+		WazeroVersion:  wazeroVersion(),
+	}
+}
+
+// wazeroVersion returns the version of wazero in the build information of
+// the program, following replacements.
+func wazeroVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != wazeroModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		return dep.Version
+	}
+	return ""
+}
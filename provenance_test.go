@@ -0,0 +1,33 @@
+package prost
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	p := Provenance()
+	if p.Version != Version || p.DownloadURL != DownloadURL || p.SHA256 != WASMSHA256 {
+		t.Fatalf("unexpected provenance: %+v", p)
+	}
+	if p.Embedded != (ProtocGenProstWASM() != nil) {
+		t.Fatalf("Embedded = %v, but ProtocGenProstWASM returned %v bytes", p.Embedded, len(ProtocGenProstWASM()))
+	}
+	if p.WazeroVersion == "" {
+		t.Log("wazero version unavailable in build info")
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"name", "version", "downloadUrl", "sha256", "embedded"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("missing JSON field %q in %s", name, data)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package prost
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// PruneRequest removes the proto files no file to generate imports, directly
+// or transitively, and the source_code_info of the remaining dependencies.
+// Plugins only read comments of the files they generate, so the output does
+// not change, while requests built from monorepo-wide descriptor sets shrink
+// along with the guest memory needed to decode them. Returns the names of the
+// removed files, sorted.
+func PruneRequest(req *pluginpb.CodeGeneratorRequest) []string {
+	used := make(map[string]bool)
+	for _, name := range TransitiveImports(ImportGraph(req), req.GetFileToGenerate()) {
+		used[name] = true
+	}
+	generate := make(map[string]bool, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		generate[name] = true
+	}
+
+	var removed []string
+	kept := req.GetProtoFile()[:0]
+	for _, fd := range req.GetProtoFile() {
+		if !used[fd.GetName()] {
+			removed = append(removed, fd.GetName())
+			continue
+		}
+		if !generate[fd.GetName()] {
+			fd.SourceCodeInfo = nil
+		}
+		kept = append(kept, fd)
+	}
+	clear(req.GetProtoFile()[len(kept):])
+	req.ProtoFile = kept
+	sort.Strings(removed)
+	return removed
+}
+
+// WithPruning runs PruneRequest on each request before generation, so the
+// guest only decodes the descriptors it needs.
+func WithPruning() Option {
+	return func(o *options) {
+		o.prune = true
+	}
+}
@@ -0,0 +1,65 @@
+package prost
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestPruneRequest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"common.proto": {Data: []byte(`syntax = "proto3"; package common; // Common.
+message Common {}`)},
+		"unused.proto": {Data: []byte(`syntax = "proto3"; package unused; message Unused {}`)},
+		"api.proto": {Data: []byte(`syntax = "proto3"; package api; import "common.proto";
+// Api.
+message Api { common.Common c = 1; }`)},
+	}
+	req, err := RequestFromFS(fsys, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.FileToGenerate = []string{"api.proto"}
+
+	removed := PruneRequest(req)
+	if !slices.Equal(removed, []string{"unused.proto"}) {
+		t.Fatalf("unexpected files removed: %v", removed)
+	}
+	names := make([]string, len(req.GetProtoFile()))
+	for i, fd := range req.GetProtoFile() {
+		names[i] = fd.GetName()
+	}
+	if !slices.Equal(names, []string{"common.proto", "api.proto"}) {
+		t.Fatalf("unexpected files kept: %v", names)
+	}
+	if req.GetProtoFile()[0].SourceCodeInfo != nil || req.GetProtoFile()[1].SourceCodeInfo == nil {
+		t.Fatal("expected source info only on the files to generate")
+	}
+	if removed := PruneRequest(req); removed != nil {
+		t.Fatalf("expected nothing to remove, got %v", removed)
+	}
+}
+
+func TestProtocGenProst_WithPruning(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r, WithPruning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	req := wktStrippedRequest(t)
+	InjectWellKnownTypes(req)
+	req.ProtoFile = append(req.ProtoFile, &descriptorpb.FileDescriptorProto{Name: proto.String("unused.proto")})
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil || resp.GetError() != "" || len(resp.GetFile()) == 0 {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+}
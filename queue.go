@@ -0,0 +1,189 @@
+package prost
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority orders the calls queued on a Pool.
+type Priority int
+
+const (
+	// PriorityInteractive is for calls someone is waiting on, e.g. from an
+	// editor or a build. It is the default.
+	PriorityInteractive Priority = iota
+	// PriorityBulk is for batch regenerations and backfills. Bulk calls run
+	// when no interactive calls are queued, and now and then in between so
+	// they are not starved, see PoolOptions.InteractiveBurst.
+	PriorityBulk
+
+	// numPriorities is the number of priorities.
+	numPriorities
+)
+
+// String returns the name of the priority, e.g. "interactive".
+func (pri Priority) String() string {
+	switch pri {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return "unknown"
+	}
+}
+
+// Priorities lists the priorities from highest to lowest.
+func Priorities() []Priority {
+	return []Priority{PriorityInteractive, PriorityBulk}
+}
+
+// defaultInteractiveBurst is the default of PoolOptions.InteractiveBurst.
+const defaultInteractiveBurst = 8
+
+// priorityKey is the context key of WithPriority.
+type priorityKey struct{}
+
+// WithPriority returns a context queuing Pool calls made with it at pri.
+func WithPriority(ctx context.Context, pri Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, pri)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or
+// PriorityInteractive.
+func PriorityFromContext(ctx context.Context) Priority {
+	if pri, ok := ctx.Value(priorityKey{}).(Priority); ok && pri >= 0 && pri < numPriorities {
+		return pri
+	}
+	return PriorityInteractive
+}
+
+// jobQueue is an unbounded queue of pool jobs, FIFO within each priority.
+type jobQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   [numPriorities][]*poolJob
+	closed bool
+
+	// burst is the number of higher priority jobs popped in a row while
+	// lower priority jobs wait before one of those is popped.
+	burst int
+	// streak counts the jobs popped in a row while lower priority jobs wait.
+	streak int
+}
+
+// newJobQueue creates an empty open queue.
+func newJobQueue(burst int) *jobQueue {
+	q := &jobQueue{burst: burst}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push appends job. Returns false if the queue is closed.
+func (q *jobQueue) push(job *poolJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.jobs[job.priority] = append(q.jobs[job.priority], job)
+	q.cond.Signal()
+	return true
+}
+
+// pop removes the next job, waiting for one. Returns false once the queue
+// is closed.
+func (q *jobQueue) pop() (*poolJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.lenLocked() == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	pri, ok := q.nextLocked()
+	if !ok {
+		return nil, false
+	}
+	job := q.jobs[pri][0]
+	q.jobs[pri][0] = nil
+	q.jobs[pri] = q.jobs[pri][1:]
+	return job, true
+}
+
+// nextLocked picks the priority of the next job and updates the streak.
+func (q *jobQueue) nextLocked() (Priority, bool) {
+	first := Priority(-1)
+	for pri := range numPriorities {
+		if len(q.jobs[pri]) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = pri
+			continue
+		}
+		// Lower priority jobs are waiting.
+		if q.streak >= q.burst {
+			q.streak = 0
+			return pri, true
+		}
+		q.streak++
+		return first, true
+	}
+	if first < 0 {
+		return 0, false
+	}
+	q.streak = 0
+	return first, true
+}
+
+// remove removes job if it is still queued.
+func (q *jobQueue) remove(job *poolJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := q.jobs[job.priority]
+	for i, queued := range jobs {
+		if queued == job {
+			q.jobs[job.priority] = append(jobs[:i], jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// depth returns the number of jobs queued at pri.
+func (q *jobQueue) depth(pri Priority) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs[pri])
+}
+
+// lenLocked returns the number of queued jobs.
+func (q *jobQueue) lenLocked() int {
+	var n int
+	for _, jobs := range q.jobs {
+		n += len(jobs)
+	}
+	return n
+}
+
+// stop stops accepting jobs. Queued jobs are still popped, after which pop
+// returns false.
+func (q *jobQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// close stops accepting jobs and returns the queued ones.
+func (q *jobQueue) close() []*poolJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobs []*poolJob
+	for pri := range q.jobs {
+		jobs = append(jobs, q.jobs[pri]...)
+		q.jobs[pri] = nil
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	return jobs
+}
@@ -0,0 +1,54 @@
+package prost
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJobQueue_Priority(t *testing.T) {
+	q := newJobQueue(2)
+	var order []string
+	push := func(name string, pri Priority) {
+		job := &poolJob{priority: pri, input: []byte(name)}
+		if !q.push(job) {
+			t.Fatal("push failed")
+		}
+	}
+	for _, name := range []string{"b1", "b2"} {
+		push(name, PriorityBulk)
+	}
+	for _, name := range []string{"i1", "i2", "i3", "i4", "i5"} {
+		push(name, PriorityInteractive)
+	}
+	if n := q.depth(PriorityInteractive); n != 5 {
+		t.Fatalf("expected 5 interactive jobs queued, got %d", n)
+	}
+	for range 7 {
+		job, ok := q.pop()
+		if !ok {
+			t.Fatal("pop failed")
+		}
+		order = append(order, string(job.input))
+	}
+
+	// One bulk job runs after each burst of two interactive jobs.
+	want := []string{"i1", "i2", "b1", "i3", "i4", "b2", "i5"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	ctx := context.Background()
+	if pri := PriorityFromContext(ctx); pri != PriorityInteractive {
+		t.Fatalf("expected interactive by default, got %v", pri)
+	}
+	if pri := PriorityFromContext(WithPriority(ctx, PriorityBulk)); pri != PriorityBulk {
+		t.Fatalf("expected bulk, got %v", pri)
+	}
+	if pri := PriorityFromContext(WithPriority(ctx, Priority(42))); pri != PriorityInteractive {
+		t.Fatalf("expected interactive for an unknown priority, got %v", pri)
+	}
+}
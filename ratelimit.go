@@ -0,0 +1,284 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrResourceExhausted matches a *RemoteError with errors.Is if the server
+// rejected the request because a ServeLimits limit was reached. Retry later.
+var ErrResourceExhausted = errors.New("resource exhausted")
+
+// ServeLimits caps the load clients can put on a server started with
+// ServeListenerWithLimits, so one misbehaving client cannot starve the rest.
+// Requests over a limit are rejected immediately with a resource exhausted
+// error instead of being queued. Zero values disable the limit.
+type ServeLimits struct {
+	// Rate is the number of requests per second accepted from all clients.
+	Rate float64
+	// Burst is the number of requests accepted at once above Rate.
+	// Defaults to Rate rounded up.
+	Burst int
+	// ClientRate is the number of requests per second accepted from each
+	// client, as identified by ClientKey.
+	ClientRate float64
+	// ClientBurst is the number of requests accepted at once from each
+	// client above ClientRate. Defaults to ClientRate rounded up.
+	ClientBurst int
+	// ClientKey identifies the client of a connection for ClientRate.
+	// Defaults to the user of the peer process on Unix sockets where peer
+	// credentials are available, the remote IP address on TCP connections,
+	// and the connection itself otherwise.
+	ClientKey func(net.Conn) string
+	// HTTPClientKey identifies the client of an HTTP request for ClientRate
+	// in LimitHandler, e.g. by an authenticated user. Defaults to the IP
+	// address of the request's RemoteAddr.
+	HTTPClientKey func(*http.Request) string
+	// MaxInFlight is the number of requests executing or waiting for the
+	// instance at once.
+	MaxInFlight int
+	// MaxRequestSize is the largest request accepted, in bytes. Requests are
+	// admitted before their body is read. Defaults to 64 MiB.
+	MaxRequestSize int
+}
+
+// ServeListenerWithLimits is like ServeListener but rejects requests over
+// limits with a *RemoteError matching ErrResourceExhausted.
+func ServeListenerWithLimits(ctx context.Context, p *ProtocGenProst, ln net.Listener, limits ServeLimits) error {
+	return serveListener(ctx, p.ExecuteInto, ln, newServeLimiter(limits), nil)
+}
+
+// frameAdmitter is called before executing each request of a connection.
+// It returns a function releasing the admission once the request is done,
+// or an error wrapping ErrResourceExhausted to reject the request.
+type frameAdmitter func() (func(), error)
+
+// serveLimiter enforces ServeLimits across the connections of a listener.
+type serveLimiter struct {
+	limits   ServeLimits
+	global   *tokenBucket
+	inFlight chan struct{}
+
+	mu      sync.Mutex
+	clients map[string]*clientBucket
+	swept   time.Time
+}
+
+// clientBucket is the rate limit of one client, shared by its connections.
+type clientBucket struct {
+	bucket *tokenBucket
+	// conns is the number of connections or requests using bucket.
+	conns int
+}
+
+// newServeLimiter creates a limiter for limits.
+func newServeLimiter(limits ServeLimits) *serveLimiter {
+	l := &serveLimiter{limits: limits, clients: make(map[string]*clientBucket)}
+	if limits.Rate > 0 {
+		l.global = newTokenBucket(limits.Rate, limits.Burst)
+	}
+	if limits.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return l
+}
+
+// maxRequestSize returns the largest request accepted by the server.
+func (l *serveLimiter) maxRequestSize() uint32 {
+	if l.limits.MaxRequestSize <= 0 {
+		return defaultMaxRequestSize
+	}
+	return uint32(min(l.limits.MaxRequestSize, maxFrameSize))
+}
+
+// clientSweepInterval is the minimum time between sweeps of the idle client
+// buckets.
+const clientSweepInterval = time.Minute
+
+// open returns the admission check of the requests on conn, and a function
+// to call when the connection is closed.
+func (l *serveLimiter) open(conn net.Conn) (frameAdmitter, func()) {
+	if l.limits.ClientRate <= 0 {
+		return l.admitter(nil), func() {}
+	}
+	key := defaultClientKey(conn)
+	if l.limits.ClientKey != nil {
+		key = l.limits.ClientKey(conn)
+	}
+	client, release := l.client(key)
+	return l.admitter(client), release
+}
+
+// admitter returns the admission check of the requests of a client, or of
+// any client if client is nil. The client token is refunded if the request
+// is rejected by a global limit, so rejections only count against the
+// client that exceeded its own rate.
+func (l *serveLimiter) admitter(client *tokenBucket) frameAdmitter {
+	return func() (func(), error) {
+		if client != nil && !client.allow() {
+			return nil, fmt.Errorf("%w: client rate limit of %g requests per second", ErrResourceExhausted, l.limits.ClientRate)
+		}
+		if l.global != nil && !l.global.allow() {
+			client.refund()
+			return nil, fmt.Errorf("%w: rate limit of %g requests per second", ErrResourceExhausted, l.limits.Rate)
+		}
+		if l.inFlight == nil {
+			return func() {}, nil
+		}
+		select {
+		case l.inFlight <- struct{}{}:
+			return func() { <-l.inFlight }, nil
+		default:
+			client.refund()
+			l.global.refund()
+			return nil, fmt.Errorf("%w: %d requests in flight", ErrResourceExhausted, l.limits.MaxInFlight)
+		}
+	}
+}
+
+// client returns the bucket of the client key and a function releasing it
+// once the connection or request is done. Buckets outlive their
+// connections, so reconnecting does not reset the limit of a client; idle
+// buckets are dropped once they have refilled.
+func (l *serveLimiter) client(key string) (*tokenBucket, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now := time.Now(); now.Sub(l.swept) >= clientSweepInterval {
+		for k, c := range l.clients {
+			if c.conns == 0 && c.bucket.full(now) {
+				delete(l.clients, k)
+			}
+		}
+		l.swept = now
+	}
+	c := l.clients[key]
+	if c == nil {
+		c = &clientBucket{bucket: newTokenBucket(l.limits.ClientRate, l.limits.ClientBurst)}
+		l.clients[key] = c
+	}
+	c.conns++
+	return c.bucket, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		c.conns--
+	}
+}
+
+// defaultClientKey identifies the client of conn when ServeLimits.ClientKey
+// is not set.
+func defaultClientKey(conn net.Conn) string {
+	if uid, ok := peerUID(conn); ok {
+		return "uid:" + strconv.FormatUint(uint64(uid), 10)
+	}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return "ip:" + addr.IP.String()
+	}
+	return fmt.Sprintf("conn:%p", conn)
+}
+
+// LimitHandler wraps h to reject requests over limits with status 429 Too
+// Many Requests and a Connect resource_exhausted error, like
+// ServeListenerWithLimits for the framed protocol. Clients are identified
+// by limits.HTTPClientKey for ClientRate. Request bodies are capped at
+// limits.MaxRequestSize.
+//
+// Set the timeouts of the http.Server, e.g. ReadHeaderTimeout, so slow
+// clients cannot hold connections open without sending requests.
+func LimitHandler(h http.Handler, limits ServeLimits) http.Handler {
+	l := newServeLimiter(limits)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var client *tokenBucket
+		if limits.ClientRate > 0 {
+			key := httpClientKey(r)
+			if limits.HTTPClientKey != nil {
+				key = limits.HTTPClientKey(r)
+			}
+			var release func()
+			client, release = l.client(key)
+			defer release()
+		}
+		maxSize := int64(l.maxRequestSize())
+		if r.ContentLength > maxSize {
+			writeConnectError(w, http.StatusRequestEntityTooLarge, "resource_exhausted", fmt.Sprintf("request of %d bytes exceeds the limit of %d bytes", r.ContentLength, maxSize))
+			return
+		}
+		done, err := l.admitter(client)()
+		if err != nil {
+			writeConnectError(w, http.StatusTooManyRequests, "resource_exhausted", err.Error())
+			return
+		}
+		defer done()
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// httpClientKey identifies the client of r when ServeLimits.HTTPClientKey
+// is not set.
+func httpClientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket refilling at rate tokens per second.
+// burst defaults to rate rounded up.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = float64(int(rate))
+		if b < rate {
+			b++
+		}
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refund returns a token taken by allow. A nil bucket is ignored.
+func (b *tokenBucket) refund() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// full reports whether the bucket has refilled to its burst at now, so
+// replacing it with a new bucket does not change the limit.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
@@ -0,0 +1,34 @@
+package prost
+
+import "time"
+
+// WithMaxExecutions re-instantiates the module after it ran n calls,
+// bounding guest memory growth and any state leaking between requests in
+// long-running services. Like WithInstanceTTL, the instance is replaced
+// before the next call, and reported to the Observer as recycled.
+// Has no effect in command mode, where each call runs in a fresh instance.
+func WithMaxExecutions(n int) Option {
+	return func(o *options) {
+		o.maxExecutions = n
+	}
+}
+
+// WithInstanceTTL re-instantiates the module once it is older than ttl, so
+// services running for weeks do not accumulate slow guest memory growth.
+// The age is checked before each call; an idle instance keeps its memory
+// until the next one. Combine with WithMaxExecutions for a bound on both.
+// Pass it in PoolOptions.Options to recycle the instances of a Pool.
+func WithInstanceTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.instanceTTL = ttl
+	}
+}
+
+// recycleDueLocked reports whether the current instance reached the
+// WithMaxExecutions or WithInstanceTTL limit. Must be called with mu held.
+func (p *ProtocGenProst) recycleDueLocked() bool {
+	if p.opts.maxExecutions > 0 && p.runs >= p.opts.maxExecutions {
+		return true
+	}
+	return p.opts.instanceTTL > 0 && time.Since(p.instantiatedAt) >= p.opts.instanceTTL
+}
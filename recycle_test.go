@@ -0,0 +1,82 @@
+package prost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// recycleObserver counts recycled instances.
+type recycleObserver struct {
+	recycled int
+}
+
+func (o *recycleObserver) ExecuteStarted()          {}
+func (o *recycleObserver) ExecuteDone(ExecuteEvent) {}
+func (o *recycleObserver) Instantiated(recycled bool) {
+	if recycled {
+		o.recycled++
+	}
+}
+
+func TestProtocGenProst_MaxExecutions(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	obs := &recycleObserver{}
+	p, err := NewProtocGenProst(ctx, r, WithMaxExecutions(2), WithObserver(obs))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	for range 5 {
+		if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	// Replaced before the third and fifth calls.
+	if obs.recycled != 2 {
+		t.Fatalf("expected 2 recycled instances, got %d", obs.recycled)
+	}
+}
+
+func TestProtocGenProst_InstanceTTL(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	obs := &recycleObserver{}
+	p, err := NewProtocGenProst(ctx, r, WithInstanceTTL(time.Hour), WithObserver(obs))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	execute := func() {
+		t.Helper()
+		if _, err := p.Execute(ctx, minimalRequestInput(t)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	execute()
+	if obs.recycled != 0 {
+		t.Fatalf("expected no recycling within the TTL, got %d", obs.recycled)
+	}
+
+	// Age the instance past the TTL.
+	p.mu.Lock()
+	p.instantiatedAt = p.instantiatedAt.Add(-2 * time.Hour)
+	mod := p.mod
+	p.mu.Unlock()
+	execute()
+	if obs.recycled != 1 || p.mod == mod {
+		t.Fatalf("expected the instance to be recycled, got %d recycles", obs.recycled)
+	}
+	if !mod.IsClosed() {
+		t.Fatal("expected the expired instance to be closed")
+	}
+}
@@ -0,0 +1,123 @@
+package prost
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// PathRule maps generated files to a custom output location.
+//
+// A rule matches if all of its non-empty selectors match. Example mapping
+// everything under mycompany.billing into a crate source directory:
+//
+//	PathRule{Package: "mycompany.billing.*", Dir: "crates/billing-proto/src"}
+type PathRule struct {
+	// Package selects files by proto package.
+	// "a.b" matches exactly, "a.b.*" matches a.b and all packages below it,
+	// and "*" matches every package.
+	Package string
+	// Path selects files by generated file name using path.Match syntax.
+	Path string
+	// TrimPrefix is removed from the start of the file name before joining with Dir.
+	TrimPrefix string
+	// Dir is the directory the file is placed in, relative to the output directory.
+	Dir string
+}
+
+// Matches checks if the rule selects the generated file name with the proto package.
+// An empty pkg never matches a Package selector.
+func (r *PathRule) Matches(name, pkg string) bool {
+	if r.Package == "" && r.Path == "" {
+		return false
+	}
+	if r.Package != "" && !matchPackage(r.Package, pkg) {
+		return false
+	}
+	if r.Path != "" {
+		if ok, _ := path.Match(r.Path, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the remapped file name.
+func (r *PathRule) Apply(name string) string {
+	return path.Join(r.Dir, strings.TrimPrefix(name, r.TrimPrefix))
+}
+
+// PathRules is an ordered list of path rules. The first matching rule wins.
+type PathRules []PathRule
+
+// Map returns the remapped name of the generated file.
+// Returns name unchanged if no rule matches.
+func (rs PathRules) Map(name, pkg string) string {
+	for i := range rs {
+		if rs[i].Matches(name, pkg) {
+			return rs[i].Apply(name)
+		}
+	}
+	return name
+}
+
+// RemapPaths renames the files in resp according to rules.
+// The request is used to resolve the proto package of each generated file.
+func RemapPaths(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, rules PathRules) {
+	if len(rules) == 0 {
+		return
+	}
+	pkgs := OutputPackages(req)
+	for _, f := range resp.GetFile() {
+		f.Name = proto.String(rules.Map(f.GetName(), pkgs[f.GetName()]))
+	}
+}
+
+// OutputPackages maps the expected generated file names of a request to their proto package.
+//
+// protoc-gen-prost writes the output for a/b.proto in package x.y to
+// x/y/b.pb.rs, or b.pb.rs with flat_output_dir. Both names are included, as
+// is a/b.pb.rs (see OutputFileName). If names collide, the file listed first
+// wins. Only files listed in file_to_generate are included.
+func OutputPackages(req *pluginpb.CodeGeneratorRequest) map[string]string {
+	pkgs := make(map[string]string)
+	if req == nil {
+		return pkgs
+	}
+	add := func(name, pkg string) {
+		if _, ok := pkgs[name]; !ok {
+			pkgs[name] = pkg
+		}
+	}
+	for _, fd := range FilesToGenerate(req) {
+		pkg := fd.GetPackage()
+		base := path.Base(OutputFileName(fd.GetName()))
+		if pkg != "" {
+			add(path.Join(strings.ReplaceAll(pkg, ".", "/"), base), pkg)
+		}
+		add(base, pkg)
+		add(OutputFileName(fd.GetName()), pkg)
+	}
+	return pkgs
+}
+
+// OutputFileName returns the name of the file generated for a proto file.
+func OutputFileName(protoFile string) string {
+	return strings.TrimSuffix(protoFile, ".proto") + ".pb.rs"
+}
+
+// matchPackage checks if pkg matches the package pattern.
+func matchPackage(pattern, pkg string) bool {
+	if pkg == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+".")
+	}
+	return pattern == pkg
+}
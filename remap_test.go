@@ -0,0 +1,42 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRemapPaths(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"mycompany/billing/v1/invoice.proto", "mycompany/users/user.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("mycompany/billing/v1/invoice.proto"), Package: proto.String("mycompany.billing.v1")},
+			{Name: proto.String("mycompany/users/user.proto"), Package: proto.String("mycompany.users")},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("mycompany/billing/v1/invoice.pb.rs")},
+			{Name: proto.String("mycompany/users/user.pb.rs")},
+			{Name: proto.String("other.rs")},
+		},
+	}
+	rules := PathRules{
+		{Package: "mycompany.billing.*", TrimPrefix: "mycompany/billing/", Dir: "crates/billing-proto/src"},
+		{Path: "mycompany/*/*.rs", Dir: "crates/common/src"},
+	}
+	RemapPaths(resp, req, rules)
+
+	want := []string{
+		"crates/billing-proto/src/v1/invoice.pb.rs",
+		"crates/common/src/mycompany/users/user.pb.rs",
+		"other.rs",
+	}
+	for i, f := range resp.GetFile() {
+		if f.GetName() != want[i] {
+			t.Errorf("file %d: got %q, want %q", i, f.GetName(), want[i])
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package prost
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RemotePluginPath is the Connect procedure of buf's remote plugin
+// execution API served by NewRemotePluginHandler.
+const RemotePluginPath = "/buf.alpha.registry.v1alpha1.CodeGenerationService/GenerateCode"
+
+// maxRemotePluginRequest is the largest request body accepted by the remote
+// plugin handler, after decompression. LimitHandler can lower the limit of
+// the compressed body.
+const maxRemotePluginRequest = defaultMaxRequestSize
+
+// Field numbers of the remote plugin API messages.
+const (
+	genReqImage           protowire.Number = 1 // GenerateCodeRequest.image
+	genReqRequests        protowire.Number = 2 // GenerateCodeRequest.requests
+	genReqIncludeImports  protowire.Number = 3 // GenerateCodeRequest.include_imports
+	genReqIncludeWKT      protowire.Number = 4 // GenerateCodeRequest.include_well_known_types
+	pluginReqReference    protowire.Number = 1 // PluginGenerationRequest.plugin_reference
+	pluginReqParameters   protowire.Number = 2 // PluginGenerationRequest.parameters
+	pluginReqImports      protowire.Number = 3 // PluginGenerationRequest.include_imports
+	pluginReqWKT          protowire.Number = 4 // PluginGenerationRequest.include_well_known_types
+	pluginRefOwner        protowire.Number = 1 // CuratedPluginReference.owner
+	pluginRefName         protowire.Number = 2 // CuratedPluginReference.name
+	pluginRefVersion      protowire.Number = 3 // CuratedPluginReference.version
+	pluginRefRevision     protowire.Number = 4 // CuratedPluginReference.revision
+	imageFileField        protowire.Number = 1 // Image.file
+	imageFileBufExtension protowire.Number = 8042
+	bufExtIsImport        protowire.Number = 1 // ImageFileExtension.is_import
+	genRespResponses      protowire.Number = 1 // GenerateCodeResponse.responses
+	pluginRespResponse    protowire.Number = 1 // PluginGenerationResponse.response
+)
+
+// RemotePluginRef references a plugin in a remote plugin call, written
+// <remote>/<owner>/<name>:<version> in buf.gen.yaml.
+type RemotePluginRef struct {
+	// Owner is the user or organization owning the plugin.
+	Owner string `json:"owner"`
+	// Name is the plugin name.
+	Name string `json:"name"`
+	// Version is the plugin version, or empty for the latest.
+	Version string `json:"version"`
+	// Revision is the revision of the version, or 0 for the latest.
+	Revision uint32 `json:"revision"`
+}
+
+// String returns the reference as owner/name[:version].
+func (r RemotePluginRef) String() string {
+	s := r.Owner + "/" + r.Name
+	if r.Version != "" {
+		s += ":" + r.Version
+	}
+	return s
+}
+
+// RemotePluginResolver returns the Executor of a plugin reference, or an
+// error wrapping ErrGeneratorNotFound if the server does not provide it.
+type RemotePluginResolver func(ref RemotePluginRef) (Executor, error)
+
+// RegistryPluginResolver resolves the plugins of owner to the generators of
+// reg, name@version or the latest version of name if the reference has
+// none. References of other owners are not found.
+func RegistryPluginResolver(reg *GeneratorRegistry, owner string) RemotePluginResolver {
+	return func(ref RemotePluginRef) (Executor, error) {
+		if ref.Owner != owner {
+			return nil, fmt.Errorf("%w: %s", ErrGeneratorNotFound, ref)
+		}
+		key := ref.Name
+		if ref.Version != "" {
+			key += "@" + ref.Version
+		}
+		return reg.Lookup(key)
+	}
+}
+
+// remotePluginRequest is a decoded GenerateCodeRequest.
+type remotePluginRequest struct {
+	files          []imageFile
+	plugins        []remotePluginCall
+	includeImports bool
+	includeWKT     bool
+}
+
+// remotePluginCall is a decoded PluginGenerationRequest.
+type remotePluginCall struct {
+	ref            RemotePluginRef
+	parameters     []string
+	includeImports *bool
+	includeWKT     *bool
+}
+
+// NewRemotePluginHandler returns an http.Handler serving the code generation
+// procedure of buf's remote plugin execution API, RemotePluginPath, so an
+// organization can run its own plugin server for remote plugin references
+// in `buf generate` templates without Docker images.
+//
+// Each plugin of a call is resolved with resolve and runs on the image sent
+// by buf. The Connect unary protocol is served with binary or JSON messages,
+// optionally gzip-compressed. Plugin errors are returned in the responses,
+// like the plugins of the registry. Wrap the handler with LimitHandler to
+// cap the load of each client.
+//
+// Only the code generation procedure is served; the registry APIs buf uses
+// to look up plugins and authenticate must be provided by a BSR instance or
+// proxy in front of the handler.
+func NewRemotePluginHandler(resolve RemotePluginResolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveRemotePlugin(w, r, resolve)
+	})
+}
+
+// serveRemotePlugin handles one GenerateCode call.
+func serveRemotePlugin(w http.ResponseWriter, r *http.Request, resolve RemotePluginResolver) {
+	if r.URL.Path != RemotePluginPath {
+		writeConnectError(w, http.StatusNotFound, "unimplemented", r.URL.Path+" is not served")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeConnectError(w, http.StatusMethodNotAllowed, "unimplemented", "method must be POST")
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var useJSON bool
+	switch contentType {
+	case "application/proto":
+	case "application/json":
+		useJSON = true
+	default:
+		writeConnectError(w, http.StatusUnsupportedMediaType, "unknown", fmt.Sprintf("unsupported content type %q", contentType))
+		return
+	}
+
+	body, err := readConnectBody(r)
+	if err != nil {
+		writeConnectError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	var req *remotePluginRequest
+	if useJSON {
+		req, err = decodeRemotePluginJSON(body)
+	} else {
+		req, err = decodeRemotePluginProto(body)
+	}
+	if err != nil {
+		writeConnectError(w, http.StatusBadRequest, "invalid_argument", fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	resps, err := runRemotePlugins(r.Context(), req, resolve)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrGeneratorNotFound):
+			writeConnectError(w, http.StatusNotFound, "not_found", err.Error())
+		case errors.Is(err, ErrResourceExhausted):
+			writeConnectError(w, http.StatusTooManyRequests, "resource_exhausted", err.Error())
+		case r.Context().Err() != nil:
+			writeConnectError(w, http.StatusRequestTimeout, "canceled", err.Error())
+		default:
+			writeConnectError(w, http.StatusInternalServerError, "internal", err.Error())
+		}
+		return
+	}
+
+	var out []byte
+	if useJSON {
+		out, err = encodeRemotePluginJSON(resps)
+	} else {
+		out, err = encodeRemotePluginProto(resps)
+	}
+	if err != nil {
+		writeConnectError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(out)
+}
+
+// runRemotePlugins runs each plugin of req on its image.
+func runRemotePlugins(ctx context.Context, req *remotePluginRequest, resolve RemotePluginResolver) ([]*pluginpb.CodeGeneratorResponse, error) {
+	resps := make([]*pluginpb.CodeGeneratorResponse, len(req.plugins))
+	for i, call := range req.plugins {
+		exec, err := resolve(call.ref)
+		if err != nil {
+			return nil, err
+		}
+		includeImports, includeWKT := req.includeImports, req.includeWKT
+		if call.includeImports != nil {
+			includeImports = *call.includeImports
+		}
+		if call.includeWKT != nil {
+			includeWKT = *call.includeWKT
+		}
+		resp, err := exec.ExecuteRequest(ctx, imageRequest(req.files, call.parameters, includeImports, includeWKT))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", call.ref, err)
+		}
+		resps[i] = resp
+	}
+	return resps, nil
+}
+
+// imageRequest builds the request of a plugin run on the files of an image.
+// The files of the module are generated, plus its imports with
+// includeImports, plus the well-known types with includeWKT.
+func imageRequest(files []imageFile, parameters []string, includeImports, includeWKT bool) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{}
+	if len(parameters) != 0 {
+		req.Parameter = proto.String(strings.Join(parameters, ","))
+	}
+	for _, f := range files {
+		req.ProtoFile = append(req.ProtoFile, f.fd)
+		name := f.fd.GetName()
+		generate := !f.isImport ||
+			(includeImports && (includeWKT || !strings.HasPrefix(name, wellKnownTypesPrefix)))
+		if generate {
+			req.FileToGenerate = append(req.FileToGenerate, name)
+		}
+	}
+	return req
+}
+
+// wellKnownTypesPrefix is the directory of the well-known type files.
+const wellKnownTypesPrefix = "google/protobuf/"
+
+// readConnectBody reads a Connect unary request body, decompressing it if
+// the Content-Encoding is gzip.
+func readConnectBody(r *http.Request) ([]byte, error) {
+	body := io.Reader(r.Body)
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", enc)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxRemotePluginRequest+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemotePluginRequest {
+		return nil, fmt.Errorf("request larger than %d bytes", maxRemotePluginRequest)
+	}
+	return data, nil
+}
+
+// writeConnectError writes a Connect unary error.
+func writeConnectError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
+
+// decodeRemotePluginProto decodes a binary GenerateCodeRequest.
+func decodeRemotePluginProto(b []byte) (*remotePluginRequest, error) {
+	req := &remotePluginRequest{}
+	err := walkFields(b, func(num protowire.Number, v []byte, x uint64) error {
+		switch num {
+		case genReqImage:
+			return walkFields(v, func(num protowire.Number, v []byte, _ uint64) error {
+				if num != imageFileField {
+					return nil
+				}
+				f, err := decodeProtoImageFile(v)
+				req.files = append(req.files, f)
+				return err
+			})
+		case genReqRequests:
+			call, err := decodePluginCallProto(v)
+			req.plugins = append(req.plugins, call)
+			return err
+		case genReqIncludeImports:
+			req.includeImports = x != 0
+		case genReqIncludeWKT:
+			req.includeWKT = x != 0
+		}
+		return nil
+	})
+	return req, err
+}
+
+// decodePluginCallProto decodes a binary PluginGenerationRequest.
+func decodePluginCallProto(b []byte) (remotePluginCall, error) {
+	var call remotePluginCall
+	err := walkFields(b, func(num protowire.Number, v []byte, x uint64) error {
+		switch num {
+		case pluginReqReference:
+			return walkFields(v, func(num protowire.Number, v []byte, x uint64) error {
+				switch num {
+				case pluginRefOwner:
+					call.ref.Owner = string(v)
+				case pluginRefName:
+					call.ref.Name = string(v)
+				case pluginRefVersion:
+					call.ref.Version = string(v)
+				case pluginRefRevision:
+					call.ref.Revision = uint32(x)
+				}
+				return nil
+			})
+		case pluginReqParameters:
+			call.parameters = append(call.parameters, string(v))
+		case pluginReqImports:
+			call.includeImports = proto.Bool(x != 0)
+		case pluginReqWKT:
+			call.includeWKT = proto.Bool(x != 0)
+		}
+		return nil
+	})
+	return call, err
+}
+
+// decodeProtoImageFile decodes a binary ImageFile. Its fields match
+// FileDescriptorProto, plus the buf extension.
+func decodeProtoImageFile(b []byte) (imageFile, error) {
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return imageFile{}, err
+	}
+	f := imageFile{fd: fd}
+	unknown := fd.ProtoReflect().GetUnknown()
+	fd.ProtoReflect().SetUnknown(nil)
+	err := walkFields(unknown, func(num protowire.Number, v []byte, _ uint64) error {
+		if num != imageFileBufExtension {
+			return nil
+		}
+		return walkFields(v, func(num protowire.Number, _ []byte, x uint64) error {
+			if num == bufExtIsImport {
+				f.isImport = x != 0
+			}
+			return nil
+		})
+	})
+	return f, err
+}
+
+// walkFields calls fn for each field of an encoded message with the value of
+// length-delimited fields or the varint value, skipping other wire types.
+func walkFields(b []byte, fn func(num protowire.Number, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v []byte
+		var x uint64
+		switch typ {
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n >= 0 {
+				b = b[n:]
+				continue
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageFile is a file of a buf image.
+type imageFile struct {
+	fd *descriptorpb.FileDescriptorProto
+	// isImport is set for the files of dependencies.
+	isImport bool
+}
+
+// decodeJSONImageFile decodes a JSON ImageFile, a FileDescriptorProto with
+// a buf extension.
+func decodeJSONImageFile(raw json.RawMessage) (imageFile, error) {
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, fd); err != nil {
+		return imageFile{}, err
+	}
+	var ext struct {
+		BufExtension struct {
+			IsImport bool `json:"isImport"`
+		} `json:"bufExtension"`
+	}
+	if err := json.Unmarshal(raw, &ext); err != nil {
+		return imageFile{}, err
+	}
+	return imageFile{fd: fd, isImport: ext.BufExtension.IsImport}, nil
+}
+
+// decodeRemotePluginJSON decodes a JSON GenerateCodeRequest.
+func decodeRemotePluginJSON(data []byte) (*remotePluginRequest, error) {
+	var msg struct {
+		Image struct {
+			File []json.RawMessage `json:"file"`
+		} `json:"image"`
+		Requests []struct {
+			PluginReference       RemotePluginRef `json:"pluginReference"`
+			Parameters            []string        `json:"parameters"`
+			IncludeImports        *bool           `json:"includeImports"`
+			IncludeWellKnownTypes *bool           `json:"includeWellKnownTypes"`
+		} `json:"requests"`
+		IncludeImports        bool `json:"includeImports"`
+		IncludeWellKnownTypes bool `json:"includeWellKnownTypes"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	req := &remotePluginRequest{includeImports: msg.IncludeImports, includeWKT: msg.IncludeWellKnownTypes}
+	for _, raw := range msg.Image.File {
+		f, err := decodeJSONImageFile(raw)
+		if err != nil {
+			return nil, err
+		}
+		req.files = append(req.files, f)
+	}
+	for _, r := range msg.Requests {
+		req.plugins = append(req.plugins, remotePluginCall{
+			ref:            r.PluginReference,
+			parameters:     r.Parameters,
+			includeImports: r.IncludeImports,
+			includeWKT:     r.IncludeWellKnownTypes,
+		})
+	}
+	return req, nil
+}
+
+// encodeRemotePluginProto encodes a binary GenerateCodeResponse.
+func encodeRemotePluginProto(resps []*pluginpb.CodeGeneratorResponse) ([]byte, error) {
+	var out []byte
+	for _, resp := range resps {
+		data, err := proto.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		item := protowire.AppendTag(nil, pluginRespResponse, protowire.BytesType)
+		item = protowire.AppendBytes(item, data)
+		out = protowire.AppendTag(out, genRespResponses, protowire.BytesType)
+		out = protowire.AppendBytes(out, item)
+	}
+	return out, nil
+}
+
+// encodeRemotePluginJSON encodes a JSON GenerateCodeResponse.
+func encodeRemotePluginJSON(resps []*pluginpb.CodeGeneratorResponse) ([]byte, error) {
+	type pluginResponse struct {
+		Response json.RawMessage `json:"response"`
+	}
+	msg := struct {
+		Responses []pluginResponse `json:"responses"`
+	}{Responses: make([]pluginResponse, len(resps))}
+	for i, resp := range resps {
+		data, err := protojson.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		msg.Responses[i].Response = data
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
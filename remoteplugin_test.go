@@ -0,0 +1,206 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// newRemotePluginServer serves the embedded plugin as acme/prost.
+func newRemotePluginServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	t.Cleanup(func() { r.Close(ctx) })
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	reg := NewGeneratorRegistry()
+	if err := reg.Register("prost", Version, p); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewRemotePluginHandler(RegistryPluginResolver(reg, "acme")))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// protoGenerateCodeRequest encodes a GenerateCodeRequest running name on an
+// image of test.proto.
+func protoGenerateCodeRequest(t *testing.T, owner, name string) []byte {
+	t.Helper()
+	fd, err := proto.Marshal(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := protowire.AppendTag(nil, imageFileField, protowire.BytesType)
+	image = protowire.AppendBytes(image, fd)
+
+	var ref []byte
+	ref = protowire.AppendTag(ref, pluginRefOwner, protowire.BytesType)
+	ref = protowire.AppendString(ref, owner)
+	ref = protowire.AppendTag(ref, pluginRefName, protowire.BytesType)
+	ref = protowire.AppendString(ref, name)
+	call := protowire.AppendTag(nil, pluginReqReference, protowire.BytesType)
+	call = protowire.AppendBytes(call, ref)
+
+	req := protowire.AppendTag(nil, genReqImage, protowire.BytesType)
+	req = protowire.AppendBytes(req, image)
+	req = protowire.AppendTag(req, genReqRequests, protowire.BytesType)
+	return protowire.AppendBytes(req, call)
+}
+
+// decodeGenerateCodeResponse decodes the responses of a binary
+// GenerateCodeResponse.
+func decodeGenerateCodeResponse(t *testing.T, data []byte) []*pluginpb.CodeGeneratorResponse {
+	t.Helper()
+	var resps []*pluginpb.CodeGeneratorResponse
+	err := walkFields(data, func(num protowire.Number, v []byte, _ uint64) error {
+		if num != genRespResponses {
+			return nil
+		}
+		return walkFields(v, func(num protowire.Number, v []byte, _ uint64) error {
+			if num != pluginRespResponse {
+				return nil
+			}
+			resp := &pluginpb.CodeGeneratorResponse{}
+			resps = append(resps, resp)
+			return proto.Unmarshal(v, resp)
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resps
+}
+
+func postRemotePlugin(t *testing.T, srv *httptest.Server, contentType string, body []byte) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := http.Post(srv.URL+RemotePluginPath, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestRemotePluginHandler_Proto(t *testing.T) {
+	srv := newRemotePluginServer(t)
+	resp, data := postRemotePlugin(t, srv, "application/proto", protoGenerateCodeRequest(t, "acme", "prost"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s: %s", resp.Status, data)
+	}
+	resps := decodeGenerateCodeResponse(t, data)
+	if len(resps) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(resps))
+	}
+	if resps[0].Error != nil {
+		t.Fatalf("plugin returned error: %s", resps[0].GetError())
+	}
+	if len(resps[0].GetFile()) == 0 || resps[0].GetFile()[0].GetName() != "test/test.pb.rs" {
+		t.Fatalf("expected test/test.pb.rs, got %v", resps[0].GetFile())
+	}
+
+	// Plugins of other owners are not found.
+	resp, data = postRemotePlugin(t, srv, "application/proto", protoGenerateCodeRequest(t, "other", "prost"))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %s: %s", resp.Status, data)
+	}
+}
+
+func TestRemotePluginHandler_JSON(t *testing.T) {
+	srv := newRemotePluginServer(t)
+	body := `{"image": {"file": [
+	    {"name": "test.proto", "package": "test", "syntax": "proto3"},
+	    {"name": "dep.proto", "package": "dep", "syntax": "proto3", "bufExtension": {"isImport": true}}]},
+	  "requests": [{"pluginReference": {"owner": "acme", "name": "prost", "version": "` + Version + `"}}]}`
+	resp, data := postRemotePlugin(t, srv, "application/json", []byte(body))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s: %s", resp.Status, data)
+	}
+	var msg struct {
+		Responses []struct {
+			Response json.RawMessage `json:"response"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg.Responses) != 1 {
+		t.Fatalf("unexpected response %s: %v", data, err)
+	}
+	gen := &pluginpb.CodeGeneratorResponse{}
+	if err := protojson.Unmarshal(msg.Responses[0].Response, gen); err != nil {
+		t.Fatal(err)
+	}
+	if gen.Error != nil {
+		t.Fatalf("plugin returned error: %s", gen.GetError())
+	}
+	// Imports are not generated.
+	if len(gen.GetFile()) != 1 || gen.GetFile()[0].GetName() != "test/test.pb.rs" {
+		t.Fatalf("expected only test/test.pb.rs, got %v", gen.GetFile())
+	}
+}
+
+func TestRemotePluginHandler_Errors(t *testing.T) {
+	srv := newRemotePluginServer(t)
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{"text/plain", "", http.StatusUnsupportedMediaType, "unknown"},
+		{"application/proto", "\xff", http.StatusBadRequest, "invalid_argument"},
+		{"application/json", `{"requests": [{"pluginReference": {"owner": "acme", "name": "tonic"}}]}`, http.StatusNotFound, "not_found"},
+	} {
+		resp, data := postRemotePlugin(t, srv, tc.contentType, []byte(tc.body))
+		var connectErr struct{ Code string }
+		if err := json.Unmarshal(data, &connectErr); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status || connectErr.Code != tc.code {
+			t.Fatalf("%s %q: expected %d %s, got %s: %s", tc.contentType, tc.body, tc.status, tc.code, resp.Status, data)
+		}
+	}
+}
+
+func TestImageRequest(t *testing.T) {
+	files := []imageFile{
+		{fd: &descriptorpb.FileDescriptorProto{Name: proto.String("a.proto")}},
+		{fd: &descriptorpb.FileDescriptorProto{Name: proto.String("dep.proto")}, isImport: true},
+		{fd: &descriptorpb.FileDescriptorProto{Name: proto.String("google/protobuf/empty.proto")}, isImport: true},
+	}
+	for _, tc := range []struct {
+		imports, wkt bool
+		want         int
+	}{{false, false, 1}, {true, false, 2}, {true, true, 3}} {
+		req := imageRequest(files, []string{"a", "b=c"}, tc.imports, tc.wkt)
+		if len(req.GetFileToGenerate()) != tc.want || len(req.GetProtoFile()) != 3 {
+			t.Fatalf("imports=%v wkt=%v: unexpected files %v", tc.imports, tc.wkt, req.GetFileToGenerate())
+		}
+		if req.GetParameter() != "a,b=c" {
+			t.Fatalf("unexpected parameter %q", req.GetParameter())
+		}
+	}
+	if _, err := RegistryPluginResolver(NewGeneratorRegistry(), "acme")(RemotePluginRef{Owner: "acme", Name: "x"}); !errors.Is(err, ErrGeneratorNotFound) {
+		t.Fatalf("expected ErrGeneratorNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package prost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Report summarizes a generation run.
+type Report struct {
+	// PluginVersion is the protoc-gen-prost version.
+	PluginVersion string `json:"pluginVersion"`
+	// Duration is the time spent generating.
+	Duration time.Duration `json:"durationNs"`
+	// Files contains statistics per proto file to generate.
+	Files []ReportFile `json:"files"`
+	// Outputs contains the size of each generated file.
+	Outputs []ReportOutput `json:"outputs"`
+	// TotalBytes is the sum of all generated file sizes.
+	TotalBytes int `json:"totalBytes"`
+}
+
+// ReportFile contains statistics for a proto file.
+type ReportFile struct {
+	// Name is the proto file name.
+	Name string `json:"name"`
+	// Package is the proto package.
+	Package string `json:"package,omitempty"`
+	// Messages is the number of messages including nested messages.
+	Messages int `json:"messages"`
+	// Enums is the number of enums including nested enums.
+	Enums int `json:"enums"`
+	// Services is the number of services.
+	Services int `json:"services"`
+}
+
+// ReportOutput contains statistics for a generated file.
+type ReportOutput struct {
+	// Name is the generated file name.
+	Name string `json:"name"`
+	// Bytes is the size of the generated content.
+	Bytes int `json:"bytes"`
+}
+
+// NewReport builds a report for a request and its response.
+func NewReport(req *pluginpb.CodeGeneratorRequest, resp *pluginpb.CodeGeneratorResponse, duration time.Duration) *Report {
+	r := &Report{
+		PluginVersion: Version,
+		Duration:      duration,
+	}
+
+	for _, fd := range FilesToGenerate(req) {
+		files := []*descriptorpb.FileDescriptorProto{fd}
+		r.Files = append(r.Files, ReportFile{
+			Name:     fd.GetName(),
+			Package:  fd.GetPackage(),
+			Messages: len(ListMessages(files)),
+			Enums:    len(ListEnums(files)),
+			Services: len(ListServices(files)),
+		})
+	}
+
+	outputs := make(map[string]int)
+	for _, f := range resp.GetFile() {
+		name := f.GetName()
+		if _, ok := outputs[name]; !ok {
+			r.Outputs = append(r.Outputs, ReportOutput{Name: name})
+		}
+		outputs[name] += len(f.GetContent())
+		r.TotalBytes += len(f.GetContent())
+	}
+	for i := range r.Outputs {
+		r.Outputs[i].Bytes = outputs[r.Outputs[i].Name]
+	}
+	return r
+}
+
+// WriteTable writes the report as human-readable tables.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FILE\tPACKAGE\tMESSAGES\tENUMS\tSERVICES\n")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", f.Name, f.Package, f.Messages, f.Enums, f.Services)
+	}
+	fmt.Fprintf(tw, "\nOUTPUT\tBYTES\n")
+	for _, o := range r.Outputs {
+		fmt.Fprintf(tw, "%s\t%d\n", o.Name, o.Bytes)
+	}
+	fmt.Fprintf(tw, "\nTOTAL\t%d bytes in %s (%s)\n", r.TotalBytes, r.Duration, r.PluginVersion)
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ExecuteWithReport runs ExecuteRequest and builds a Report for the result.
+func (p *ProtocGenProst) ExecuteWithReport(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, *Report, error) {
+	start := time.Now()
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, NewReport(req, resp, time.Since(start)), nil
+}
@@ -0,0 +1,49 @@
+package prost
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestNewReport(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("a.proto"),
+			Package: proto.String("a"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name:       proto.String("Outer"),
+				NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Inner")}},
+				EnumType:   []*descriptorpb.EnumDescriptorProto{{Name: proto.String("Kind")}},
+			}},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{Name: proto.String("Status")}},
+			Service:  []*descriptorpb.ServiceDescriptorProto{{Name: proto.String("Svc")}},
+		}},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{{Name: proto.String("a.pb.rs"), Content: proto.String("0123456789")}},
+	}
+
+	r := NewReport(req, resp, time.Second)
+	want := ReportFile{Name: "a.proto", Package: "a", Messages: 2, Enums: 2, Services: 1}
+	if len(r.Files) != 1 || r.Files[0] != want {
+		t.Fatalf("unexpected files: %+v", r.Files)
+	}
+	if r.TotalBytes != 10 || len(r.Outputs) != 1 || r.Outputs[0].Bytes != 10 {
+		t.Fatalf("unexpected outputs: %+v", r.Outputs)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteTable(&buf); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(buf.String(), "a.pb.rs") {
+		t.Fatalf("table missing output:\n%s", buf.String())
+	}
+}
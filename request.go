@@ -0,0 +1,57 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// processRequest applies the request options to a serialized request.
+// Returns input unchanged if no request options are configured or none
+// modified the request.
+func (p *ProtocGenProst) processRequest(ctx context.Context, input []byte) ([]byte, error) {
+	if !p.opts.hasRequestOptions() {
+		return input, nil
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(input, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+	modified := false
+	if p.opts.defaultParams != "" {
+		if param := MergeParameters(p.opts.defaultParams, req.GetParameter()); param != req.GetParameter() {
+			req.Parameter = proto.String(param)
+			modified = true
+		}
+	}
+	if p.opts.injectWKT {
+		modified = len(InjectWellKnownTypes(req)) != 0 || modified
+	}
+	if p.opts.prune {
+		sized := proto.Size(req)
+		PruneRequest(req)
+		modified = modified || proto.Size(req) != sized
+	}
+	if p.opts.checkCollisions {
+		if err := CheckCollisions(req); err != nil {
+			return nil, err
+		}
+	}
+	if p.opts.checkSyntax {
+		features := func() (*Features, error) { return p.syntaxFeatures(ctx) }
+		if err := checkSyntax(req, features); err != nil {
+			return nil, err
+		}
+	}
+	if !modified {
+		return input, nil
+	}
+	out, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,36 @@
+package prost
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// processResponse applies the response options to a serialized response.
+// Appends the processed response to dst. output may alias the capacity of dst:
+// it is fully decoded before dst is written.
+func (p *ProtocGenProst) processResponse(dst, output []byte) ([]byte, error) {
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if p.opts.fileFilter != nil {
+		FilterFiles(resp, p.opts.fileFilter)
+	}
+	if p.opts.sortResponse {
+		SortResponse(resp)
+	}
+	return proto.MarshalOptions{Deterministic: true}.MarshalAppend(dst, resp)
+}
+
+// SortResponse sorts the response files by name.
+//
+// The sort is stable, so insertion points stay after the file they target
+// and keep their relative order.
+func SortResponse(resp *pluginpb.CodeGeneratorResponse) {
+	sort.SliceStable(resp.File, func(i, j int) bool {
+		return resp.File[i].GetName() < resp.File[j].GetName()
+	})
+}
@@ -0,0 +1,32 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestSortResponse(t *testing.T) {
+	file := func(name, point string) *pluginpb.CodeGeneratorResponse_File {
+		f := &pluginpb.CodeGeneratorResponse_File{Name: proto.String(name)}
+		if point != "" {
+			f.InsertionPoint = proto.String(point)
+		}
+		return f
+	}
+	resp := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		file("b.rs", ""),
+		file("b.rs", "second"),
+		file("a.rs", ""),
+		file("b.rs", "third"),
+	}}
+	SortResponse(resp)
+
+	want := []string{"a.rs:", "b.rs:", "b.rs:second", "b.rs:third"}
+	for i, f := range resp.GetFile() {
+		if got := f.GetName() + ":" + f.GetInsertionPoint(); got != want[i] {
+			t.Errorf("file %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/diff"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DiffResponses returns a unified diff of the files generated in oldResp and
+// newResp, e.g. by two versions of the plugin for the same request.
+// Returns an empty string if they generate the same files.
+//
+// Files are compared with insertion points applied. Added and removed files
+// are diffed against /dev/null. A plugin error is diffed as a file named
+// "ERROR".
+func DiffResponses(oldResp, newResp *pluginpb.CodeGeneratorResponse) (string, error) {
+	oldFiles, err := responseContents(oldResp)
+	if err != nil {
+		return "", err
+	}
+	newFiles, err := responseContents(newResp)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		oldContent, inOld := oldFiles[name]
+		newContent, inNew := newFiles[name]
+		oldName, newName := "a/"+name, "b/"+name
+		if !inOld {
+			oldName = "/dev/null"
+		}
+		if !inNew {
+			newName = "/dev/null"
+		}
+		sb.WriteString(diff.Unified(oldName, newName, oldContent, newContent))
+	}
+	return sb.String(), nil
+}
+
+// responseContents maps the files of a response to their content.
+// A plugin error is included as a file named "ERROR".
+func responseContents(resp *pluginpb.CodeGeneratorResponse) (map[string]string, error) {
+	m := make(map[string]string)
+	if msg := resp.GetError(); msg != "" {
+		m["ERROR"] = msg + "\n"
+		return m, nil
+	}
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		m[f.Name] = f.Content
+	}
+	return m, nil
+}
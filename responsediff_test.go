@@ -0,0 +1,49 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestDiffResponses(t *testing.T) {
+	oldResp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a.rs"), Content: proto.String("one\ntwo\n")},
+			{Name: proto.String("gone.rs"), Content: proto.String("x\n")},
+		},
+	}
+	newResp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a.rs"), Content: proto.String("one\nthree\n")},
+			{Name: proto.String("new.rs"), Content: proto.String("y\n")},
+		},
+	}
+	got, err := DiffResponses(oldResp, newResp)
+	if err != nil {
+		t.Fatalf("DiffResponses failed: %v", err)
+	}
+	want := `--- a/a.rs
++++ b/a.rs
+@@ -1,2 +1,2 @@
+ one
+-two
++three
+--- a/gone.rs
++++ /dev/null
+@@ -1 +0,0 @@
+-x
+--- /dev/null
++++ b/new.rs
+@@ -0,0 +1 @@
++y
+`
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+
+	if got, _ := DiffResponses(oldResp, oldResp); got != "" {
+		t.Fatalf("expected empty diff, got:\n%s", got)
+	}
+}
@@ -0,0 +1,66 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// shouldRetry checks if the failed attempt should be retried.
+func (p *ProtocGenProst) shouldRetry(err error, attempt int) bool {
+	if attempt >= p.opts.retries || ctxDone(err) {
+		return false
+	}
+	var trapErr *TrapError
+	return errors.As(err, &trapErr) && !isInterruptExit(err)
+}
+
+// maxRetryBackoff caps the delay between retries.
+const maxRetryBackoff = time.Minute
+
+// retryDelay returns the delay before the retry following attempt, doubling
+// from backoff up to maxRetryBackoff.
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	delay := backoff
+	for range attempt {
+		if delay >= maxRetryBackoff/2 {
+			return maxRetryBackoff
+		}
+		delay <<= 1
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// retryBackoff waits before the retry following attempt.
+func (p *ProtocGenProst) retryBackoff(ctx context.Context, attempt int) error {
+	delay := retryDelay(p.opts.retryBackoff, attempt)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ctxDone checks if err was caused by context cancellation or deadline.
+func ctxDone(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		return code == sys.ExitCodeContextCanceled || code == sys.ExitCodeDeadlineExceeded
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestProtocGenProst_ShouldRetry(t *testing.T) {
+	p := &ProtocGenProst{opts: newOptions([]Option{WithRetry(2, 0)})}
+	trap := &TrapError{Function: ExportProstExecute, Err: errors.New("unreachable")}
+
+	cases := []struct {
+		name    string
+		err     error
+		attempt int
+		want    bool
+	}{
+		{"trap", trap, 0, true},
+		{"trap last attempt", trap, 2, false},
+		{"plain error", errors.New("cache get failed"), 0, false},
+		{"interrupt", &TrapError{Function: ExportProstExecute, Err: sys.NewExitError(InterruptExitCode)}, 0, false},
+		{"canceled", &TrapError{Function: ExportProstExecute, Err: context.Canceled}, 0, false},
+	}
+	for _, c := range cases {
+		if got := p.shouldRetry(c.err, c.attempt); got != c.want {
+			t.Errorf("%s: shouldRetry() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		backoff time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{0, 3, 0},
+		{time.Millisecond, 0, time.Millisecond},
+		{time.Millisecond, 3, 8 * time.Millisecond},
+		{time.Second, 10, maxRetryBackoff},
+		// Shifts past the width of time.Duration must not wrap around.
+		{time.Millisecond, 64, maxRetryBackoff},
+		{time.Duration(1) << 62, 1, maxRetryBackoff},
+	}
+	for _, c := range cases {
+		if got := retryDelay(c.backoff, c.attempt); got != c.want {
+			t.Errorf("retryDelay(%v, %d) = %v, want %v", c.backoff, c.attempt, got, c.want)
+		}
+	}
+}
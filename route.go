@@ -0,0 +1,121 @@
+package prost
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Route sends the files generated for matching proto packages to their own
+// output root.
+type Route struct {
+	// Package selects proto packages, with the syntax of PathRule.Package.
+	Package string `yaml:"package" json:"package"`
+	// Out is the output root of the matching files.
+	Out string `yaml:"out" json:"out"`
+	// TrimPrefix is removed from the start of the file names.
+	TrimPrefix string `yaml:"trim_prefix,omitempty" json:"trimPrefix,omitempty"`
+}
+
+// Routes is an ordered list of routes. The first matching route wins.
+type Routes []Route
+
+// RoutedResponse is the part of a response written to one output root.
+type RoutedResponse struct {
+	// Dir is the output root.
+	Dir string
+	// Response holds the files of the root with insertion points applied.
+	Response *pluginpb.CodeGeneratorResponse
+}
+
+// RouteResponse splits resp into one response per output root.
+//
+// Files are routed by the proto package resolved from req. Files matching no
+// route go to defaultDir, or cause an error if defaultDir is empty. Roots are
+// returned in the order of routes, followed by defaultDir. Each response can
+// be written with WriteResponse, so every root gets its own marker file and
+// stale file removal.
+func RouteResponse(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, routes Routes, defaultDir string) ([]RoutedResponse, error) {
+	if msg := resp.GetError(); msg != "" {
+		return nil, &PluginError{Message: msg}
+	}
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := OutputPackages(req)
+	byDir := make(map[string]*pluginpb.CodeGeneratorResponse)
+	add := func(dir, name, content string) {
+		out := byDir[dir]
+		if out == nil {
+			out = &pluginpb.CodeGeneratorResponse{SupportedFeatures: resp.SupportedFeatures}
+			byDir[dir] = out
+		}
+		out.File = append(out.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(content),
+		})
+	}
+	for _, f := range files {
+		route := routes.match(pkgs[f.Name])
+		switch {
+		case route != nil:
+			add(route.Out, path.Clean(strings.TrimPrefix(f.Name, route.TrimPrefix)), f.Content)
+		case defaultDir != "":
+			add(defaultDir, f.Name, f.Content)
+		default:
+			return nil, fmt.Errorf("%s: no route matches package %q", f.Name, pkgs[f.Name])
+		}
+	}
+	var dirs []string
+	for _, r := range routes {
+		if !slices.Contains(dirs, r.Out) {
+			dirs = append(dirs, r.Out)
+		}
+	}
+	if !slices.Contains(dirs, defaultDir) {
+		dirs = append(dirs, defaultDir)
+	}
+	out := make([]RoutedResponse, 0, len(dirs))
+	for _, dir := range dirs {
+		if resp := byDir[dir]; resp != nil {
+			out = append(out, RoutedResponse{Dir: dir, Response: resp})
+		}
+	}
+	return out, nil
+}
+
+// WriteRouted writes resp split by RouteResponse. opts applies to every root
+// after routing; opts.Request is set to req.
+func WriteRouted(resp *pluginpb.CodeGeneratorResponse, req *pluginpb.CodeGeneratorRequest, routes Routes, defaultDir string, opts *WriteOptions) error {
+	routed, err := RouteResponse(resp, req, routes, defaultDir)
+	if err != nil {
+		return err
+	}
+	rootOpts := WriteOptions{}
+	if opts != nil {
+		rootOpts = *opts
+	}
+	rootOpts.Request = req
+	for _, r := range routed {
+		if err := WriteResponse(r.Dir, r.Response, &rootOpts); err != nil {
+			return fmt.Errorf("%s: %w", r.Dir, err)
+		}
+	}
+	return nil
+}
+
+// match returns the first route matching pkg.
+func (rs Routes) match(pkg string) *Route {
+	for i := range rs {
+		if matchPackage(rs[i].Package, pkg) {
+			return &rs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package prost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWriteRouted(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"internal/db.proto", "api/v1/api.proto", "misc.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("internal/db.proto"), Package: proto.String("acme.internal")},
+			{Name: proto.String("api/v1/api.proto"), Package: proto.String("acme.api.v1")},
+			{Name: proto.String("misc.proto"), Package: proto.String("misc")},
+		},
+	}
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("acme/internal/db.pb.rs"), Content: proto.String("// db\n")},
+			{Name: proto.String("acme/api/v1/api.pb.rs"), Content: proto.String("// api\n")},
+			{Name: proto.String("misc/misc.pb.rs"), Content: proto.String("// misc\n")},
+		},
+	}
+	root := t.TempDir()
+	internal, api, gen := filepath.Join(root, "internal"), filepath.Join(root, "api"), filepath.Join(root, "gen")
+	routes := Routes{
+		{Package: "acme.internal.*", Out: internal},
+		{Package: "acme.api.*", Out: api, TrimPrefix: "acme/api/"},
+	}
+	if err := WriteRouted(resp, req, routes, gen, nil); err != nil {
+		t.Fatalf("WriteRouted failed: %v", err)
+	}
+	for _, name := range []string{
+		filepath.Join(internal, "acme", "internal", "db.pb.rs"),
+		filepath.Join(internal, MarkerFilename),
+		filepath.Join(api, "v1", "api.pb.rs"),
+		filepath.Join(gen, "misc", "misc.pb.rs"),
+	} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	if _, err := RouteResponse(resp, req, routes, ""); err == nil {
+		t.Fatal("expected error for unrouted file without default")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultConfigFilename)
+	data := "out: gen\nroutes:\n  - package: acme.api.*\n    out: crates/api/src\n    trim_prefix: acme/api/\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Out != filepath.Join(dir, "gen") || len(cfg.Routes) != 1 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if r := cfg.Routes[0]; r.Out != filepath.Join(dir, "crates", "api", "src") || r.TrimPrefix != "acme/api/" {
+		t.Fatalf("unexpected route %+v", r)
+	}
+
+	if err := os.WriteFile(path, []byte("outt: gen\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
@@ -0,0 +1,116 @@
+package prost
+
+import (
+	"sort"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Capabilities denied by WithHardenedSandbox.
+const (
+	CapabilityClock      = "clock"
+	CapabilityRandom     = "random"
+	CapabilityFilesystem = "filesystem"
+	CapabilityNetwork    = "network"
+)
+
+// SandboxError is returned when a guest requires a capability denied by
+// WithHardenedSandbox.
+type SandboxError struct {
+	// Capability is the denied capability, e.g. CapabilityClock.
+	Capability string
+	// Function is the WASI function requiring it.
+	Function string
+}
+
+// Error returns the error message.
+func (e *SandboxError) Error() string {
+	return "hardened sandbox: guest requires " + e.Capability + " access (" + e.Function + ")"
+}
+
+// sandboxedImports maps WASI functions to the capability they require.
+//
+// random_get and the environ functions are not listed: language runtimes
+// import them unconditionally, so they are denied at call time instead.
+var sandboxedImports = map[string]string{
+	"clock_res_get":  CapabilityClock,
+	"clock_time_get": CapabilityClock,
+	"poll_oneoff":    CapabilityClock,
+
+	"fd_advise":               CapabilityFilesystem,
+	"fd_allocate":             CapabilityFilesystem,
+	"fd_datasync":             CapabilityFilesystem,
+	"fd_filestat_get":         CapabilityFilesystem,
+	"fd_filestat_set_size":    CapabilityFilesystem,
+	"fd_filestat_set_times":   CapabilityFilesystem,
+	"fd_pread":                CapabilityFilesystem,
+	"fd_prestat_dir_name":     CapabilityFilesystem,
+	"fd_prestat_get":          CapabilityFilesystem,
+	"fd_pwrite":               CapabilityFilesystem,
+	"fd_readdir":              CapabilityFilesystem,
+	"fd_renumber":             CapabilityFilesystem,
+	"fd_sync":                 CapabilityFilesystem,
+	"path_create_directory":   CapabilityFilesystem,
+	"path_filestat_get":       CapabilityFilesystem,
+	"path_filestat_set_times": CapabilityFilesystem,
+	"path_link":               CapabilityFilesystem,
+	"path_open":               CapabilityFilesystem,
+	"path_readlink":           CapabilityFilesystem,
+	"path_remove_directory":   CapabilityFilesystem,
+	"path_rename":             CapabilityFilesystem,
+	"path_symlink":            CapabilityFilesystem,
+	"path_unlink_file":        CapabilityFilesystem,
+
+	"sock_accept":   CapabilityNetwork,
+	"sock_recv":     CapabilityNetwork,
+	"sock_send":     CapabilityNetwork,
+	"sock_shutdown": CapabilityNetwork,
+}
+
+// checkSandboxImports returns a *SandboxError for the first WASI import of
+// compiled that requires a capability denied by the hardened sandbox.
+func checkSandboxImports(compiled wazero.CompiledModule) error {
+	var names []string
+	for _, def := range compiled.ImportedFunctions() {
+		module, name, _ := def.Import()
+		if module == wasi_snapshot_preview1.ModuleName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if capability, ok := sandboxedImports[name]; ok {
+			return &SandboxError{Capability: capability, Function: name}
+		}
+	}
+	return nil
+}
+
+// sandboxModuleConfig denies the clock and random capabilities of cfg.
+//
+// The default module config already has no filesystem and an empty
+// environment. Clock and random sources trap with a *SandboxError instead of
+// returning wazero's deterministic fakes, so a guest depending on them fails
+// loudly rather than silently producing different output.
+func sandboxModuleConfig(cfg wazero.ModuleConfig) wazero.ModuleConfig {
+	return cfg.
+		WithRandSource(deniedReader{}).
+		WithWalltime(func() (int64, int32) {
+			panic(&SandboxError{Capability: CapabilityClock, Function: "clock_time_get"})
+		}, 1).
+		WithNanotime(func() int64 {
+			panic(&SandboxError{Capability: CapabilityClock, Function: "clock_time_get"})
+		}, 1).
+		WithNanosleep(func(int64) {
+			panic(&SandboxError{Capability: CapabilityClock, Function: "poll_oneoff"})
+		})
+}
+
+// deniedReader is a random source trapping on use.
+type deniedReader struct{}
+
+// Read panics with a *SandboxError, which the runtime turns into a trap.
+func (deniedReader) Read([]byte) (int, error) {
+	panic(&SandboxError{Capability: CapabilityRandom, Function: "random_get"})
+}
@@ -0,0 +1,54 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestHardenedSandbox_DeniesRandom(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// The embedded module only imports random_get and environ, so it is
+	// accepted, but seeding its hash maps traps.
+	p, err := NewProtocGenProst(ctx, r, WithHardenedSandbox())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	_, err = p.Execute(ctx, minimalRequestInput(t))
+	var sandboxErr *SandboxError
+	if !errors.As(err, &sandboxErr) {
+		t.Fatalf("expected SandboxError, got %v", err)
+	}
+	if sandboxErr.Capability != CapabilityRandom || sandboxErr.Function != "random_get" {
+		t.Fatalf("unexpected sandbox error: %v", sandboxErr)
+	}
+}
+
+func TestHardenedSandbox_RejectsClockImport(t *testing.T) {
+	wasm := buildCommandPlugin(t)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+
+	// The Go runtime imports the WASI clock.
+	_, err = NewProtocGenProstWithModule(ctx, r, compiled, WithHardenedSandbox())
+	var sandboxErr *SandboxError
+	if !errors.As(err, &sandboxErr) {
+		t.Fatalf("expected SandboxError, got %v", err)
+	}
+	if sandboxErr.Capability != CapabilityClock {
+		t.Fatalf("unexpected sandbox error: %v", sandboxErr)
+	}
+}
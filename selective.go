@@ -0,0 +1,80 @@
+package prost
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ReverseImportGraph maps each file of an import graph to the files importing it.
+func ReverseImportGraph(graph map[string][]string) map[string][]string {
+	reverse := make(map[string][]string, len(graph))
+	for name, imports := range graph {
+		for _, imp := range imports {
+			reverse[imp] = append(reverse[imp], name)
+		}
+	}
+	for _, importers := range reverse {
+		sort.Strings(importers)
+	}
+	return reverse
+}
+
+// AffectedPackages returns the packages of the files to generate whose output
+// may change if the changed proto files change, sorted by name.
+//
+// A package is affected if one of its files is changed or imports a changed
+// file, directly or transitively: generated code depends on imported types,
+// e.g. for derived traits and boxing. Since protoc-gen-prost writes one file
+// per package, the whole package must be regenerated.
+func AffectedPackages(req *pluginpb.CodeGeneratorRequest, changed []string) []string {
+	dependents := TransitiveImports(ReverseImportGraph(ImportGraph(req)), changed)
+	byName := FilesByName(req)
+	generate := make(map[string]struct{}, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		generate[name] = struct{}{}
+	}
+	seen := make(map[string]struct{})
+	var pkgs []string
+	for _, name := range dependents {
+		if _, ok := generate[name]; !ok {
+			continue
+		}
+		pkg := byName[name].GetPackage()
+		if _, ok := seen[pkg]; ok {
+			continue
+		}
+		seen[pkg] = struct{}{}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// SelectChanged returns a copy of req generating only the packages affected by
+// the changed proto files, see AffectedPackages. Returns nil if no package is
+// affected.
+//
+// The response of the returned request only contains the affected packages:
+// write it without WriteOptions.RemoveStale, which would remove the rest.
+func SelectChanged(req *pluginpb.CodeGeneratorRequest, changed []string) *pluginpb.CodeGeneratorRequest {
+	pkgs := AffectedPackages(req, changed)
+	if len(pkgs) == 0 {
+		return nil
+	}
+	affected := make(map[string]struct{}, len(pkgs))
+	for _, pkg := range pkgs {
+		affected[pkg] = struct{}{}
+	}
+
+	out := proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	out.FileToGenerate = nil
+	byName := FilesByName(req)
+	for _, name := range req.GetFileToGenerate() {
+		if _, ok := affected[byName[name].GetPackage()]; ok {
+			out.FileToGenerate = append(out.FileToGenerate, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package prost
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestSelectChanged(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"common/money.proto", "common/time.proto", "billing/invoice.proto", "users/user.proto"},
+		Parameter:      proto.String("btree_map=."),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("common/money.proto"), Package: proto.String("common")},
+			{Name: proto.String("common/time.proto"), Package: proto.String("common")},
+			{Name: proto.String("billing/invoice.proto"), Package: proto.String("billing"), Dependency: []string{"common/money.proto"}},
+			{Name: proto.String("users/user.proto"), Package: proto.String("users"), Dependency: []string{"common/time.proto"}},
+		},
+	}
+
+	if got := AffectedPackages(req, []string{"common/money.proto"}); !reflect.DeepEqual(got, []string{"billing", "common"}) {
+		t.Fatalf("unexpected affected packages: %v", got)
+	}
+	if got := AffectedPackages(req, []string{"users/user.proto"}); !reflect.DeepEqual(got, []string{"users"}) {
+		t.Fatalf("unexpected affected packages: %v", got)
+	}
+
+	sel := SelectChanged(req, []string{"billing/invoice.proto"})
+	if sel == nil || !reflect.DeepEqual(sel.GetFileToGenerate(), []string{"billing/invoice.proto"}) {
+		t.Fatalf("unexpected selection: %v", sel.GetFileToGenerate())
+	}
+	if sel.GetParameter() != "btree_map=." || len(sel.GetProtoFile()) != 4 {
+		t.Fatal("expected parameter and proto files to be kept")
+	}
+	if len(req.GetFileToGenerate()) != 4 {
+		t.Fatal("request was modified")
+	}
+	if SelectChanged(req, []string{"other.proto"}) != nil {
+		t.Fatal("expected nil for unrelated change")
+	}
+}
@@ -0,0 +1,137 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ErrSessionUnsupported is returned by GenerateFiles if the module does not
+// export the per-file session functions.
+var ErrSessionUnsupported = errors.New("per-file generation not supported by module")
+
+// FileResponseFunc receives the response generated for one proto file.
+type FileResponseFunc func(file string, resp *pluginpb.CodeGeneratorResponse) error
+
+// HasSession returns true if the module exports the per-file session
+// functions (see lowlevel.ExportSessionBegin).
+func (p *ProtocGenProst) HasSession() bool {
+	return p.ll != nil && p.ll.HasSession()
+}
+
+// GenerateFiles generates the files of req one at a time with the per-file
+// session ABI, instead of passing the whole request to prost_execute.
+//
+// The proto files are passed to the guest in request order, which protoc
+// guarantees to be dependency order. Files not listed in file_to_generate
+// are only added as dependencies. fn is called with the response of each
+// generated file as soon as it is available, so large requests can be
+// streamed into later pipeline stages, and per-file responses can be cached
+// independently. Stops at the first error, including one returned by fn.
+//
+// Returns ErrSessionUnsupported if HasSession is false. Cache and response
+// options do not apply.
+func (p *ProtocGenProst) GenerateFiles(ctx context.Context, req *pluginpb.CodeGeneratorRequest, fn FileResponseFunc) error {
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx = p.hostContext(ctx)
+	if p.command {
+		return ErrSessionUnsupported
+	}
+	if p.mod.IsClosed() || p.recycleDueLocked() {
+		p.mod.Close(ctx)
+		if err := p.instantiate(ctx); err != nil {
+			return fmt.Errorf("failed to re-instantiate module: %w", err)
+		}
+	}
+	if !p.ll.HasSession() {
+		return ErrSessionUnsupported
+	}
+
+	err := p.generateFilesLocked(ctx, req, fn)
+	p.runs++
+	if err != nil && isInterruptExit(err) {
+		return ErrInterrupted
+	}
+	// Discard the instance after a trap, like Execute
+	var trapErr *TrapError
+	if errors.As(err, &trapErr) {
+		p.mod.Close(ctx)
+	}
+	return err
+}
+
+// generateFilesLocked runs a session for req. Must be called with mu held.
+func (p *ProtocGenProst) generateFilesLocked(ctx context.Context, req *pluginpb.CodeGeneratorRequest, fn FileResponseFunc) error {
+	if err := p.ll.SessionBegin(ctx, []byte(req.GetParameter())); err != nil {
+		return p.sessionError(lowlevel.ExportSessionBegin, err)
+	}
+	defer p.ll.SessionEnd(ctx)
+
+	generate := make(map[string]bool, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		generate[name] = true
+	}
+	for _, fd := range req.GetProtoFile() {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fd)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", fd.GetName(), err)
+		}
+		if !generate[fd.GetName()] {
+			if err := p.ll.SessionAdd(ctx, data); err != nil {
+				return p.sessionError(lowlevel.ExportSessionAdd, err)
+			}
+			continue
+		}
+
+		resp, err := p.sessionGenerate(ctx, data)
+		if err != nil {
+			return err
+		}
+		if err := fn(fd.GetName(), resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionGenerate generates one serialized file and decodes the response.
+func (p *ProtocGenProst) sessionGenerate(ctx context.Context, file []byte) (*pluginpb.CodeGeneratorResponse, error) {
+	n, err := p.ll.SessionGenerate(ctx, file)
+	if err != nil {
+		return nil, p.sessionError(lowlevel.ExportSessionGenerate, err)
+	}
+	output, err := p.ll.ReadOutput(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	err = proto.Unmarshal(output, resp)
+	if cerr := p.ll.ClearOutput(ctx); cerr != nil {
+		return nil, newTrapError(p.ll.Names().ClearOutput, cerr, p.ll.Memory())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp, nil
+}
+
+// sessionError wraps runtime failures of a session function in a *TrapError.
+// Status codes reported by the guest are returned unchanged.
+func (p *ProtocGenProst) sessionError(function string, err error) error {
+	var execErr *ExecuteError
+	if errors.As(err, &execErr) {
+		return err
+	}
+	return newTrapError(function, err, p.ll.Memory())
+}
@@ -0,0 +1,126 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// abiStubModule describes a module implementing the ABI with one page of
+// memory, followed by extra. prost_malloc always returns 1024. Globals 0 and
+// 1 hold the output pointer and length, and prost_execute returns status.
+func abiStubModule(status int32, extra ...wasmtest.Func) *wasmtest.Module {
+	i32 := []byte{wasmtest.I32}
+	funcs := []wasmtest.Func{
+		{Name: lowlevel.ExportMalloc, Params: i32, Results: i32, Code: wasmtest.I32Const(1024)},
+		{Name: lowlevel.ExportFree, Params: []byte{wasmtest.I32, wasmtest.I32}},
+		{Name: lowlevel.ExportExecute, Params: []byte{wasmtest.I32, wasmtest.I32}, Results: i32, Code: wasmtest.I32Const(status)},
+		{Name: lowlevel.ExportGetOutputPtr, Results: i32, Code: wasmtest.GlobalGet(0)},
+		{Name: lowlevel.ExportGetOutputLen, Results: i32, Code: wasmtest.GlobalGet(1)},
+		{Name: lowlevel.ExportClearOutput, Code: wasmtest.Code(wasmtest.I32Const(0), wasmtest.GlobalSet(1))},
+	}
+	return &wasmtest.Module{
+		Funcs:       append(funcs, extra...),
+		MemoryPages: 1,
+		Globals:     2,
+	}
+}
+
+// sessionStubModule encodes a module implementing the ABI and the session
+// exports. prost_session_generate echoes its input as output, so a
+// FileDescriptorProto with only a name decodes as a response with that name
+// as the error.
+func sessionStubModule() []byte {
+	i32x2 := []byte{wasmtest.I32, wasmtest.I32}
+	i32 := []byte{wasmtest.I32}
+	return abiStubModule(0,
+		wasmtest.Func{Name: lowlevel.ExportSessionBegin, Params: i32x2, Results: i32, Code: wasmtest.I32Const(0)},
+		wasmtest.Func{Name: lowlevel.ExportSessionAdd, Params: i32x2, Results: i32, Code: wasmtest.I32Const(0)},
+		wasmtest.Func{Name: lowlevel.ExportSessionGenerate, Params: i32x2, Results: i32, Code: wasmtest.Code(
+			wasmtest.LocalGet(0), wasmtest.GlobalSet(0),
+			wasmtest.LocalGet(1), wasmtest.GlobalSet(1),
+			wasmtest.LocalGet(1),
+		)},
+		wasmtest.Func{Name: lowlevel.ExportSessionEnd},
+	).Encode()
+}
+
+func TestGenerateFiles(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, sessionStubModule())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+	if !p.HasSession() {
+		t.Fatal("expected session support")
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"b.proto", "c.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("a.proto")},
+			{Name: proto.String("b.proto")},
+			{Name: proto.String("c.proto")},
+		},
+	}
+	var files []string
+	err = p.GenerateFiles(ctx, req, func(file string, resp *pluginpb.CodeGeneratorResponse) error {
+		if resp.GetError() != file {
+			t.Fatalf("unexpected response for %s: %v", file, resp)
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateFiles failed: %v", err)
+	}
+	if !slices.Equal(files, []string{"b.proto", "c.proto"}) {
+		t.Fatalf("unexpected generated files: %v", files)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = p.GenerateFiles(ctx, req, func(string, *pluginpb.CodeGeneratorResponse) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("expected to stop after the first file, got %v after %d calls", err, calls)
+	}
+}
+
+func TestGenerateFiles_Unsupported(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if p.HasSession() {
+		t.Skip("embedded module supports sessions")
+	}
+	err = p.GenerateFiles(ctx, &pluginpb.CodeGeneratorRequest{}, nil)
+	if !errors.Is(err, ErrSessionUnsupported) {
+		t.Fatalf("expected ErrSessionUnsupported, got %v", err)
+	}
+}
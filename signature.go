@@ -0,0 +1,174 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrSignatureInvalid is returned when a WASM binary does not match its
+// detached signature.
+var ErrSignatureInvalid = errors.New("invalid wasm signature")
+
+// SignatureVerifier verifies a detached signature over a WASM binary against
+// a pinned public key.
+type SignatureVerifier interface {
+	// VerifySignature returns nil if sig is a valid signature of wasm.
+	// Returns an error wrapping ErrSignatureInvalid if it is not.
+	VerifySignature(wasm, sig []byte) error
+}
+
+// CompileVerifiedWASM verifies the detached signature of a protoc-gen-prost
+// build loaded from disk or the network, then compiles it.
+// Nothing is compiled if verification fails.
+func CompileVerifiedWASM(ctx context.Context, r wazero.Runtime, wasm, sig []byte, v SignatureVerifier) (wazero.CompiledModule, error) {
+	if err := v.VerifySignature(wasm, sig); err != nil {
+		return nil, err
+	}
+	return compileWASM(ctx, r, wasm)
+}
+
+// minisign signature algorithms.
+const (
+	// minisignLegacy signs the message itself.
+	minisignLegacy = "Ed"
+	// minisignPrehashed signs the BLAKE2b-512 digest of the message.
+	minisignPrehashed = "ED"
+)
+
+// minisignVerifier verifies minisign signatures.
+type minisignVerifier struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+// NewMinisignVerifier creates a SignatureVerifier for minisign signatures.
+//
+// publicKey is the base64 key printed by `minisign -G`, or the contents of
+// the public key file including its untrusted comment. Both legacy and
+// prehashed signatures are accepted; the trusted comment is verified too.
+func NewMinisignVerifier(publicKey string) (SignatureVerifier, error) {
+	line := lastLine(publicKey)
+	data, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != minisignLegacy {
+		return nil, errors.New("invalid minisign public key: unexpected length or algorithm")
+	}
+	v := &minisignVerifier{key: ed25519.PublicKey(data[10:])}
+	copy(v.keyID[:], data[2:10])
+	return v, nil
+}
+
+// VerifySignature verifies the contents of a .minisig file.
+func (v *minisignVerifier) VerifySignature(wasm, sig []byte) error {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(sig), "\r\n", "\n")), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("%w: malformed minisign signature", ErrSignatureInvalid)
+	}
+	data, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(data) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", ErrSignatureInvalid)
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign trusted comment signature", ErrSignatureInvalid)
+	}
+
+	alg, keyID, signature := string(data[:2]), data[2:10], data[10:]
+	if !bytes.Equal(keyID, v.keyID[:]) {
+		return fmt.Errorf("%w: signed by key %X, expected %X", ErrSignatureInvalid, reverse(keyID), reverse(v.keyID[:]))
+	}
+	msg := wasm
+	switch alg {
+	case minisignLegacy:
+	case minisignPrehashed:
+		sum := blake2b.Sum512(wasm)
+		msg = sum[:]
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", ErrSignatureInvalid, alg)
+	}
+	if !ed25519.Verify(v.key, msg, signature) {
+		return ErrSignatureInvalid
+	}
+
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(v.key, append(append([]byte{}, signature...), trusted...), global) {
+		return fmt.Errorf("%w: trusted comment signature mismatch", ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// cosignVerifier verifies cosign blob signatures.
+type cosignVerifier struct {
+	key any
+}
+
+// NewCosignVerifier creates a SignatureVerifier for signatures produced by
+// `cosign sign-blob --key`.
+//
+// pemPublicKey is a PEM-encoded PKIX public key, as written to cosign.pub.
+// ECDSA and Ed25519 keys are supported. Signatures are base64 encoded.
+func NewCosignVerifier(pemPublicKey []byte) (SignatureVerifier, error) {
+	block, _ := pem.Decode(pemPublicKey)
+	if block == nil {
+		return nil, errors.New("invalid cosign public key: no PEM block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cosign public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("invalid cosign public key: unsupported key type %T", key)
+	}
+	return &cosignVerifier{key: key}, nil
+}
+
+// VerifySignature verifies a base64 cosign signature.
+func (v *cosignVerifier) VerifySignature(wasm, sig []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed cosign signature", ErrSignatureInvalid)
+	}
+	var ok bool
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(wasm)
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, wasm, signature)
+	}
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of s, trimmed.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// reverse returns b reversed. minisign prints key IDs in little-endian order.
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+	return out
+}
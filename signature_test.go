@@ -0,0 +1,124 @@
+package prost
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignSign produces a minisign public key and signature for data.
+func minisignSign(t *testing.T, data []byte, alg string) (string, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	pubKey := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(minisignLegacy), keyID...), pub...)) + "\n"
+
+	msg := data
+	if alg == minisignPrehashed {
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	}
+	sig := ed25519.Sign(priv, msg)
+	trusted := "timestamp:1 file:protoc-gen-prost.wasm"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), trusted...))
+	sigFile := "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(alg), keyID...), sig...)) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n"
+	return pubKey, []byte(sigFile)
+}
+
+func TestMinisignVerifier(t *testing.T) {
+	data := []byte("wasm bytes")
+	for _, alg := range []string{minisignLegacy, minisignPrehashed} {
+		pubKey, sig := minisignSign(t, data, alg)
+		v, err := NewMinisignVerifier(pubKey)
+		if err != nil {
+			t.Fatalf("NewMinisignVerifier failed: %v", err)
+		}
+		if err := v.VerifySignature(data, sig); err != nil {
+			t.Fatalf("%s: VerifySignature failed: %v", alg, err)
+		}
+		if err := v.VerifySignature([]byte("tampered"), sig); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("%s: expected ErrSignatureInvalid, got %v", alg, err)
+		}
+
+		// A key from a different pair with the same key ID is rejected.
+		otherKey, _ := minisignSign(t, data, alg)
+		other, err := NewMinisignVerifier(otherKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := other.VerifySignature(data, sig); !errors.Is(err, ErrSignatureInvalid) {
+			t.Fatalf("%s: expected ErrSignatureInvalid for other key, got %v", alg, err)
+		}
+	}
+}
+
+func TestCosignVerifier(t *testing.T) {
+	data := []byte("wasm bytes")
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewCosignVerifier(pubPEM)
+	if err != nil {
+		t.Fatalf("NewCosignVerifier failed: %v", err)
+	}
+	if err := v.VerifySignature(data, []byte(base64.StdEncoding.EncodeToString(sig)+"\n")); err != nil {
+		t.Fatalf("VerifySignature failed: %v", err)
+	}
+	if err := v.VerifySignature([]byte("tampered"), []byte(base64.StdEncoding.EncodeToString(sig))); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected ErrSignatureInvalid, got %v", err)
+	}
+}
+
+func TestCompileVerifiedWASM(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	wasm := ProtocGenProstWASM()
+	pubKey, sig := minisignSign(t, wasm, minisignPrehashed)
+	v, err := NewMinisignVerifier(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := CompileVerifiedWASM(ctx, r, wasm, sig, v)
+	if err != nil {
+		t.Fatalf("CompileVerifiedWASM failed: %v", err)
+	}
+	compiled.Close(ctx)
+
+	tampered := append([]byte{}, wasm...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := CompileVerifiedWASM(ctx, r, tampered, sig, v); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected ErrSignatureInvalid, got %v", err)
+	}
+}
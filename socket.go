@@ -0,0 +1,197 @@
+package prost
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ErrClientClosed is returned by Client.Execute after Close.
+var ErrClientClosed = errors.New("client closed")
+
+// ServeListener serves the framed protocol of ServeFrames on each connection
+// accepted from ln, sharing the warm instance p between all clients.
+//
+// Calls from different connections are serialized by p. Requests larger than
+// 64 MiB are rejected, see ServeLimits.MaxRequestSize. Closes ln and returns
+// nil when ctx is canceled, after waiting for open connections to finish.
+func ServeListener(ctx context.Context, p *ProtocGenProst, ln net.Listener) error {
+	return serveListener(ctx, p.ExecuteInto, ln, nil, nil)
+}
+
+// serveListener implements ServeListener on exec, admitting requests with
+// limiter if not nil and queuing the requests of each connection at the
+// priority returned by priority if not nil.
+func serveListener(ctx context.Context, exec executeIntoFunc, ln net.Listener, limiter *serveLimiter, priority func(net.Conn) Priority) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var connsMu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+		connsMu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		connsMu.Unlock()
+	})
+	defer stop()
+
+	var err error
+	for {
+		var conn net.Conn
+		conn, err = ln.Accept()
+		if err != nil {
+			break
+		}
+		connsMu.Lock()
+		conns[conn] = struct{}{}
+		connsMu.Unlock()
+		if ctx.Err() != nil {
+			// Raced with shutdown after the connections were closed.
+			conn.Close()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var admit frameAdmitter
+			maxSize := uint32(defaultMaxRequestSize)
+			if limiter != nil {
+				maxSize = limiter.maxRequestSize()
+				var closeConn func()
+				admit, closeConn = limiter.open(conn)
+				defer closeConn()
+			}
+			connCtx := ctx
+			if priority != nil {
+				connCtx = WithPriority(ctx, priority(conn))
+			}
+			_ = serveFrames(connCtx, exec, conn, conn, admit, maxSize)
+			conn.Close()
+			connsMu.Lock()
+			delete(conns, conn)
+			connsMu.Unlock()
+		}()
+	}
+
+	if ctx.Err() != nil {
+		err = nil
+	}
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// Client executes requests against a server started with ServeListener,
+// such as `go-prost serve --socket`.
+//
+// A Client is safe for concurrent use; calls are sent one at a time.
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	err    error
+	closed bool
+}
+
+// DialSocket connects to a server listening on the Unix socket at path.
+func DialSocket(ctx context.Context, path string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient creates a Client using an established connection.
+// The Client takes ownership of conn.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Execute sends a serialized CodeGeneratorRequest to the server and returns
+// the serialized CodeGeneratorResponse. Errors reported by the server are
+// returned as *RemoteError.
+//
+// If ctx is canceled the connection is broken and later calls fail.
+func (c *Client) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	if c.err != nil {
+		return nil, fmt.Errorf("connection failed: %w", c.err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Unblock the round trip when ctx is canceled.
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	out, err := roundTripFrame(c.conn, c.r, input)
+	if !stop() {
+		if err == nil {
+			// The reply arrived but the deadline may be set; reset it.
+			_ = c.conn.SetDeadline(time.Time{})
+		} else {
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		var remoteErr *RemoteError
+		if !errors.As(err, &remoteErr) {
+			c.err = err
+			c.conn.Close()
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExecuteRequest executes a CodeGeneratorRequest on the server and returns
+// the parsed CodeGeneratorResponse.
+func (c *Client) ExecuteRequest(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	output, err := c.Execute(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(output, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.err != nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,340 @@
+package prost
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestServeListener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	sock := filepath.Join(t.TempDir(), "prost.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- ServeListener(ctx, p, ln) }()
+
+	// Several clients share the one instance.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := DialSocket(ctx, sock)
+			if err != nil {
+				t.Errorf("DialSocket failed: %v", err)
+				return
+			}
+			defer c.Close()
+			for j := 0; j < 2; j++ {
+				output, err := c.Execute(ctx, minimalRequestInput(t))
+				if err != nil {
+					t.Errorf("Execute failed: %v", err)
+					return
+				}
+				if resp := mustUnmarshalResponse(t, output); resp.Error != nil {
+					t.Errorf("plugin returned error: %s", resp.GetError())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	c, err := DialSocket(ctx, sock)
+	if err != nil {
+		t.Fatalf("DialSocket failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := c.Execute(ctx, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("ServeListener failed: %v", err)
+	}
+}
+
+func TestClient_RemoteError(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	server, conn := net.Pipe()
+	go func() {
+		_ = ServeFrames(ctx, p, server, server)
+		server.Close()
+	}()
+	c := NewClient(conn)
+	defer c.Close()
+
+	// Errors reported by the server do not break the connection.
+	for i := 0; i < 2; i++ {
+		_, err := c.Execute(ctx, minimalRequestInput(t))
+		var remoteErr *RemoteError
+		if !errors.As(err, &remoteErr) {
+			t.Fatalf("expected RemoteError, got %T: %v", err, err)
+		}
+		if remoteErr.Message != ErrClosed.Error() {
+			t.Fatalf("unexpected message %q", remoteErr.Message)
+		}
+	}
+}
+
+func TestServeListenerWithLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	// Admitted requests fail fast with ErrClosed.
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "prost.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	// Each connection is its own client; by default they share the user.
+	limits := ServeLimits{
+		Rate: 0.001, Burst: 3, ClientRate: 0.001, ClientBurst: 2,
+		ClientKey: func(c net.Conn) string { return fmt.Sprintf("%p", c) },
+	}
+	served := make(chan error, 1)
+	go func() { served <- ServeListenerWithLimits(ctx, p, ln, limits) }()
+
+	execute := func(c *Client) error {
+		_, err := c.Execute(ctx, minimalRequestInput(t))
+		return err
+	}
+	c1, err := DialSocket(ctx, sock)
+	if err != nil {
+		t.Fatalf("DialSocket failed: %v", err)
+	}
+	defer c1.Close()
+	for i := 0; i < 2; i++ {
+		if err := execute(c1); errors.Is(err, ErrResourceExhausted) {
+			t.Fatalf("request %d rejected: %v", i, err)
+		}
+	}
+	// The client burst is spent, the connection stays usable.
+	for i := 0; i < 2; i++ {
+		err := execute(c1)
+		var remoteErr *RemoteError
+		if !errors.As(err, &remoteErr) || !errors.Is(err, ErrResourceExhausted) {
+			t.Fatalf("expected ErrResourceExhausted, got %v", err)
+		}
+	}
+
+	// Another client has its own burst but shares the global one.
+	c2, err := DialSocket(ctx, sock)
+	if err != nil {
+		t.Fatalf("DialSocket failed: %v", err)
+	}
+	defer c2.Close()
+	if err := execute(c2); errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("request rejected: %v", err)
+	}
+	if err := execute(c2); !errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("expected ErrResourceExhausted, got %v", err)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("ServeListenerWithLimits failed: %v", err)
+	}
+}
+
+func TestServeFrames_MaxRequestSize(t *testing.T) {
+	ctx := context.Background()
+	echo := func(ctx context.Context, input, out []byte) ([]byte, error) {
+		return append(out, input...), nil
+	}
+	var req bytes.Buffer
+	for _, frame := range [][]byte{bytes.Repeat([]byte{1}, 9), []byte("ok")} {
+		if err := writeFrame(&req, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var resp bytes.Buffer
+	if err := serveFrames(ctx, echo, &req, &resp, nil, 8); err != nil {
+		t.Fatalf("serveFrames failed: %v", err)
+	}
+
+	br := bufio.NewReader(&resp)
+	// The first request is rejected and its body discarded.
+	_, err := roundTripFrame(io.Discard, br, nil)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 8 bytes") {
+		t.Fatalf("expected size error, got %v", err)
+	}
+	// The connection stays usable.
+	out, err := roundTripFrame(io.Discard, br, nil)
+	if err != nil || string(out) != "ok" {
+		t.Fatalf("expected echo, got %q, %v", out, err)
+	}
+}
+
+func TestServeLimiter_MaxInFlight(t *testing.T) {
+	l := newServeLimiter(ServeLimits{MaxInFlight: 1})
+	admit, closeConn := l.open(nil)
+	defer closeConn()
+
+	release, err := admit()
+	if err != nil {
+		t.Fatalf("admit failed: %v", err)
+	}
+	if _, err := admit(); !errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("expected ErrResourceExhausted, got %v", err)
+	}
+	release()
+	release, err = admit()
+	if err != nil {
+		t.Fatalf("admit after release failed: %v", err)
+	}
+	release()
+}
+
+func TestServeLimiter_RefundsClientToken(t *testing.T) {
+	l := newServeLimiter(ServeLimits{Rate: 0.001, Burst: 1, ClientRate: 0.001, ClientBurst: 1})
+	a, releaseA := l.client("a")
+	defer releaseA()
+	b, releaseB := l.client("b")
+	defer releaseB()
+
+	if _, err := l.admitter(a)(); err != nil {
+		t.Fatalf("admit failed: %v", err)
+	}
+	// The global limit rejects b without spending its own token.
+	if _, err := l.admitter(b)(); !errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("expected ErrResourceExhausted, got %v", err)
+	}
+	if !b.allow() {
+		t.Fatal("expected the client token to be refunded")
+	}
+}
+
+func TestServeLimiter_DefaultClientKey(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on Linux")
+	}
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "prost.sock"))
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	accept := func() net.Conn {
+		t.Helper()
+		c, err := net.Dial("unix", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	l := newServeLimiter(ServeLimits{ClientRate: 0.001, ClientBurst: 1})
+	admit, closeConn := l.open(accept())
+	if _, err := admit(); err != nil {
+		t.Fatalf("admit failed: %v", err)
+	}
+	closeConn()
+	// Reconnecting as the same user does not reset the client limit.
+	admit, closeConn = l.open(accept())
+	defer closeConn()
+	if _, err := admit(); !errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("expected ErrResourceExhausted, got %v", err)
+	}
+}
+
+func TestLimitHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(LimitHandler(ok, ServeLimits{ClientRate: 0.001, ClientBurst: 2}))
+	defer srv.Close()
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		// New connections are the same client.
+		req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Close = true
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: expected %d, got %s", i, want, resp.Status)
+		}
+	}
+}
+
+func TestLimitHandler_MaxRequestSize(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	})
+	srv := httptest.NewServer(LimitHandler(ok, ServeLimits{MaxRequestSize: 8}))
+	defer srv.Close()
+
+	for _, body := range []string{"small", "larger than the limit"} {
+		resp, err := http.Post(srv.URL, "application/proto", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		want := http.StatusOK
+		if len(body) > 8 {
+			want = http.StatusRequestEntityTooLarge
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("%q: expected %d, got %s", body, want, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package prost
+
+import (
+	"context"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+)
+
+// AllocatorStats contains guest allocator statistics.
+type AllocatorStats = lowlevel.AllocatorStats
+
+// HasAllocatorStats returns true if the module exports allocator statistics.
+func (p *ProtocGenProst) HasAllocatorStats() bool {
+	p.modMu.Lock()
+	ll := p.ll
+	p.modMu.Unlock()
+	return ll != nil && ll.HasAllocatorStats()
+}
+
+// AllocatorStats returns the live and peak allocation statistics of the guest.
+//
+// Comparing LiveAllocs before and after a series of Execute calls detects
+// leaks in the plugin. Returns ErrAllocatorStatsUnsupported if the module
+// does not export the statistics functions.
+func (p *ProtocGenProst) AllocatorStats(ctx context.Context) (AllocatorStats, error) {
+	if !p.HasAllocatorStats() {
+		return AllocatorStats{}, ErrAllocatorStatsUnsupported
+	}
+	if err := p.acquire(); err != nil {
+		return AllocatorStats{}, err
+	}
+	defer p.release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ll.AllocatorStats(ctx)
+}
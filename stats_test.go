@@ -0,0 +1,78 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+func TestProtocGenProst_AllocatorStats(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	if !p.HasAllocatorStats() {
+		if _, err := p.AllocatorStats(ctx); !errors.Is(err, ErrAllocatorStatsUnsupported) {
+			t.Fatalf("expected ErrAllocatorStatsUnsupported, got %v", err)
+		}
+		t.Skip("module does not export allocator statistics")
+	}
+
+	input := minimalRequestInput(t)
+	if _, err := p.Execute(ctx, input); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	before, err := p.AllocatorStats(ctx)
+	if err != nil {
+		t.Fatalf("AllocatorStats failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := p.Execute(ctx, input); err != nil {
+			t.Fatalf("Execute %d failed: %v", i, err)
+		}
+	}
+	after, err := p.AllocatorStats(ctx)
+	if err != nil {
+		t.Fatalf("AllocatorStats failed: %v", err)
+	}
+	if after.LiveAllocs > before.LiveAllocs {
+		t.Fatalf("live allocations grew from %d to %d", before.LiveAllocs, after.LiveAllocs)
+	}
+}
+
+func TestProtocGenProst_HasAllocatorStatsConcurrent(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r, WithMaxExecutions(1))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	// Re-instantiating on every call swaps the bindings read by
+	// HasAllocatorStats; run with -race to check the access is guarded.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			p.HasAllocatorStats()
+		}
+	}()
+	input := minimalRequestInput(t)
+	for i := 0; i < 3; i++ {
+		if _, err := p.Execute(ctx, input); err != nil {
+			t.Fatalf("Execute %d failed: %v", i, err)
+		}
+	}
+	<-done
+}
@@ -0,0 +1,347 @@
+package prost
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ParseDigest parses the hex encoding of a digest.
+func ParseDigest(s string) (Digest, error) {
+	var d Digest
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(d) {
+		return d, fmt.Errorf("invalid digest: %q", s)
+	}
+	copy(d[:], b)
+	return d, nil
+}
+
+// OutputStore is a content-addressable store of generated files.
+//
+// File contents are stored as blobs named by their SHA-256, and each
+// generation run as a Manifest mapping file names to blobs, itself stored by
+// digest. Named refs point at manifests and keep a history, so switching an
+// output directory to an earlier run is instant. Identical files are stored
+// once across runs.
+//
+// Blobs and manifests are immutable and written with an atomic rename, so
+// concurrent writers sharing a store never observe partial files. Layout:
+//
+//	blobs/ab/abcd...       file contents
+//	manifests/ef/ef01...   Manifest JSON
+//	refs/<name>            digest of the current manifest
+//	refs/<name>.log        previous manifest digests, oldest first
+//	refs/<name>.lock       held while a writer updates the ref
+//
+// Ref updates take the lock file of the ref, so concurrent SetRef and
+// Rollback calls never lose history entries.
+type OutputStore struct {
+	dir string
+}
+
+// NewOutputStore creates an OutputStore rooted at dir.
+// The directory is created on the first write if it does not exist.
+func NewOutputStore(dir string) *OutputStore {
+	return &OutputStore{dir: dir}
+}
+
+// Put stores the files and a manifest listing them.
+// Returns the digest of the manifest. requestDigest is recorded in the
+// manifest if set.
+func (s *OutputStore) Put(files []ResolvedFile, requestDigest Digest) (Digest, error) {
+	for _, f := range files {
+		sum := sha256.Sum256([]byte(f.Content))
+		if err := s.writeObject(s.blobPath(sum), []byte(f.Content)); err != nil {
+			return Digest{}, err
+		}
+	}
+	data, err := NewManifest(requestDigest, files).Marshal()
+	if err != nil {
+		return Digest{}, err
+	}
+	digest := Digest(sha256.Sum256(data))
+	if err := s.writeObject(s.manifestPath(digest), data); err != nil {
+		return Digest{}, err
+	}
+	return digest, nil
+}
+
+// PutResponse stores the files WriteResponse would write for resp.
+// The filter, path rules, header and request digest of opts are applied.
+func (s *OutputStore) PutResponse(resp *pluginpb.CodeGeneratorResponse, opts *WriteOptions) (Digest, error) {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	files, err := prepareFiles(resp, opts)
+	if err != nil {
+		return Digest{}, err
+	}
+	return s.Put(files, opts.RequestDigest)
+}
+
+// Manifest reads a stored manifest.
+func (s *OutputStore) Manifest(digest Digest) (*Manifest, error) {
+	return ReadManifest(s.manifestPath(digest))
+}
+
+// ReadBlob reads the file content with the given SHA-256.
+func (s *OutputStore) ReadBlob(sum Digest) ([]byte, error) {
+	return os.ReadFile(s.blobPath(sum))
+}
+
+// SetRef points the named ref at a stored manifest and records the previous
+// target in its history. The ref is replaced atomically.
+func (s *OutputStore) SetRef(name string, digest Digest) error {
+	if err := checkRefName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.manifestPath(digest)); err != nil {
+		return fmt.Errorf("manifest %s: %w", digest, err)
+	}
+	unlock, err := s.lockRef(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	prev, err := s.Ref(name)
+	switch {
+	case err == nil:
+		history, err := s.History(name)
+		if err != nil {
+			return err
+		}
+		if err := s.writeHistory(name, append(history, prev)); err != nil {
+			return err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	return writeFileAtomic(s.refPath(name), []byte(digest.String()+"\n"))
+}
+
+// Ref returns the manifest digest the named ref points at.
+// Returns an error wrapping fs.ErrNotExist if the ref was never set.
+func (s *OutputStore) Ref(name string) (Digest, error) {
+	if err := checkRefName(name); err != nil {
+		return Digest{}, err
+	}
+	data, err := os.ReadFile(s.refPath(name))
+	if err != nil {
+		return Digest{}, err
+	}
+	return ParseDigest(strings.TrimSpace(string(data)))
+}
+
+// History returns the previous targets of the named ref, oldest first.
+func (s *OutputStore) History(name string) ([]Digest, error) {
+	if err := checkRefName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.refPath(name) + ".log")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []Digest
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		d, err := ParseDigest(sc.Text())
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, d)
+	}
+	return history, sc.Err()
+}
+
+// Rollback points the named ref back at its previous target and removes that
+// entry from the history. Returns the new target.
+func (s *OutputStore) Rollback(name string) (Digest, error) {
+	if err := checkRefName(name); err != nil {
+		return Digest{}, err
+	}
+	unlock, err := s.lockRef(name)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer unlock()
+	history, err := s.History(name)
+	if err != nil {
+		return Digest{}, err
+	}
+	if len(history) == 0 {
+		return Digest{}, fmt.Errorf("ref %s: no previous target", name)
+	}
+	prev := history[len(history)-1]
+	if err := writeFileAtomic(s.refPath(name), []byte(prev.String()+"\n")); err != nil {
+		return Digest{}, err
+	}
+	return prev, s.writeHistory(name, history[:len(history)-1])
+}
+
+// Checkout materializes a stored manifest in dir as symlinks to the blobs.
+//
+// File names are checked with ValidateFileName, so a tampered manifest cannot
+// place links outside dir. Each link is replaced atomically. Links into this store that are not part
+// of the manifest are removed; other files in dir are never touched.
+func (s *OutputStore) Checkout(dir string, digest Digest) error {
+	m, err := s.Manifest(digest)
+	if err != nil {
+		return err
+	}
+	blobs, err := filepath.Abs(filepath.Join(s.dir, "blobs"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range m.Files {
+		if err := ValidateFileName(f.Name); err != nil {
+			return fmt.Errorf("manifest %s: %w", digest, err)
+		}
+	}
+
+	current := make(map[string]struct{}, len(m.Files))
+	for _, f := range m.Files {
+		sum, err := ParseDigest(f.SHA256)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		target, err := filepath.Abs(s.blobPath(sum))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		current[path] = struct{}{}
+		if err := symlinkAtomic(target, path); err != nil {
+			return err
+		}
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		if _, ok := current[path]; ok {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(target, blobs+string(filepath.Separator)) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// writeObject writes an immutable object unless it already exists.
+func (s *OutputStore) writeObject(path string, data []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return writeFileAtomic(path, data)
+}
+
+// refLockTimeout bounds how long ref updates wait for another writer.
+const refLockTimeout = 10 * time.Second
+
+// lockRef creates the lock file of the named ref, waiting while another
+// writer holds it. Returns a function removing the lock file.
+func (s *OutputStore) lockRef(name string) (func(), error) {
+	path := s.refPath(name) + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(refLockTimeout)
+	for delay := time.Millisecond; ; delay = min(2*delay, 100*time.Millisecond) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("ref %s is locked by another writer; remove %s if none is running", name, path)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// writeHistory replaces the history of the named ref.
+func (s *OutputStore) writeHistory(name string, history []Digest) error {
+	var sb strings.Builder
+	for _, d := range history {
+		sb.WriteString(d.String())
+		sb.WriteByte('\n')
+	}
+	return writeFileAtomic(s.refPath(name)+".log", []byte(sb.String()))
+}
+
+// blobPath returns the path of the blob with the given SHA-256.
+func (s *OutputStore) blobPath(sum Digest) string {
+	key := sum.String()
+	return filepath.Join(s.dir, "blobs", key[:2], key)
+}
+
+// manifestPath returns the path of the manifest with the given digest.
+func (s *OutputStore) manifestPath(digest Digest) string {
+	key := digest.String()
+	return filepath.Join(s.dir, "manifests", key[:2], key)
+}
+
+// refPath returns the path of the named ref.
+func (s *OutputStore) refPath(name string) string {
+	return filepath.Join(s.dir, "refs", filepath.FromSlash(name))
+}
+
+// checkRefName checks that a ref name is a valid slash-separated path
+// without .log suffix.
+func checkRefName(name string) error {
+	if !fs.ValidPath(name) || name == "." || strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".lock") {
+		return fmt.Errorf("invalid ref name: %q", name)
+	}
+	return nil
+}
+
+// symlinkAtomic points the symlink at path to target, replacing any existing
+// file with a rename. Parent directories are created as needed.
+func symlinkAtomic(target, path string) error {
+	if existing, err := os.Readlink(path); err == nil && existing == target {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	f.Close()
+	os.Remove(tmpName)
+	if err := os.Symlink(target, tmpName); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
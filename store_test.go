@@ -0,0 +1,135 @@
+package prost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestOutputStore(t *testing.T) {
+	s := NewOutputStore(t.TempDir())
+	v1, err := s.Put([]ResolvedFile{
+		{Name: "a/a.pb.rs", Content: "// a v1\n"},
+		{Name: "b.pb.rs", Content: "// b\n"},
+	}, Digest{})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	v2, err := s.Put([]ResolvedFile{
+		{Name: "a/a.pb.rs", Content: "// a v2\n"},
+	}, Digest{})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if again, _ := s.Put([]ResolvedFile{{Name: "a/a.pb.rs", Content: "// a v2\n"}}, Digest{}); again != v2 {
+		t.Fatal("expected identical outputs to have the same digest")
+	}
+
+	if err := s.SetRef("main", v1); err != nil {
+		t.Fatalf("SetRef failed: %v", err)
+	}
+	if err := s.SetRef("main", v2); err != nil {
+		t.Fatalf("SetRef failed: %v", err)
+	}
+	if ref, err := s.Ref("main"); err != nil || ref != v2 {
+		t.Fatalf("unexpected ref %s: %v", ref, err)
+	}
+
+	out := t.TempDir()
+	handWritten := filepath.Join(out, "lib.rs")
+	if err := os.WriteFile(handWritten, []byte("// mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Checkout(out, v1); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if err := s.Checkout(out, v2); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "a", "a.pb.rs")); err != nil || string(data) != "// a v2\n" {
+		t.Fatalf("unexpected content %q: %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(out, "b.pb.rs")); !os.IsNotExist(err) {
+		t.Fatalf("expected b.pb.rs to be removed, got %v", err)
+	}
+	if _, err := os.Stat(handWritten); err != nil {
+		t.Fatal("hand-written file was removed")
+	}
+
+	prev, err := s.Rollback("main")
+	if err != nil || prev != v1 {
+		t.Fatalf("unexpected rollback target %s: %v", prev, err)
+	}
+	if err := s.Checkout(out, prev); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "b.pb.rs")); string(data) != "// b\n" {
+		t.Fatalf("unexpected content after rollback %q", data)
+	}
+	if _, err := s.Rollback("main"); err == nil {
+		t.Fatal("expected error without history")
+	}
+	if err := s.SetRef("../escape", v1); err == nil {
+		t.Fatal("expected invalid ref name error")
+	}
+}
+
+func TestOutputStore_ConcurrentSetRef(t *testing.T) {
+	s := NewOutputStore(t.TempDir())
+	const writers = 16
+	digests := make([]Digest, writers)
+	for i := range digests {
+		d, err := s.Put([]ResolvedFile{{Name: "a.pb.rs", Content: fmt.Sprintf("// %d\n", i)}}, Digest{})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		digests[i] = d
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range digests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.SetRef("main", d); err != nil {
+				t.Errorf("SetRef failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every update but the last one is recorded in the history.
+	history, err := s.History("main")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	ref, err := s.Ref("main")
+	if err != nil {
+		t.Fatalf("Ref failed: %v", err)
+	}
+	seen := map[Digest]bool{ref: true}
+	for _, d := range history {
+		seen[d] = true
+	}
+	if len(history) != writers-1 || len(seen) != writers {
+		t.Fatalf("expected %d distinct history entries, got %d of %d", writers-1, len(seen)-1, len(history))
+	}
+}
+
+func TestOutputStore_CheckoutRejectsUnsafeNames(t *testing.T) {
+	s := NewOutputStore(t.TempDir())
+	d, err := s.Put([]ResolvedFile{{Name: "../escape.rs", Content: "// evil\n"}}, Digest{})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	parent := t.TempDir()
+	out := filepath.Join(parent, "out")
+	if err := s.Checkout(out, d); err == nil {
+		t.Fatal("expected unsafe file name error")
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "escape.rs")); !os.IsNotExist(err) {
+		t.Fatalf("expected no link outside the directory, got %v", err)
+	}
+}
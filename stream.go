@@ -0,0 +1,247 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// CodeGeneratorResponse and CodeGeneratorResponse.File field numbers decoded
+// by ExecuteToDir.
+const (
+	respError              protowire.Number = 1
+	respFile               protowire.Number = 15
+	respFileName           protowire.Number = 1
+	respFileInsertionPoint protowire.Number = 2
+	respFileContent        protowire.Number = 15
+)
+
+// ExecuteToDir runs the plugin with a serialized CodeGeneratorRequest and
+// writes the generated files into dir like WriteResponse.
+//
+// The response is decoded in place from guest memory and each file is
+// written as it is decoded, so host memory stays near the size of the
+// largest file instead of the whole response, e.g. for outputs of hundreds
+// of megabytes. Returns the names of the written files in response order.
+//
+// Request options apply. The cache, interceptors and response options are
+// bypassed, since the response is never materialized. Insertion points are
+// not supported. Returns a *PluginError if the plugin reported an error;
+// nothing is written then, nor if the response is invalid.
+func (p *ProtocGenProst) ExecuteToDir(ctx context.Context, input []byte, dir string, opts *WriteOptions) ([]string, error) {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	input, err := p.processRequest(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	sink := &outputSink{dir: dir, opts: opts, pkgs: opts.outputPackages()}
+	out, err := p.execute(context.WithValue(ctx, outputSinkKey{}, sink), input, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !sink.consumed {
+		// Command mode collects the output on the host.
+		if err := sink.consumeOutput(out); err != nil {
+			return nil, err
+		}
+	}
+	return sink.finish()
+}
+
+// outputSinkKey is the context key of the outputSink of ExecuteToDir.
+type outputSinkKey struct{}
+
+// outputSink writes the files of an encoded response to a directory.
+type outputSink struct {
+	dir  string
+	opts *WriteOptions
+	pkgs map[string]string
+
+	// consumed is set once the output was passed to consumeOutput.
+	consumed bool
+	// files lists the written files.
+	files []ResolvedFile
+	// manifest describes the written files if opts.ManifestFilename is set.
+	manifest []ManifestFile
+}
+
+// responseFile is a file of an encoded response. Content aliases the
+// encoded response.
+type responseFile struct {
+	name           string
+	insertionPoint string
+	content        []byte
+}
+
+// consumeOutput checks the encoded response, then writes its files. The
+// output may alias guest memory and is not retained.
+func (s *outputSink) consumeOutput(output []byte) error {
+	s.consumed = true
+	s.files, s.manifest = nil, nil
+
+	// Check the whole response before writing anything.
+	seen := make(map[string]struct{})
+	var invalid []error
+	msg, err := decodeResponse(output, func(f *responseFile) error {
+		if f.insertionPoint != "" {
+			invalid = append(invalid, fmt.Errorf("%s: insertion point %q not supported when streaming", f.name, f.insertionPoint))
+			return nil
+		}
+		if err := ValidateFileName(f.name); err != nil {
+			invalid = append(invalid, err)
+			return nil
+		}
+		if _, ok := seen[f.name]; ok {
+			invalid = append(invalid, fmt.Errorf("%s: generated more than once", f.name))
+		}
+		seen[f.name] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if msg != "" {
+		return &PluginError{Message: msg}
+	}
+	if err := errors.Join(invalid...); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	_, err = decodeResponse(output, s.writeFile)
+	return err
+}
+
+// writeFile writes one file of the response.
+func (s *outputSink) writeFile(f *responseFile) error {
+	name, ok, err := s.opts.outputName(f.name, s.pkgs)
+	if err != nil || !ok {
+		return err
+	}
+	data := f.content
+	if comment, ok := s.opts.header(name); ok {
+		data = append([]byte(comment), data...)
+	}
+	if err := writeFileIfChanged(filepath.Join(s.dir, filepath.FromSlash(name)), data); err != nil {
+		return err
+	}
+	s.files = append(s.files, ResolvedFile{Name: name})
+	if s.opts.ManifestFilename != "" {
+		s.manifest = append(s.manifest, newManifestFile(name, data))
+	}
+	return nil
+}
+
+// finish removes stale files and writes the manifest and marker file.
+// Returns the names of the written files.
+func (s *outputSink) finish() ([]string, error) {
+	if s.opts.RemoveStale {
+		previous, err := readMarker(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := removeStale(s.dir, previous, s.files); err != nil {
+			return nil, err
+		}
+	}
+	if s.opts.ManifestFilename != "" {
+		data, err := newManifest(s.opts.RequestDigest, s.manifest).Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileIfChanged(filepath.Join(s.dir, s.opts.ManifestFilename), data); err != nil {
+			return nil, err
+		}
+	}
+	if !s.opts.NoMarker {
+		if err := writeMarker(s.dir, s.files); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, len(s.files))
+	for i, f := range s.files {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// decodeResponse decodes an encoded CodeGeneratorResponse, calling fn for
+// each file in order. Returns the error message of the response.
+func decodeResponse(b []byte, fn func(*responseFile) error) (string, error) {
+	var msg string
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", fmt.Errorf("invalid response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if typ != protowire.BytesType || (num != respError && num != respFile) {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return "", fmt.Errorf("invalid response: %w", protowire.ParseError(n))
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return "", fmt.Errorf("invalid response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if num == respError {
+			msg = string(v)
+			continue
+		}
+		f, err := decodeResponseFile(v)
+		if err != nil {
+			return "", err
+		}
+		if err := fn(f); err != nil {
+			return "", err
+		}
+	}
+	return msg, nil
+}
+
+// decodeResponseFile decodes an encoded CodeGeneratorResponse.File.
+func decodeResponseFile(b []byte) (*responseFile, error) {
+	f := &responseFile{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid response file: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, fmt.Errorf("invalid response file: %w", protowire.ParseError(n))
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid response file: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		switch num {
+		case respFileName:
+			f.name = string(v)
+		case respFileInsertionPoint:
+			f.insertionPoint = string(v)
+		case respFileContent:
+			f.content = v
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,157 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// readTree reads the files under dir by slash-separated relative path.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestProtocGenProst_ExecuteToDir(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	req := syntaxRequest("proto3", nil)
+	req.FileToGenerate = append(req.FileToGenerate, "b/b.proto")
+	req.ProtoFile = append(req.ProtoFile, &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("b/b.proto"),
+		Package:     proto.String("b"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("B")}},
+	})
+	input, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &WriteOptions{
+		Header:           "Do not edit.",
+		ManifestFilename: DefaultManifestFilename,
+		RequestDigest:    RequestDigest(input),
+		Filter:           &FileFilter{Exclude: []string{"b/**"}},
+	}
+
+	// Matches WriteResponse on the materialized response.
+	streamed := t.TempDir()
+	names, err := p.ExecuteToDir(ctx, input, streamed, opts)
+	if err != nil {
+		t.Fatalf("ExecuteToDir failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "a/a.pb.rs" {
+		t.Fatalf("unexpected files %v", names)
+	}
+	resp, err := p.ExecuteRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	written := t.TempDir()
+	if err := WriteResponse(written, resp, opts); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	got, want := readTree(t, streamed), readTree(t, written)
+	if len(got) != len(want) {
+		t.Fatalf("got files %v, want %v", got, want)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s: got %q, want %q", name, got[name], content)
+		}
+	}
+
+	// Plugin errors write nothing.
+	req.Parameter = proto.String("no_such_option")
+	input, err = proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := t.TempDir()
+	var pluginErr *PluginError
+	if _, err := p.ExecuteToDir(ctx, input, failed, nil); !errors.As(err, &pluginErr) {
+		t.Fatalf("expected PluginError, got %v", err)
+	}
+	if files := readTree(t, failed); len(files) != 0 {
+		t.Fatalf("unexpected files %v", files)
+	}
+}
+
+func TestProtocGenProst_ExecuteToDirCommand(t *testing.T) {
+	wasm := buildCommandPlugin(t)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	input, err := proto.Marshal(&pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"a.proto"}, Parameter: proto.String("content")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := p.ExecuteToDir(ctx, input, dir, &WriteOptions{NoMarker: true}); err != nil {
+		t.Fatalf("ExecuteToDir failed: %v", err)
+	}
+	if files := readTree(t, dir); len(files) != 1 || files["a.proto.txt"] != "content" {
+		t.Fatalf("unexpected files %v", files)
+	}
+}
+
+func TestOutputSink_InsertionPoint(t *testing.T) {
+	output, err := proto.Marshal(&pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("a.rs"), Content: proto.String("// @@protoc_insertion_point(x)\n")},
+		{Name: proto.String("a.rs"), InsertionPoint: proto.String("x"), Content: proto.String("inserted")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	sink := &outputSink{dir: dir, opts: &WriteOptions{}}
+	if err := sink.consumeOutput(output); err == nil || !strings.Contains(err.Error(), "not supported when streaming") {
+		t.Fatalf("expected insertion point error, got %v", err)
+	}
+	if files := readTree(t, dir); len(files) != 0 {
+		t.Fatalf("unexpected files %v", files)
+	}
+}
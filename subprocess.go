@@ -0,0 +1,188 @@
+package prost
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// SubprocessEnv is the environment variable marking a child process started by
+// Subprocess. Its value is the protocol version.
+const SubprocessEnv = "GO_PROTOC_GEN_PROST_SUBPROCESS"
+
+// subprocessProtocol is the version of the pipe protocol.
+const subprocessProtocol = "1"
+
+// ErrSubprocessClosed is returned by Subprocess.Execute after Close.
+var ErrSubprocessClosed = errors.New("subprocess closed")
+
+// RunSubprocessChild serves Subprocess requests over stdin and stdout and
+// exits if the current process was started by a Subprocess. Otherwise it
+// returns immediately.
+//
+// Call it at the start of main (or TestMain) in any binary that uses Subprocess.
+func RunSubprocessChild() {
+	if os.Getenv(SubprocessEnv) != subprocessProtocol {
+		return
+	}
+	if err := ServeSubprocess(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "go-protoc-gen-prost subprocess:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// ServeSubprocess runs the child side of the Subprocess protocol on a new
+// instance. Reads requests from r and writes responses to w until r is closed.
+func ServeSubprocess(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) error {
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, rt, opts...)
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+
+	return ServeFrames(ctx, p, r, w)
+}
+
+// Subprocess runs protoc-gen-prost in a child process for OS-level isolation.
+//
+// The child is a re-exec of the current binary, which must call
+// RunSubprocessChild at startup. Requests are sent over pipes using the
+// framed protocol of ServeFrames, and errors reported by the child are
+// returned as *RemoteError. The child is restarted on the next Execute if it
+// exits or a call is canceled, so memory used by the plugin is returned to
+// the OS when the process is recycled.
+type Subprocess struct {
+	path string
+	args []string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	closed bool
+}
+
+// NewSubprocess creates a Subprocess re-executing the current binary.
+// The child is started lazily on the first Execute.
+func NewSubprocess() (*Subprocess, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %w", err)
+	}
+	return NewSubprocessWithCommand(path), nil
+}
+
+// NewSubprocessWithCommand creates a Subprocess running the given binary and
+// arguments. The binary must call RunSubprocessChild at startup.
+func NewSubprocessWithCommand(path string, args ...string) *Subprocess {
+	return &Subprocess{path: path, args: args}
+}
+
+// Execute runs the plugin in the child process with a serialized
+// CodeGeneratorRequest and returns the serialized CodeGeneratorResponse.
+//
+// If ctx is canceled the child is killed and ctx.Err() is returned.
+func (s *Subprocess) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSubprocessClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.cmd == nil {
+		if err := s.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	type result struct {
+		out []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := s.roundTrip(input)
+		done <- result{out, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			var remoteErr *RemoteError
+			if !errors.As(res.err, &remoteErr) {
+				s.stop()
+			}
+			return nil, res.err
+		}
+		return res.out, nil
+	case <-ctx.Done():
+		s.stop()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops the child process.
+func (s *Subprocess) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.cmd == nil {
+		return nil
+	}
+	// Closing stdin makes the child exit cleanly
+	s.stdin.Close()
+	err := s.cmd.Wait()
+	s.cmd = nil
+	return err
+}
+
+// start launches the child process. Must be called with mu held.
+func (s *Subprocess) start() error {
+	cmd := exec.Command(s.path, s.args...)
+	cmd.Env = append(os.Environ(), SubprocessEnv+"="+subprocessProtocol)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start subprocess: %w", err)
+	}
+	s.cmd, s.stdin, s.stdout = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// stop kills the child process. Must be called with mu held.
+func (s *Subprocess) stop() {
+	if s.cmd == nil {
+		return
+	}
+	_ = s.cmd.Process.Kill()
+	_ = s.cmd.Wait()
+	s.cmd = nil
+}
+
+// roundTrip sends one request and reads the response.
+func (s *Subprocess) roundTrip(input []byte) ([]byte, error) {
+	return roundTripFrame(s.stdin, s.stdout, input)
+}
@@ -0,0 +1,94 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestMain(m *testing.M) {
+	RunSubprocessChild()
+	os.Exit(m.Run())
+}
+
+func TestSubprocess_Execute(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSubprocess()
+	if err != nil {
+		t.Fatalf("NewSubprocess failed: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		output, err := s.Execute(ctx, minimalRequestInput(t))
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		resp := &pluginpb.CodeGeneratorResponse{}
+		if err := proto.Unmarshal(output, resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("plugin returned error: %s", resp.GetError())
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := s.Execute(ctx, nil); !errors.Is(err, ErrSubprocessClosed) {
+		t.Fatalf("expected ErrSubprocessClosed, got %v", err)
+	}
+}
+
+func TestSubprocess_Restarts(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSubprocess()
+	if err != nil {
+		t.Fatalf("NewSubprocess failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// Kill the child behind the Subprocess's back
+	oldCmd := s.cmd
+	if err := oldCmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = oldCmd.Process.Wait()
+	if _, err := s.Execute(ctx, minimalRequestInput(t)); err == nil {
+		t.Fatal("expected error after child exit")
+	}
+	if _, err := s.Execute(ctx, minimalRequestInput(t)); err != nil {
+		t.Fatalf("Execute after restart failed: %v", err)
+	}
+	if s.cmd == oldCmd {
+		t.Fatal("expected child to be restarted")
+	}
+}
+
+func TestServeSubprocess(t *testing.T) {
+	var in, out bytes.Buffer
+	input := minimalRequestInput(t)
+	if err := writeFrame(&in, input); err != nil {
+		t.Fatal(err)
+	}
+	if err := ServeSubprocess(context.Background(), &in, &out); err != nil {
+		t.Fatalf("ServeSubprocess failed: %v", err)
+	}
+	status, err := out.ReadByte()
+	if err != nil || status != frameOK {
+		t.Fatalf("unexpected status %d: %v", status, err)
+	}
+	if _, err := readFrame(&out); err != nil {
+		t.Fatalf("readFrame failed: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Syntax levels of FileDescriptorProto.syntax.
+const (
+	syntaxProto2   = "proto2"
+	syntaxProto3   = "proto3"
+	syntaxEditions = "editions"
+)
+
+// SyntaxError reports a construct in a request file that the plugin does not
+// support or that is not valid at the syntax level of the file.
+type SyntaxError struct {
+	// File is the name of the proto file.
+	File string
+	// Element is the fully qualified name of the message or field using the
+	// construct, or empty for file-level constructs.
+	Element string
+	// Construct describes the construct, e.g. "edition 2023" or "group field".
+	Construct string
+	// Reason explains why the construct is rejected.
+	Reason string
+}
+
+// Error returns the error message.
+func (e *SyntaxError) Error() string {
+	if e.Element != "" {
+		return fmt.Sprintf("%s: %s %s: %s", e.File, e.Construct, e.Element, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Construct, e.Reason)
+}
+
+// CheckSyntax checks the syntax levels and constructs of all files in req
+// against the capabilities f reported by ProbeFeatures, so requests the
+// plugin cannot handle fail with an error naming the file and construct
+// instead of a guest panic.
+//
+// Files must use proto2, proto3, or editions within the edition range of f.
+// proto3 files must not contain groups, required fields or default values,
+// and only editions files may set features. Returns an error joining a
+// *SyntaxError for each problem found.
+func CheckSyntax(req *pluginpb.CodeGeneratorRequest, f *Features) error {
+	return checkSyntax(req, func() (*Features, error) { return f, nil })
+}
+
+// checkSyntax implements CheckSyntax, calling features only if the request
+// uses editions.
+func checkSyntax(req *pluginpb.CodeGeneratorRequest, features func() (*Features, error)) error {
+	var errs []error
+	for _, file := range req.GetProtoFile() {
+		name := file.GetName()
+		fail := func(element, construct, reason string) {
+			errs = append(errs, &SyntaxError{File: name, Element: element, Construct: construct, Reason: reason})
+		}
+
+		syntax := file.GetSyntax()
+		switch syntax {
+		case "", syntaxProto2, syntaxProto3:
+		case syntaxEditions:
+			f, err := features()
+			if err != nil {
+				return err
+			}
+			checkEdition(file.GetEdition(), f, fail)
+			continue
+		default:
+			fail("", fmt.Sprintf("syntax %q", syntax), "unknown syntax level")
+			continue
+		}
+
+		if file.GetOptions().GetFeatures() != nil {
+			fail("", "features option", "only allowed in editions files")
+		}
+		if syntax != syntaxProto3 {
+			continue
+		}
+		walkMessages(file.GetPackage(), file.GetMessageType(), func(fullName string, msg *descriptorpb.DescriptorProto) {
+			for _, field := range msg.GetField() {
+				fieldName := fullName + "." + field.GetName()
+				switch {
+				case field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+					fail(fieldName, "group field", "not allowed in proto3")
+				case field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+					fail(fieldName, "required field", "not allowed in proto3")
+				case field.DefaultValue != nil:
+					fail(fieldName, "default value", "not allowed in proto3")
+				}
+			}
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// checkEdition checks an editions file against the edition range of f.
+func checkEdition(edition descriptorpb.Edition, f *Features, fail func(element, construct, reason string)) {
+	construct := editionName(edition)
+	switch {
+	case !f.Editions:
+		fail("", construct, "editions are not supported by the plugin")
+	case edition == descriptorpb.Edition_EDITION_UNKNOWN:
+		fail("", construct, "editions file without an edition")
+	case int32(edition) < f.MinimumEdition || int32(edition) > f.MaximumEdition:
+		fail("", construct, fmt.Sprintf("outside the range supported by the plugin, %s to %s",
+			editionName(descriptorpb.Edition(f.MinimumEdition)), editionName(descriptorpb.Edition(f.MaximumEdition))))
+	}
+}
+
+// editionName formats an edition, e.g. "edition 2023".
+func editionName(edition descriptorpb.Edition) string {
+	return "edition " + strings.ToLower(strings.TrimPrefix(edition.String(), "EDITION_"))
+}
+
+// WithSyntaxCheck runs CheckSyntax on each request before generation.
+// Execute returns the *SyntaxError problems instead of running the plugin.
+// The plugin capabilities are probed on the first request using editions.
+func WithSyntaxCheck() Option {
+	return func(o *options) {
+		o.checkSyntax = true
+	}
+}
+
+// syntaxFeatures returns the capabilities used by WithSyntaxCheck. The probe
+// runs directly on the module, so it does not recurse into the request
+// options.
+func (p *ProtocGenProst) syntaxFeatures(ctx context.Context) (*Features, error) {
+	p.syntaxMu.Lock()
+	defer p.syntaxMu.Unlock()
+	if p.syntax != nil {
+		return p.syntax, nil
+	}
+	input, err := proto.Marshal(probeRequest(""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal probe request: %w", err)
+	}
+	out, err := p.execute(ctx, input, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal probe response: %w", err)
+	}
+	p.syntax = newFeatures(resp)
+	return p.syntax, nil
+}
@@ -0,0 +1,140 @@
+package prost
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// syntaxRequest returns a request with one file of the given syntax holding
+// message M, modified by fn.
+func syntaxRequest(syntax string, fn func(file *descriptorpb.FileDescriptorProto, field *descriptorpb.FieldDescriptorProto)) *pluginpb.CodeGeneratorRequest {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("x"),
+		JsonName: proto.String("x"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("a/a.proto"),
+		Package:     proto.String("a"),
+		Syntax:      proto.String(syntax),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("M"), Field: []*descriptorpb.FieldDescriptorProto{field}}},
+	}
+	if fn != nil {
+		fn(file, field)
+	}
+	return &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{"a/a.proto"}, ProtoFile: []*descriptorpb.FileDescriptorProto{file}}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	editions := &Features{Editions: true, MinimumEdition: int32(descriptorpb.Edition_EDITION_PROTO2), MaximumEdition: int32(descriptorpb.Edition_EDITION_2023)}
+	edition := func(e descriptorpb.Edition) func(*descriptorpb.FileDescriptorProto, *descriptorpb.FieldDescriptorProto) {
+		return func(file *descriptorpb.FileDescriptorProto, _ *descriptorpb.FieldDescriptorProto) {
+			file.Edition = e.Enum()
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		req      *pluginpb.CodeGeneratorRequest
+		features *Features
+		want     string
+	}{
+		{name: "proto3", req: syntaxRequest("proto3", nil)},
+		{name: "proto2 group", req: syntaxRequest("proto2", func(_ *descriptorpb.FileDescriptorProto, f *descriptorpb.FieldDescriptorProto) {
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum()
+		})},
+		{name: "empty syntax", req: syntaxRequest("", nil)},
+		{name: "unknown syntax", req: syntaxRequest("proto4", nil), want: `a/a.proto: syntax "proto4": unknown syntax level`},
+		{
+			name: "editions unsupported", req: syntaxRequest("editions", edition(descriptorpb.Edition_EDITION_2023)), features: &Features{},
+			want: "a/a.proto: edition 2023: editions are not supported by the plugin",
+		},
+		{name: "edition supported", req: syntaxRequest("editions", edition(descriptorpb.Edition_EDITION_2023)), features: editions},
+		{
+			name: "edition too new", req: syntaxRequest("editions", edition(descriptorpb.Edition_EDITION_2024)), features: editions,
+			want: "a/a.proto: edition 2024: outside the range supported by the plugin, edition proto2 to edition 2023",
+		},
+		{
+			name: "edition missing", req: syntaxRequest("editions", nil), features: editions,
+			want: "a/a.proto: edition unknown: editions file without an edition",
+		},
+		{
+			name: "proto3 group", req: syntaxRequest("proto3", func(_ *descriptorpb.FileDescriptorProto, f *descriptorpb.FieldDescriptorProto) {
+				f.Type = descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum()
+			}),
+			want: "a/a.proto: group field a.M.x: not allowed in proto3",
+		},
+		{
+			name: "proto3 required", req: syntaxRequest("proto3", func(_ *descriptorpb.FileDescriptorProto, f *descriptorpb.FieldDescriptorProto) {
+				f.Label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+			}),
+			want: "a/a.proto: required field a.M.x: not allowed in proto3",
+		},
+		{
+			name: "proto3 default", req: syntaxRequest("proto3", func(_ *descriptorpb.FileDescriptorProto, f *descriptorpb.FieldDescriptorProto) {
+				f.DefaultValue = proto.String("1")
+			}),
+			want: "a/a.proto: default value a.M.x: not allowed in proto3",
+		},
+		{
+			name: "features outside editions", req: syntaxRequest("proto2", func(file *descriptorpb.FileDescriptorProto, _ *descriptorpb.FieldDescriptorProto) {
+				file.Options = &descriptorpb.FileOptions{Features: &descriptorpb.FeatureSet{}}
+			}),
+			want: "a/a.proto: features option: only allowed in editions files",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckSyntax(tc.req, tc.features)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected SyntaxError, got %v", err)
+			}
+			if err.Error() != tc.want {
+				t.Fatalf("got %q, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestProtocGenProst_WithSyntaxCheck(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r, WithSyntaxCheck())
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	// The embedded plugin panics on editions files.
+	req := syntaxRequest("editions", func(file *descriptorpb.FileDescriptorProto, _ *descriptorpb.FieldDescriptorProto) {
+		file.Edition = descriptorpb.Edition_EDITION_2023.Enum()
+	})
+	_, err = p.ExecuteRequest(ctx, req)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.File != "a/a.proto" || syntaxErr.Construct != "edition 2023" {
+		t.Fatalf("expected SyntaxError, got %v", err)
+	}
+
+	resp, err := p.ExecuteRequest(ctx, syntaxRequest("proto3", nil))
+	if err != nil {
+		t.Fatalf("ExecuteRequest failed: %v", err)
+	}
+	if len(resp.GetFile()) != 1 || !strings.Contains(resp.GetFile()[0].GetContent(), "pub struct M") {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+}
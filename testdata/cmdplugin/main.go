@@ -0,0 +1,77 @@
+// Command cmdplugin is a minimal protoc plugin built as a WASI command for
+// testing command-mode execution.
+//
+// It emits one file per file_to_generate with the parameter as content.
+// Uses protowire directly to keep the binary small.
+package main
+
+import (
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// CodeGeneratorRequest and CodeGeneratorResponse field numbers.
+const (
+	reqFileToGenerate protowire.Number = 1
+	reqParameter      protowire.Number = 2
+	respFile          protowire.Number = 15
+	fileName          protowire.Number = 1
+	fileContent       protowire.Number = 15
+)
+
+func main() {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fail(err.Error())
+	}
+
+	var files []string
+	var param string
+	for len(input) > 0 {
+		num, typ, n := protowire.ConsumeTag(input)
+		if n < 0 {
+			fail("invalid request")
+		}
+		input = input[n:]
+		if typ == protowire.BytesType && (num == reqFileToGenerate || num == reqParameter) {
+			v, n := protowire.ConsumeBytes(input)
+			if n < 0 {
+				fail("invalid request")
+			}
+			input = input[n:]
+			if num == reqFileToGenerate {
+				files = append(files, string(v))
+			} else {
+				param = string(v)
+			}
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, input)
+		if n < 0 {
+			fail("invalid request")
+		}
+		input = input[n:]
+	}
+	if param == "fail" {
+		fail("requested failure")
+	}
+
+	var out []byte
+	for _, name := range files {
+		var file []byte
+		file = protowire.AppendTag(file, fileName, protowire.BytesType)
+		file = protowire.AppendString(file, name+".txt")
+		file = protowire.AppendTag(file, fileContent, protowire.BytesType)
+		file = protowire.AppendString(file, param)
+		out = protowire.AppendTag(out, respFile, protowire.BytesType)
+		out = protowire.AppendBytes(out, file)
+	}
+	os.Stdout.Write(out)
+}
+
+func fail(msg string) {
+	os.Stderr.WriteString(msg)
+	os.Exit(2)
+}
@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// wazero formats guest traps as "wasm error: <reason>" followed by the guest
+// stack trace.
+const (
+	trapReasonPrefix = "wasm error: "
+	trapStackHeader  = "wasm stack trace:"
+)
+
+// newTrapError wraps a runtime failure of function with diagnostics: the trap
+// reason and guest stack trace reported by the runtime, and the size of mem,
+// which may be nil.
+func newTrapError(function string, err error, mem api.Memory) *TrapError {
+	e := &TrapError{Function: function, Err: err}
+	e.Reason, e.Stack = parseTrap(err.Error())
+	if mem != nil {
+		e.MemoryPages = mem.Size() / wasmPageSize
+	}
+	return e
+}
+
+// parseTrap extracts the reason and stack frames from a runtime error message.
+// Source positions, indented below their frame, are kept as separate lines.
+func parseTrap(msg string) (string, []string) {
+	head, trace, ok := strings.Cut(msg, "\n"+trapStackHeader+"\n")
+	if !ok {
+		head, trace = msg, ""
+	}
+	reason, _, _ := strings.Cut(head, "\n")
+	reason = strings.TrimPrefix(reason, trapReasonPrefix)
+
+	var stack []string
+	for _, line := range strings.Split(trace, "\n") {
+		if line == "" {
+			// A blank line ends the guest trace, the Go runtime trace of a
+			// host panic follows.
+			break
+		}
+		stack = append(stack, strings.TrimPrefix(line, "\t"))
+	}
+	return reason, stack
+}
+
+// WriteReport writes the trap diagnostics as text, as stored in the
+// FailureCrashFile of failure artifacts.
+func (e *TrapError) WriteReport(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "function: %s\n", e.Function)
+	fmt.Fprintf(&b, "reason: %s\n", e.Reason)
+	if e.MemoryPages != 0 {
+		fmt.Fprintf(&b, "memory: %d pages (%d bytes)\n", e.MemoryPages, uint64(e.MemoryPages)*wasmPageSize)
+	}
+	if len(e.Stack) != 0 {
+		b.WriteString("stack:\n")
+		for _, frame := range e.Stack {
+			fmt.Fprintf(&b, "\t%s\n", frame)
+		}
+	} else {
+		b.WriteString("stack: unavailable\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,86 @@
+package prost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aperturerobotics/go-protoc-gen-prost/internal/wasmtest"
+	"github.com/aperturerobotics/go-protoc-gen-prost/lowlevel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestParseTrap(t *testing.T) {
+	msg := "wasm error: unreachable\nwasm stack trace:\n\t.inner()\n\t  0x1a: src/lib.rs:3:5\n\t.prost_execute(i32,i32) i32\n\nGo runtime stack trace:\nmain.main()"
+	reason, stack := parseTrap(msg)
+	if reason != "unreachable" {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+	want := []string{".inner()", "  0x1a: src/lib.rs:3:5", ".prost_execute(i32,i32) i32"}
+	if !slices.Equal(stack, want) {
+		t.Fatalf("unexpected stack: %q", stack)
+	}
+	if reason, stack := parseTrap("module closed with exit_code(2)"); reason != "module closed with exit_code(2)" || stack != nil {
+		t.Fatalf("unexpected parse: %q %q", reason, stack)
+	}
+}
+
+func TestTrapError_Diagnostics(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	stub := abiStubModule(0)
+	for i := range stub.Funcs {
+		if stub.Funcs[i].Name == lowlevel.ExportExecute {
+			stub.Funcs[i].Code = wasmtest.Unreachable
+		}
+	}
+	compiled, err := r.CompileModule(ctx, stub.Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	dir := t.TempDir()
+	p, err := NewProtocGenProstWithModule(ctx, r, compiled, WithFailureArtifacts(dir))
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	_, err = p.Execute(ctx, []byte{0x0a, 0x01, 'x'})
+	var trapErr *TrapError
+	if !errors.As(err, &trapErr) {
+		t.Fatalf("expected *TrapError, got %v", err)
+	}
+	if trapErr.Function != lowlevel.ExportExecute || trapErr.Reason != "unreachable" || trapErr.MemoryPages != 1 {
+		t.Fatalf("unexpected diagnostics: %+v", trapErr)
+	}
+	// The stub has no name section, so frames are named by index
+	if len(trapErr.Stack) != 1 || !strings.Contains(trapErr.Stack[0], "(i32,i32) i32") {
+		t.Fatalf("expected the guest stack, got %q", trapErr.Stack)
+	}
+	if !strings.Contains(err.Error(), "guest memory: 1 pages") {
+		t.Fatalf("expected memory size in error: %v", err)
+	}
+
+	var report bytes.Buffer
+	if err := trapErr.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	var artErr *FailureArtifactsError
+	if !errors.As(err, &artErr) {
+		t.Fatalf("expected failure artifacts, got %v", err)
+	}
+	crash, err := os.ReadFile(filepath.Join(artErr.Dir, FailureCrashFile))
+	if err != nil || !bytes.Equal(crash, report.Bytes()) {
+		t.Fatalf("unexpected crash file %q: %v", crash, err)
+	}
+	if !strings.Contains(string(crash), "reason: unreachable\n") {
+		t.Fatalf("unexpected report:\n%s", crash)
+	}
+}
@@ -0,0 +1,261 @@
+package prost
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// SelectTypes returns a copy of req generating only the named messages, enums
+// and services and the types they use, so one API surface of a large shared
+// package can be generated without the rest. Names are fully qualified, with
+// or without the leading dot, e.g. "acme.api.v1.PetService".
+//
+// Types are removed from the files to generate only: dependencies are kept
+// as they are. A message is kept with all its fields, and a nested type keeps
+// its enclosing messages. Extensions whose extendee or type was removed are
+// removed as well, and comments are kept for the remaining elements. Files
+// to generate left without types are no longer generated. Returns an error if
+// a name is not declared in a file to generate.
+func SelectTypes(req *pluginpb.CodeGeneratorRequest, names []string) (*pluginpb.CodeGeneratorRequest, error) {
+	out := proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	generate := make(map[string]bool, len(out.GetFileToGenerate()))
+	for _, name := range out.GetFileToGenerate() {
+		generate[name] = true
+	}
+
+	// Index all types, so references into dependencies are followed too.
+	idx := &typeIndex{decls: make(map[string]typeDecl)}
+	for _, fd := range out.GetProtoFile() {
+		idx.addFile(fd, generate[fd.GetName()])
+	}
+
+	kept := make(map[string]bool)
+	for _, name := range names {
+		name = "." + strings.TrimPrefix(name, ".")
+		if d, ok := idx.decls[name]; !ok || !d.generate {
+			return nil, fmt.Errorf("unknown type %q: not declared in a file to generate", strings.TrimPrefix(name, "."))
+		}
+		idx.keep(name, kept)
+	}
+	available := func(name string) bool {
+		d, ok := idx.decls[name]
+		return !ok || !d.generate || kept[name]
+	}
+
+	out.FileToGenerate = nil
+	for _, fd := range out.GetProtoFile() {
+		if !generate[fd.GetName()] {
+			continue
+		}
+		pruneFileTypes(fd, kept, available)
+		if len(fd.GetMessageType()) != 0 || len(fd.GetEnumType()) != 0 || len(fd.GetService()) != 0 {
+			out.FileToGenerate = append(out.FileToGenerate, fd.GetName())
+		}
+	}
+	return out, nil
+}
+
+// typeDecl is a type declared in a request.
+type typeDecl struct {
+	// parent is the enclosing message, or empty for top-level types.
+	parent string
+	// generate is set if the file declaring the type is generated.
+	generate bool
+	// refs are the types the declaration uses.
+	refs []string
+}
+
+// typeIndex indexes the types of a request by fully qualified name.
+type typeIndex struct {
+	decls map[string]typeDecl
+}
+
+// addFile indexes the messages, enums and services of fd.
+func (x *typeIndex) addFile(fd *descriptorpb.FileDescriptorProto, generate bool) {
+	prefix := ""
+	if pkg := fd.GetPackage(); pkg != "" {
+		prefix = "." + pkg
+	}
+	for _, m := range fd.GetMessageType() {
+		x.addMessage(prefix, "", m, generate)
+	}
+	for _, e := range fd.GetEnumType() {
+		x.decls[prefix+"."+e.GetName()] = typeDecl{generate: generate}
+	}
+	for _, svc := range fd.GetService() {
+		var refs []string
+		for _, m := range svc.GetMethod() {
+			refs = append(refs, m.GetInputType(), m.GetOutputType())
+		}
+		x.decls[prefix+"."+svc.GetName()] = typeDecl{generate: generate, refs: refs}
+	}
+}
+
+// addMessage indexes m and its nested types.
+func (x *typeIndex) addMessage(prefix, parent string, m *descriptorpb.DescriptorProto, generate bool) {
+	name := prefix + "." + m.GetName()
+	var refs []string
+	for _, f := range m.GetField() {
+		if f.GetTypeName() != "" {
+			refs = append(refs, f.GetTypeName())
+		}
+	}
+	x.decls[name] = typeDecl{parent: parent, generate: generate, refs: refs}
+	for _, nested := range m.GetNestedType() {
+		x.addMessage(name, name, nested, generate)
+	}
+	for _, e := range m.GetEnumType() {
+		x.decls[name+"."+e.GetName()] = typeDecl{parent: name, generate: generate}
+	}
+}
+
+// keep adds name, its enclosing messages and the types they use to kept.
+func (x *typeIndex) keep(name string, kept map[string]bool) {
+	if kept[name] {
+		return
+	}
+	kept[name] = true
+	d := x.decls[name]
+	if d.parent != "" {
+		x.keep(d.parent, kept)
+	}
+	for _, ref := range d.refs {
+		x.keep(ref, kept)
+	}
+}
+
+// pruneFileTypes removes the types of fd not in kept and the extensions
+// using unavailable types, and renumbers the source_code_info paths.
+func pruneFileTypes(fd *descriptorpb.FileDescriptorProto, kept map[string]bool, available func(string) bool) {
+	prefix := ""
+	if pkg := fd.GetPackage(); pkg != "" {
+		prefix = "." + pkg
+	}
+	paths := make(map[string][]int32)
+	fd.MessageType = pruneMessages(fd.GetMessageType(), prefix, []int32{fileMessageTag}, []int32{fileMessageTag}, kept, available, paths)
+	fd.EnumType = pruneElems(fd.GetEnumType(), []int32{fileEnumTag}, []int32{fileEnumTag}, paths, func(e *descriptorpb.EnumDescriptorProto) bool {
+		return kept[prefix+"."+e.GetName()]
+	})
+	fd.Service = pruneElems(fd.GetService(), []int32{fileServiceTag}, []int32{fileServiceTag}, paths, func(svc *descriptorpb.ServiceDescriptorProto) bool {
+		return kept[prefix+"."+svc.GetName()]
+	})
+	fd.Extension = pruneElems(fd.GetExtension(), []int32{fileExtensionTag}, []int32{fileExtensionTag}, paths, func(f *descriptorpb.FieldDescriptorProto) bool {
+		return extensionAvailable(f, available)
+	})
+
+	if info := fd.GetSourceCodeInfo(); info != nil {
+		locs := info.Location[:0]
+		for _, loc := range info.GetLocation() {
+			n := elementPathLen(loc.GetPath())
+			if n == 0 {
+				locs = append(locs, loc)
+				continue
+			}
+			newPath, ok := paths[pathKey(loc.GetPath()[:n])]
+			if !ok {
+				continue
+			}
+			loc.Path = append(slices.Clone(newPath), loc.GetPath()[n:]...)
+			locs = append(locs, loc)
+		}
+		clear(info.Location[len(locs):])
+		info.Location = locs
+	}
+}
+
+// pruneMessages removes the messages not in kept from msgs declared in scope,
+// recursively, recording the new path of each remaining element. oldPath and
+// newPath are the paths of the list before and after pruning its parents.
+func pruneMessages(msgs []*descriptorpb.DescriptorProto, scope string, oldPath, newPath []int32, kept map[string]bool, available func(string) bool, paths map[string][]int32) []*descriptorpb.DescriptorProto {
+	var out []*descriptorpb.DescriptorProto
+	for i, m := range msgs {
+		name := scope + "." + m.GetName()
+		if !kept[name] {
+			continue
+		}
+		oldElem := append(slices.Clone(oldPath), int32(i))
+		newElem := append(slices.Clone(newPath), int32(len(out)))
+		paths[pathKey(oldElem)] = newElem
+		out = append(out, m)
+
+		m.NestedType = pruneMessages(m.GetNestedType(), name, append(slices.Clone(oldElem), messageNestedTag), append(slices.Clone(newElem), messageNestedTag), kept, available, paths)
+		m.EnumType = pruneElems(m.GetEnumType(), append(slices.Clone(oldElem), messageEnumTag), append(slices.Clone(newElem), messageEnumTag), paths, func(e *descriptorpb.EnumDescriptorProto) bool {
+			return kept[name+"."+e.GetName()]
+		})
+		m.Extension = pruneElems(m.GetExtension(), append(slices.Clone(oldElem), messageExtensionTag), append(slices.Clone(newElem), messageExtensionTag), paths, func(f *descriptorpb.FieldDescriptorProto) bool {
+			return extensionAvailable(f, available)
+		})
+	}
+	return out
+}
+
+// pruneElems keeps the elems matching keep, recording the new path of each
+// remaining element. oldPath and newPath are the paths of the list.
+func pruneElems[T any](elems []T, oldPath, newPath []int32, paths map[string][]int32, keep func(T) bool) []T {
+	var out []T
+	for i, e := range elems {
+		if !keep(e) {
+			continue
+		}
+		paths[pathKey(append(slices.Clone(oldPath), int32(i)))] = append(slices.Clone(newPath), int32(len(out)))
+		out = append(out, e)
+	}
+	return out
+}
+
+// extensionAvailable checks if the extendee and type of an extension are
+// still declared.
+func extensionAvailable(f *descriptorpb.FieldDescriptorProto, available func(string) bool) bool {
+	return available(f.GetExtendee()) && (f.GetTypeName() == "" || available(f.GetTypeName()))
+}
+
+// Field numbers of the descriptor elements SelectTypes removes, as used in
+// source_code_info paths.
+const (
+	fileMessageTag      = 4
+	fileEnumTag         = 5
+	fileServiceTag      = 6
+	fileExtensionTag    = 7
+	messageNestedTag    = 3
+	messageEnumTag      = 4
+	messageExtensionTag = 6
+)
+
+// elementPathLen returns the length of the prefix of a source_code_info path
+// identifying the innermost message, enum, service or extension, or 0 if
+// the path does not point into one.
+func elementPathLen(path []int32) int {
+	n := 0
+	if len(path) < 2 {
+		return 0
+	}
+	switch path[0] {
+	case fileMessageTag:
+		n = 2
+	case fileEnumTag, fileServiceTag, fileExtensionTag:
+		return 2
+	default:
+		return 0
+	}
+	for len(path) >= n+2 {
+		switch path[n] {
+		case messageNestedTag:
+			n += 2
+		case messageEnumTag, messageExtensionTag:
+			return n + 2
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// pathKey returns a map key for a source_code_info path.
+func pathKey(path []int32) string {
+	return fmt.Sprint(path)
+}
@@ -0,0 +1,99 @@
+package prost
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSelectTypes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"common.proto": {Data: []byte(`syntax = "proto3"; package common; message Unused {} message Ref {}`)},
+		"acme.proto": {Data: []byte(`syntax = "proto3";
+package acme;
+import "common.proto";
+// Unused is removed.
+message Unused { Other o = 1; }
+message Other {}
+// Req is kept.
+message Req {
+  message Skipped {}
+  // Kind is kept.
+  enum Kind { KIND_UNSPECIFIED = 0; }
+  Kind kind = 1;
+  Resp.Part part = 2;
+}
+message Resp {
+  // Part is kept.
+  message Part { common.Ref ref = 1; }
+}
+service Pets { rpc Get(Req) returns (Resp); }
+service Admin { rpc Drop(Unused) returns (Other); }
+`)},
+		"empty.proto": {Data: []byte(`syntax = "proto3"; package acme; message Empty {}`)},
+	}
+	req, err := RequestFromFS(fsys, nil, []string{"acme.proto", "empty.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := SelectTypes(req, []string{"acme.Pets"})
+	if err != nil {
+		t.Fatalf("SelectTypes failed: %v", err)
+	}
+	if !slices.Equal(out.GetFileToGenerate(), []string{"acme.proto"}) {
+		t.Fatalf("unexpected files to generate: %v", out.GetFileToGenerate())
+	}
+	if _, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: out.GetProtoFile()}); err != nil {
+		t.Fatalf("expected a valid request: %v", err)
+	}
+	var fd *descriptorpb.FileDescriptorProto
+	for _, f := range out.GetProtoFile() {
+		if f.GetName() == "acme.proto" {
+			fd = f
+		}
+	}
+	var msgs []string
+	for _, m := range fd.GetMessageType() {
+		msgs = append(msgs, m.GetName())
+	}
+	if !slices.Equal(msgs, []string{"Req", "Resp"}) || len(fd.GetService()) != 1 {
+		t.Fatalf("unexpected types kept: %v, %d services", msgs, len(fd.GetService()))
+	}
+	if req := fd.GetMessageType()[0]; len(req.GetNestedType()) != 0 || len(req.GetEnumType()) != 1 {
+		t.Fatal("expected the unused nested message to be removed")
+	}
+
+	comments := make(map[string]string)
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		if loc.GetLeadingComments() != "" {
+			comments[pathKey(loc.GetPath())] = loc.GetLeadingComments()
+		}
+	}
+	want := map[string]string{
+		pathKey([]int32{4, 0}):       " Req is kept.\n",
+		pathKey([]int32{4, 0, 4, 0}): " Kind is kept.\n",
+		pathKey([]int32{4, 1, 3, 0}): " Part is kept.\n",
+	}
+	for path, comment := range want {
+		if comments[path] != comment {
+			t.Fatalf("%s: expected comment %q, got %q", path, comment, comments[path])
+		}
+	}
+	if len(comments) != len(want) {
+		t.Fatalf("unexpected comments: %v", comments)
+	}
+	if len(req.GetProtoFile()[0].GetMessageType()) == 0 {
+		t.Fatal("expected the request to be left unchanged")
+	}
+
+	if _, err := SelectTypes(req, []string{"common.Ref"}); err == nil {
+		t.Fatal("expected an error for a type of a dependency")
+	}
+	if _, err := SelectTypes(req, []string{".acme.Missing"}); err == nil {
+		t.Fatal("expected an error for an unknown type")
+	}
+}
@@ -7,7 +7,7 @@ set -euo pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 REPO="aperturerobotics/protoc-gen-prost"
 ASSET_NAME="protoc-gen-prost.wasm"
-OUTPUT_NAME="protoc-gen-prost.wasm"
+OUTPUT_NAME="protoc-gen-prost.wasm.gz"
 
 echo "Fetching latest release from $REPO..."
 
@@ -31,9 +31,28 @@ echo "Found release: $TAG"
 echo "Downloading $ASSET_NAME..."
 
 # Download the WASM file
-gh release download "$TAG" --repo "$REPO" --pattern "$ASSET_NAME" --output "$SCRIPT_DIR/$OUTPUT_NAME" --clobber
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+gh release download "$TAG" --repo "$REPO" --pattern "$ASSET_NAME" --output "$TMP_DIR/$ASSET_NAME" --clobber
 
-echo "Downloaded $OUTPUT_NAME ($(wc -c < "$SCRIPT_DIR/$OUTPUT_NAME" | tr -d ' ') bytes)"
+echo "Downloaded $ASSET_NAME ($(wc -c < "$TMP_DIR/$ASSET_NAME" | tr -d ' ') bytes)"
+
+# Compress for embedding; -n keeps the output reproducible
+gzip -9 -n -c "$TMP_DIR/$ASSET_NAME" > "$SCRIPT_DIR/embedded/$OUTPUT_NAME"
+
+echo "Compressed to embedded/$OUTPUT_NAME ($(wc -c < "$SCRIPT_DIR/embedded/$OUTPUT_NAME" | tr -d ' ') bytes)"
+
+WASM_SHA256=$(sha256sum "$TMP_DIR/$ASSET_NAME" | cut -d' ' -f1)
+
+# Record the commit the release tag points at
+UPSTREAM_COMMIT=$(gh api "repos/$REPO/commits/$TAG" --jq '.sha')
+
+# Record the self-test response digest checked by Verify
+SELF_TEST_DIGEST=$(cd "$SCRIPT_DIR" && go run ./cmd/go-prost verify -print-digest)
+
+# Keep the hand-maintained crate versions
+PROST_CRATE=$(sed -n 's/.*ProstCrateVersion = "\(.*\)"/\1/p' "$SCRIPT_DIR/version.go")
+TONIC_CRATE=$(sed -n 's/.*TonicCrateVersion = "\(.*\)"/\1/p' "$SCRIPT_DIR/version.go")
 
 # Generate version info Go file
 echo "Generating version.go..."
@@ -45,10 +64,43 @@ const (
 	// Version is the protoc-gen-prost version
 	Version = "$TAG"
 	// DownloadURL is the URL where this WASM file was downloaded from
-	DownloadURL = "https://github.com/$REPO/releases/download/$TAG/$ASSET_NAME"
+	DownloadURL = "https://github.com/$REPO/releases/download/" + Version + "/$ASSET_NAME"
+	// WASMSHA256 is the hex SHA-256 checksum of the uncompressed WASM file
+	WASMSHA256 = "$WASM_SHA256"
+	// UpstreamCommit is the protoc-gen-prost commit the release was built from, if recorded
+	UpstreamCommit = "$UPSTREAM_COMMIT"
+	// SelfTestDigest is the hex SHA-256 digest of the response to the Verify self-test request
+	SelfTestDigest = "$SELF_TEST_DIGEST"
+)
+
+// Rust crate versions compatible with the code generated by this plugin version.
+const (
+	// ProstCrateVersion is the version requirement of the prost and prost-types crates.
+	ProstCrateVersion = "$PROST_CRATE"
+	// TonicCrateVersion is the version requirement of the tonic and tonic-prost crates.
+	TonicCrateVersion = "$TONIC_CRATE"
 )
 EOF
 
 echo "Generated version.go with version $TAG"
+
+# Record the release in the embedded module, checked against Version by the
+# host tests
+cat > "$SCRIPT_DIR/embedded/version.go" << EOF
+package embedded
+
+// Version is the protoc-gen-prost release of the embedded build.
+const Version = "$TAG"
+EOF
+
+# Require the next release of the embedded module, which must be tagged as
+# embedded/$EMBEDDED_VERSION before the host module is released
+if [ -n "${EMBEDDED_VERSION:-}" ]; then
+  (cd "$SCRIPT_DIR" && go mod edit -require="github.com/aperturerobotics/go-protoc-gen-prost/embedded@$EMBEDDED_VERSION")
+  echo "Required embedded module $EMBEDDED_VERSION"
+fi
+
 echo ""
 echo "Update complete!"
+echo "Tag embedded/<version> for the new artifact and require it in go.mod"
+echo "(EMBEDDED_VERSION=<version> ./update-prost.bash) before tagging a release."
@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ValidateResponse checks that a CodeGeneratorResponse is safe to write.
+//
+// File names must be non-empty, relative, slash-separated, and must not
+// contain ".." elements. Each file may be generated only once, and every
+// insertion point must target an earlier file containing the insertion point.
+// Returns an error joining all problems found.
+func ValidateResponse(resp *pluginpb.CodeGeneratorResponse) error {
+	var errs []error
+	contents := make(map[string]string)
+	for i, f := range resp.GetFile() {
+		name := f.GetName()
+		if err := ValidateFileName(name); err != nil {
+			errs = append(errs, fmt.Errorf("file %d: %w", i, err))
+			continue
+		}
+
+		point := f.GetInsertionPoint()
+		if point == "" {
+			if _, ok := contents[name]; ok {
+				errs = append(errs, fmt.Errorf("%s: generated more than once", name))
+				continue
+			}
+			contents[name] = f.GetContent()
+			continue
+		}
+
+		target, ok := contents[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: insertion point %q targets a file not in the response", name, point))
+			continue
+		}
+		if !strings.Contains(target, "@@protoc_insertion_point("+point+")") {
+			errs = append(errs, fmt.Errorf("%s: insertion point %q not found", name, point))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateFileName checks that a generated file name is a safe relative path.
+func ValidateFileName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("empty file name")
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("%q: file name contains NUL", name)
+	case strings.Contains(name, `\`):
+		return fmt.Errorf("%q: file name contains backslash", name)
+	case path.IsAbs(name) || (len(name) >= 2 && name[1] == ':'):
+		return fmt.Errorf("%q: file name is absolute", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return fmt.Errorf("%q: file name escapes the output directory", name)
+		}
+	}
+	if path.Clean(name) != name {
+		return fmt.Errorf("%q: file name is not clean", name)
+	}
+	return nil
+}
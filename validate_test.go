@@ -0,0 +1,43 @@
+package prost
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestValidateResponse(t *testing.T) {
+	file := func(name, point, content string) *pluginpb.CodeGeneratorResponse_File {
+		f := &pluginpb.CodeGeneratorResponse_File{Name: proto.String(name), Content: proto.String(content)}
+		if point != "" {
+			f.InsertionPoint = proto.String(point)
+		}
+		return f
+	}
+
+	valid := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		file("a/a.rs", "", "// @@protoc_insertion_point(module)\n"),
+		file("a/a.rs", "module", "pub struct A;\n"),
+	}}
+	if err := ValidateResponse(valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, f := range []*pluginpb.CodeGeneratorResponse_File{
+		file("", "", ""),
+		file("/etc/passwd", "", ""),
+		file("a/../../b.rs", "", ""),
+		file(`a\b.rs`, "", ""),
+		file("C:/x.rs", "", ""),
+		file("./a.rs", "", ""),
+		file("b.rs", "module", ""),
+		file("a/a.rs", "missing", ""),
+		file("a/a.rs", "", ""),
+	} {
+		resp := &pluginpb.CodeGeneratorResponse{File: append([]*pluginpb.CodeGeneratorResponse_File{valid.File[0]}, f)}
+		if err := ValidateResponse(resp); err == nil {
+			t.Errorf("expected error for name=%q insertion_point=%q", f.GetName(), f.GetInsertionPoint())
+		}
+	}
+}
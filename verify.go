@@ -0,0 +1,142 @@
+package prost
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// VerifyError is returned by Verify if the self-test response differs from
+// the one recorded for the embedded build.
+type VerifyError struct {
+	// Want is the recorded digest, SelfTestDigest.
+	Want string
+	// Got is the digest of the response.
+	Got string
+	// Version is the version of the plugin module, if known.
+	Version string
+}
+
+// Error returns the error message.
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("self-test failed: response digest %s, want %s for protoc-gen-prost %s (plugin version %q)", e.Got, e.Want, Version, e.Version)
+}
+
+// Verify runs a canned request and compares the digest of the response with
+// SelfTestDigest, recorded when the build was embedded. This detects a
+// runtime miscompiling the module or a corrupted or mismatched artifact
+// before real workloads run; a mismatch is reported as a *VerifyError.
+//
+// The request runs directly on the module, bypassing the cache,
+// interceptors and request and response options. For builds other than the
+// embedded one, compare SelfTest with the digest recorded for that build.
+func (p *ProtocGenProst) Verify(ctx context.Context) error {
+	got, err := p.SelfTest(ctx)
+	if err != nil {
+		return err
+	}
+	if got.String() != SelfTestDigest {
+		return &VerifyError{Want: SelfTestDigest, Got: got.String(), Version: p.version}
+	}
+	return nil
+}
+
+// SelfTest runs the canned request of Verify and returns the SHA-256 digest
+// of the serialized response. Unlike RequestDigest, the digest is not salted
+// with Version, so it can be recorded before version.go is regenerated.
+func (p *ProtocGenProst) SelfTest(ctx context.Context) (Digest, error) {
+	input, err := proto.Marshal(selfTestRequest())
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to marshal self-test request: %w", err)
+	}
+	if err := p.acquire(); err != nil {
+		return Digest{}, err
+	}
+	defer p.release()
+	out, err := p.executeOnce(ctx, input, nil)
+	if err != nil {
+		return Digest{}, fmt.Errorf("self-test failed: %w", err)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(out, resp); err != nil {
+		return Digest{}, fmt.Errorf("self-test failed: invalid response: %w", err)
+	}
+	if resp.Error != nil {
+		return Digest{}, fmt.Errorf("self-test failed: %w", &PluginError{Message: resp.GetError()})
+	}
+	return Digest(sha256.Sum256(out)), nil
+}
+
+// selfTestRequest builds the request of SelfTest. It covers scalar,
+// repeated, map, enum, oneof and nested message fields. Changing it changes
+// the response digest, which must then be recorded again.
+func selfTestRequest() *pluginpb.CodeGeneratorRequest {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label,
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	oneofField := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(0)
+		return f
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"selftest/v1/selftest.proto"},
+		Parameter:      proto.String("enable_type_names"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("selftest/v1/selftest.proto"),
+			Package: proto.String("selftest.v1"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Sample"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_UINT64, ""),
+					field("name", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("payload", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+					field("scores", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_SINT32, ""),
+					field("labels", 5, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".selftest.v1.Sample.LabelsEntry"),
+					field("kind", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".selftest.v1.Sample.Kind"),
+					field("child", 7, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".selftest.v1.Sample.Child"),
+					oneofField(field("text", 8, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+					oneofField(field("ratio", 9, optional, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "")),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("LabelsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}, {
+					Name: proto.String("Child"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("flags", 1, repeated, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+					},
+				}},
+				EnumType: []*descriptorpb.EnumDescriptorProto{{
+					Name: proto.String("Kind"),
+					Value: []*descriptorpb.EnumValueDescriptorProto{
+						{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+						{Name: proto.String("KIND_PRIMARY"), Number: proto.Int32(1)},
+					},
+				}},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("value")}},
+			}},
+		}},
+	}
+}
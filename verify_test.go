@@ -0,0 +1,52 @@
+package prost
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestProtocGenProst_Verify(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// The self-test runs directly on the module.
+	intercepted := WithInterceptors(func(ctx context.Context, req *pluginpb.CodeGeneratorRequest, next ExecuteFunc) (*pluginpb.CodeGeneratorResponse, error) {
+		t.Error("interceptor called by Verify")
+		return next(ctx, req)
+	})
+	p, err := NewProtocGenProst(ctx, r, intercepted, WithDefaultParameters("skip_debug=."))
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+	if err := p.Verify(ctx); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	// A module answering with an empty response does not match.
+	r2 := wazero.NewRuntime(ctx)
+	defer r2.Close(ctx)
+	compiled, err := r2.CompileModule(ctx, abiStubModule(0).Encode())
+	if err != nil {
+		t.Fatalf("CompileModule failed: %v", err)
+	}
+	stub, err := NewProtocGenProstWithModule(ctx, r2, compiled)
+	if err != nil {
+		t.Fatalf("NewProtocGenProstWithModule failed: %v", err)
+	}
+	defer stub.Close(ctx)
+	err = stub.Verify(ctx)
+	var verifyErr *VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expected VerifyError, got %v", err)
+	}
+	if verifyErr.Want != SelfTestDigest || verifyErr.Got != Digest(sha256.Sum256(nil)).String() {
+		t.Fatalf("unexpected error: %+v", verifyErr)
+	}
+}
@@ -5,5 +5,19 @@ const (
 	// Version is the protoc-gen-prost version
 	Version = "v0.5.0-wasi"
 	// DownloadURL is the URL where this WASM file was downloaded from
-	DownloadURL = "https://github.com/aperturerobotics/protoc-gen-prost/releases/download/v0.5.0-wasi/protoc-gen-prost.wasm"
+	DownloadURL = "https://github.com/aperturerobotics/protoc-gen-prost/releases/download/" + Version + "/protoc-gen-prost.wasm"
+	// WASMSHA256 is the hex SHA-256 checksum of the uncompressed WASM file
+	WASMSHA256 = "556827c9dae4bef6d27852024b7ccf618cbe16cc5ca7dae802c84e935794fe41"
+	// UpstreamCommit is the protoc-gen-prost commit the release was built from, if recorded
+	UpstreamCommit = ""
+	// SelfTestDigest is the hex SHA-256 digest of the response to the Verify self-test request
+	SelfTestDigest = "f795c2fe6d5ce8ba07e0ab1c0c5f7eb4c8e1570d7c7ff14063120392ad5835c2"
+)
+
+// Rust crate versions compatible with the code generated by this plugin version.
+const (
+	// ProstCrateVersion is the version requirement of the prost and prost-types crates.
+	ProstCrateVersion = "0.14"
+	// TonicCrateVersion is the version requirement of the tonic and tonic-prost crates.
+	TonicCrateVersion = "0.14"
 )
@@ -0,0 +1,97 @@
+package prost
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// wellKnownFiles are the descriptors of the google/protobuf files compiled
+// into the protobuf runtime, by file name.
+var wellKnownFiles = func() map[string]protoreflect.FileDescriptor {
+	files := make(map[string]protoreflect.FileDescriptor)
+	for _, fd := range []protoreflect.FileDescriptor{
+		anypb.File_google_protobuf_any_proto,
+		apipb.File_google_protobuf_api_proto,
+		descriptorpb.File_google_protobuf_descriptor_proto,
+		durationpb.File_google_protobuf_duration_proto,
+		emptypb.File_google_protobuf_empty_proto,
+		fieldmaskpb.File_google_protobuf_field_mask_proto,
+		pluginpb.File_google_protobuf_compiler_plugin_proto,
+		sourcecontextpb.File_google_protobuf_source_context_proto,
+		structpb.File_google_protobuf_struct_proto,
+		timestamppb.File_google_protobuf_timestamp_proto,
+		typepb.File_google_protobuf_type_proto,
+		wrapperspb.File_google_protobuf_wrappers_proto,
+	} {
+		files[fd.Path()] = fd
+	}
+	return files
+}()
+
+// InjectWellKnownTypes adds the descriptors of google/protobuf files imported
+// by req but missing from proto_file, e.g. because a tool stripped them. The
+// plugin would otherwise fail to resolve the types. The descriptors come from
+// the protobuf runtime and are inserted before the first file, in dependency
+// order. Returns the names of the added files, sorted.
+//
+// Other missing imports are left alone.
+func InjectWellKnownTypes(req *pluginpb.CodeGeneratorRequest) []string {
+	present := make(map[string]bool, len(req.GetProtoFile()))
+	for _, fd := range req.GetProtoFile() {
+		present[fd.GetName()] = true
+	}
+
+	var added []*descriptorpb.FileDescriptorProto
+	var names []string
+	var add func(name string)
+	add = func(name string) {
+		fd, ok := wellKnownFiles[name]
+		if present[name] || !ok {
+			return
+		}
+		present[name] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			add(imports.Get(i).Path())
+		}
+		added = append(added, protodesc.ToFileDescriptorProto(fd))
+		names = append(names, name)
+	}
+	for _, fd := range req.GetProtoFile() {
+		for _, dep := range fd.GetDependency() {
+			if strings.HasPrefix(dep, "google/protobuf/") {
+				add(dep)
+			}
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	req.ProtoFile = append(added, req.GetProtoFile()...)
+	sort.Strings(names)
+	return names
+}
+
+// WithWellKnownTypes runs InjectWellKnownTypes on each request before
+// generation, so requests missing the well-known type descriptors they
+// import generate as if protoc had included them.
+func WithWellKnownTypes() Option {
+	return func(o *options) {
+		o.injectWKT = true
+	}
+}
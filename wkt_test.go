@@ -0,0 +1,71 @@
+package prost
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// wktStrippedRequest returns a request importing well-known types without
+// their descriptors.
+func wktStrippedRequest(t *testing.T) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	fsys := fstest.MapFS{"a.proto": {Data: []byte(`syntax = "proto3";
+package a;
+import "google/protobuf/api.proto";
+import "google/protobuf/timestamp.proto";
+message A { google.protobuf.Api api = 1; google.protobuf.Timestamp at = 2; }
+`)}}
+	req, err := RequestFromFS(fsys, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ProtoFile = slices.DeleteFunc(req.ProtoFile, func(fd *descriptorpb.FileDescriptorProto) bool {
+		return strings.HasPrefix(fd.GetName(), "google/")
+	})
+	return req
+}
+
+func TestInjectWellKnownTypes(t *testing.T) {
+	req := wktStrippedRequest(t)
+	added := InjectWellKnownTypes(req)
+	want := []string{
+		"google/protobuf/any.proto",
+		"google/protobuf/api.proto",
+		"google/protobuf/source_context.proto",
+		"google/protobuf/timestamp.proto",
+		"google/protobuf/type.proto",
+	}
+	if !slices.Equal(added, want) {
+		t.Fatalf("unexpected files added: %v", added)
+	}
+	if _, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: req.GetProtoFile()}); err != nil {
+		t.Fatalf("expected a valid request in dependency order: %v", err)
+	}
+	if added := InjectWellKnownTypes(req); added != nil {
+		t.Fatalf("expected nothing to add, got %v", added)
+	}
+}
+
+func TestProtocGenProst_WithWellKnownTypes(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	p, err := NewProtocGenProst(ctx, r, WithWellKnownTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	resp, err := p.ExecuteRequest(ctx, wktStrippedRequest(t))
+	if err != nil || resp.GetError() != "" || len(resp.GetFile()) == 0 {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+}
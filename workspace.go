@@ -0,0 +1,166 @@
+package prost
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// WorkspaceConfig describes a Cargo workspace of generated crates.
+type WorkspaceConfig struct {
+	// Dir is the directory of the crates relative to the workspace root.
+	// Defaults to "crates".
+	Dir string
+	// Version is the version shared by all crates. Defaults to "0.1.0".
+	Version string
+	// Edition is the Rust edition shared by all crates. Defaults to "2021".
+	Edition string
+	// License is the SPDX license expression shared by all crates, if set.
+	License string
+	// Tonic adds the tonic dependencies to all crates.
+	Tonic bool
+	// README adds a README.md stub to each crate.
+	README bool
+}
+
+// GenerateWorkspace generates one crate per crate of rules and a root
+// Cargo.toml tying them together in a workspace.
+//
+// The files to generate in req are grouped by crate and each group is run
+// separately with extern_path parameters pointing at the other crates, so
+// references across crates resolve. Crates referencing another crate depend
+// on it by path, and all registry dependency versions are declared once in
+// the workspace. Returns an error if a file to generate matches no rule.
+//
+// The returned response can be written to the workspace root with
+// WriteResponse.
+func GenerateWorkspace(ctx context.Context, e Executor, req *pluginpb.CodeGeneratorRequest, rules CrateRules, cfg *WorkspaceConfig) (*pluginpb.CodeGeneratorResponse, error) {
+	if cfg == nil {
+		cfg = &WorkspaceConfig{}
+	}
+	params, err := ParseParams(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the files to generate by crate
+	filesByCrate := make(map[string][]string)
+	packageCrates := make(map[string]string)
+	for _, fd := range FilesToGenerate(req) {
+		crate, ok := rules.CrateFor(fd.GetPackage())
+		if !ok {
+			return nil, fmt.Errorf("%s: package %q matches no crate rule", fd.GetName(), fd.GetPackage())
+		}
+		filesByCrate[crate] = append(filesByCrate[crate], fd.GetName())
+		packageCrates[fd.GetPackage()] = crate
+	}
+	crates := make([]string, 0, len(filesByCrate))
+	for crate := range filesByCrate {
+		crates = append(crates, crate)
+	}
+	sort.Strings(crates)
+
+	byName := FilesByName(req)
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "crates"
+	}
+	out := &pluginpb.CodeGeneratorResponse{}
+	var wkt bool
+	for _, crate := range crates {
+		sub := proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+		sub.FileToGenerate = filesByCrate[crate]
+		subParams := *params
+		subParams.ExternPaths = append(append([]PathValue(nil), params.ExternPaths...), rules.ExternPaths(req, crate)...)
+		sub.Parameter = proto.String(subParams.String())
+
+		resp, err := e.ExecuteRequest(ctx, sub)
+		if err != nil {
+			return nil, fmt.Errorf("crate %s: %w", crate, err)
+		}
+		crateResp, err := BuildCrate(resp, sub, &CrateConfig{
+			Name:         crate,
+			License:      cfg.License,
+			Tonic:        cfg.Tonic,
+			README:       cfg.README,
+			Dependencies: importedCrates(sub.FileToGenerate, byName, packageCrates, crate),
+			Workspace:    true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("crate %s: %w", crate, err)
+		}
+		wkt = wkt || usesWellKnownTypes(sub)
+		out.SupportedFeatures = crateResp.SupportedFeatures
+		for _, f := range crateResp.GetFile() {
+			f.Name = proto.String(path.Join(dir, crate, f.GetName()))
+			out.File = append(out.File, f)
+		}
+	}
+
+	out.File = append(out.File, &pluginpb.CodeGeneratorResponse_File{
+		Name:    proto.String("Cargo.toml"),
+		Content: proto.String(cfg.cargoToml(dir, crates, wkt)),
+	})
+	return out, nil
+}
+
+// importedCrates returns the other crates generating packages imported by
+// files, sorted by name.
+func importedCrates(files []string, byName map[string]*descriptorpb.FileDescriptorProto, packageCrates map[string]string, crate string) []string {
+	seen := make(map[string]bool)
+	var deps []string
+	for _, name := range files {
+		for _, dep := range byName[name].GetDependency() {
+			other, ok := packageCrates[byName[dep].GetPackage()]
+			if !ok || other == crate || seen[other] {
+				continue
+			}
+			seen[other] = true
+			deps = append(deps, other)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// cargoToml renders the workspace manifest.
+func (c *WorkspaceConfig) cargoToml(dir string, crates []string, wkt bool) string {
+	version, edition := c.Version, c.Edition
+	if version == "" {
+		version = "0.1.0"
+	}
+	if edition == "" {
+		edition = "2021"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[workspace]\n")
+	sb.WriteString("resolver = \"2\"\n")
+	sb.WriteString("members = [\n")
+	for _, crate := range crates {
+		fmt.Fprintf(&sb, "    %s,\n", tomlString(path.Join(dir, crate)))
+	}
+	sb.WriteString("]\n")
+
+	sb.WriteString("\n[workspace.package]\n")
+	fmt.Fprintf(&sb, "version = %s\n", tomlString(version))
+	fmt.Fprintf(&sb, "edition = %s\n", tomlString(edition))
+	if c.License != "" {
+		fmt.Fprintf(&sb, "license = %s\n", tomlString(c.License))
+	}
+
+	sb.WriteString("\n[workspace.dependencies]\n")
+	for _, dep := range crateDependencies(wkt, c.Tonic) {
+		fmt.Fprintf(&sb, "%s = %s\n", dep.name, dep.spec)
+	}
+	for _, crate := range crates {
+		fmt.Fprintf(&sb, "%s = { path = %s, version = %s }\n", crate, tomlString(path.Join(dir, crate)), tomlString(version))
+	}
+	return sb.String()
+}
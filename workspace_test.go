@@ -0,0 +1,93 @@
+package prost
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestGenerateWorkspace(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	p, err := NewProtocGenProst(ctx, r)
+	if err != nil {
+		t.Fatalf("NewProtocGenProst failed: %v", err)
+	}
+	defer p.Close(ctx)
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"common.proto", "invoice.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("common.proto"),
+				Package:     proto.String("acme.common"),
+				Syntax:      proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Money")}},
+			},
+			{
+				Name:       proto.String("invoice.proto"),
+				Package:    proto.String("acme.billing"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"common.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Invoice"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("total"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".acme.common.Money"),
+						JsonName: proto.String("total"),
+					}},
+				}},
+			},
+		},
+	}
+	rules := CrateRules{
+		{Package: "acme.billing", Crate: "billing-proto"},
+		{Package: "acme.common", Crate: "common-proto"},
+	}
+
+	resp, err := GenerateWorkspace(ctx, p, req, rules, &WorkspaceConfig{License: "MIT"})
+	if err != nil {
+		t.Fatalf("GenerateWorkspace failed: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range resp.GetFile() {
+		files[f.GetName()] = f.GetContent()
+	}
+
+	root := files["Cargo.toml"]
+	for _, want := range []string{
+		`"crates/billing-proto",`,
+		`license = "MIT"`,
+		`prost = "` + ProstCrateVersion + `"`,
+		`common-proto = { path = "crates/common-proto", version = "0.1.0" }`,
+	} {
+		if !strings.Contains(root, want) {
+			t.Errorf("workspace Cargo.toml missing %q:\n%s", want, root)
+		}
+	}
+
+	billing := files["crates/billing-proto/Cargo.toml"]
+	for _, want := range []string{"version.workspace = true", "license.workspace = true", "prost.workspace = true", "common-proto.workspace = true"} {
+		if !strings.Contains(billing, want) {
+			t.Errorf("crate Cargo.toml missing %q:\n%s", want, billing)
+		}
+	}
+	if strings.Contains(files["crates/common-proto/Cargo.toml"], "billing-proto") {
+		t.Error("common-proto should not depend on billing-proto")
+	}
+
+	invoice := files["crates/billing-proto/src/acme/billing/invoice.pb.rs"]
+	if !strings.Contains(invoice, "::common_proto::acme::common::Money") {
+		t.Fatalf("expected reference to the common crate:\n%s", invoice)
+	}
+}
@@ -0,0 +1,346 @@
+package prost
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// MarkerFilename is the name of the marker file listing generated files.
+// WriteResponse writes it to the root of the output directory.
+const MarkerFilename = ".prost-generated"
+
+// WriteOptions configures WriteResponse.
+type WriteOptions struct {
+	// RemoveStale removes files listed in the previous marker file that are
+	// not part of the response. Files not written by WriteResponse are never removed.
+	RemoveStale bool
+	// NoMarker disables writing the marker file.
+	NoMarker bool
+	// ManifestFilename is the path relative to the output directory to write
+	// a JSON Manifest to. If empty, no manifest is written.
+	ManifestFilename string
+	// RequestDigest is recorded in the manifest if set.
+	RequestDigest Digest
+	// Header is prepended as a comment to each generated file if set.
+	// See ApplyHeader and DefaultHeader.
+	Header string
+	// PathRules remaps the output path of generated files.
+	PathRules PathRules
+	// Request is the request that produced the response.
+	// Used to resolve proto packages for PathRules.
+	Request *pluginpb.CodeGeneratorRequest
+	// Filter selects the files to write if set.
+	// Names are matched before PathRules are applied.
+	Filter *FileFilter
+}
+
+// WriteResponse writes the files of a CodeGeneratorResponse into dir.
+//
+// Insertion points are applied to files earlier in the same response. Each
+// file is written to a temporary file and renamed into place, so readers never
+// observe a partially written file. Files with unchanged content are not
+// rewritten. Returns a *PluginError if the response contains an error.
+// The response is checked with ValidateResponse before anything is written.
+func WriteResponse(dir string, resp *pluginpb.CodeGeneratorResponse, opts *WriteOptions) error {
+	_, err := writeResponse(dir, resp, opts)
+	return err
+}
+
+// writeResponse implements WriteResponse, returning the files written after
+// the filter and path rules of opts are applied.
+func writeResponse(dir string, resp *pluginpb.CodeGeneratorResponse, opts *WriteOptions) ([]ResolvedFile, error) {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	files, err := prepareFiles(resp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := writeFileIfChanged(path, []byte(f.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.RemoveStale {
+		previous, err := readMarker(dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := removeStale(dir, previous, files); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ManifestFilename != "" {
+		data, err := NewManifest(opts.RequestDigest, files).Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileIfChanged(filepath.Join(dir, opts.ManifestFilename), data); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.NoMarker {
+		if err := writeMarker(dir, files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// prepareFiles resolves the files of a response and applies the filter,
+// path rules, and header of opts.
+func prepareFiles(resp *pluginpb.CodeGeneratorResponse, opts *WriteOptions) ([]ResolvedFile, error) {
+	if msg := resp.GetError(); msg != "" {
+		return nil, &PluginError{Message: msg}
+	}
+	if err := ValidateResponse(resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	files, err := ResolveFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := opts.outputPackages()
+	kept := files[:0]
+	for _, f := range files {
+		name, ok, err := opts.outputName(f.Name, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		f.Name = name
+		if comment, ok := opts.header(name); ok {
+			f.Content = comment + f.Content
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// outputPackages returns the proto packages of the output files if needed
+// by the path rules of opts.
+func (opts *WriteOptions) outputPackages() map[string]string {
+	if len(opts.PathRules) == 0 {
+		return nil
+	}
+	return OutputPackages(opts.Request)
+}
+
+// outputName applies the filter and path rules of opts to the name of a
+// generated file. Returns false if the file is filtered out.
+func (opts *WriteOptions) outputName(name string, pkgs map[string]string) (string, bool, error) {
+	if opts.Filter != nil && !opts.Filter.Match(name) {
+		return "", false, nil
+	}
+	if len(opts.PathRules) == 0 {
+		return name, true, nil
+	}
+	mapped := opts.PathRules.Map(name, pkgs[name])
+	if !filepath.IsLocal(filepath.FromSlash(mapped)) {
+		return "", false, fmt.Errorf("path rule maps %q outside of the output directory: %q", name, mapped)
+	}
+	return mapped, true, nil
+}
+
+// header returns the header comment of opts for an output file, if any.
+func (opts *WriteOptions) header(name string) (string, bool) {
+	if opts.Header == "" {
+		return "", false
+	}
+	return CommentHeader(name, opts.Header)
+}
+
+// ResolvedFile is a generated file with insertion points applied.
+type ResolvedFile struct {
+	// Name is the slash-separated path relative to the output directory.
+	Name string
+	// Content is the file content.
+	Content string
+}
+
+// ResolveFiles applies insertion points and returns the files of the response.
+// Files are returned in the order they first appear in the response.
+func ResolveFiles(resp *pluginpb.CodeGeneratorResponse) ([]ResolvedFile, error) {
+	var files []ResolvedFile
+	index := make(map[string]int)
+	for _, f := range resp.GetFile() {
+		name := f.GetName()
+		if err := ValidateFileName(name); err != nil {
+			return nil, err
+		}
+
+		if point := f.GetInsertionPoint(); point != "" {
+			i, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: insertion point %q targets a file not in the response", name, point)
+			}
+			content, err := applyInsertion(files[i].Content, point, f.GetContent())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			files[i].Content = content
+			continue
+		}
+
+		if i, ok := index[name]; ok {
+			files[i].Content = f.GetContent()
+			continue
+		}
+		index[name] = len(files)
+		files = append(files, ResolvedFile{Name: name, Content: f.GetContent()})
+	}
+	return files, nil
+}
+
+// applyInsertion inserts content before the line containing the insertion point.
+// Each inserted line is indented to match the insertion point line.
+func applyInsertion(target, point, content string) (string, error) {
+	marker := "@@protoc_insertion_point(" + point + ")"
+	idx := strings.Index(target, marker)
+	if idx < 0 {
+		return "", fmt.Errorf("insertion point %q not found", point)
+	}
+	lineStart := strings.LastIndexByte(target[:idx], '\n') + 1
+	indent := target[lineStart:idx]
+	indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+
+	var sb strings.Builder
+	sb.WriteString(target[:lineStart])
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		if line != "\n" {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(line)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(target[lineStart:])
+	return sb.String(), nil
+}
+
+// readMarker reads the file names listed in the marker file in dir.
+// Returns nil if the marker does not exist.
+func readMarker(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MarkerFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, sc.Err()
+}
+
+// writeMarker writes the marker file listing the generated files.
+func writeMarker(dir string, files []ResolvedFile) error {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Files generated by protoc-gen-prost. Do not edit.\n")
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+	return writeFileIfChanged(filepath.Join(dir, MarkerFilename), buf.Bytes())
+}
+
+// removeStale removes previously generated files that are not in files.
+// Directories left empty are removed up to dir.
+func removeStale(dir string, previous []string, files []ResolvedFile) error {
+	current := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		current[f.Name] = struct{}{}
+	}
+	for _, name := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		// Never follow marker entries outside of dir
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for parent := filepath.Dir(path); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// writeFileIfChanged atomically writes data to path unless it already has that content.
+func writeFileIfChanged(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path.
+// Parent directories are created as needed.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
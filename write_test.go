@@ -0,0 +1,67 @@
+package prost
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWriteResponse(t *testing.T) {
+	dir := t.TempDir()
+	handWritten := filepath.Join(dir, "lib.rs")
+	if err := os.WriteFile(handWritten, []byte("mod gen;\n"), 0o644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a/a.rs"), Content: proto.String("pub mod a {\n    // @@protoc_insertion_point(module)\n}\n")},
+			{Name: proto.String("b/b.rs"), Content: proto.String("// b\n")},
+			{Name: proto.String("a/a.rs"), InsertionPoint: proto.String("module"), Content: proto.String("pub struct A;\n")},
+		},
+	}
+	if err := WriteResponse(dir, resp, nil); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a", "a.rs"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	want := "pub mod a {\n    pub struct A;\n    // @@protoc_insertion_point(module)\n}\n"
+	if string(data) != want {
+		t.Fatalf("unexpected content:\n%s", data)
+	}
+
+	// Regenerate without b.rs and remove the stale file
+	resp.File = resp.File[:1]
+	if err := WriteResponse(dir, resp, &WriteOptions{RemoveStale: true}); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Fatal("expected stale file and its directory to be removed")
+	}
+	if _, err := os.Stat(handWritten); err != nil {
+		t.Fatalf("hand-written file was removed: %v", err)
+	}
+}
+
+func TestWriteResponse_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	resp := &pluginpb.CodeGeneratorResponse{Error: proto.String("invalid parameter: x")}
+	var pluginErr *PluginError
+	if err := WriteResponse(dir, resp, nil); !errors.As(err, &pluginErr) {
+		t.Fatalf("expected PluginError, got %v", err)
+	}
+
+	resp = &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{{Name: proto.String("../escape.rs")}},
+	}
+	if err := WriteResponse(dir, resp, nil); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+}